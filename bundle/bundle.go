@@ -0,0 +1,185 @@
+// Package bundle reads and writes self-contained archives of a batch and its
+// generated proofs, used to move them to air-gapped signing machines.
+package bundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"merkle-cli/models"
+	"merkle-cli/utils"
+)
+
+// ErrIntegrity is returned when a bundle's contents do not match its manifest
+var ErrIntegrity = errors.New("bundle integrity check failed")
+
+// FormatVersion is the version of the bundle layout
+const FormatVersion = 1
+
+// ManifestName is the archive entry holding the manifest
+const ManifestName = "manifest.json"
+
+// maxEntrySize bounds every archive entry so a corrupt bundle cannot exhaust memory
+const maxEntrySize = 256 << 20
+
+// Manifest describes the contents of a bundle and the parameters needed to
+// regenerate its output
+type Manifest struct {
+	FormatVersion       int                 `json:"formatVersion"`
+	CreatedAt           string              `json:"createdAt"`
+	OneSigID            uint64              `json:"oneSigId"`
+	ContractAddr        string              `json:"contractAddr,omitempty"`
+	LeafEncodingVersion uint8               `json:"leafEncodingVersion"`
+	TreeOptions         *models.TreeOptions `json:"treeOptions,omitempty"`
+	MerkleRoot          string              `json:"merkleRoot"`
+	Seed                string              `json:"seed"`
+	Expiry              uint64              `json:"expiry,omitempty"`
+	Digest              string              `json:"digest"`
+	Fingerprint         string              `json:"fingerprint"`
+	// Source is the spreadsheet and revision the batch was read from, if any
+	Source *models.BatchSource `json:"source,omitempty"`
+	Files  map[string]string   `json:"files"`
+}
+
+// Write creates a gzipped tar archive holding the files and a manifest that
+// records their SHA-256 hashes
+func Write(path string, manifest Manifest, files map[string][]byte) error {
+	manifest.FormatVersion = FormatVersion
+	if manifest.CreatedAt == "" {
+		manifest.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	}
+	manifest.Files = make(map[string]string, len(files))
+	names := make([]string, 0, len(files))
+	for name, data := range files {
+		if name == ManifestName {
+			return fmt.Errorf("%s is reserved for the manifest", ManifestName)
+		}
+		manifest.Files[name] = sha256Hex(data)
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	write := func(name string, data []byte) error {
+		header := &tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), ModTime: time.Unix(0, 0)}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		_, err := tw.Write(data)
+		return err
+	}
+
+	if err := write(ManifestName, append(manifestData, '\n')); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	for _, name := range names {
+		if err := write(name, files[name]); err != nil {
+			return fmt.Errorf("failed to write bundle: %w", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	return nil
+}
+
+// Read opens a bundle and checks that it holds exactly the files listed in
+// its manifest, each with the recorded SHA-256 hash
+func Read(path string) (*Manifest, map[string][]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open bundle: %w", err)
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read bundle: %w", err)
+	}
+	tr := tar.NewReader(gz)
+
+	entries := make(map[string][]byte)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read bundle: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			return nil, nil, fmt.Errorf("bundle entry %q is not a regular file", header.Name)
+		}
+		if _, dup := entries[header.Name]; dup {
+			return nil, nil, fmt.Errorf("bundle entry %q appears twice", header.Name)
+		}
+		if header.Size > maxEntrySize {
+			return nil, nil, fmt.Errorf("bundle entry %q is too large (%d bytes)", header.Name, header.Size)
+		}
+		data, err := io.ReadAll(io.LimitReader(tr, maxEntrySize))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read bundle entry %q: %w", header.Name, err)
+		}
+		entries[header.Name] = data
+	}
+
+	manifestData, ok := entries[ManifestName]
+	if !ok {
+		return nil, nil, fmt.Errorf("bundle has no %s", ManifestName)
+	}
+	delete(entries, ManifestName)
+
+	var manifest Manifest
+	if err := json.Unmarshal(manifestData, &manifest); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	if manifest.FormatVersion != FormatVersion {
+		return nil, nil, fmt.Errorf("%w: bundle format version %d", utils.ErrUnsupportedVersion, manifest.FormatVersion)
+	}
+
+	for name, expected := range manifest.Files {
+		data, ok := entries[name]
+		if !ok {
+			return nil, nil, fmt.Errorf("%w: bundle is missing %s", ErrIntegrity, name)
+		}
+		if actual := sha256Hex(data); actual != expected {
+			return nil, nil, fmt.Errorf("%w: %s hash mismatch: manifest %s, file %s", ErrIntegrity, name, expected, actual)
+		}
+	}
+	for name := range entries {
+		if _, ok := manifest.Files[name]; !ok {
+			return nil, nil, fmt.Errorf("%w: bundle entry %s is not listed in the manifest", ErrIntegrity, name)
+		}
+	}
+
+	return &manifest, entries, nil
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
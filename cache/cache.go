@@ -0,0 +1,86 @@
+// Package cache stores generation results in a directory, keyed by the hash
+// of everything that determines them, so unchanged inputs are not generated
+// twice.
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Dir is a cache of JSON documents stored as files of a directory
+type Dir struct {
+	path string
+}
+
+// New returns the cache stored in the directory at path
+func New(path string) *Dir {
+	return &Dir{path: path}
+}
+
+// Key hashes the parts into a cache key. Every part is length prefixed, so
+// distinct part lists never share a key.
+func Key(parts ...[]byte) string {
+	h := sha256.New()
+	var length [8]byte
+	for _, part := range parts {
+		binary.BigEndian.PutUint64(length[:], uint64(len(part)))
+		h.Write(length[:])
+		h.Write(part)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Get decodes the document stored under key into v and reports whether it was found
+func (d *Dir) Get(key string, v interface{}) (bool, error) {
+	data, err := os.ReadFile(d.file(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read cache entry: %w", err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return false, fmt.Errorf("failed to decode cache entry %s: %w", key, err)
+	}
+	return true, nil
+}
+
+// Put stores v under key. The entry is written to a temporary file and
+// renamed into place, so concurrent runs never read a partial entry.
+func (d *Dir) Put(key string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode cache entry: %w", err)
+	}
+	if err := os.MkdirAll(d.path, 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(d.path, key+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), d.file(key)); err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	return nil
+}
+
+func (d *Dir) file(key string) string {
+	return filepath.Join(d.path, key+".json")
+}
@@ -0,0 +1,241 @@
+package chain
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// Client is a minimal Ethereum JSON-RPC client
+type Client struct {
+	url        string
+	httpClient *http.Client
+	nextID     int
+}
+
+// rpcRequest is a JSON-RPC 2.0 request
+type rpcRequest struct {
+	JSONRPC string        `json:"jsonrpc"`
+	ID      int           `json:"id"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+// rpcResponse is a JSON-RPC 2.0 response
+type rpcResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  *RPCError       `json:"error"`
+}
+
+// RPCError is an error returned by the JSON-RPC endpoint
+type RPCError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("rpc error %d: %s", e.Code, e.Message)
+}
+
+// CallMsg holds the parameters of an eth_call, eth_estimateGas or eth_sendTransaction request
+type CallMsg struct {
+	From     common.Address  `json:"from"`
+	To       common.Address  `json:"to"`
+	Value    *hexutil.Big    `json:"value,omitempty"`
+	Data     hexutil.Bytes   `json:"data,omitempty"`
+	Gas      *hexutil.Uint64 `json:"gas,omitempty"`
+	GasPrice *hexutil.Big    `json:"gasPrice,omitempty"`
+}
+
+// NewClient creates a client for the JSON-RPC endpoint at url
+func NewClient(url string) *Client {
+	return &Client{
+		url:        url,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// ErrTransport is returned when an RPC request does not reach the node or gets no valid response
+var ErrTransport = errors.New("rpc transport error")
+
+// Call invokes a JSON-RPC method and decodes its result into result
+func (c *Client) Call(result interface{}, method string, params ...interface{}) error {
+	if params == nil {
+		params = []interface{}{}
+	}
+
+	c.nextID++
+	body, err := json.Marshal(rpcRequest{JSONRPC: "2.0", ID: c.nextID, Method: method, Params: params})
+	if err != nil {
+		return fmt.Errorf("failed to encode %s request: %w", method, err)
+	}
+
+	resp, err := c.httpClient.Post(c.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%w: %s request failed: %w", ErrTransport, method, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: %s request failed: HTTP %s", ErrTransport, method, resp.Status)
+	}
+
+	var rpcResp rpcResponse
+	if err := json.NewDecoder(resp.Body).Decode(&rpcResp); err != nil {
+		return fmt.Errorf("%w: failed to decode %s response: %w", ErrTransport, method, err)
+	}
+	if rpcResp.Error != nil {
+		return rpcResp.Error
+	}
+	if result == nil {
+		return nil
+	}
+
+	if err := json.Unmarshal(rpcResp.Result, result); err != nil {
+		return fmt.Errorf("failed to decode %s result: %w", method, err)
+	}
+	return nil
+}
+
+// ChainID returns the chain ID of the connected network
+func (c *Client) ChainID() (*big.Int, error) {
+	var result hexutil.Big
+	if err := c.Call(&result, "eth_chainId"); err != nil {
+		return nil, err
+	}
+	return result.ToInt(), nil
+}
+
+// PendingNonce returns the next transaction nonce for an account
+func (c *Client) PendingNonce(account common.Address) (uint64, error) {
+	var result hexutil.Uint64
+	if err := c.Call(&result, "eth_getTransactionCount", account, "pending"); err != nil {
+		return 0, err
+	}
+	return uint64(result), nil
+}
+
+// GasPrice returns the suggested legacy gas price
+func (c *Client) GasPrice() (*big.Int, error) {
+	var result hexutil.Big
+	if err := c.Call(&result, "eth_gasPrice"); err != nil {
+		return nil, err
+	}
+	return result.ToInt(), nil
+}
+
+// MaxPriorityFeePerGas returns the suggested EIP-1559 priority fee
+func (c *Client) MaxPriorityFeePerGas() (*big.Int, error) {
+	var result hexutil.Big
+	if err := c.Call(&result, "eth_maxPriorityFeePerGas"); err != nil {
+		return nil, err
+	}
+	return result.ToInt(), nil
+}
+
+// BaseFee returns the base fee of the latest block
+func (c *Client) BaseFee() (*big.Int, error) {
+	var block struct {
+		BaseFeePerGas *hexutil.Big `json:"baseFeePerGas"`
+	}
+	if err := c.Call(&block, "eth_getBlockByNumber", "latest", false); err != nil {
+		return nil, err
+	}
+	if block.BaseFeePerGas == nil {
+		return nil, fmt.Errorf("latest block has no base fee (pre-London chain?)")
+	}
+	return block.BaseFeePerGas.ToInt(), nil
+}
+
+// EstimateGas estimates the gas needed to execute msg
+func (c *Client) EstimateGas(msg CallMsg) (uint64, error) {
+	var result hexutil.Uint64
+	if err := c.Call(&result, "eth_estimateGas", msg); err != nil {
+		return 0, err
+	}
+	return uint64(result), nil
+}
+
+// Balance returns the native balance of an account at the latest block
+func (c *Client) Balance(account common.Address) (*big.Int, error) {
+	var result hexutil.Big
+	if err := c.Call(&result, "eth_getBalance", account, "latest"); err != nil {
+		return nil, err
+	}
+	return result.ToInt(), nil
+}
+
+// Code returns the code deployed at an account at the latest block
+func (c *Client) Code(account common.Address) ([]byte, error) {
+	var result hexutil.Bytes
+	if err := c.Call(&result, "eth_getCode", account, "latest"); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// StorageAt returns a storage slot of an account at the latest block
+func (c *Client) StorageAt(account common.Address, slot common.Hash) (common.Hash, error) {
+	var result common.Hash
+	if err := c.Call(&result, "eth_getStorageAt", account, slot, "latest"); err != nil {
+		return common.Hash{}, err
+	}
+	return result, nil
+}
+
+// CallContract executes msg with eth_call at the latest block and returns its return data
+func (c *Client) CallContract(msg CallMsg) ([]byte, error) {
+	var result hexutil.Bytes
+	if err := c.Call(&result, "eth_call", msg, "latest"); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// SendTransaction asks the node to send a transaction from an unlocked or impersonated account
+func (c *Client) SendTransaction(msg CallMsg) (common.Hash, error) {
+	var result common.Hash
+	if err := c.Call(&result, "eth_sendTransaction", msg); err != nil {
+		return common.Hash{}, err
+	}
+	return result, nil
+}
+
+// Deploy asks the node to send a contract creation transaction from an unlocked account
+func (c *Client) Deploy(from common.Address, code []byte) (common.Hash, error) {
+	var result common.Hash
+	msg := struct {
+		From common.Address `json:"from"`
+		Data hexutil.Bytes  `json:"data"`
+	}{from, code}
+	if err := c.Call(&result, "eth_sendTransaction", msg); err != nil {
+		return common.Hash{}, err
+	}
+	return result, nil
+}
+
+// Accounts returns the accounts the node holds unlocked keys for
+func (c *Client) Accounts() ([]common.Address, error) {
+	var result []common.Address
+	if err := c.Call(&result, "eth_accounts"); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// SendRawTransaction broadcasts a signed transaction and returns its hash
+func (c *Client) SendRawTransaction(raw []byte) (common.Hash, error) {
+	var result common.Hash
+	if err := c.Call(&result, "eth_sendRawTransaction", hexutil.Bytes(raw)); err != nil {
+		return common.Hash{}, err
+	}
+	return result, nil
+}
@@ -0,0 +1,77 @@
+package chain
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var (
+	// oneSigNonceSelector is nonce() on a OneSig contract
+	oneSigNonceSelector = crypto.Keccak256([]byte("nonce()"))[:4]
+	// oneSigRootSelector is merkleRoot() on a OneSig contract
+	oneSigRootSelector = crypto.Keccak256([]byte("merkleRoot()"))[:4]
+	// oneSigIDSelector is ONE_SIG_ID() on a OneSig contract
+	oneSigIDSelector = crypto.Keccak256([]byte("ONE_SIG_ID()"))[:4]
+	// oneSigSeedSelector is seed() on a OneSig contract
+	oneSigSeedSelector = crypto.Keccak256([]byte("seed()"))[:4]
+)
+
+// OneSigNonce returns the next nonce a OneSig contract executes. OneSig
+// executes nonces in order, so every lower nonce has been executed.
+func (c *Client) OneSigNonce(oneSig common.Address) (uint64, error) {
+	result, err := c.CallContract(CallMsg{To: oneSig, Data: oneSigNonceSelector})
+	if err != nil {
+		return 0, err
+	}
+	if len(result) != 32 {
+		return 0, fmt.Errorf("nonce() of %s returned %d bytes", oneSig.Hex(), len(result))
+	}
+	nonce := new(big.Int).SetBytes(result)
+	if !nonce.IsUint64() {
+		return 0, fmt.Errorf("nonce() of %s returned %s", oneSig.Hex(), nonce)
+	}
+	return nonce.Uint64(), nil
+}
+
+// OneSigRoot returns the Merkle root currently set on a OneSig contract
+func (c *Client) OneSigRoot(oneSig common.Address) (common.Hash, error) {
+	result, err := c.CallContract(CallMsg{To: oneSig, Data: oneSigRootSelector})
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if len(result) != 32 {
+		return common.Hash{}, fmt.Errorf("merkleRoot() of %s returned %d bytes", oneSig.Hex(), len(result))
+	}
+	return common.BytesToHash(result), nil
+}
+
+// OneSigID returns the OneSig ID a OneSig contract was deployed with
+func (c *Client) OneSigID(oneSig common.Address) (uint64, error) {
+	result, err := c.CallContract(CallMsg{To: oneSig, Data: oneSigIDSelector})
+	if err != nil {
+		return 0, err
+	}
+	if len(result) != 32 {
+		return 0, fmt.Errorf("ONE_SIG_ID() of %s returned %d bytes", oneSig.Hex(), len(result))
+	}
+	id := new(big.Int).SetBytes(result)
+	if !id.IsUint64() {
+		return 0, fmt.Errorf("ONE_SIG_ID() of %s returned %s", oneSig.Hex(), id)
+	}
+	return id.Uint64(), nil
+}
+
+// OneSigSeed returns the seed signers of a OneSig contract bind their root signatures to
+func (c *Client) OneSigSeed(oneSig common.Address) (common.Hash, error) {
+	result, err := c.CallContract(CallMsg{To: oneSig, Data: oneSigSeedSelector})
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if len(result) != 32 {
+		return common.Hash{}, fmt.Errorf("seed() of %s returned %d bytes", oneSig.Hex(), len(result))
+	}
+	return common.BytesToHash(result), nil
+}
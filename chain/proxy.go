@@ -0,0 +1,43 @@
+package chain
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var (
+	// implementationSlot is the EIP-1967 implementation slot of proxies
+	implementationSlot = common.HexToHash("0x360894a13ba1a3210667c828492db98dca3e2076cc3735a920a3ca505d382bbc")
+	// beaconSlot is the EIP-1967 beacon slot of beacon proxies
+	beaconSlot = common.HexToHash("0xa3f0ad74e5423aebfd80d3ef4346578335a9a72aeae9f8bb4a0c3a2d3d9c6d50")
+	// implementationSelector is implementation() on a beacon
+	implementationSelector = crypto.Keccak256([]byte("implementation()"))[:4]
+)
+
+// ProxyImplementation returns the implementation of an EIP-1967 proxy, read
+// from its implementation slot or from its beacon, or the zero address if the
+// account is not a proxy
+func (c *Client) ProxyImplementation(proxy common.Address) (common.Address, error) {
+	slot, err := c.StorageAt(proxy, implementationSlot)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if implementation := common.BytesToAddress(slot[:]); implementation != (common.Address{}) {
+		return implementation, nil
+	}
+
+	if slot, err = c.StorageAt(proxy, beaconSlot); err != nil {
+		return common.Address{}, err
+	}
+	beacon := common.BytesToAddress(slot[:])
+	if beacon == (common.Address{}) {
+		return common.Address{}, nil
+	}
+	result, err := c.CallContract(CallMsg{To: beacon, Data: implementationSelector})
+	if err != nil {
+		return common.Address{}, fmt.Errorf("failed to read the implementation of beacon %s: %w", beacon.Hex(), err)
+	}
+	return common.BytesToAddress(result), nil
+}
@@ -0,0 +1,110 @@
+package chain
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// receiptPollInterval is how often WaitForReceipt polls the endpoint
+const receiptPollInterval = 2 * time.Second
+
+// Log is an event log emitted by a transaction
+type Log struct {
+	Address         common.Address `json:"address"`
+	Topics          []common.Hash  `json:"topics"`
+	Data            hexutil.Bytes  `json:"data"`
+	BlockNumber     hexutil.Uint64 `json:"blockNumber"`
+	TransactionHash common.Hash    `json:"transactionHash"`
+	LogIndex        hexutil.Uint   `json:"logIndex"`
+}
+
+// Receipt is the subset of a transaction receipt used by the CLI
+type Receipt struct {
+	TransactionHash common.Hash    `json:"transactionHash"`
+	BlockNumber     hexutil.Uint64 `json:"blockNumber"`
+	Status          hexutil.Uint64 `json:"status"`
+	GasUsed         hexutil.Uint64 `json:"gasUsed"`
+	Logs            []Log          `json:"logs"`
+	// ContractAddress is the contract created by the transaction, if any
+	ContractAddress *common.Address `json:"contractAddress"`
+}
+
+// Succeeded reports whether the transaction executed without reverting
+func (r *Receipt) Succeeded() bool {
+	return r.Status == 1
+}
+
+// BlockNumber returns the number of the latest block
+func (c *Client) BlockNumber() (uint64, error) {
+	var result hexutil.Uint64
+	if err := c.Call(&result, "eth_blockNumber"); err != nil {
+		return 0, err
+	}
+	return uint64(result), nil
+}
+
+// FilterLogs returns the logs emitted by address in the inclusive block range
+// whose first topic is topic0
+func (c *Client) FilterLogs(address common.Address, topic0 common.Hash, fromBlock uint64, toBlock uint64) ([]Log, error) {
+	filter := map[string]interface{}{
+		"address":   address,
+		"topics":    []interface{}{topic0},
+		"fromBlock": hexutil.Uint64(fromBlock),
+		"toBlock":   hexutil.Uint64(toBlock),
+	}
+	var logs []Log
+	if err := c.Call(&logs, "eth_getLogs", filter); err != nil {
+		return nil, err
+	}
+	return logs, nil
+}
+
+// TransactionReceipt returns the receipt of a mined transaction, or nil if it is still pending
+func (c *Client) TransactionReceipt(hash common.Hash) (*Receipt, error) {
+	var receipt *Receipt
+	if err := c.Call(&receipt, "eth_getTransactionReceipt", hash); err != nil {
+		return nil, err
+	}
+	return receipt, nil
+}
+
+// WaitForReceipt polls until the transaction has the requested number of confirmations
+func (c *Client) WaitForReceipt(hash common.Hash, confirmations uint64, timeout time.Duration) (*Receipt, error) {
+	deadline := time.Now().Add(timeout)
+
+	for {
+		receipt, err := c.TransactionReceipt(hash)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch receipt for %s: %w", hash.Hex(), err)
+		}
+
+		if receipt != nil {
+			head, err := c.BlockNumber()
+			if err != nil {
+				return nil, fmt.Errorf("failed to fetch block number: %w", err)
+			}
+			if confirmations <= 1 || head+1 >= uint64(receipt.BlockNumber)+confirmations {
+				return receipt, nil
+			}
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for transaction %s", hash.Hex())
+		}
+		time.Sleep(receiptPollInterval)
+	}
+}
+
+// TransactionKnown reports whether the node knows about a transaction, mined or pending
+func (c *Client) TransactionKnown(hash common.Hash) (bool, error) {
+	var tx *struct {
+		Hash common.Hash `json:"hash"`
+	}
+	if err := c.Call(&tx, "eth_getTransactionByHash", hash); err != nil {
+		return false, err
+	}
+	return tx != nil, nil
+}
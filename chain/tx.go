@@ -0,0 +1,197 @@
+package chain
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// dynamicFeeTxType is the EIP-2718 type byte of EIP-1559 transactions
+const dynamicFeeTxType byte = 0x02
+
+// Transaction holds the fields of a transaction before it is signed.
+// Setting GasPrice produces an EIP-155 legacy transaction, while setting
+// MaxFeePerGas and MaxPriorityFeePerGas produces an EIP-1559 transaction.
+type Transaction struct {
+	ChainID              *big.Int
+	Nonce                uint64
+	To                   common.Address
+	Value                *big.Int
+	Data                 []byte
+	GasLimit             uint64
+	GasPrice             *big.Int
+	MaxFeePerGas         *big.Int
+	MaxPriorityFeePerGas *big.Int
+}
+
+// IsDynamicFee reports whether the transaction uses EIP-1559 fee fields
+func (tx *Transaction) IsDynamicFee() bool {
+	return tx.GasPrice == nil
+}
+
+// Validate checks that every field needed for signing is populated
+func (tx *Transaction) Validate() error {
+	if tx.ChainID == nil {
+		return fmt.Errorf("chain ID is not set")
+	}
+	if tx.GasLimit == 0 {
+		return fmt.Errorf("gas limit is not set")
+	}
+	if tx.IsDynamicFee() {
+		if tx.MaxFeePerGas == nil || tx.MaxPriorityFeePerGas == nil {
+			return fmt.Errorf("max fee per gas and max priority fee per gas must both be set")
+		}
+		if tx.MaxPriorityFeePerGas.Cmp(tx.MaxFeePerGas) > 0 {
+			return fmt.Errorf("max priority fee per gas (%s) exceeds max fee per gas (%s)", tx.MaxPriorityFeePerGas, tx.MaxFeePerGas)
+		}
+	}
+	return nil
+}
+
+// UnsignedBytes returns the payload that is hashed when signing the transaction
+func (tx *Transaction) UnsignedBytes() ([]byte, error) {
+	if err := tx.Validate(); err != nil {
+		return nil, err
+	}
+
+	if tx.IsDynamicFee() {
+		payload, err := rlp.EncodeToBytes(tx.dynamicFeeFields())
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode transaction: %w", err)
+		}
+		return append([]byte{dynamicFeeTxType}, payload...), nil
+	}
+
+	// EIP-155 replay protection appends the chain ID and two empty values
+	payload, err := rlp.EncodeToBytes(append(tx.legacyFields(), tx.ChainID, uint(0), uint(0)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode transaction: %w", err)
+	}
+	return payload, nil
+}
+
+// SigningHash returns the hash that the sender signs
+func (tx *Transaction) SigningHash() ([]byte, error) {
+	payload, err := tx.UnsignedBytes()
+	if err != nil {
+		return nil, err
+	}
+	return crypto.Keccak256(payload), nil
+}
+
+// Sign signs the transaction and returns its raw encoding, ready for eth_sendRawTransaction
+func (tx *Transaction) Sign(key *ecdsa.PrivateKey) ([]byte, error) {
+	hash, err := tx.SigningHash()
+	if err != nil {
+		return nil, err
+	}
+
+	sig, err := crypto.Sign(hash, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign transaction: %w", err)
+	}
+	r := new(big.Int).SetBytes(sig[:32])
+	s := new(big.Int).SetBytes(sig[32:64])
+	recoveryID := uint64(sig[crypto.RecoveryIDOffset])
+
+	if tx.IsDynamicFee() {
+		payload, err := rlp.EncodeToBytes(append(tx.dynamicFeeFields(), recoveryID, r, s))
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode signed transaction: %w", err)
+		}
+		return append([]byte{dynamicFeeTxType}, payload...), nil
+	}
+
+	// EIP-155: v = recoveryID + chainID * 2 + 35
+	v := new(big.Int).Mul(tx.ChainID, big.NewInt(2))
+	v.Add(v, new(big.Int).SetUint64(recoveryID+35))
+
+	payload, err := rlp.EncodeToBytes(append(tx.legacyFields(), v, r, s))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode signed transaction: %w", err)
+	}
+	return payload, nil
+}
+
+// legacyFields returns the RLP list shared by signed and unsigned legacy transactions
+func (tx *Transaction) legacyFields() []interface{} {
+	return []interface{}{tx.Nonce, tx.GasPrice, tx.GasLimit, tx.To, valueOrZero(tx.Value), tx.Data}
+}
+
+// dynamicFeeFields returns the RLP list shared by signed and unsigned EIP-1559 transactions
+func (tx *Transaction) dynamicFeeFields() []interface{} {
+	return []interface{}{
+		tx.ChainID,
+		tx.Nonce,
+		tx.MaxPriorityFeePerGas,
+		tx.MaxFeePerGas,
+		tx.GasLimit,
+		tx.To,
+		valueOrZero(tx.Value),
+		tx.Data,
+		[]interface{}{}, // empty access list
+	}
+}
+
+// valueOrZero treats a nil value as zero
+func valueOrZero(value *big.Int) *big.Int {
+	if value == nil {
+		return new(big.Int)
+	}
+	return value
+}
+
+// TransactionHash returns the hash of a raw signed transaction
+func TransactionHash(raw []byte) common.Hash {
+	return crypto.Keccak256Hash(raw)
+}
+
+// Fill populates the unset fields of tx from the network, estimating gas as sent from sender
+func (c *Client) Fill(tx *Transaction, sender common.Address, nonceSet bool) error {
+	var err error
+
+	if tx.ChainID == nil {
+		if tx.ChainID, err = c.ChainID(); err != nil {
+			return fmt.Errorf("failed to fetch chain ID: %w", err)
+		}
+	}
+
+	if !nonceSet {
+		if tx.Nonce, err = c.PendingNonce(sender); err != nil {
+			return fmt.Errorf("failed to fetch account nonce: %w", err)
+		}
+	}
+
+	if tx.IsDynamicFee() {
+		if tx.MaxPriorityFeePerGas == nil {
+			if tx.MaxPriorityFeePerGas, err = c.MaxPriorityFeePerGas(); err != nil {
+				return fmt.Errorf("failed to fetch priority fee: %w", err)
+			}
+		}
+		if tx.MaxFeePerGas == nil {
+			baseFee, err := c.BaseFee()
+			if err != nil {
+				return fmt.Errorf("failed to fetch base fee: %w", err)
+			}
+			// Leave room for the base fee to double before the transaction is included
+			tx.MaxFeePerGas = new(big.Int).Add(new(big.Int).Mul(baseFee, big.NewInt(2)), tx.MaxPriorityFeePerGas)
+		}
+	}
+
+	if tx.GasLimit == 0 {
+		msg := CallMsg{From: sender, To: tx.To, Data: tx.Data}
+		if tx.Value != nil {
+			msg.Value = (*hexutil.Big)(tx.Value)
+		}
+		if tx.GasLimit, err = c.EstimateGas(msg); err != nil {
+			return fmt.Errorf("failed to estimate gas: %w", err)
+		}
+	}
+
+	return nil
+}
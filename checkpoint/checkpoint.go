@@ -0,0 +1,141 @@
+package checkpoint
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Step records a leaf processed by an operation and the transaction that processed it
+type Step struct {
+	Leaf   string `json:"leaf"`
+	TxHash string `json:"txHash,omitempty"`
+}
+
+// State records the progress of a long running operation over a Merkle root.
+// A State with an empty path keeps progress in memory only.
+type State struct {
+	Operation  string `json:"operation"`
+	MerkleRoot string `json:"merkleRoot"`
+	Completed  []Step `json:"completed"`
+	Pending    []Step `json:"pending,omitempty"`
+
+	path string
+}
+
+// Load reads the checkpoint at path, or starts a new one if the file does not exist.
+// It fails if the file was written for a different operation or root.
+func Load(path string, operation string, merkleRoot string) (*State, error) {
+	state := &State{Operation: operation, MerkleRoot: merkleRoot, path: path}
+	if path == "" {
+		return state, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint file: %w", err)
+	}
+
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint file: %w", err)
+	}
+	if state.Operation != operation {
+		return nil, fmt.Errorf("checkpoint file was written by %q, not %q", state.Operation, operation)
+	}
+	if state.MerkleRoot != merkleRoot {
+		return nil, fmt.Errorf("checkpoint file belongs to root %s, not %s", state.MerkleRoot, merkleRoot)
+	}
+
+	return state, nil
+}
+
+// Done reports whether the leaf was already completed
+func (s *State) Done(leaf string) bool {
+	for _, step := range s.Completed {
+		if step.Leaf == leaf {
+			return true
+		}
+	}
+	return false
+}
+
+// PendingTx returns the transaction sent for a leaf that was not confirmed yet
+func (s *State) PendingTx(leaf string) string {
+	for _, step := range s.Pending {
+		if step.Leaf == leaf {
+			return step.TxHash
+		}
+	}
+	return ""
+}
+
+// MarkPending records that a transaction was sent for the leaf and saves the checkpoint
+func (s *State) MarkPending(leaf string, txHash string) error {
+	s.removePending(leaf)
+	s.Pending = append(s.Pending, Step{Leaf: leaf, TxHash: txHash})
+	return s.save()
+}
+
+// ClearPending forgets the pending transaction of a leaf and saves the checkpoint
+func (s *State) ClearPending(leaf string) error {
+	s.removePending(leaf)
+	return s.save()
+}
+
+// MarkCompleted records that the leaf was processed and saves the checkpoint
+func (s *State) MarkCompleted(leaf string, txHash string) error {
+	s.removePending(leaf)
+	s.Completed = append(s.Completed, Step{Leaf: leaf, TxHash: txHash})
+	return s.save()
+}
+
+// removePending drops the pending entry of a leaf
+func (s *State) removePending(leaf string) {
+	pending := s.Pending[:0]
+	for _, step := range s.Pending {
+		if step.Leaf != leaf {
+			pending = append(pending, step)
+		}
+	}
+	s.Pending = pending
+}
+
+// save atomically replaces the checkpoint file so an interruption never leaves it truncated
+func (s *State) save() error {
+	if s.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode checkpoint: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to write checkpoint file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write checkpoint file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write checkpoint file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write checkpoint file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to write checkpoint file: %w", err)
+	}
+	return nil
+}
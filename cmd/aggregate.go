@@ -0,0 +1,165 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"merkle-cli/chain"
+	"merkle-cli/models"
+	"merkle-cli/signer"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	aggregateRoot      string
+	aggregateSeed      string
+	aggregateThreshold int
+	aggregateSigners   []string
+	aggregateOutput    string
+	aggregateRPCURL    string
+	aggregateExpiry    expiryOptions
+)
+
+// aggregateCmd combines signature files into the payload expected by the OneSig contract
+var aggregateCmd = &cobra.Command{
+	Use:   "aggregate [signature files...]",
+	Short: "Combine signature files into a OneSig signature payload",
+	Long: `Combine signature files into a OneSig signature payload
+
+Recovers the signer of every signature file from the root digest, checks it
+against the signer set, orders the signatures by ascending signer address as
+the OneSig contract expects and prints the concatenated payload.
+
+The signer set and threshold come from the config file unless overridden with
+--signers and --threshold. Unknown signers, duplicate signatures and an unmet
+threshold are errors. Signatures from smart contract signers (e.g. a Safe) are
+checked with EIP-1271 isValidSignature through --rpc-url.
+
+Roots bound to an expiry need the same --expiry the signers signed with; a
+signature file recording another expiry is an error.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		root, seed, err := parseRootAndSeed(aggregateRoot, aggregateSeed)
+		if err != nil {
+			return err
+		}
+
+		expiry, err := aggregateExpiry.parse(0)
+		if err != nil {
+			return err
+		}
+
+		digest, err := rootDigest(root, seed, expiry)
+		if err != nil {
+			return err
+		}
+
+		files, err := readSignatureFiles(args)
+		if err != nil {
+			return err
+		}
+		for i, file := range files {
+			if file.Expiry != expiry {
+				return fmt.Errorf("signature file %s signs expiry %d, not %d", args[i], file.Expiry, expiry)
+			}
+		}
+
+		quorum, hasSignerSet, err := resolveQuorum(cmd, aggregateSigners, aggregateThreshold)
+		if err != nil {
+			return err
+		}
+		if !hasSignerSet {
+			fmt.Fprintln(os.Stderr, "Warning: no signer set configured, signatures from any address are accepted")
+		}
+
+		recovered, err := signer.Aggregate(digest, files, quorum, contractVerifier(aggregateRPCURL))
+		if err != nil {
+			return err
+		}
+
+		result := models.AggregatedSignatures{
+			MerkleRoot: fmt.Sprintf("0x%x", root),
+			Seed:       fmt.Sprintf("0x%x", seed),
+			Expiry:     expiry,
+			Digest:     fmt.Sprintf("0x%x", digest),
+			Signatures: fmt.Sprintf("0x%x", signer.Concat(recovered)),
+		}
+		for _, r := range recovered {
+			result.Signers = append(result.Signers, r.Signer.Hex())
+		}
+
+		fmt.Println("Signers:")
+		for _, s := range result.Signers {
+			fmt.Println("  " + s)
+		}
+		fmt.Println("Signatures:", result.Signatures)
+
+		if aggregateOutput != "" {
+			return writeJSON(aggregateOutput, result)
+		}
+		return nil
+	},
+}
+
+// readSignatureFiles reads signature files written by the sign command
+func readSignatureFiles(paths []string) ([]models.SignatureFile, error) {
+	var files []models.SignatureFile
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read signature file: %w", err)
+		}
+
+		var file models.SignatureFile
+		if err := json.Unmarshal(data, &file); err != nil {
+			return nil, fmt.Errorf("failed to parse signature file %s: %w", path, err)
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// contractVerifier returns an EIP-1271 verifier for the endpoint, or nil to only accept EOA signers
+func contractVerifier(rpcURL string) *signer.ContractVerifier {
+	if rpcURL == "" {
+		return nil
+	}
+	return signer.NewContractVerifier(chain.NewClient(rpcURL))
+}
+
+// readAggregatedSignatures reads an aggregated signatures file, checking that
+// it approves the expected root
+func readAggregatedSignatures(path string, root []byte) (*models.AggregatedSignatures, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signatures file: %w", err)
+	}
+
+	var aggregated models.AggregatedSignatures
+	if err := json.Unmarshal(data, &aggregated); err != nil {
+		return nil, fmt.Errorf("failed to parse signatures file: %w", err)
+	}
+	if !strings.EqualFold(aggregated.MerkleRoot, fmt.Sprintf("0x%x", root)) {
+		return nil, fmt.Errorf("signatures file approves root %s, not 0x%x", aggregated.MerkleRoot, root)
+	}
+
+	return &aggregated, nil
+}
+
+func init() {
+	rootCmd.AddCommand(aggregateCmd)
+
+	aggregateCmd.Flags().StringVarP(&aggregateRoot, "root", "r", "", "Merkle root the signatures approve")
+	aggregateCmd.MarkFlagRequired("root")
+
+	aggregateCmd.Flags().StringVar(&aggregateSeed, "seed", "", "OneSig seed (defaults to zero)")
+	aggregateCmd.Flags().IntVar(&aggregateThreshold, "threshold", 1, "Number of distinct signers required (overrides the config signer set)")
+	aggregateCmd.Flags().StringSliceVar(&aggregateSigners, "signers", nil, "Comma separated addresses of the allowed signers (overrides the config signer set)")
+	cobra.CheckErr(aggregateCmd.RegisterFlagCompletionFunc("signers", completeSigners))
+	aggregateCmd.Flags().StringVar(&aggregateOutput, "output", "", "Write the aggregated signatures as JSON to this file")
+	aggregateCmd.Flags().StringVar(&aggregateRPCURL, "rpc-url", "", "JSON-RPC endpoint used to verify EIP-1271 contract signers")
+	aggregateExpiry.register(aggregateCmd)
+}
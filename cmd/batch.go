@@ -0,0 +1,251 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"merkle-cli/models"
+	"merkle-cli/remote"
+	"merkle-cli/sheet"
+	"merkle-cli/utils"
+)
+
+// Handling of calls without data selectable with --empty-data
+const (
+	emptyDataAllow = "allow"
+	emptyDataWarn  = "warn"
+	emptyDataError = "error"
+)
+
+// Batch file formats selectable with --input-format
+const (
+	inputFormatGroups = "groups"
+	inputFormatLegacy = "legacy"
+	inputFormatXLSX   = "xlsx"
+)
+
+var (
+	// expectSHA256 is the SHA-256 the transaction batch file must have, if set
+	expectSHA256 string
+	inputFormat  string
+	// inputSheet is the sheet of XLSX batch files to read, the first when empty
+	inputSheet string
+	// allowUnknownFields accepts keys of JSON batches that are not batch fields
+	allowUnknownFields bool
+	// emptyData is how calls with missing or empty data are handled
+	emptyData string
+)
+
+// readBatchData reads a transaction batch file from a local path, an
+// https://, s3:// or gs:// URL or a gsheet:// Google Sheet, checks it against
+// --expect-sha256 and substitutes its template variables
+func readBatchData(ctx context.Context, path string) ([]byte, error) {
+	var data []byte
+	var err error
+	if sheet.IsGoogleSheet(path) {
+		data, err = readGoogleSheet(ctx, path)
+	} else if remote.IsRemote(path) {
+		data, err = remote.Read(ctx, path)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transaction batch file: %w", err)
+	}
+
+	if expectSHA256 != "" {
+		expected, err := hex.DecodeString(strings.TrimPrefix(expectSHA256, "0x"))
+		if err != nil || len(expected) != sha256.Size {
+			return nil, fmt.Errorf("--expect-sha256 must be 32 bytes of hex")
+		}
+		sum := sha256.Sum256(data)
+		if !bytes.Equal(sum[:], expected) {
+			actual := hex.EncodeToString(sum[:])
+			return nil, withExitCode(exitVerification, fmt.Errorf("transaction batch file has SHA-256 %s, expected %s", actual, expectSHA256))
+		}
+	}
+	return expandTemplate(data)
+}
+
+// parseBatch parses a transaction batch in the format selected with
+// --input-format and resolves the names given as call targets
+func parseBatch(data []byte) (*models.TransactionBatch, error) {
+	batch, err := decodeBatch(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkEmptyData(batch); err != nil {
+		return nil, err
+	}
+	if err := resolveNames(batch); err != nil {
+		return nil, err
+	}
+	return batch, nil
+}
+
+// decodeBatch decodes a transaction batch in the format selected with --input-format
+func decodeBatch(data []byte) (*models.TransactionBatch, error) {
+	switch inputFormat {
+	case inputFormatGroups:
+		var document struct {
+			models.TransactionBatch
+			Transactions json.RawMessage `json:"transactions"`
+		}
+		if err := json.Unmarshal(data, &document); err != nil {
+			return nil, fmt.Errorf("failed to parse transaction batch: %w", err)
+		}
+		if err := checkBatchFields(data, &document); err != nil {
+			return nil, err
+		}
+		version := document.FormatVersion
+		if version < 0 || version > models.BatchFormatVersion {
+			return nil, fmt.Errorf("%w: batch format version %d, this merkle-cli reads versions up to %d", utils.ErrUnsupportedVersion, version, models.BatchFormatVersion)
+		}
+		if document.Transactions != nil {
+			// Unversioned batches listing transactions are in the legacy format
+			if version != 0 || len(document.Groups) != 0 {
+				return nil, fmt.Errorf("transaction batch has both groups and transactions")
+			}
+			fmt.Fprintln(os.Stderr, "Note: reading a legacy transaction batch, convert it with the migrate command")
+			return parseLegacyBatch(data)
+		}
+		document.FormatVersion = models.BatchFormatVersion
+		return &document.TransactionBatch, nil
+	case inputFormatLegacy:
+		return parseLegacyBatch(data)
+	case inputFormatXLSX:
+		return parseXLSXBatch(data)
+	default:
+		return nil, fmt.Errorf("unsupported input format %q, expected %s, %s or %s", inputFormat, inputFormatGroups, inputFormatLegacy, inputFormatXLSX)
+	}
+}
+
+// parseLegacyBatch parses a legacy transaction list, either as an object with
+// a transactions array or as the bare array, and converts it into groups
+func parseLegacyBatch(data []byte) (*models.TransactionBatch, error) {
+	var legacy models.LegacyBatch
+	trimmed := bytes.TrimSpace(data)
+	var err error
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		err = json.Unmarshal(trimmed, &legacy.Transactions)
+	} else {
+		err = json.Unmarshal(trimmed, &legacy)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse legacy transaction batch: %w", err)
+	}
+	if trimmed[0] == '[' {
+		err = checkBatchFields(trimmed, &legacy.Transactions)
+	} else {
+		err = checkBatchFields(trimmed, &legacy)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(legacy.Transactions) == 0 {
+		return nil, fmt.Errorf("legacy transaction batch has no transactions")
+	}
+	batch := legacy.Batch()
+	batch.FormatVersion = models.BatchFormatVersion
+	return batch, nil
+}
+
+// checkEmptyData warns about or rejects calls whose data is missing or empty
+// rather than "0x", as selected with --empty-data. --strict-hex turns the
+// warnings into errors.
+func checkEmptyData(batch *models.TransactionBatch) error {
+	mode := emptyData
+	if mode == emptyDataWarn && strictHex {
+		mode = emptyDataError
+	}
+	switch mode {
+	case emptyDataAllow:
+		return nil
+	case emptyDataWarn, emptyDataError:
+	default:
+		return fmt.Errorf("unsupported --empty-data %q, expected %s, %s or %s", emptyData, emptyDataAllow, emptyDataWarn, emptyDataError)
+	}
+
+	paths := utils.EmptyDataCalls(batch)
+	if len(paths) == 0 {
+		return nil
+	}
+	if mode == emptyDataError {
+		return &utils.HexError{Field: paths[0], Reason: fmt.Sprintf("data is missing or empty, write \"0x\" for a call without calldata (%d call(s) affected)", len(paths))}
+	}
+	for _, path := range paths {
+		fmt.Fprintf(os.Stderr, "Warning: %s is missing or empty, write \"0x\" if the call has no calldata\n", path)
+	}
+	return nil
+}
+
+// checkBatchFields rejects keys of a JSON batch that are not fields of the
+// document it is decoded into, unless --allow-unknown-fields is set
+func checkBatchFields(data []byte, document interface{}) error {
+	if allowUnknownFields {
+		return nil
+	}
+	if err := utils.CheckUnknownFields(data, document); err != nil {
+		return fmt.Errorf("invalid transaction batch: %w (pass --allow-unknown-fields to ignore it)", err)
+	}
+	return nil
+}
+
+// parseXLSXBatch reads a transaction batch from the --sheet sheet of an XLSX workbook
+func parseXLSXBatch(data []byte) (*models.TransactionBatch, error) {
+	rows, err := sheet.ReadXLSX(data, inputSheet)
+	if err != nil {
+		return nil, err
+	}
+	batch, err := sheet.Batch(rows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse XLSX transaction batch: %w", err)
+	}
+	return batch, nil
+}
+
+// readGoogleSheet reads a transaction batch from a Google Sheet laid out as
+// XLSX batches are, and returns it as JSON recording the sheet revision
+func readGoogleSheet(ctx context.Context, location string) ([]byte, error) {
+	if inputFormat != inputFormatGroups {
+		return nil, fmt.Errorf("Google Sheets are read without --input-format")
+	}
+	rows, revision, err := sheet.ReadGoogleSheet(ctx, location)
+	if err != nil {
+		return nil, err
+	}
+	batch, err := sheet.Batch(rows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Google Sheet transaction batch: %w", err)
+	}
+	batch.Source = &models.BatchSource{Location: location, Revision: revision}
+	data, err := json.MarshalIndent(batch, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode batch: %w", err)
+	}
+	return append(data, '\n'), nil
+}
+
+// readBatchFile reads and parses a transaction batch file
+func readBatchFile(ctx context.Context, path string) (*models.TransactionBatch, error) {
+	data, err := readBatchData(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return parseBatch(data)
+}
+
+// requireAddressTargets rejects batches whose calls target 32 byte ids, which
+// commands checking calls against an EVM chain cannot look up
+func requireAddressTargets(batch *models.TransactionBatch, command string) error {
+	if batch.TargetFormat == models.TargetFormatBytes32 {
+		return withExitCode(exitValidation, fmt.Errorf("%s only supports batches with address targets", command))
+	}
+	return nil
+}
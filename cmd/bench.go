@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"fmt"
+	"math/rand"
+	"runtime"
+	"time"
+
+	"merkle-cli/merkle"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	benchLeaves int
+	benchRuns   int
+	benchSeed   int64
+	benchVerify bool
+)
+
+// benchCmd measures the time and allocations of building trees
+var benchCmd = &cobra.Command{
+	Use:   "bench",
+	Short: "Measure the time and allocations of building a tree",
+	Long: `Measure the time and allocations of building a tree
+
+Builds the levels of a tree of --leaves pseudo-random leaf hashes --runs times
+and reports the time, heap allocations and allocated bytes per build. Node
+hashes are allocated from one arena per build, so allocations grow with the
+number of levels rather than the number of leaves. Large levels are hashed by
+GOMAXPROCS goroutines; compare with --serial to see the speedup. The keccak
+backend is reported too; build with -tags keccak_circl to compare the
+vectorized one. --verify first checks that the backend hashes every pair of
+the leaves as go-ethereum does, and fails with status 4 otherwise.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if benchLeaves < 1 {
+			return fmt.Errorf("--leaves must be at least 1")
+		}
+		if benchRuns < 1 {
+			return fmt.Errorf("--runs must be at least 1")
+		}
+		order, err := merkle.ParsePairOrder(pairOrder)
+		if err != nil {
+			return err
+		}
+		if err := merkle.CheckArity(arity); err != nil {
+			return err
+		}
+
+		rng := rand.New(rand.NewSource(benchSeed))
+		leaves := make([][]byte, benchLeaves)
+		for i := range leaves {
+			leaves[i] = make([]byte, 32)
+			rng.Read(leaves[i])
+		}
+		leaves = merkle.SortLeaves(leaves)
+
+		if benchVerify {
+			pairs, err := merkle.CheckPairHasher(leaves)
+			if err != nil {
+				return withExitCode(exitVerification, err)
+			}
+			fmt.Printf("Verified:      %d pairs hashed as go-ethereum does\n", pairs)
+		}
+
+		var before, after runtime.MemStats
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+		started := time.Now()
+		for i := 0; i < benchRuns; i++ {
+			if _, err := merkle.BuildLevelsArity(cmd.Context(), leaves, order, arity, hashWorkers()); err != nil {
+				return err
+			}
+		}
+		elapsed := time.Since(started)
+		runtime.ReadMemStats(&after)
+
+		runs := uint64(benchRuns)
+		workers := runtime.GOMAXPROCS(0)
+		if serialHashing {
+			workers = 1
+		}
+		fmt.Printf("Leaves:        %d\n", benchLeaves)
+		fmt.Printf("Arity:         %d\n", arity)
+		fmt.Printf("Workers:       %d\n", workers)
+		fmt.Printf("Keccak:        %s\n", merkle.KeccakBackendStatus())
+		fmt.Printf("Time/build:    %s\n", elapsed/time.Duration(benchRuns))
+		fmt.Printf("Allocs/build:  %d\n", (after.Mallocs-before.Mallocs)/runs)
+		fmt.Printf("Bytes/build:   %d\n", (after.TotalAlloc-before.TotalAlloc)/runs)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(benchCmd)
+	addPairOrderFlags(benchCmd)
+
+	benchCmd.Flags().IntVar(&benchLeaves, "leaves", 1_000_000, "Number of leaves of the tree")
+	benchCmd.Flags().IntVar(&benchRuns, "runs", 5, "Number of builds to average over")
+	benchCmd.Flags().Int64Var(&benchSeed, "seed", 1, "Seed of the pseudo-random leaf hashes")
+	benchCmd.Flags().BoolVar(&benchVerify, "verify", false, "Check that the keccak backend hashes every pair as go-ethereum does before measuring")
+}
@@ -0,0 +1,301 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"merkle-cli/bundle"
+	"merkle-cli/merkle"
+	"merkle-cli/models"
+	"merkle-cli/utils"
+
+	"github.com/spf13/cobra"
+)
+
+// Files stored in a bundle next to the manifest
+const (
+	bundleBatchFile  = "batch.json"
+	bundleProofsFile = "proofs.json"
+	bundleDigestFile = "digest.txt"
+)
+
+var (
+	exportBatchFile    string
+	exportOneSigID     uint64
+	exportContractAddr string
+	exportLeafVersion  uint8
+	exportSeed         string
+	exportOutput       string
+	exportExpiry       expiryOptions
+
+	importBundle      string
+	importExtractDir  string
+	importPrintDigest bool
+)
+
+// exportBundleCmd packs a batch, its proofs and digest into a verifiable archive
+var exportBundleCmd = &cobra.Command{
+	Use:   "export-bundle",
+	Short: "Write a batch and its proofs to a self-contained bundle",
+	Long: `Write a batch and its proofs to a self-contained bundle
+
+Generates the tree for the batch and writes a gzipped tar archive holding the
+input batch, the canonical proofs output, the root digest and a manifest with
+the SHA-256 of every file. Check it on the signing machine with import-bundle.
+
+Roots bound to an expiry are exported with --expiry, which is recorded in the
+manifest and signed over by the digest.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		batchData, err := readBatchData(cmd.Context(), exportBatchFile)
+		if err != nil {
+			return err
+		}
+		if inputFormat != inputFormatGroups {
+			// Bundles always hold the current format, which import-bundle reads
+			if batchData, err = migrateBatch(batchData); err != nil {
+				return err
+			}
+		}
+		// Names are resolved now, as import-bundle runs without network access
+		if batchData, err = resolvedBatchData(batchData); err != nil {
+			return err
+		}
+
+		seed, err := parseSeedFlag(exportSeed)
+		if err != nil {
+			return err
+		}
+
+		expiry, err := exportExpiry.parse(0)
+		if err != nil {
+			return err
+		}
+
+		options, err := treeOptions()
+		if err != nil {
+			return err
+		}
+
+		manifest := bundle.Manifest{
+			OneSigID:            exportOneSigID,
+			ContractAddr:        exportContractAddr,
+			LeafEncodingVersion: exportLeafVersion,
+			TreeOptions:         &options,
+			Seed:                utils.NormalizeHex(seed),
+			Expiry:              expiry,
+		}
+		proofs, digestText, err := regenerateBundle(cmd.Context(), batchData, &manifest, models.OutputFormatVersion)
+		if err != nil {
+			return err
+		}
+
+		files := map[string][]byte{
+			bundleBatchFile:  batchData,
+			bundleProofsFile: proofs,
+			bundleDigestFile: digestText,
+		}
+		if err := bundle.Write(exportOutput, manifest, files); err != nil {
+			return err
+		}
+
+		fmt.Println("Merkle Root:", manifest.MerkleRoot)
+		fmt.Println("Digest:", manifest.Digest)
+		fmt.Println("Fingerprint:", manifest.Fingerprint)
+		if manifest.Source != nil {
+			fmt.Printf("Source: %s (revision %s)\n", manifest.Source.Location, manifest.Source.Revision)
+		}
+		fmt.Println("Bundle:", exportOutput)
+		return nil
+	},
+}
+
+// importBundleCmd verifies a bundle on an offline machine before signing
+var importBundleCmd = &cobra.Command{
+	Use:   "import-bundle",
+	Short: "Verify a bundle written by export-bundle",
+	Long: `Verify a bundle written by export-bundle
+
+Checks the SHA-256 of every file against the manifest, regenerates the tree
+from the bundled batch and checks that the root, the proofs output and the
+EIP-712 digest all match. Needs no network access.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		manifest, files, err := bundle.Read(importBundle)
+		if err != nil {
+			return err
+		}
+
+		for _, name := range []string{bundleBatchFile, bundleProofsFile, bundleDigestFile} {
+			if _, ok := files[name]; !ok {
+				return fmt.Errorf("bundle is missing %s", name)
+			}
+		}
+
+		// Bundles written before format versioning hold unversioned proofs
+		var bundled struct {
+			FormatVersion int `json:"formatVersion"`
+		}
+		if err := json.Unmarshal(files[bundleProofsFile], &bundled); err != nil {
+			return fmt.Errorf("failed to parse bundled %s: %w", bundleProofsFile, err)
+		}
+		if bundled.FormatVersion < 0 || bundled.FormatVersion > models.OutputFormatVersion {
+			return fmt.Errorf("%w: bundled output format version %d", utils.ErrUnsupportedVersion, bundled.FormatVersion)
+		}
+
+		regenerated := *manifest
+		proofs, digestText, err := regenerateBundle(cmd.Context(), files[bundleBatchFile], &regenerated, bundled.FormatVersion)
+		if err != nil {
+			return fmt.Errorf("failed to regenerate bundle: %w", err)
+		}
+
+		checks := []struct {
+			name     string
+			ok       bool
+			expected string
+			actual   string
+		}{
+			{"merkle root", strings.EqualFold(regenerated.MerkleRoot, manifest.MerkleRoot), manifest.MerkleRoot, regenerated.MerkleRoot},
+			{"digest", strings.EqualFold(regenerated.Digest, manifest.Digest), manifest.Digest, regenerated.Digest},
+			{bundleProofsFile, bytes.Equal(proofs, files[bundleProofsFile]), "bundled file", "regenerated output"},
+			{bundleDigestFile, bytes.Equal(digestText, files[bundleDigestFile]), "bundled file", "regenerated digest"},
+		}
+		for _, check := range checks {
+			if !check.ok {
+				return withExitCode(exitVerification, fmt.Errorf("bundle verification failed: %s differs (%s vs %s)", check.name, check.expected, check.actual))
+			}
+		}
+
+		fmt.Println("Bundle verified")
+		fmt.Println("Created:", manifest.CreatedAt)
+		fmt.Println("Merkle Root:", manifest.MerkleRoot)
+		fmt.Println("Seed:", manifest.Seed)
+		if manifest.Expiry != 0 {
+			fmt.Println("Expiry:", manifest.Expiry)
+		}
+		fmt.Println("Digest:", manifest.Digest)
+		fmt.Println("Fingerprint:", manifest.Fingerprint)
+		if manifest.Source != nil {
+			fmt.Printf("Source: %s (revision %s)\n", manifest.Source.Location, manifest.Source.Revision)
+		}
+
+		if importPrintDigest {
+			root, seed, err := decodeRootAndSeed(manifest)
+			if err != nil {
+				return err
+			}
+			if err := printDigest(os.Stdout, root, seed, manifest.Expiry); err != nil {
+				return err
+			}
+		}
+
+		if importExtractDir != "" {
+			if err := os.MkdirAll(importExtractDir, 0755); err != nil {
+				return fmt.Errorf("failed to create %s: %w", importExtractDir, err)
+			}
+			names := make([]string, 0, len(files))
+			for name := range files {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				path := filepath.Join(importExtractDir, filepath.Base(name))
+				if err := os.WriteFile(path, files[name], 0644); err != nil {
+					return fmt.Errorf("failed to write %s: %w", path, err)
+				}
+				fmt.Println("Extracted:", path)
+			}
+		}
+		return nil
+	},
+}
+
+// regenerateBundle builds the canonical proofs output, in the given output
+// format version, and digest text for a batch, filling the root, digest and
+// fingerprint into the manifest
+func regenerateBundle(ctx context.Context, batchData []byte, manifest *bundle.Manifest, formatVersion int) ([]byte, []byte, error) {
+	var batch models.TransactionBatch
+	if err := json.Unmarshal(batchData, &batch); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse transaction batch: %w", err)
+	}
+
+	tree, entries, err := generateTree(ctx, &batch, merkle.Params{
+		OneSigID:     manifest.OneSigID,
+		ContractAddr: manifest.ContractAddr,
+		LeafVersion:  manifest.LeafEncodingVersion,
+		Options:      manifest.TreeOptions,
+		Limits:       batchLimits(),
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	output := buildOutput(tree, manifest.LeafEncodingVersion, manifest.TreeOptions, entries, utils.HashEncodingHex)
+	output.FormatVersion = formatVersion
+	proofs, err := encodeOutput(&output, outputFormatJSON, false)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	manifest.MerkleRoot = tree.GetRootHex()
+	manifest.Source = batch.Source
+	root, seed, err := decodeRootAndSeed(manifest)
+	if err != nil {
+		return nil, nil, err
+	}
+	digest, err := rootDigest(root, seed, manifest.Expiry)
+	if err != nil {
+		return nil, nil, err
+	}
+	manifest.Digest = utils.NormalizeHex(digest)
+	manifest.Fingerprint = utils.Fingerprint(digest)
+
+	digestText := fmt.Sprintf("Merkle Root: %s\nSeed: %s\n", manifest.MerkleRoot, manifest.Seed)
+	if manifest.Expiry != 0 {
+		digestText += fmt.Sprintf("Expiry: %d\n", manifest.Expiry)
+	}
+	digestText += fmt.Sprintf("Digest: %s\nFingerprint: %s\n", manifest.Digest, manifest.Fingerprint)
+	return proofs, []byte(digestText), nil
+}
+
+// decodeRootAndSeed decodes the hex root and seed recorded in a manifest
+func decodeRootAndSeed(manifest *bundle.Manifest) ([]byte, []byte, error) {
+	root, err := utils.ParseHex(manifest.MerkleRoot, "merkleRoot", true)
+	if err != nil {
+		return nil, nil, err
+	}
+	seed, err := utils.ParseHex(manifest.Seed, "seed", true)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(root) != 32 || len(seed) != 32 {
+		return nil, nil, fmt.Errorf("manifest root and seed must be 32 bytes")
+	}
+	return root, seed, nil
+}
+
+func init() {
+	rootCmd.AddCommand(exportBundleCmd)
+	addTreeFlags(exportBundleCmd)
+	rootCmd.AddCommand(importBundleCmd)
+
+	exportBundleCmd.Flags().StringVarP(&exportBatchFile, "batch-file", "f", "", "Path or https://, s3:// or gs:// URL of the transaction batch JSON file")
+	exportBundleCmd.MarkFlagRequired("batch-file")
+	exportBundleCmd.Flags().Uint64VarP(&exportOneSigID, "onesig-id", "o", 0, "OneSig ID (typically Chain ID)")
+	exportBundleCmd.MarkFlagRequired("onesig-id")
+	exportBundleCmd.Flags().StringVarP(&exportContractAddr, "contract-addr", "c", "", "OneSig contract address (defaults to 0xdEaD if not provided)")
+	exportBundleCmd.Flags().Uint8Var(&exportLeafVersion, "leaf-version", utils.LeafEncodingVersion, "Leaf encoding version")
+	exportBundleCmd.RegisterFlagCompletionFunc("leaf-version", completeLeafVersions)
+	exportBundleCmd.Flags().StringVar(&exportSeed, "seed", "", "OneSig seed (defaults to zero)")
+	exportBundleCmd.Flags().StringVar(&exportOutput, "output", "bundle.tar.gz", "Path of the bundle to write")
+	exportExpiry.register(exportBundleCmd)
+
+	importBundleCmd.Flags().StringVarP(&importBundle, "bundle", "b", "", "Bundle written by export-bundle")
+	importBundleCmd.MarkFlagRequired("bundle")
+	importBundleCmd.Flags().StringVar(&importExtractDir, "extract-dir", "", "Write the verified files to this directory")
+	importBundleCmd.Flags().BoolVar(&importPrintDigest, "print-digest", false, "Show the root and digest as QR codes and fingerprint words")
+}
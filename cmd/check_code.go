@@ -0,0 +1,209 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"merkle-cli/chain"
+	"merkle-cli/lint"
+	"merkle-cli/models"
+	"merkle-cli/utils"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/spf13/cobra"
+)
+
+var (
+	checkCodeBatchFile string
+	checkCodeRPCURL    string
+	checkCodePin       string
+)
+
+// checkCodeCmd checks the code of every call target of a batch
+var checkCodeCmd = &cobra.Command{
+	Use:   "check-code",
+	Short: "Check that every call target has the expected code",
+	Long: `Check that every call target has the expected code
+
+Fails unless every call target has code, except for calls flagged with
+"eoa": true, which must target an account without code. Calls pinning a
+"codeHash" must find exactly that code, and calls pinning an "implementation"
+must find their proxy still pointing at it.
+
+With --pin, writes the batch with the current code hash of every contract
+target and the implementation of every EIP-1967 proxy target pinned, for the
+checks to run again before execution. execute checks pinned calls before
+submitting each leaf.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		batch, err := readBatchFile(cmd.Context(), checkCodeBatchFile)
+		if err != nil {
+			return err
+		}
+		if err := requireAddressTargets(batch, "check-code"); err != nil {
+			return err
+		}
+
+		checker := newCodeChecker(chain.NewClient(checkCodeRPCURL), true)
+		failed, checked := 0, 0
+		for g := range batch.Groups {
+			group := &batch.Groups[g]
+			for i := range group.Calls {
+				call := &group.Calls[i]
+				problem, err := checker.check(*call)
+				if err != nil {
+					return fmt.Errorf("failed to check nonce %d call %d: %w", group.Nonce, i, err)
+				}
+				checked++
+				if problem != "" {
+					failed++
+					fmt.Printf("FAILED nonce %d call %d to %s: %s\n", group.Nonce, i, call.To, problem)
+					continue
+				}
+				if checkCodePin != "" {
+					if err := checker.pin(call); err != nil {
+						return fmt.Errorf("failed to pin nonce %d call %d: %w", group.Nonce, i, err)
+					}
+				}
+			}
+		}
+		if failed > 0 {
+			return withExitCode(exitVerification, fmt.Errorf("%d of %d call(s) failed the code check", failed, checked))
+		}
+		fmt.Printf("%d call(s) checked\n", checked)
+
+		if checkCodePin != "" {
+			pinned, err := json.MarshalIndent(batch, "", "  ")
+			if err != nil {
+				return fmt.Errorf("failed to encode batch: %w", err)
+			}
+			if err := os.WriteFile(checkCodePin, append(pinned, '\n'), 0644); err != nil {
+				return fmt.Errorf("failed to write pinned batch: %w", err)
+			}
+			fmt.Println("Pinned batch:", checkCodePin)
+		}
+		return nil
+	},
+}
+
+// codeChecker checks call targets against the chain, caching what it reads
+type codeChecker struct {
+	client *chain.Client
+	// requireCode fails calls to accounts without code that are not flagged as EOA transfers
+	requireCode     bool
+	codes           map[common.Address][]byte
+	implementations map[common.Address]common.Address
+}
+
+func newCodeChecker(client *chain.Client, requireCode bool) *codeChecker {
+	return &codeChecker{
+		client:          client,
+		requireCode:     requireCode,
+		codes:           make(map[common.Address][]byte),
+		implementations: make(map[common.Address]common.Address),
+	}
+}
+
+// code returns the code of an account
+func (c *codeChecker) code(account common.Address) ([]byte, error) {
+	if code, ok := c.codes[account]; ok {
+		return code, nil
+	}
+	code, err := c.client.Code(account)
+	if err != nil {
+		return nil, err
+	}
+	c.codes[account] = code
+	return code, nil
+}
+
+// implementation returns the implementation of a proxy, or the zero address
+func (c *codeChecker) implementation(proxy common.Address) (common.Address, error) {
+	if implementation, ok := c.implementations[proxy]; ok {
+		return implementation, nil
+	}
+	implementation, err := c.client.ProxyImplementation(proxy)
+	if err != nil {
+		return common.Address{}, err
+	}
+	c.implementations[proxy] = implementation
+	return implementation, nil
+}
+
+// check returns why the target of a call does not have the expected code, or
+// an empty string if it does
+func (c *codeChecker) check(call models.Call) (string, error) {
+	if !common.IsHexAddress(call.To) {
+		return fmt.Sprintf("invalid target address %q", call.To), nil
+	}
+	to := common.HexToAddress(call.To)
+	if !call.EOA && !c.requireCode && call.CodeHash == "" && call.Implementation == "" {
+		return "", nil
+	}
+	code, err := c.code(to)
+	if err != nil {
+		return "", err
+	}
+
+	if call.EOA {
+		if len(code) > 0 {
+			return "call is flagged as a transfer to an EOA but the target has code", nil
+		}
+		return "", nil
+	}
+	if len(code) == 0 && (c.requireCode || call.CodeHash != "") {
+		return `target has no code (flag transfers to accounts without code with "eoa": true)`, nil
+	}
+	if call.CodeHash != "" {
+		pinned, err := utils.ParseHex(call.CodeHash, "codeHash", false)
+		if err != nil || len(pinned) != 32 {
+			return fmt.Sprintf("pinned codeHash %q is not 32 bytes of hex", call.CodeHash), nil
+		}
+		if actual := crypto.Keccak256(code); !bytes.Equal(actual, pinned) {
+			return fmt.Sprintf("code hash changed to 0x%x since the batch pinned 0x%x", actual, pinned), nil
+		}
+	}
+	if call.Implementation != "" {
+		implementation, err := c.implementation(to)
+		if err != nil {
+			return "", err
+		}
+		if message := lint.CheckImplementation(call.Implementation, implementation); message != "" {
+			return message, nil
+		}
+	}
+	return "", nil
+}
+
+// pin records the code hash and proxy implementation of a checked call's target
+func (c *codeChecker) pin(call *models.Call) error {
+	if call.EOA {
+		return nil
+	}
+	to := common.HexToAddress(call.To)
+	code, err := c.code(to)
+	if err != nil {
+		return err
+	}
+	call.CodeHash = fmt.Sprintf("0x%x", crypto.Keccak256(code))
+	implementation, err := c.implementation(to)
+	if err != nil {
+		return err
+	}
+	if implementation != (common.Address{}) {
+		call.Implementation = implementation.Hex()
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(checkCodeCmd)
+
+	checkCodeCmd.Flags().StringVarP(&checkCodeBatchFile, "batch-file", "f", "", "Path or https://, s3:// or gs:// URL of the transaction batch JSON file")
+	checkCodeCmd.MarkFlagRequired("batch-file")
+	checkCodeCmd.Flags().StringVar(&checkCodeRPCURL, "rpc-url", "", "JSON-RPC endpoint of the chain the calls execute on")
+	checkCodeCmd.MarkFlagRequired("rpc-url")
+	checkCodeCmd.Flags().StringVar(&checkCodePin, "pin", "", "Write the batch with the code hash and implementation of every target pinned to this file")
+}
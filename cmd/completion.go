@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"merkle-cli/lint"
+	"merkle-cli/utils"
+
+	"github.com/spf13/cobra"
+)
+
+// completionCmd prints shell completion scripts
+var completionCmd = &cobra.Command{
+	Use:   "completion [bash|zsh|fish|powershell]",
+	Short: "Generate a shell completion script",
+	Long: `Generate a shell completion script
+
+Prints the completion script for the given shell to stdout. For example:
+
+  bash:  source <(merkle-cli completion bash)
+  zsh:   merkle-cli completion zsh > "${fpath[1]}/_merkle-cli"
+  fish:  merkle-cli completion fish > ~/.config/fish/completions/merkle-cli.fish
+
+Besides commands and flags, values are completed for --leaf-version,
+--hash-encoding, --output-format, lint rule names for --disable and the signer
+addresses declared in the --config file for --signers.`,
+	Args:                  cobra.ExactValidArgs(1),
+	ValidArgs:             []string{"bash", "zsh", "fish", "powershell"},
+	DisableFlagsInUseLine: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		switch args[0] {
+		case "bash":
+			return rootCmd.GenBashCompletionV2(os.Stdout, true)
+		case "zsh":
+			return rootCmd.GenZshCompletion(os.Stdout)
+		case "fish":
+			return rootCmd.GenFishCompletion(os.Stdout, true)
+		default:
+			return rootCmd.GenPowerShellCompletionWithDesc(os.Stdout)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(completionCmd)
+}
+
+// fixedCompletion completes a flag from a fixed list of values
+func fixedCompletion(values ...string) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+		return values, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// completeLeafVersions completes --leaf-version with the supported encoding versions
+func completeLeafVersions(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	var versions []string
+	for _, v := range utils.SupportedLeafEncodingVersions() {
+		desc := "calls only"
+		switch {
+		case v >= utils.LeafEncodingVersionSalted:
+			desc = "adds validAfter/validUntil, call operations and a salt"
+		case v >= utils.LeafEncodingVersionOperation:
+			desc = "adds validAfter/validUntil and call operations"
+		case v >= utils.LeafEncodingVersionWindowed:
+			desc = "adds validAfter/validUntil"
+		}
+		versions = append(versions, fmt.Sprintf("%d\t%s", v, desc))
+	}
+	return versions, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeLintRules completes --disable with the lint rule names
+func completeLintRules(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return lint.Rules, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeSigners completes --signers with the signer set from the config file
+func completeSigners(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	cfg, err := loadConfig()
+	if err != nil || cfg.SignerSet == nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return cfg.SignerSet.Signers, cobra.ShellCompDirectiveNoFileComp
+}
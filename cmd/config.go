@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"merkle-cli/config"
+	"merkle-cli/signer"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var configFile string
+
+// loadConfig reads the file given with --config, returning an empty config if none was given
+func loadConfig() (*config.Config, error) {
+	if configFile == "" {
+		return &config.Config{}, nil
+	}
+	return config.Load(configFile)
+}
+
+// envPrefix is prepended to flag names to form their environment variables,
+// so --onesig-id can also be set with MERKLE_CLI_ONESIG_ID
+const envPrefix = "MERKLE_CLI_"
+
+// flagEnvName returns the environment variable holding a flag's default
+func flagEnvName(name string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// applyFlagDefaults sets every flag not given on the command line from its
+// environment variable, then from the flags section of the config file
+func applyFlagDefaults(cmd *cobra.Command) error {
+	var err error
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		if err != nil || f.Changed || f.Name == "help" {
+			return
+		}
+		env := flagEnvName(f.Name)
+		if value, ok := os.LookupEnv(env); ok {
+			if setErr := cmd.Flags().Set(f.Name, value); setErr != nil {
+				err = fmt.Errorf("invalid value for %s: %w", env, setErr)
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		if err != nil || f.Changed || f.Name == "help" || f.Name == "config" {
+			return
+		}
+		value, ok, valueErr := cfg.FlagValue(f.Name)
+		if valueErr != nil {
+			err = valueErr
+			return
+		}
+		if ok {
+			if setErr := cmd.Flags().Set(f.Name, value); setErr != nil {
+				err = fmt.Errorf("invalid value for %s in config file: %w", f.Name, setErr)
+			}
+		}
+	})
+	return err
+}
+
+// resolveQuorum builds the signer quorum from the --signers and --threshold flags,
+// falling back to the signer set declared in the config file
+func resolveQuorum(cmd *cobra.Command, signers []string, threshold int) (signer.Quorum, bool, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return signer.Quorum{}, false, err
+	}
+
+	set := config.SignerSet{Threshold: threshold}
+	if cfg.SignerSet != nil {
+		set = *cfg.SignerSet
+	}
+	if cmd.Flags().Changed("signers") {
+		set.Signers = signers
+	}
+	if cmd.Flags().Changed("threshold") {
+		set.Threshold = threshold
+	}
+
+	if len(set.Signers) == 0 {
+		return signer.Quorum{Threshold: set.Threshold}, false, nil
+	}
+
+	addresses, err := set.Addresses()
+	if err != nil {
+		return signer.Quorum{}, false, fmt.Errorf("invalid signer set: %w", err)
+	}
+	return signer.Quorum{Signers: addresses, Threshold: set.Threshold}, true, nil
+}
+
+// signerStrings formats addresses for output
+func signerStrings(addresses []common.Address) []string {
+	out := make([]string, 0, len(addresses))
+	for _, a := range addresses {
+		out = append(out, a.Hex())
+	}
+	return out
+}
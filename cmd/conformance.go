@@ -0,0 +1,165 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"merkle-cli/conformance"
+	"merkle-cli/signer"
+	"merkle-cli/simulation"
+	"merkle-cli/utils"
+	"merkle-cli/vectors"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/spf13/cobra"
+)
+
+var (
+	conformanceArtifact        string
+	conformanceConstructorArgs string
+	conformanceLeafVersion     uint8
+	conformanceSeed            uint64
+	conformanceCases           int
+	conformanceRPCURL          string
+	conformanceNode            string
+	conformanceNodeBinary      string
+	conformancePort            int
+	conformanceVerifyProofs    bool
+	conformanceSignerKeys      []string
+	conformanceExpiry          expiryOptions
+)
+
+// conformanceCmd compares the leaf encoder with a reference OneSig contract on a local node
+var conformanceCmd = &cobra.Command{
+	Use:   "conformance",
+	Short: "Compare the leaf encoding with a reference OneSig contract on a local node",
+	Long: `Compare the leaf encoding with a reference OneSig contract on a local node
+
+Starts a fresh anvil (or hardhat) node, deploys the OneSig contract of
+--artifact, a Foundry or Hardhat build artifact or a file of hex bytecode,
+with the ABI-encoded --constructor-args, and reads its OneSig ID.
+
+Then generates --cases pseudo-random leaves from --seed, as gen-vectors does,
+for the deployed contract and compares the hash of every leaf computed by this
+binary with the hash returned by the contract's encodeLeaf view. From leaf
+version 2, encodeLeaf takes the validity window after the nonce; from version
+3 the calls carry their operation, and odd nonces hold a delegatecall.
+
+With --verify-proofs, also builds the tree of the leaves, with their validity
+windows opened, sets its root on the contract with the signatures of the
+--signer-key keys, which must be the signers the contract was constructed with,
+and executes every leaf with its proof in nonce order from the deploying
+account, which must be allowed to execute. Before each execution, the leaf is
+submitted with a corrupted proof, which the contract must reject. The root is
+signed in the EIP-712 domain of --contract-version and bound to --expiry when
+the contract version requires one.
+
+Exits with status 4 if any leaf differs or any proof is not handled as
+expected, so a release can be certified against the exact contract it will be
+used with.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if conformanceCases < 1 {
+			return fmt.Errorf("--cases must be at least 1")
+		}
+		if conformanceVerifyProofs && len(conformanceSignerKeys) == 0 {
+			return fmt.Errorf("--verify-proofs requires the --signer-key of every signer the root needs")
+		}
+		expiry, err := conformanceExpiry.parse(0)
+		if err != nil {
+			return err
+		}
+		signers := make([]signer.Signer, 0, len(conformanceSignerKeys))
+		for _, key := range conformanceSignerKeys {
+			s, err := signer.NewKeySigner(key)
+			if err != nil {
+				return err
+			}
+			signers = append(signers, s)
+		}
+		code, err := conformance.LoadBytecode(conformanceArtifact)
+		if err != nil {
+			return err
+		}
+		var constructorArgs []byte
+		if conformanceConstructorArgs != "" {
+			if constructorArgs, err = utils.ParseHex(conformanceConstructorArgs, "--constructor-args", false); err != nil {
+				return err
+			}
+		}
+		batch, err := vectors.Batch(conformanceSeed, conformanceCases, conformanceLeafVersion)
+		if err != nil {
+			return err
+		}
+		if conformanceLeafVersion >= utils.LeafEncodingVersionOperation {
+			vectors.MarkDelegateCalls(batch)
+		}
+
+		nodeURL := conformanceRPCURL
+		if nodeURL == "" {
+			node, err := simulation.StartNode(conformanceNode, conformanceNodeBinary, "", conformancePort)
+			if err != nil {
+				return err
+			}
+			defer node.Stop()
+			nodeURL = node.URL
+		}
+
+		harness, err := conformance.Deploy(conformanceNode, nodeURL, code, constructorArgs)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Deployed %s with OneSig ID %d\n", harness.Contract.Hex(), harness.OneSigID)
+
+		mismatches, err := harness.CheckBatch(cmd.Context(), batch, conformanceLeafVersion)
+		if err != nil {
+			return err
+		}
+		for _, m := range mismatches {
+			fmt.Printf("  nonce %d: encoder %s, contract %s\n", m.Nonce, m.Expected, m.Contract)
+		}
+		if len(mismatches) > 0 {
+			return withExitCode(exitVerification, fmt.Errorf("%d of %d leaves are hashed differently by the contract", len(mismatches), conformanceCases))
+		}
+		fmt.Printf("All %d leaves (leaf version %d, seed %d) match the contract\n", conformanceCases, conformanceLeafVersion, conformanceSeed)
+		if !conformanceVerifyProofs {
+			return nil
+		}
+
+		digest := func(root []byte, seed []byte) ([]byte, error) {
+			return rootDigest(root, seed, expiry)
+		}
+		root, failures, err := harness.VerifyProofs(cmd.Context(), batch, conformanceLeafVersion, signers, digest, expiry)
+		if err != nil {
+			return err
+		}
+		fmt.Println("Merkle Root:", hexutil.Encode(root))
+		for _, f := range failures {
+			fmt.Printf("  nonce %d: %s\n", f.Nonce, f.Reason)
+		}
+		if len(failures) > 0 {
+			return withExitCode(exitVerification, fmt.Errorf("%d proof check(s) failed on the contract", len(failures)))
+		}
+		fmt.Printf("All %d proofs were accepted by the contract and their corruptions rejected\n", conformanceCases)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(conformanceCmd)
+
+	conformanceCmd.Flags().StringVar(&conformanceArtifact, "artifact", "", "Foundry or Hardhat artifact, or hex bytecode file, of the reference OneSig contract")
+	conformanceCmd.MarkFlagRequired("artifact")
+	conformanceCmd.Flags().StringVar(&conformanceConstructorArgs, "constructor-args", "", "ABI-encoded constructor arguments appended to the bytecode")
+	conformanceCmd.Flags().Uint8Var(&conformanceLeafVersion, "leaf-version", utils.LeafEncodingVersion, "Leaf encoding version of the contract")
+	conformanceCmd.Flags().Uint64Var(&conformanceSeed, "seed", 0, "Seed of the pseudo-random leaves")
+	conformanceCmd.Flags().IntVar(&conformanceCases, "cases", 256, "Number of leaves to compare")
+	conformanceCmd.Flags().StringVar(&conformanceRPCURL, "rpc-url", "", "Use an already running development node instead of spawning one")
+	conformanceCmd.Flags().StringVar(&conformanceNode, "node", "anvil", "Development node to use ("+strings.Join(simulation.NodeKinds, "|")+")")
+	conformanceCmd.Flags().StringVar(&conformanceNodeBinary, "node-binary", "", "Path to the node executable (defaults to anvil or npx)")
+	conformanceCmd.Flags().IntVar(&conformancePort, "port", 8545, "Port for the spawned node")
+	conformanceCmd.Flags().BoolVar(&conformanceVerifyProofs, "verify-proofs", false, "Also set the root of the leaves on the contract and execute every leaf with its proof")
+	conformanceCmd.Flags().StringArrayVar(&conformanceSignerKeys, "signer-key", nil, "Private key of a signer of the contract, signing the root for --verify-proofs (repeatable)")
+	conformanceExpiry.register(conformanceCmd)
+	conformanceCmd.RegisterFlagCompletionFunc("leaf-version", completeLeafVersions)
+}
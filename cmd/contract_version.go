@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"merkle-cli/profile"
+	"merkle-cli/utils"
+
+	"github.com/spf13/cobra"
+)
+
+// contractVersion is the --contract-version profile; selectedProfile is set
+// from it before a command runs
+var (
+	contractVersion string
+	selectedProfile *profile.Profile
+)
+
+// applyContractVersion sets the flags determined by the --contract-version
+// profile that were not given, and rejects those given with another value
+func applyContractVersion(cmd *cobra.Command) error {
+	if contractVersion == "" {
+		return nil
+	}
+	p, err := profile.Lookup(contractVersion)
+	if err != nil {
+		return err
+	}
+	for name, value := range p.FlagDefaults() {
+		f := cmd.Flags().Lookup(name)
+		if f == nil {
+			continue
+		}
+		if f.Changed {
+			if f.Value.String() != value {
+				return fmt.Errorf("--%s %s conflicts with --contract-version %s, which uses %s", name, f.Value.String(), p.Name, value)
+			}
+			continue
+		}
+		if err := cmd.Flags().Set(name, value); err != nil {
+			return err
+		}
+	}
+	selectedProfile = &p
+	return nil
+}
+
+// signingDomain returns the EIP-712 domain of the --contract-version, or the
+// OneSig domain without one
+func signingDomain() utils.EIP712Domain {
+	if selectedProfile != nil {
+		return selectedProfile.Domain
+	}
+	return utils.DefaultDomain
+}
+
+// checkRootExpiry checks that a root is bound to an expiry exactly when the
+// --contract-version binds roots to one
+func checkRootExpiry(expiry uint64) error {
+	if selectedProfile == nil {
+		return nil
+	}
+	return withExitCode(exitValidation, selectedProfile.CheckExpiry(expiry))
+}
+
+// rootDigest returns the EIP-712 digest of a root in the domain of the
+// --contract-version. A non-zero expiry binds the root to it.
+func rootDigest(root []byte, seed []byte, expiry uint64) ([]byte, error) {
+	if err := checkRootExpiry(expiry); err != nil {
+		return nil, err
+	}
+	return signingDomain().RootDigest(root, seed, expiry)
+}
+
+// checkProofsLeafVersion rejects a proofs file built with another leaf
+// encoding version than the --contract-version executes
+func checkProofsLeafVersion(version uint8) error {
+	if selectedProfile == nil || selectedProfile.LeafVersion == version {
+		return nil
+	}
+	matching := "no contract version executes it"
+	if names := profile.ForLeafVersion(version); len(names) > 0 {
+		matching = "use --contract-version " + strings.Join(names, " or ")
+	}
+	return withExitCode(exitValidation, fmt.Errorf("proofs file uses leaf encoding version %d, but contract version %s executes version %d; %s",
+		version, selectedProfile.Name, selectedProfile.LeafVersion, matching))
+}
+
+// contractVersionsCmd lists the contract version profiles
+var contractVersionsCmd = &cobra.Command{
+	Use:   "contract-versions",
+	Short: "List the OneSig contract versions selectable with --contract-version",
+	Long: `List the OneSig contract versions selectable with --contract-version
+
+Every OneSig contract version is a profile of the leaf encoding version, the
+EIP-712 domain of root signatures, the execute function, the root submission
+function and whether roots are bound to an expiry. Passing --contract-version
+to any command sets the flags the profile determines and rejects flags given
+with a conflicting value.
+
+Profiles are not pinned to contract artifacts: the functions listed document
+what the encoding expects. Certify a deployment with conformance before
+relying on its profile.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tLEAF\tDOMAIN\tROOT\tEXECUTE\tDESCRIPTION")
+		for _, p := range profile.Profiles {
+			root := p.RootMethod
+			if p.RootExpiry {
+				root += " (expiry)"
+			}
+			fmt.Fprintf(w, "%s\t%d\t%s %s, chain %d, %s\t%s\t%s\t%s\n", p.Name, p.LeafVersion,
+				p.Domain.Name, p.Domain.Version, p.Domain.ChainID, p.Domain.VerifyingContract.Hex(), root, p.Execute, p.Description)
+		}
+		return w.Flush()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(contractVersionsCmd)
+}
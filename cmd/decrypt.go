@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"merkle-cli/encryption"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	decryptInput    string
+	decryptIdentity string
+	decryptOutput   string
+)
+
+// decryptCmd decrypts an output file written with --encrypt-to or --encrypt-gpg
+var decryptCmd = &cobra.Command{
+	Use:   "decrypt",
+	Short: "Decrypt an encrypted proofs file",
+	Long: `Decrypt an encrypted proofs file
+
+Decrypts a file written with --encrypt-to (age) or --encrypt-gpg (gpg). age files
+need the recipient's identity file; gpg files are decrypted with the local keyring.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		data, err := os.ReadFile(decryptInput)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", decryptInput, err)
+		}
+
+		plain, err := encryption.Decrypt(data, decryptIdentity)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt %s: %w", decryptInput, err)
+		}
+
+		if decryptOutput == "" {
+			_, err = os.Stdout.Write(plain)
+			return err
+		}
+		if err := os.WriteFile(decryptOutput, plain, 0600); err != nil {
+			return fmt.Errorf("failed to write %s: %w", decryptOutput, err)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(decryptCmd)
+
+	decryptCmd.Flags().StringVarP(&decryptInput, "input", "i", "", "Encrypted file to decrypt")
+	decryptCmd.MarkFlagRequired("input")
+
+	decryptCmd.Flags().StringVar(&decryptIdentity, "identity", "", "age identity file")
+	decryptCmd.Flags().StringVar(&decryptOutput, "output", "", "Write the decrypted file here instead of stdout")
+}
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"merkle-cli/models"
+)
+
+// Color modes selectable with --color
+const (
+	colorAuto   = "auto"
+	colorAlways = "always"
+	colorNever  = "never"
+)
+
+// ANSI escapes of the failure output
+const (
+	ansiReset = "\033[0m"
+	ansiRed   = "\033[31m"
+	ansiGreen = "\033[32m"
+	ansiBold  = "\033[1m"
+	ansiDim   = "\033[2m"
+)
+
+// checkColorMode validates a --color value
+func checkColorMode(mode string) error {
+	switch mode {
+	case colorAuto, colorAlways, colorNever:
+		return nil
+	}
+	return fmt.Errorf("unsupported color mode %q, expected %s, %s or %s", mode, colorAuto, colorAlways, colorNever)
+}
+
+// useColor reports whether output to f is colored in the given mode. In auto
+// mode, terminals are colored unless NO_COLOR is set.
+func useColor(mode string, f *os.File) bool {
+	switch mode {
+	case colorAlways:
+		return true
+	case colorNever:
+		return false
+	}
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// fieldDiff is a value of a proofs file that differs from the value computed
+// for it. Path locates the field in the file, as proofs[3].leafHash.
+type fieldDiff struct {
+	Path     string
+	File     string
+	Computed string
+}
+
+// checkFailure is a failed check. Failures of one proof entry carry the entry,
+// its path and the fields that differ.
+type checkFailure struct {
+	Message string
+	Path    string
+	Entry   *models.ProofEntry
+	Diffs   []fieldDiff
+}
+
+// printFailure prints a failure followed by a summary of the offending leaf
+// and, diff style, the value in the file and the computed value of each field
+func printFailure(w io.Writer, color bool, f checkFailure) {
+	paint := func(code, s string) string {
+		if !color {
+			return s
+		}
+		return code + s + ansiReset
+	}
+
+	fmt.Fprintf(w, "%s %s\n", paint(ansiBold+ansiRed, "FAIL:"), f.Message)
+	if f.Entry != nil {
+		leaf := fmt.Sprintf("%s: oneSigId %d, nonce %d, leafIndex %d, %d call(s)", f.Path, f.Entry.OneSigID, f.Entry.Nonce, f.Entry.LeafIndex, len(f.Entry.Calls))
+		if f.Entry.HasValidityWindow() {
+			leaf += fmt.Sprintf(", valid %d..%d", f.Entry.ValidAfter, f.Entry.ValidUntil)
+		}
+		fmt.Fprintln(w, "  "+paint(ansiDim, leaf))
+	}
+	for _, d := range f.Diffs {
+		fmt.Fprintln(w, "  "+paint(ansiRed, fmt.Sprintf("- %s: %s", d.Path, d.File))+paint(ansiDim, "  (file)"))
+		fmt.Fprintln(w, "  "+paint(ansiGreen, fmt.Sprintf("+ %s: %s", d.Path, d.Computed))+paint(ansiDim, "  (computed)"))
+	}
+}
@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os/exec"
+
+	"merkle-cli/utils"
+)
+
+// printDigest shows the root and its EIP-712 digest as QR codes and
+// fingerprint words, so signers can compare them on an air-gapped device.
+// QR codes are rendered with the qrencode binary and skipped if it is missing.
+// A non-zero expiry is included in the digest.
+func printDigest(w io.Writer, root []byte, seed []byte, expiry uint64) error {
+	digest, err := rootDigest(root, seed, expiry)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range []struct {
+		label string
+		value []byte
+	}{
+		{"Merkle Root", root},
+		{"EIP-712 Digest", digest},
+	} {
+		fmt.Fprintf(w, "\n%s: 0x%x\n", item.label, item.value)
+		fmt.Fprintf(w, "Fingerprint: %s\n", utils.Fingerprint(item.value))
+
+		qr, err := exec.Command("qrencode", "-t", "UTF8", "-m", "2", fmt.Sprintf("0x%x", item.value)).Output()
+		if err != nil {
+			fmt.Fprintln(w, "(install qrencode to show a QR code)")
+			continue
+		}
+		w.Write(qr)
+	}
+	return nil
+}
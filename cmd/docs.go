@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var docsOutputDir string
+
+// docsCmd writes a man page for every command
+var docsCmd = &cobra.Command{
+	Use:   "docs",
+	Short: "Generate man pages",
+	Long: `Generate man pages
+
+Writes a section 1 man page for merkle-cli and each of its subcommands to
+--output-dir, named after the command path (merkle-cli-sign.1 and so on).`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := os.MkdirAll(docsOutputDir, 0755); err != nil {
+			return fmt.Errorf("failed to create %s: %w", docsOutputDir, err)
+		}
+		return writeManTree(rootCmd, docsOutputDir)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(docsCmd)
+
+	docsCmd.Flags().StringVar(&docsOutputDir, "output-dir", "man", "Directory to write the man pages to")
+}
+
+// writeManTree writes the man page of c and all its visible subcommands
+func writeManTree(c *cobra.Command, dir string) error {
+	for _, sub := range c.Commands() {
+		if !sub.IsAvailableCommand() || sub.IsAdditionalHelpTopicCommand() {
+			continue
+		}
+		if err := writeManTree(sub, dir); err != nil {
+			return err
+		}
+	}
+
+	path := filepath.Join(dir, manName(c)+".1")
+	if err := os.WriteFile(path, manPage(c), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// manName is the man page name of a command, such as merkle-cli-sign
+func manName(c *cobra.Command) string {
+	return strings.ReplaceAll(c.CommandPath(), " ", "-")
+}
+
+// manPage renders the roff source of a command's man page
+func manPage(c *cobra.Command) []byte {
+	var b bytes.Buffer
+	name := manName(c)
+
+	fmt.Fprintf(&b, ".TH \"%s\" \"1\" \"\" \"%s\" \"\"\n", strings.ToUpper(name), c.Root().Name())
+	fmt.Fprintf(&b, ".SH NAME\n%s \\- %s\n", name, roffEscape(c.Short))
+	fmt.Fprintf(&b, ".SH SYNOPSIS\n\\fB%s\\fP\n", roffEscape(c.UseLine()))
+
+	description := c.Long
+	if description == "" {
+		description = c.Short
+	}
+	fmt.Fprintf(&b, ".SH DESCRIPTION\n.nf\n%s\n.fi\n", roffEscape(description))
+
+	writeManFlags(&b, "OPTIONS", c.NonInheritedFlags())
+	writeManFlags(&b, "OPTIONS INHERITED FROM PARENT COMMANDS", c.InheritedFlags())
+
+	var related []string
+	if c.HasParent() {
+		related = append(related, manName(c.Parent()))
+	}
+	for _, sub := range c.Commands() {
+		if sub.IsAvailableCommand() && !sub.IsAdditionalHelpTopicCommand() {
+			related = append(related, manName(sub))
+		}
+	}
+	if len(related) > 0 {
+		b.WriteString(".SH SEE ALSO\n")
+		for i, r := range related {
+			if i > 0 {
+				b.WriteString(",\n")
+			}
+			fmt.Fprintf(&b, "\\fB%s(1)\\fP", r)
+		}
+		b.WriteString("\n")
+	}
+	return b.Bytes()
+}
+
+// writeManFlags renders a flag set as a man page section
+func writeManFlags(b *bytes.Buffer, section string, flags *pflag.FlagSet) {
+	if !flags.HasAvailableFlags() {
+		return
+	}
+	fmt.Fprintf(b, ".SH %s\n", section)
+	flags.VisitAll(func(f *pflag.Flag) {
+		if f.Hidden {
+			return
+		}
+		b.WriteString(".PP\n")
+		if f.Shorthand != "" && f.ShorthandDeprecated == "" {
+			fmt.Fprintf(b, "\\fB\\-%s\\fP, ", f.Shorthand)
+		}
+		fmt.Fprintf(b, "\\fB\\-\\-%s\\fP", f.Name)
+		if f.Value.Type() != "bool" && f.DefValue != "" && f.DefValue != "[]" {
+			fmt.Fprintf(b, "=%s", roffEscape(f.DefValue))
+		}
+		fmt.Fprintf(b, "\n.RS\n%s\n.RE\n", roffEscape(f.Usage))
+	})
+}
+
+// roffEscape escapes backslashes and leading control characters for roff
+func roffEscape(s string) string {
+	s = strings.ReplaceAll(s, "\\", "\\e")
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, ".") || strings.HasPrefix(line, "'") {
+			lines[i] = "\\&" + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
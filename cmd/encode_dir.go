@@ -0,0 +1,229 @@
+package cmd
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+
+	"merkle-cli/encryption"
+	"merkle-cli/merkle"
+	"merkle-cli/models"
+	"merkle-cli/utils"
+
+	"github.com/spf13/cobra"
+)
+
+// encodeDirIndexFile is the file listing the root of every processed batch
+const encodeDirIndexFile = "index.json"
+
+var (
+	encodeDirInput        string
+	encodeDirOutput       string
+	encodeDirOneSigID     uint64
+	encodeDirContractAddr string
+	encodeDirLeafVersion  uint8
+	encodeDirJobs         int
+)
+
+// encodeDirIndex is the index file written next to the outputs
+type encodeDirIndex struct {
+	OneSigID            uint64              `json:"oneSigId"`
+	ContractAddr        string              `json:"contractAddr,omitempty"`
+	LeafEncodingVersion uint8               `json:"leafEncodingVersion"`
+	TreeOptions         *models.TreeOptions `json:"treeOptions"`
+	Files               []encodeDirResult   `json:"files"`
+}
+
+// encodeDirResult is the outcome of processing one batch file
+type encodeDirResult struct {
+	Input       string `json:"input"`
+	InputSHA256 string `json:"inputSha256,omitempty"`
+	Output      string `json:"output,omitempty"`
+	MerkleRoot  string `json:"merkleRoot,omitempty"`
+	Leaves      int    `json:"leaves,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// encodeDirCmd generates the tree of every batch file in a directory
+var encodeDirCmd = &cobra.Command{
+	Use:   "encode-dir",
+	Short: "Generate the trees of every batch file in a directory",
+	Long: `Generate the trees of every batch file in a directory
+
+Processes every *.json file in --input-dir in parallel, each as its own batch
+with its own root, and writes its proofs file under the same name to
+--output-dir. index.json in --output-dir lists the input hash, output file and
+root of every batch, or the error it failed with. The command fails if any
+batch fails.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if encodeDirJobs < 1 {
+			return fmt.Errorf("--jobs must be at least 1")
+		}
+		options, err := treeOptions()
+		if err != nil {
+			return err
+		}
+
+		inputs, err := filepath.Glob(filepath.Join(encodeDirInput, "*.json"))
+		if err != nil {
+			return err
+		}
+		if len(inputs) == 0 {
+			return fmt.Errorf("no *.json files in %s", encodeDirInput)
+		}
+		sort.Strings(inputs)
+		for _, input := range inputs {
+			if filepath.Base(input) == encodeDirIndexFile {
+				return fmt.Errorf("%s in the input directory would be overwritten by the index", encodeDirIndexFile)
+			}
+		}
+		same, err := sameDir(encodeDirInput, encodeDirOutput)
+		if err != nil {
+			return err
+		}
+		if same {
+			return fmt.Errorf("--output-dir must differ from --input-dir")
+		}
+
+		if err := os.MkdirAll(encodeDirOutput, 0755); err != nil {
+			return fmt.Errorf("failed to create %s: %w", encodeDirOutput, err)
+		}
+
+		results := make([]encodeDirResult, len(inputs))
+		work := make(chan int)
+		var wg sync.WaitGroup
+		for w := 0; w < encodeDirJobs && w < len(inputs); w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range work {
+					results[i] = encodeDirFile(cmd.Context(), inputs[i], &options)
+				}
+			}()
+		}
+		for i := range inputs {
+			work <- i
+		}
+		close(work)
+		wg.Wait()
+		if err := cmd.Context().Err(); err != nil {
+			return err
+		}
+
+		index := encodeDirIndex{
+			OneSigID:            encodeDirOneSigID,
+			ContractAddr:        encodeDirContractAddr,
+			LeafEncodingVersion: encodeDirLeafVersion,
+			TreeOptions:         &options,
+			Files:               results,
+		}
+		data, err := json.MarshalIndent(index, "", "  ")
+		if err != nil {
+			return err
+		}
+		indexPath := filepath.Join(encodeDirOutput, encodeDirIndexFile)
+		if err := os.WriteFile(indexPath, append(data, '\n'), 0644); err != nil {
+			return fmt.Errorf("failed to write index: %w", err)
+		}
+
+		failed := 0
+		for _, result := range results {
+			if result.Error != "" {
+				failed++
+				fmt.Printf("FAILED %s: %s\n", result.Input, result.Error)
+			} else {
+				fmt.Printf("%s %s (%d leaves)\n", result.MerkleRoot, result.Input, result.Leaves)
+			}
+		}
+		fmt.Println("Index:", indexPath)
+		if failed > 0 {
+			return fmt.Errorf("%d of %d batch file(s) failed", failed, len(results))
+		}
+		return nil
+	},
+}
+
+// sameDir reports whether two paths name the same directory
+func sameDir(a, b string) (bool, error) {
+	ia, err := os.Stat(a)
+	if err != nil {
+		return false, err
+	}
+	ib, err := os.Stat(b)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return os.SameFile(ia, ib), nil
+}
+
+// encodeDirFile generates the tree of one batch file and writes its proofs file
+func encodeDirFile(ctx context.Context, input string, options *models.TreeOptions) encodeDirResult {
+	name := filepath.Base(input)
+	result := encodeDirResult{Input: name}
+
+	data, err := os.ReadFile(input)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	sum := sha256.Sum256(data)
+	result.InputSHA256 = hex.EncodeToString(sum[:])
+
+	if data, err = expandTemplate(data); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	batch, err := parseBatch(data)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	tree, entries, err := generateTreeCached(ctx, batch, merkle.Params{
+		OneSigID:     encodeDirOneSigID,
+		ContractAddr: encodeDirContractAddr,
+		LeafVersion:  encodeDirLeafVersion,
+		Options:      options,
+		Limits:       batchLimits(),
+	})
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	output := buildOutput(tree, encodeDirLeafVersion, options, entries, utils.HashEncodingHex)
+	if err := writeOutputFile(filepath.Join(encodeDirOutput, name), &output, outputFormatJSON, false, encryption.Options{}); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	result.Output = name
+	result.MerkleRoot = output.MerkleRoot
+	result.Leaves = len(entries)
+	return result
+}
+
+func init() {
+	rootCmd.AddCommand(encodeDirCmd)
+	addTreeFlags(encodeDirCmd)
+
+	encodeDirCmd.Flags().StringVar(&encodeDirInput, "input-dir", "", "Directory holding the transaction batch JSON files")
+	encodeDirCmd.MarkFlagRequired("input-dir")
+	encodeDirCmd.Flags().StringVar(&encodeDirOutput, "output-dir", "", "Directory to write the proofs files and index.json to")
+	encodeDirCmd.MarkFlagRequired("output-dir")
+	encodeDirCmd.Flags().Uint64VarP(&encodeDirOneSigID, "onesig-id", "o", 0, "OneSig ID (typically chain ID)")
+	encodeDirCmd.MarkFlagRequired("onesig-id")
+	encodeDirCmd.Flags().StringVarP(&encodeDirContractAddr, "contract-addr", "c", "", "OneSig contract address (defaults to 0xdEaD if not provided)")
+	encodeDirCmd.Flags().Uint8Var(&encodeDirLeafVersion, "leaf-version", utils.LeafEncodingVersion, "Leaf encoding version")
+	encodeDirCmd.Flags().IntVar(&encodeDirJobs, "jobs", runtime.NumCPU(), "Number of batch files processed in parallel")
+	encodeDirCmd.RegisterFlagCompletionFunc("leaf-version", completeLeafVersions)
+}
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"merkle-cli/etherscan"
+
+	"github.com/spf13/cobra"
+)
+
+// etherscanOptions holds the flags of the optional explorer lookups
+type etherscanOptions struct {
+	enabled  bool
+	url      string
+	apiKey   string
+	chainID  uint64
+	offline  bool
+	cacheDir string
+}
+
+// register adds the explorer flags to cmd
+func (o *etherscanOptions) register(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&o.enabled, "etherscan", false, "Look up the verified source of every call target on an Etherscan-compatible API")
+	cmd.Flags().StringVar(&o.url, "etherscan-url", etherscan.DefaultURL, "Etherscan-compatible API endpoint")
+	cmd.Flags().StringVar(&o.apiKey, "etherscan-api-key", "", "API key of the explorer")
+	cmd.Flags().Uint64Var(&o.chainID, "etherscan-chain-id", 1, "Chain ID of the contracts looked up (the OneSig ID is not always one)")
+	cmd.Flags().BoolVar(&o.offline, "etherscan-offline", false, "Only use contracts already in the explorer cache")
+	cmd.Flags().StringVar(&o.cacheDir, "etherscan-cache-dir", "", "Directory caching verified contracts (defaults to the user cache directory)")
+}
+
+// client returns the explorer client, or nil if lookups are not enabled
+func (o *etherscanOptions) client() (*etherscan.Client, error) {
+	if !o.enabled && !o.offline {
+		return nil, nil
+	}
+	cacheDir := o.cacheDir
+	if cacheDir == "" {
+		userCache, err := os.UserCacheDir()
+		if err != nil {
+			return nil, fmt.Errorf("failed to locate the explorer cache, set --etherscan-cache-dir: %w", err)
+		}
+		cacheDir = filepath.Join(userCache, "merkle-cli", "etherscan")
+	}
+	return &etherscan.Client{
+		URL:      o.url,
+		APIKey:   o.apiKey,
+		ChainID:  o.chainID,
+		CacheDir: cacheDir,
+		Offline:  o.offline,
+	}, nil
+}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"fmt"
+
+	"merkle-cli/models"
+	"merkle-cli/utils"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	execPayloadProofsFile string
+	execPayloadNonce      uint64
+	execPayloadLeaf       string
+)
+
+// execPayloadCmd prints the calldata that executes a single leaf on the OneSig contract
+var execPayloadCmd = &cobra.Command{
+	Use:   "exec-payload",
+	Short: "Build the OneSig execute calldata for a proof entry",
+	Long: `Build the OneSig execute calldata for a proof entry
+
+Reads a proofs file written with --output and ABI-encodes the execute call for
+the selected nonce, so relayers can submit it to the OneSig contract directly.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		output, err := readOutputFile(execPayloadProofsFile)
+		if err != nil {
+			return err
+		}
+
+		if err := checkProofsLeafVersion(output.LeafEncodingVersion); err != nil {
+			return err
+		}
+		entry, err := selectEntry(output, execPayloadNonce, execPayloadLeaf)
+		if err != nil {
+			return err
+		}
+
+		if err := checkEntryLeafHash(entry, output.LeafEncodingVersion); err != nil {
+			return err
+		}
+
+		calldata, err := utils.EncodeExecute(*entry, output.LeafEncodingVersion)
+		if err != nil {
+			return err
+		}
+
+		fmt.Println("Merkle Root:", output.MerkleRoot)
+		fmt.Println("Nonce:", entry.Nonce)
+		fmt.Println("Leaf:", entry.LeafHash)
+		fmt.Println("Total Call Value:", models.TotalValue(entry.Calls).String())
+		fmt.Printf("Calldata: 0x%x\n", calldata)
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(execPayloadCmd)
+
+	execPayloadCmd.Flags().StringVarP(&execPayloadProofsFile, "proofs-file", "p", "", "Path to a proofs file written with --output")
+	execPayloadCmd.MarkFlagRequired("proofs-file")
+
+	execPayloadCmd.Flags().Uint64VarP(&execPayloadNonce, "nonce", "n", 0, "Nonce of the leaf to execute")
+	execPayloadCmd.MarkFlagRequired("nonce")
+
+	execPayloadCmd.Flags().StringVar(&execPayloadLeaf, "leaf", "", "Leaf hash, required when several leaves share the nonce")
+}
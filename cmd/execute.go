@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"merkle-cli/chain"
+	"merkle-cli/checkpoint"
+	"merkle-cli/models"
+	"merkle-cli/utils"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/spf13/cobra"
+)
+
+var (
+	executeProofsFile   string
+	executeOneSigID     uint64
+	executeContract     string
+	executeCheckpoint   string
+	executeForwardValue bool
+	executeTx           txOptions
+)
+
+// executeCmd relays every leaf of a OneSig instance to the chain in nonce order
+var executeCmd = &cobra.Command{
+	Use:   "execute",
+	Short: "Submit executions for every leaf of a OneSig instance",
+	Long: `Submit executions for every leaf of a OneSig instance
+
+Reads a proofs file written with --output and submits an execute transaction
+for each leaf of the given OneSig ID in nonce order, waiting for confirmations
+before moving on. Confirmed leaves are recorded in the checkpoint file so an
+interrupted run resumes where it stopped.
+
+With --rpc-url, calls pinning a code hash or proxy implementation, or flagged
+as EOA transfers, are checked before their leaf is submitted (see check-code).`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		output, err := readOutputFile(executeProofsFile)
+		if err != nil {
+			return err
+		}
+		if err := checkProofsLeafVersion(output.LeafEncodingVersion); err != nil {
+			return err
+		}
+
+		var entries []models.ProofEntry
+		for _, entry := range output.Proofs {
+			if entry.OneSigID == executeOneSigID {
+				entries = append(entries, entry)
+			}
+		}
+		if len(entries) == 0 {
+			return fmt.Errorf("no proofs for OneSig ID %d in %s", executeOneSigID, executeProofsFile)
+		}
+		sort.SliceStable(entries, func(i, j int) bool {
+			return entries[i].Nonce < entries[j].Nonce
+		})
+
+		state, err := checkpoint.Load(executeCheckpoint, "execute", output.MerkleRoot)
+		if err != nil {
+			return err
+		}
+
+		var checker *codeChecker
+		if executeTx.rpcURL != "" {
+			checker = newCodeChecker(chain.NewClient(executeTx.rpcURL), false)
+		}
+
+		for _, entry := range entries {
+			entry := entry
+			if state.Done(entry.LeafHash) {
+				fmt.Printf("Nonce %d: already executed, skipping\n", entry.Nonce)
+				continue
+			}
+			if err := checkEntryLeafHash(&entry, output.LeafEncodingVersion); err != nil {
+				return err
+			}
+			if checker != nil {
+				for i, call := range entry.Calls {
+					problem, err := checker.check(call)
+					if err != nil {
+						return fmt.Errorf("failed to check nonce %d call %d: %w", entry.Nonce, i, err)
+					}
+					if problem != "" {
+						return withExitCode(exitVerification, fmt.Errorf("nonce %d call %d to %s: %s", entry.Nonce, i, call.To, problem))
+					}
+				}
+			}
+
+			target := executeContract
+			if target == "" {
+				target = entry.ContractAddr
+			}
+			if !common.IsHexAddress(target) {
+				return fmt.Errorf("nonce %d has no OneSig contract address, provide --contract-addr", entry.Nonce)
+			}
+
+			fmt.Printf("Nonce %d:\n", entry.Nonce)
+			err := executeTx.submitAndWait(state, entry.LeafHash, func() (*chain.Transaction, *ecdsa.PrivateKey, error) {
+				calldata, err := utils.EncodeExecute(entry, output.LeafEncodingVersion)
+				if err != nil {
+					return nil, nil, err
+				}
+
+				var value *big.Int
+				if executeForwardValue {
+					value = models.TotalValue(entry.Calls)
+				}
+
+				return executeTx.buildTransaction(cmd, common.HexToAddress(target), value, calldata)
+			})
+			if err != nil {
+				return fmt.Errorf("execution for nonce %d failed: %w", entry.Nonce, err)
+			}
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(executeCmd)
+
+	executeCmd.Flags().StringVarP(&executeProofsFile, "proofs-file", "p", "", "Path to a proofs file written with --output")
+	executeCmd.MarkFlagRequired("proofs-file")
+
+	executeCmd.Flags().Uint64VarP(&executeOneSigID, "onesig-id", "o", 0, "OneSig ID whose leaves are executed")
+	executeCmd.MarkFlagRequired("onesig-id")
+
+	executeCmd.Flags().StringVarP(&executeContract, "contract-addr", "c", "", "OneSig contract address (defaults to the address in the proofs file)")
+	executeCmd.Flags().StringVar(&executeCheckpoint, "checkpoint", "", "Checkpoint file recording executed leaves, used to resume interrupted runs")
+	executeCmd.Flags().BoolVar(&executeForwardValue, "forward-value", false, "Attach the sum of the leaf's call values to the execute transaction")
+
+	executeTx.register(executeCmd)
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net"
+
+	"merkle-cli/bundle"
+	"merkle-cli/chain"
+	"merkle-cli/merkle"
+	"merkle-cli/mmr"
+	"merkle-cli/signer"
+	"merkle-cli/utils"
+)
+
+// Exit codes returned by the CLI, documented in the README
+const (
+	exitFailure      = 1
+	exitValidation   = 2
+	exitEncoding     = 3
+	exitVerification = 4
+	exitRPC          = 5
+	exitIO           = 6
+	exitInterrupted  = 130
+)
+
+// commandStarted is set once flag and argument validation passed, so errors
+// returned before it are usage errors
+var commandStarted bool
+
+// exitError attaches an exit code to an error
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+// withExitCode makes err exit the CLI with code
+func withExitCode(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &exitError{code: code, err: err}
+}
+
+// exitCode maps an error returned by a command to the process exit code
+func exitCode(err error) int {
+	var exitErr *exitError
+	var rpcErr *chain.RPCError
+	var netErr net.Error
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var pathErr *fs.PathError
+
+	switch {
+	case errors.As(err, &exitErr):
+		return exitErr.code
+	case !commandStarted:
+		return exitValidation
+	case errors.Is(err, context.Canceled):
+		return exitInterrupted
+	case errors.As(err, &pathErr):
+		// Checked before net.Error, which the wrapped syscall errors also satisfy
+		return exitIO
+	case errors.As(err, &rpcErr), errors.Is(err, chain.ErrTransport), errors.As(err, &netErr):
+		return exitRPC
+	case errors.Is(err, signer.ErrVerificationFailed), errors.Is(err, bundle.ErrIntegrity), errors.Is(err, merkle.ErrLeafNotFound),
+		errors.Is(err, mmr.ErrInvalidProof):
+		return exitVerification
+	case errors.Is(err, utils.ErrInvalidHex), errors.Is(err, utils.ErrDuplicateNonce), errors.Is(err, utils.ErrDependency),
+		errors.Is(err, utils.ErrUnsupportedVersion), errors.Is(err, utils.ErrLimitExceeded), errors.Is(err, utils.ErrTargetFormat),
+		errors.Is(err, utils.ErrValueRange), errors.Is(err, utils.ErrUnknownField), errors.As(err, &syntaxErr), errors.As(err, &typeErr):
+		return exitValidation
+	case errors.Is(err, utils.ErrEncoding):
+		return exitEncoding
+	default:
+		return exitFailure
+	}
+}
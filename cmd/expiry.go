@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// expiryOptions holds the flags binding a root to an expiry timestamp, for
+// OneSig deployments that reject roots once their expiry has passed
+type expiryOptions struct {
+	value  string
+	margin time.Duration
+}
+
+// register adds the expiry flags to the command
+func (o *expiryOptions) register(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&o.value, "expiry", "", "Expiry the root is bound to, as unix seconds or RFC 3339 (for OneSig deployments with root expiry)")
+	cmd.Flags().DurationVar(&o.margin, "expiry-margin", time.Hour, "How far in the future the expiry must be")
+}
+
+// parse returns the expiry given with --expiry, or fallback if it is not set,
+// and checks that it is at least the margin in the future. 0 means no expiry.
+func (o *expiryOptions) parse(fallback uint64) (uint64, error) {
+	expiry := fallback
+	if o.value != "" {
+		parsed, err := parseTimestamp("--expiry", o.value)
+		if err != nil {
+			return 0, err
+		}
+		if fallback != 0 && parsed != fallback {
+			return 0, fmt.Errorf("--expiry %d differs from the expiry %d in the signatures file", parsed, fallback)
+		}
+		expiry = parsed
+	}
+	if expiry == 0 {
+		return 0, nil
+	}
+
+	deadline := time.Now().Add(o.margin)
+	if time.Unix(int64(expiry), 0).Before(deadline) {
+		return 0, withExitCode(exitValidation, fmt.Errorf("expiry %s is less than %s in the future",
+			time.Unix(int64(expiry), 0).UTC().Format(time.RFC3339), o.margin))
+	}
+	return expiry, nil
+}
+
+// parseTimestamp parses a timestamp flag given as unix seconds or RFC 3339
+func parseTimestamp(flag string, value string) (uint64, error) {
+	if seconds, err := strconv.ParseUint(value, 10, 63); err == nil {
+		if seconds == 0 {
+			return 0, fmt.Errorf("invalid %s %q: must be after the unix epoch", flag, value)
+		}
+		return seconds, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q: expected unix seconds or an RFC 3339 time", flag, value)
+	}
+	if t.Unix() <= 0 {
+		return 0, fmt.Errorf("invalid %s %q: must be after the unix epoch", flag, value)
+	}
+	return uint64(t.Unix()), nil
+}
@@ -0,0 +1,303 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"merkle-cli/merkle"
+	"merkle-cli/models"
+	"merkle-cli/names"
+	"merkle-cli/proofbin"
+	"merkle-cli/utils"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/spf13/cobra"
+)
+
+var (
+	explainProofEntry  string
+	explainLeafVersion uint8
+)
+
+// explainCmd prints how a proof entry hashes up to the root, step by step
+var explainCmd = &cobra.Command{
+	Use:   "explain",
+	Short: "Print a step-by-step derivation of a proof",
+	Long: `Print a step-by-step derivation of a proof
+
+Shows the fields of the leaf pre-image, the two keccak256 rounds producing the
+leaf hash, every sibling the proof combines it with and the resulting root.
+--proof-entry is either a file holding a single proof entry, or a proofs file
+followed by the position of the entry, as in proofs.json:3.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		entry, output, err := readProofEntryRef(explainProofEntry)
+		if err != nil {
+			return err
+		}
+
+		version := explainLeafVersion
+		options, err := treeOptions()
+		if err != nil {
+			return err
+		}
+		if output != nil {
+			version = output.LeafEncodingVersion
+			if output.TreeOptions != nil {
+				if err := checkPairOrderFlags(cmd, output.TreeOptions); err != nil {
+					return err
+				}
+				options = *output.TreeOptions
+			}
+		}
+		order, err := merkle.CheckTreeOptions(&options)
+		if err != nil {
+			return err
+		}
+
+		var expectedRoot string
+		if output != nil {
+			expectedRoot = output.MerkleRoot
+		}
+		labels, err := addressLabels()
+		if err != nil {
+			return err
+		}
+		return explainEntry(os.Stdout, entry, version, order, merkle.ArityOf(&options), expectedRoot, labels)
+	},
+}
+
+// readProofEntryRef reads the entry named by --proof-entry. The proofs file
+// the entry came from is returned as well, or nil for a single entry file.
+func readProofEntryRef(ref string) (*models.ProofEntry, *models.OutputFormat, error) {
+	path, position := ref, -1
+	if i := strings.LastIndex(ref, ":"); i > 0 {
+		if n, err := strconv.Atoi(ref[i+1:]); err == nil {
+			path, position = ref[:i], n
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read proof entry: %w", err)
+	}
+
+	var document struct {
+		Proofs json.RawMessage `json:"proofs"`
+	}
+	if !proofbin.IsBinary(data) {
+		if err := json.Unmarshal(data, &document); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse proof entry: %w", err)
+		}
+	}
+	if !proofbin.IsBinary(data) && document.Proofs == nil {
+		var entry models.ProofEntry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse proof entry: %w", err)
+		}
+		// Convert the hashes to hex, the form proofs files are read into
+		if entry.LeafHash != "" {
+			if entry.LeafHash, err = hashToHex(entry.LeafHash, hashEncoding, "leafHash"); err != nil {
+				return nil, nil, fmt.Errorf("invalid proof entry: %w", err)
+			}
+		}
+		for i := range entry.Proof {
+			if entry.Proof[i], err = hashToHex(entry.Proof[i], hashEncoding, fmt.Sprintf("proof[%d]", i)); err != nil {
+				return nil, nil, fmt.Errorf("invalid proof entry: %w", err)
+			}
+		}
+		return &entry, nil, nil
+	}
+
+	output, _, err := decodeOutputFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if position < 0 {
+		if len(output.Proofs) != 1 {
+			return nil, nil, fmt.Errorf("%s has %d proof entries, select one with %s:<position>", path, len(output.Proofs), path)
+		}
+		position = 0
+	}
+	if position >= len(output.Proofs) {
+		return nil, nil, fmt.Errorf("%s has no proof entry at position %d", path, position)
+	}
+	return &output.Proofs[position], output, nil
+}
+
+// preimageField is a fixed size field at the start of the leaf pre-image
+type preimageField struct {
+	name string
+	size int
+	note string
+}
+
+// explainEntry writes the derivation of an entry's leaf hash and root
+func explainEntry(w io.Writer, entry *models.ProofEntry, version uint8, order merkle.PairOrder, arity int, expectedRoot string, labels names.Labels) error {
+	var leaf []byte
+	if len(entry.Calls) > 0 {
+		preimage, err := utils.EncodeLeafData(entry.Leaf, version)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(w, "Leaf pre-image (encoding version %d, %d bytes)\n", version, len(preimage))
+		fields := []preimageField{
+			{"version", 1, strconv.Itoa(int(version))},
+			{"oneSigId", 8, strconv.FormatUint(entry.OneSigID, 10)},
+			{"contractAddr", 32, "address as bytes32"},
+			{"nonce", 8, strconv.FormatUint(entry.Nonce, 10)},
+		}
+		if version >= utils.LeafEncodingVersionWindowed {
+			fields = append(fields,
+				preimageField{"validAfter", 8, strconv.FormatUint(entry.ValidAfter, 10)},
+				preimageField{"validUntil", 8, strconv.FormatUint(entry.ValidUntil, 10)})
+		}
+		if version >= utils.LeafEncodingVersionSalted {
+			salt := "no salt, zero"
+			if entry.Salt != "" {
+				salt = "bytes32"
+			}
+			fields = append(fields, preimageField{"salt", utils.SaltSize, salt})
+		}
+		offset := 0
+		for _, f := range fields {
+			fmt.Fprintf(w, "  %-14s 0x%x  (%s)\n", f.name, preimage[offset:offset+f.size], f.note)
+			offset += f.size
+		}
+		fmt.Fprintf(w, "  %-14s %d bytes, abi.encode of %d call(s)\n", "calls", len(preimage)-offset, len(entry.Calls))
+		for i, call := range entry.Calls {
+			value := "0"
+			if call.Value != nil {
+				value = call.Value.String()
+			}
+			to := labelled(call.To, labels)
+			if call.ToName != "" {
+				to = fmt.Sprintf("%s (%s)", call.To, call.ToName)
+			}
+			operation := ""
+			if call.IsDelegateCall() {
+				operation = " (delegatecall)"
+			}
+			fmt.Fprintf(w, "    [%d] to %s value %s data %s%s\n", i, to, value, call.Data, operation)
+		}
+
+		inner := crypto.Keccak256(preimage)
+		leaf = crypto.Keccak256(inner)
+		fmt.Fprintln(w, "\nLeaf hash")
+		fmt.Fprintf(w, "  keccak256(pre-image)   0x%x\n", inner)
+		fmt.Fprintf(w, "  keccak256(previous)    0x%x\n", leaf)
+	}
+
+	if entry.LeafHash != "" {
+		recorded, err := utils.HexToBytes(entry.LeafHash)
+		if err != nil {
+			return fmt.Errorf("invalid leaf hash: %w", err)
+		}
+		switch {
+		case leaf == nil:
+			fmt.Fprintln(w, "Leaf hash (entry has no calls)")
+			fmt.Fprintf(w, "  recorded               %s\n", entry.LeafHash)
+		case bytes.Equal(leaf, recorded):
+			fmt.Fprintf(w, "  recorded               %s  (matches)\n", entry.LeafHash)
+		default:
+			fmt.Fprintf(w, "  recorded               %s  (MISMATCH, the proof is checked with the encoded leaf)\n", entry.LeafHash)
+		}
+		if leaf == nil {
+			leaf = recorded
+		}
+	}
+	if leaf == nil {
+		return fmt.Errorf("proof entry has neither calls nor a leaf hash")
+	}
+
+	proof := make([][]byte, 0, len(entry.Proof))
+	for i, p := range entry.Proof {
+		b, err := utils.HexToBytes(p)
+		if err != nil {
+			return fmt.Errorf("invalid proof[%d]: %w", i, err)
+		}
+		proof = append(proof, b)
+	}
+
+	fmt.Fprintf(w, "\nProof (%d sibling(s), pair order %s, leaf index %d)\n", len(proof), order, entry.LeafIndex)
+	if arity > merkle.DefaultArity {
+		if len(proof)%(arity-1) != 0 {
+			return fmt.Errorf("proof of %d hashes is not a whole number of levels of %d siblings", len(proof), arity-1)
+		}
+		root := leaf
+		for i, step := range merkle.TraceProofArity(leaf, proof, entry.LeafIndex, order, arity) {
+			fmt.Fprintf(w, "  round %d\n", i+1)
+			fmt.Fprintf(w, "    %-25s0x%x\n", "node", step.Node)
+			for _, sibling := range step.Siblings {
+				fmt.Fprintf(w, "    %-25s0x%x\n", "sibling", sibling)
+			}
+			fmt.Fprintf(w, "    %-25s0x%x\n", fmt.Sprintf("keccak256(%d children)", len(step.Children)), step.Parent)
+			root = step.Parent
+		}
+		return explainRoot(w, root, expectedRoot)
+	}
+	root := leaf
+	for i, step := range merkle.TraceProof(leaf, proof, entry.LeafIndex, order) {
+		fmt.Fprintf(w, "  round %d\n", i+1)
+		first := "node"
+		if !bytes.Equal(step.Left, step.Node) {
+			first = "sibling"
+		}
+		fmt.Fprintf(w, "    %-25s0x%x\n", "node", step.Node)
+		fmt.Fprintf(w, "    %-25s0x%x\n", "sibling", step.Sibling)
+		fmt.Fprintf(w, "    %-25s0x%x\n", "keccak256("+first+" first)", step.Parent)
+		root = step.Parent
+	}
+	return explainRoot(w, root, expectedRoot)
+}
+
+// explainRoot prints the derived root and compares it with the proofs file's
+func explainRoot(w io.Writer, root []byte, expectedRoot string) error {
+	fmt.Fprintln(w, "\nRoot")
+	fmt.Fprintf(w, "  derived                0x%x\n", root)
+	if expectedRoot != "" {
+		status := "matches"
+		if !strings.EqualFold(expectedRoot, fmt.Sprintf("0x%x", root)) {
+			status = "MISMATCH"
+		}
+		fmt.Fprintf(w, "  proofs file            %s  (%s)\n", expectedRoot, status)
+	}
+	return nil
+}
+
+// checkPairOrderFlags rejects --pair-order and --arity when they were set to
+// something other than the tree options recorded in a proofs file
+func checkPairOrderFlags(cmd *cobra.Command, options *models.TreeOptions) error {
+	recorded, err := merkle.CheckTreeOptions(options)
+	if err != nil {
+		return err
+	}
+	if cmd.Flags().Changed("pair-order") {
+		order, err := merkle.ParsePairOrder(pairOrder)
+		if err != nil {
+			return err
+		}
+		if order != recorded {
+			return withExitCode(exitValidation, fmt.Errorf("--pair-order %s conflicts with the pair order %s recorded in the proofs file", order, recorded))
+		}
+	}
+	if cmd.Flags().Changed("arity") && arity != merkle.ArityOf(options) {
+		return withExitCode(exitValidation, fmt.Errorf("--arity %d conflicts with the arity %d recorded in the proofs file", arity, merkle.ArityOf(options)))
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(explainCmd)
+	addPairOrderFlags(explainCmd)
+
+	explainCmd.Flags().StringVar(&explainProofEntry, "proof-entry", "", "Proof entry file, or proofs file and entry position as file:position")
+	explainCmd.MarkFlagRequired("proof-entry")
+	explainCmd.Flags().Uint8Var(&explainLeafVersion, "leaf-version", utils.LeafEncodingVersion, "Leaf encoding version of a single entry file")
+	explainCmd.RegisterFlagCompletionFunc("leaf-version", completeLeafVersions)
+}
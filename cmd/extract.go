@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"fmt"
+
+	"merkle-cli/encryption"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	extractFilter       string
+	extractOutput       string
+	extractOutputFormat string
+)
+
+// extractCmd writes the proof entries of a proofs file that match a filter
+var extractCmd = &cobra.Command{
+	Use:   "extract <proofs-file>",
+	Short: "Write the proofs matching a filter expression to a new file",
+	Long: `Write the proofs matching a filter expression to a new file
+
+Keeps the root and tree options of the proofs file and only the proof entries
+matching --filter. Comparisons of a field with a number or quoted string
+(==, !=, <, <=, >, >=) are combined with &&, || and ! and grouped with
+parentheses:
+
+  oneSigId == "30101" && nonce >= 50
+  to == 0x1234... || selector == 0xa9059cbb
+
+Fields: oneSigId, nonce, leafIndex, validAfter, validUntil, calls (count),
+value (total wei), contractAddr, leafHash, to and selector (any call matches).
+Redacted files only carry leafIndex and leafHash.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if extractOutputFormat != outputFormatJSON && extractOutputFormat != outputFormatBinary {
+			return fmt.Errorf("unsupported output format %q, expected %s or %s", extractOutputFormat, outputFormatJSON, outputFormatBinary)
+		}
+		output, redacted, err := decodeOutputFile(args[0])
+		if err != nil {
+			return err
+		}
+		total := len(output.Proofs)
+		if err := filterProofs(output, extractFilter); err != nil {
+			return err
+		}
+		if err := writeOutputFile(extractOutput, output, extractOutputFormat, redacted, encryption.Options{}); err != nil {
+			return err
+		}
+		fmt.Printf("Extracted %d of %d proof(s) to %s\n", len(output.Proofs), total, extractOutput)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(extractCmd)
+
+	extractCmd.Flags().StringVar(&extractFilter, "filter", "", "Expression selecting the proofs to keep")
+	extractCmd.MarkFlagRequired("filter")
+	extractCmd.Flags().StringVar(&extractOutput, "output", "", "File to write the selected proofs to")
+	extractCmd.MarkFlagRequired("output")
+	extractCmd.Flags().StringVar(&extractOutputFormat, "output-format", outputFormatJSON, "Format of the output file: json or bin")
+	extractCmd.RegisterFlagCompletionFunc("output-format", fixedCompletion(outputFormatJSON, outputFormatBinary))
+}
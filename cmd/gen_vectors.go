@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"merkle-cli/merkle"
+	"merkle-cli/utils"
+	"merkle-cli/vectors"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	genVectorsSeed         uint64
+	genVectorsLeaves       int
+	genVectorsOneSigID     uint64
+	genVectorsContractAddr string
+	genVectorsLeafVersion  uint8
+	genVectorsOutputDir    string
+)
+
+// genVectorsCmd writes a deterministic batch and its expected output as conformance vectors
+var genVectorsCmd = &cobra.Command{
+	Use:   "gen-vectors",
+	Short: "Generate deterministic conformance test vectors",
+	Long: `Generate deterministic conformance test vectors
+
+Writes a pseudo-random transaction batch derived from --seed to input.json and
+the expected root and proofs to expected.json. The same seed, leaf count and
+parameters always produce identical files, so other implementations can check
+their encoding against them.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		batch, err := vectors.Batch(genVectorsSeed, genVectorsLeaves, genVectorsLeafVersion)
+		if err != nil {
+			return err
+		}
+
+		options, err := treeOptions()
+		if err != nil {
+			return err
+		}
+		tree, entries, err := generateTree(cmd.Context(), batch, merkle.Params{
+			OneSigID:     genVectorsOneSigID,
+			ContractAddr: genVectorsContractAddr,
+			LeafVersion:  genVectorsLeafVersion,
+			Options:      &options,
+			Limits:       batchLimits(),
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := os.MkdirAll(genVectorsOutputDir, 0755); err != nil {
+			return fmt.Errorf("failed to create %s: %w", genVectorsOutputDir, err)
+		}
+		if err := writeJSON(filepath.Join(genVectorsOutputDir, "input.json"), batch); err != nil {
+			return err
+		}
+		output := buildOutput(tree, genVectorsLeafVersion, &options, entries, utils.HashEncodingHex)
+		if err := writeJSON(filepath.Join(genVectorsOutputDir, "expected.json"), output); err != nil {
+			return err
+		}
+
+		fmt.Println("Merkle Root:", tree.GetRootHex())
+		fmt.Printf("Wrote %d leaves to %s\n", len(entries), genVectorsOutputDir)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(genVectorsCmd)
+	addTreeFlags(genVectorsCmd)
+
+	genVectorsCmd.Flags().Uint64Var(&genVectorsSeed, "seed", 0, "Seed of the pseudo-random batch")
+	genVectorsCmd.Flags().IntVar(&genVectorsLeaves, "leaves", 16, "Number of leaves to generate")
+	genVectorsCmd.Flags().Uint64VarP(&genVectorsOneSigID, "onesig-id", "o", 1, "OneSig ID of the leaves")
+	genVectorsCmd.Flags().StringVarP(&genVectorsContractAddr, "contract-addr", "c", "", "OneSig contract address (defaults to 0xdEaD)")
+	genVectorsCmd.Flags().Uint8Var(&genVectorsLeafVersion, "leaf-version", utils.LeafEncodingVersion, "Leaf encoding version")
+	genVectorsCmd.RegisterFlagCompletionFunc("leaf-version", completeLeafVersions)
+	genVectorsCmd.Flags().StringVar(&genVectorsOutputDir, "output-dir", "vectors", "Directory to write input.json and expected.json to")
+}
@@ -0,0 +1,225 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"merkle-cli/cache"
+	"merkle-cli/merkle"
+	"merkle-cli/models"
+	"merkle-cli/policy"
+	"merkle-cli/utils"
+
+	"github.com/spf13/cobra"
+)
+
+// serialHashing hashes every level of a tree on one goroutine, for debugging
+var serialHashing bool
+
+// hashWorkers returns the number of goroutines hashing each level of a tree:
+// one with --serial, GOMAXPROCS otherwise
+func hashWorkers() int {
+	if serialHashing {
+		return 1
+	}
+	return 0
+}
+
+var (
+	// autoSalt derives the salt of every leaf without one from its OneSig ID,
+	// nonce and saltDomain
+	autoSalt   bool
+	saltDomain string
+)
+
+// applySaltFlags sets the salt derivation of params from --auto-salt and
+// --salt-domain
+func applySaltFlags(params *merkle.Params) error {
+	if saltDomain != "" && !autoSalt {
+		return fmt.Errorf("--salt-domain requires --auto-salt")
+	}
+	if autoSalt && params.LeafVersion < utils.LeafEncodingVersionSalted {
+		return fmt.Errorf("--auto-salt requires leaf encoding version %d or later, got %d", utils.LeafEncodingVersionSalted, params.LeafVersion)
+	}
+	params.AutoSalt, params.SaltDomain = autoSalt, saltDomain
+	return nil
+}
+
+// generateTree builds the Merkle tree of a batch and the proof of every leaf,
+// enforcing the selector policy of the config file
+func generateTree(ctx context.Context, batch *models.TransactionBatch, params merkle.Params) (*merkle.MerkleTree, []merkle.Entry, error) {
+	if err := applySaltFlags(&params); err != nil {
+		return nil, nil, err
+	}
+	params.Validate = enforcePolicy
+	params.Workers = hashWorkers()
+	closeTrace, err := traceTree(&params)
+	if err != nil {
+		return nil, nil, err
+	}
+	result, err := merkle.NewMerkleModule(params).Generate(ctx, batch)
+	if closeErr := closeTrace(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return result.Tree, result.Entries, nil
+}
+
+// generateTreeCached is generateTree reusing the results cached in --cache-dir
+// for unchanged inputs. The policy is still enforced on a hit, as the config
+// file is not part of the key. Commands verifying a tree must not use it. The
+// cache is bypassed with --trace-hashes, as a cached tree hashes no pairs.
+func generateTreeCached(ctx context.Context, batch *models.TransactionBatch, params merkle.Params) (*merkle.MerkleTree, []merkle.Entry, error) {
+	if cacheDir == "" || traceHashes != "" {
+		return generateTree(ctx, batch, params)
+	}
+	if err := applySaltFlags(&params); err != nil {
+		return nil, nil, err
+	}
+	key, err := generationKey(batch, params)
+	if err != nil {
+		return nil, nil, err
+	}
+	store := cache.New(cacheDir)
+
+	var cached merkle.Result
+	found, err := store.Get(key, &cached)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Warning: ignoring cache entry:", err)
+	} else if found && cached.Tree != nil {
+		leaves := make([]models.Leaf, len(cached.Entries))
+		for i, entry := range cached.Entries {
+			leaves[i] = entry.Leaf
+		}
+		if err := enforcePolicy(leaves); err != nil {
+			return nil, nil, err
+		}
+		fmt.Fprintln(os.Stderr, "Using cached tree", key)
+		return cached.Tree, cached.Entries, nil
+	}
+
+	tree, entries, err := generateTree(ctx, batch, params)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := store.Put(key, merkle.Result{Tree: tree, Entries: entries}); err != nil {
+		fmt.Fprintln(os.Stderr, "Warning:", err)
+	}
+	return tree, entries, nil
+}
+
+// generationKey is the cache key of a generation: the batch as parsed, the
+// parameters and the encoder revision
+func generationKey(batch *models.TransactionBatch, params merkle.Params) (string, error) {
+	batchData, err := json.Marshal(batch)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode cache key: %w", err)
+	}
+	paramData, err := json.Marshal(struct {
+		OneSigID        uint64
+		ContractAddr    string
+		LeafVersion     uint8
+		Options         *models.TreeOptions
+		Limits          utils.Limits
+		AutoSalt        bool
+		SaltDomain      string
+		EncoderRevision int
+	}{params.OneSigID, params.ContractAddr, params.LeafVersion, params.Options, params.Limits, params.AutoSalt, params.SaltDomain, utils.EncoderRevision})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode cache key: %w", err)
+	}
+	return cache.Key(batchData, paramData), nil
+}
+
+// treeOptions returns the tree options selected with --pair-order,
+// --leaf-order and --arity, warning
+// when they produce trees the OneSig contract cannot verify
+func treeOptions() (models.TreeOptions, error) {
+	order, err := merkle.ParsePairOrder(pairOrder)
+	if err != nil {
+		return models.TreeOptions{}, err
+	}
+	if order != merkle.PairOrderSorted {
+		fmt.Fprintf(os.Stderr, "WARNING: --pair-order %s builds roots and proofs that the OneSig contract cannot verify\n", order)
+	}
+	ordering, err := merkle.ParseLeafOrder(leafOrder)
+	if err != nil {
+		return models.TreeOptions{}, err
+	}
+	if err := merkle.CheckArity(arity); err != nil {
+		return models.TreeOptions{}, err
+	}
+	options := merkle.TreeOptionsWith(order, ordering)
+	if arity != merkle.DefaultArity {
+		fmt.Fprintf(os.Stderr, "WARNING: --arity %d builds roots and proofs that the OneSig contract cannot verify\n", arity)
+		options.Arity = arity
+	}
+	return options, nil
+}
+
+// addPairOrderFlags registers --pair-order and --arity on a command hashing
+// trees or proofs. Commands reading proofs files do not take them, as the file
+// records its tree options.
+func addPairOrderFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&pairOrder, "pair-order", string(merkle.DefaultPairOrder), "How sibling nodes are ordered before hashing: sorted (OneSig) or positional")
+	cmd.Flags().IntVar(&arity, "arity", merkle.DefaultArity, "Number of children of every node of the tree; wider trees have shorter proofs but cannot be verified by OneSig")
+	cobra.CheckErr(cmd.RegisterFlagCompletionFunc("pair-order", fixedCompletion(string(merkle.PairOrderSorted), string(merkle.PairOrderPositional))))
+}
+
+// addTreeFlags registers the flags of treeOptions on a command building trees
+func addTreeFlags(cmd *cobra.Command) {
+	addPairOrderFlags(cmd)
+	cmd.Flags().StringVar(&leafOrder, "leaf-order", string(merkle.DefaultLeafOrder), "Order in which leaves feed the tree: sorted (by hash, OneSig), input, by-nonce or by-onesig-nonce")
+	cobra.CheckErr(cmd.RegisterFlagCompletionFunc("leaf-order", fixedCompletion(string(merkle.LeafOrderSorted), string(merkle.LeafOrderInput), string(merkle.LeafOrderByNonce), string(merkle.LeafOrderByOneSigNonce))))
+}
+
+// batchLimits returns the limits set with the --max-* flags
+func batchLimits() utils.Limits {
+	return utils.Limits{
+		MaxLeaves:        maxLeaves,
+		MaxCallDataBytes: maxCallDataBytes,
+		MaxCallsPerLeaf:  maxCallsPerLeaf,
+		MaxProofLength:   maxProofLength,
+	}
+}
+
+// enforcePolicy rejects the batch if any call breaks the selector policy in
+// the config file or the batch exceeds one of its budgets. Delegatecalls are
+// rejected unless the policy allows them, even without a policy.
+func enforcePolicy(leaves []models.Leaf) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	engine, err := policy.New(cfg)
+	if err != nil {
+		return fmt.Errorf("invalid policy: %w", err)
+	}
+	violations, err := engine.Check(leaves)
+	if err != nil {
+		return err
+	}
+	if len(violations) > 0 {
+		for _, v := range violations {
+			fmt.Fprintln(os.Stderr, "policy violation:", v)
+		}
+		return withExitCode(exitValidation, fmt.Errorf("batch violates the selector policy (%d violation(s))", len(violations)))
+	}
+
+	overspends, err := engine.CheckBudgets(leaves)
+	if err != nil {
+		return err
+	}
+	if len(overspends) > 0 {
+		for _, o := range overspends {
+			fmt.Fprintln(os.Stderr, "budget exceeded:", o)
+		}
+		return withExitCode(exitValidation, fmt.Errorf("batch exceeds %d budget(s)", len(overspends)))
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"merkle-cli/leafstore"
+	"merkle-cli/merkle"
+)
+
+var leafStorePath string
+
+// checkLeafStore warns about leaves repeating the calls of a leaf of another
+// root recorded in --leaf-store, then records the leaves of this root
+func checkLeafStore(root string, entries []merkle.Entry) error {
+	if leafStorePath == "" {
+		return nil
+	}
+	store, err := leafstore.Open(leafStorePath)
+	if err != nil {
+		return err
+	}
+
+	repeated := 0
+	for _, entry := range entries {
+		fingerprint, err := leafstore.Fingerprint(entry.Leaf, leafVersion)
+		if err != nil {
+			return err
+		}
+		if record, ok := store.Lookup(fingerprint); ok && !strings.EqualFold(record.MerkleRoot, root) {
+			repeated++
+			fmt.Fprintf(os.Stderr, "Warning: nonce %d repeats the calls of nonce %d in root %s\n", entry.Leaf.Nonce, record.Nonce, record.MerkleRoot)
+		}
+		store.Add(fingerprint, leafstore.Record{
+			MerkleRoot: root,
+			OneSigID:   entry.Leaf.OneSigID,
+			Nonce:      entry.Leaf.Nonce,
+			LeafHash:   fmt.Sprintf("0x%x", entry.Hash),
+		})
+	}
+	if repeated > 0 {
+		fmt.Fprintf(os.Stderr, "Warning: %d leaf(s) repeat an action of an earlier root, check they are not copy-paste duplicates\n", repeated)
+	}
+	return store.Save()
+}
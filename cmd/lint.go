@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"fmt"
+
+	"merkle-cli/chain"
+	"merkle-cli/lint"
+	"merkle-cli/utils"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/spf13/cobra"
+)
+
+var (
+	lintBatchFile    string
+	lintContractAddr string
+	lintRPCURL       string
+	lintDisable      []string
+	lintJSON         bool
+	lintFailOnWarn   bool
+	lintEtherscan    etherscanOptions
+)
+
+// lintCmd reports suspicious calls in a transaction batch
+var lintCmd = &cobra.Command{
+	Use:   "lint",
+	Short: "Report suspicious calls in a transaction batch",
+	Long: `Report suspicious calls in a transaction batch
+
+Rules:
+  empty-call            call has neither data nor value
+  zero-address          call targets the zero address
+  self-call             call targets the OneSig contract itself
+  non-payable-transfer  plain value transfer to a contract that rejects it (needs --rpc-url)
+  unverified-target     call with data to a contract without verified source (needs --etherscan)
+  unknown-selector      selector missing from the target's verified ABI, or for an
+                        EIP-1967 proxy its implementation's (needs --etherscan)
+  implementation-changed  proxy implementation differs from the one pinned in the
+                        call's "implementation" (needs --rpc-url)
+
+Rules can be disabled with --disable or in the "lint" section of the config file.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		batch, err := readBatchFile(cmd.Context(), lintBatchFile)
+		if err != nil {
+			return err
+		}
+		if err := requireAddressTargets(batch, "lint"); err != nil {
+			return err
+		}
+		if err := utils.CheckLimits(batch, batchLimits()); err != nil {
+			return err
+		}
+
+		opts, err := lintOptions(lintContractAddr, lintDisable)
+		if err != nil {
+			return err
+		}
+		if lintRPCURL != "" {
+			opts.Client = chain.NewClient(lintRPCURL)
+		}
+		if opts.Explorer, err = lintEtherscan.client(); err != nil {
+			return err
+		}
+
+		warnings, err := lint.Batch(cmd.Context(), batch, opts)
+		if err != nil {
+			return err
+		}
+
+		if lintJSON {
+			if warnings == nil {
+				warnings = []lint.Warning{}
+			}
+			if err := writeJSON("", warnings); err != nil {
+				return err
+			}
+		} else {
+			for _, w := range warnings {
+				fmt.Println("warning:", w)
+			}
+			fmt.Printf("%d warning(s)\n", len(warnings))
+		}
+
+		if lintFailOnWarn && len(warnings) > 0 {
+			return withExitCode(exitValidation, fmt.Errorf("lint found %d warning(s)", len(warnings)))
+		}
+		return nil
+	},
+}
+
+// lintOptions builds the lint options for a OneSig contract, disabling the
+// given rules and those disabled in the config file
+func lintOptions(contractAddr string, disable []string) (lint.Options, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return lint.Options{}, err
+	}
+	disabled := append([]string{}, disable...)
+	if cfg.Lint != nil {
+		disabled = append(disabled, cfg.Lint.Disabled...)
+	}
+	if err := lint.CheckRules(disabled); err != nil {
+		return lint.Options{}, err
+	}
+
+	opts := lint.Options{
+		OneSig:   common.HexToAddress("0xdEaD"),
+		Disabled: make(map[string]bool),
+	}
+	if contractAddr != "" {
+		opts.OneSig = common.HexToAddress(contractAddr)
+	}
+	for _, rule := range disabled {
+		opts.Disabled[rule] = true
+	}
+	return opts, nil
+}
+
+func init() {
+	rootCmd.AddCommand(lintCmd)
+
+	lintCmd.Flags().StringVarP(&lintBatchFile, "batch-file", "f", "", "Path or https://, s3:// or gs:// URL of the transaction batch JSON file")
+	lintCmd.MarkFlagRequired("batch-file")
+
+	lintCmd.Flags().StringVarP(&lintContractAddr, "contract-addr", "c", "", "OneSig contract address (defaults to 0xdEaD)")
+	lintCmd.Flags().StringVar(&lintRPCURL, "rpc-url", "", "RPC endpoint used to check value transfers to contracts")
+	lintCmd.Flags().StringSliceVar(&lintDisable, "disable", nil, "Lint rules to skip")
+	lintCmd.RegisterFlagCompletionFunc("disable", completeLintRules)
+	lintCmd.Flags().BoolVar(&lintJSON, "json", false, "Print warnings as JSON")
+	lintCmd.Flags().BoolVar(&lintFailOnWarn, "fail-on-warning", false, "Exit with an error if any warning is reported")
+	lintEtherscan.register(lintCmd)
+}
@@ -0,0 +1,214 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"merkle-cli/encryption"
+	"merkle-cli/filter"
+	"merkle-cli/merkle"
+	"merkle-cli/utils"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	mergeInputs       []string
+	mergeLeafVersion  uint8
+	mergeOutput       string
+	mergeOutputFormat string
+	mergeSplit        bool
+	mergeFilter       string
+	mergeIndex        bool
+)
+
+// mergeCmd builds one tree over batch files prepared for different OneSig instances
+var mergeCmd = &cobra.Command{
+	Use:   "merge",
+	Short: "Build a single tree from several batch files",
+	Long: `Build a single tree from several batch files
+
+Each --input is a batch file followed by the OneSig ID and optionally the
+contract address its leaves are for, as in mainnet.json:30101:0x1234...; the
+contract address defaults to 0xdEaD. The leaves of all inputs are combined into
+one tree with a single root. Two inputs holding the same nonce for the same
+OneSig ID are rejected.
+
+With --split-by-onesig the proofs of every OneSig ID are written to their own
+file, named after --output with the OneSig ID appended (proofs-30101.json),
+all holding the same root.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if outputFormat := mergeOutputFormat; outputFormat != outputFormatJSON && outputFormat != outputFormatBinary {
+			return fmt.Errorf("unsupported output format %q, expected %s or %s", outputFormat, outputFormatJSON, outputFormatBinary)
+		}
+		if err := utils.CheckHashEncoding(hashEncoding); err != nil {
+			return err
+		}
+		if (mergeSplit || mergeFilter != "") && mergeOutput == "" {
+			return fmt.Errorf("--split-by-onesig and --filter require --output")
+		}
+		if mergeIndex && (mergeOutput == "" || mergeOutputFormat != outputFormatJSON) {
+			return fmt.Errorf("--index requires an --output file in json format")
+		}
+		if mergeFilter != "" {
+			if _, err := filter.Parse(mergeFilter); err != nil {
+				return fmt.Errorf("invalid --filter: %w", err)
+			}
+		}
+		if expectSHA256 != "" && len(mergeInputs) > 1 {
+			return fmt.Errorf("--expect-sha256 checks a single batch file, not %d", len(mergeInputs))
+		}
+
+		var sources []merkle.Source
+		for _, input := range mergeInputs {
+			source, err := parseMergeInput(input)
+			if err != nil {
+				return err
+			}
+			data, err := readBatchData(cmd.Context(), source.Name)
+			if err != nil {
+				return err
+			}
+			if source.Batch, err = parseBatch(data); err != nil {
+				return fmt.Errorf("%s: %w", source.Name, err)
+			}
+			sources = append(sources, *source)
+		}
+
+		options, err := treeOptions()
+		if err != nil {
+			return err
+		}
+		params := merkle.Params{
+			LeafVersion: mergeLeafVersion,
+			Options:     &options,
+			Limits:      batchLimits(),
+			Validate:    enforcePolicy,
+			Workers:     hashWorkers(),
+		}
+		if err := applySaltFlags(&params); err != nil {
+			return err
+		}
+		closeTrace, err := traceTree(&params)
+		if err != nil {
+			return err
+		}
+		result, err := merkle.NewMerkleModule(params).GenerateMerged(cmd.Context(), sources)
+		if closeErr := closeTrace(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return err
+		}
+
+		fmt.Println("Merkle Root:", utils.FormatHash(result.Tree.Root, hashEncoding))
+		// Entries are sorted by OneSig ID
+		for i := 0; i < len(result.Entries); {
+			j := i
+			for j < len(result.Entries) && result.Entries[j].Leaf.OneSigID == result.Entries[i].Leaf.OneSigID {
+				j++
+			}
+			fmt.Printf("  OneSig ID %d: %d leaves\n", result.Entries[i].Leaf.OneSigID, j-i)
+			i = j
+		}
+		subtrees, err := computeSubtrees(cmd.Context(), result.Tree, result.Entries, &options)
+		if err != nil {
+			return err
+		}
+		printSubtrees(os.Stdout, subtrees)
+
+		if mergeOutput != "" {
+			encoding := hashEncoding
+			if mergeOutputFormat == outputFormatBinary {
+				encoding = utils.HashEncodingHex
+			}
+			output := buildOutput(result.Tree, mergeLeafVersion, &options, result.Entries, encoding)
+			output.Subtrees = subtreeOutput(subtrees, encoding)
+			if err := filterProofs(&output, mergeFilter); err != nil {
+				return err
+			}
+			if !mergeSplit {
+				if err := writeOutputFile(mergeOutput, &output, mergeOutputFormat, false, encryption.Options{}); err != nil {
+					return err
+				}
+				fmt.Println("Output:", mergeOutput)
+				return writeMergeIndex(mergeOutput)
+			}
+			for _, part := range splitByOneSig(&output) {
+				path := splitOutputPath(mergeOutput, part.Proofs[0].OneSigID)
+				if err := writeOutputFile(path, part, mergeOutputFormat, false, encryption.Options{}); err != nil {
+					return err
+				}
+				fmt.Println("Output:", path)
+				if err := writeMergeIndex(path); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	},
+}
+
+// writeMergeIndex writes the index of a proofs file if --index is set
+func writeMergeIndex(path string) error {
+	if !mergeIndex {
+		return nil
+	}
+	index, err := writeIndexFile(path)
+	if err != nil {
+		return err
+	}
+	fmt.Println("Index:", index)
+	return nil
+}
+
+// parseMergeInput parses a file:oneSigId[:contractAddr] input
+func parseMergeInput(input string) (*merkle.Source, error) {
+	invalid := fmt.Errorf("invalid --input %q, expected file:oneSigId or file:oneSigId:contractAddr", input)
+
+	rest, last, ok := cutLast(input, ":")
+	if !ok {
+		return nil, invalid
+	}
+	source := &merkle.Source{}
+	if strings.HasPrefix(last, "0x") {
+		source.ContractAddr = last
+		if rest, last, ok = cutLast(rest, ":"); !ok {
+			return nil, invalid
+		}
+	}
+	id, err := strconv.ParseUint(last, 10, 64)
+	if err != nil || rest == "" {
+		return nil, invalid
+	}
+	source.Name = rest
+	source.OneSigID = id
+	return source, nil
+}
+
+// cutLast slices s around the last instance of sep
+func cutLast(s string, sep string) (string, string, bool) {
+	i := strings.LastIndex(s, sep)
+	if i < 0 {
+		return s, "", false
+	}
+	return s[:i], s[i+len(sep):], true
+}
+
+func init() {
+	rootCmd.AddCommand(mergeCmd)
+	addTreeFlags(mergeCmd)
+
+	mergeCmd.Flags().StringArrayVarP(&mergeInputs, "input", "i", nil, "Batch file and the instance its leaves are for, as file:oneSigId[:contractAddr] (repeatable)")
+	mergeCmd.MarkFlagRequired("input")
+	mergeCmd.Flags().Uint8Var(&mergeLeafVersion, "leaf-version", utils.LeafEncodingVersion, "Leaf encoding version")
+	mergeCmd.Flags().StringVar(&mergeOutput, "output", "", "Write the root and proofs of the combined tree to this file")
+	mergeCmd.Flags().StringVar(&mergeOutputFormat, "output-format", outputFormatJSON, "Format of the output file: json or bin")
+	mergeCmd.Flags().BoolVar(&mergeSplit, "split-by-onesig", false, "Write the proofs of every OneSig ID to a separate file")
+	mergeCmd.Flags().StringVar(&mergeFilter, "filter", "", "Write only the proofs matching this expression, e.g. 'oneSigId == 30101 && nonce >= 50'")
+	mergeCmd.Flags().BoolVar(&mergeIndex, "index", false, "Also write an index locating every proof by oneSigId and nonce next to each output file")
+	mergeCmd.RegisterFlagCompletionFunc("leaf-version", completeLeafVersions)
+	mergeCmd.RegisterFlagCompletionFunc("output-format", fixedCompletion(outputFormatJSON, outputFormatBinary))
+}
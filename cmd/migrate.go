@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"merkle-cli/sheet"
+
+	"github.com/spf13/cobra"
+)
+
+var migrateOutput string
+
+// migrateCmd converts a legacy transaction list into the group-based format
+var migrateCmd = &cobra.Command{
+	Use:   "migrate <legacy-batch-file>",
+	Short: "Convert a legacy transaction batch into the group-based format",
+	Long: `Convert a legacy transaction batch into the group-based format
+
+Legacy batches list transactions, each with its own nonce and calls, either as
+{"transactions": [...]} or as a bare array. Transactions sharing a nonce are
+merged into one group, keeping the order of their calls, so the converted batch
+produces the same leaves. Every command can also read legacy batches directly
+with --input-format legacy.
+
+With --input-format xlsx, converts a spreadsheet batch into JSON instead, to
+review or commit the batch a workbook holds. A gsheet:// Google Sheet is
+written as JSON recording its revision.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		data, err := readBatchData(cmd.Context(), args[0])
+		if err != nil {
+			return err
+		}
+		// Google Sheets are already read into the group-based format
+		converted := data
+		if !sheet.IsGoogleSheet(args[0]) {
+			if converted, err = migrateBatch(data); err != nil {
+				return err
+			}
+		}
+
+		if migrateOutput == "" {
+			_, err = os.Stdout.Write(converted)
+			return err
+		}
+		if err := os.WriteFile(migrateOutput, converted, 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", migrateOutput, err)
+		}
+		fmt.Println("Converted batch:", migrateOutput)
+		return nil
+	},
+}
+
+// migrateBatch converts a legacy or, with --input-format xlsx, an XLSX batch
+// into indented group-based JSON
+func migrateBatch(data []byte) ([]byte, error) {
+	parse := parseLegacyBatch
+	if inputFormat == inputFormatXLSX {
+		parse = parseXLSXBatch
+	}
+	batch, err := parse(data)
+	if err != nil {
+		return nil, err
+	}
+	converted, err := json.MarshalIndent(batch, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode batch: %w", err)
+	}
+	return append(converted, '\n'), nil
+}
+
+func init() {
+	rootCmd.AddCommand(migrateCmd)
+
+	migrateCmd.Flags().StringVar(&migrateOutput, "output", "", "Write the converted batch to this file instead of stdout")
+}
@@ -0,0 +1,300 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"merkle-cli/merkle"
+	"merkle-cli/mmr"
+	"merkle-cli/models"
+	"merkle-cli/utils"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	mmrLogFile      string
+	mmrBatchFile    string
+	mmrOneSigID     uint64
+	mmrContractAddr string
+	mmrLeafVersion  uint8
+	mmrLeafIndex    int64
+	mmrNonce        uint64
+	mmrOutput       string
+	mmrExpectedRoot string
+)
+
+// mmrAppendCmd appends the leaves of a batch to a Merkle mountain range log
+var mmrAppendCmd = &cobra.Command{
+	Use:   "mmr-append",
+	Short: "Append the leaves of a batch to a Merkle mountain range log",
+	Long: `Append the leaves of a batch to a Merkle mountain range log
+
+For deployments committing to an ever-growing log of authorized operations
+rather than to fixed batches. The leaves of the batch are encoded as for a
+tree, then appended in OneSig ID and nonce order to the MMR kept in --log,
+which is created if it does not exist. Earlier leaves keep their index and the
+nodes above them, so their proofs only gain peaks as the log grows. A leaf
+already in the log is rejected.
+
+The MMR root bags the peaks from right to left and commits to the number of
+leaves. It is not a OneSig Merkle root: it is verified by an MMR verifier,
+not by the OneSig contract.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := utils.CheckHashEncoding(hashEncoding); err != nil {
+			return err
+		}
+		log, err := mmr.OpenLog(mmrLogFile, mmrLeafVersion)
+		if err != nil {
+			return err
+		}
+		if log.LeafVersion != mmrLeafVersion {
+			return fmt.Errorf("the MMR log holds leaf encoding version %d, not %d", log.LeafVersion, mmrLeafVersion)
+		}
+
+		data, err := readBatchData(cmd.Context(), mmrBatchFile)
+		if err != nil {
+			return err
+		}
+		batch, err := parseBatch(data)
+		if err != nil {
+			return err
+		}
+		limits := batchLimits()
+		limits.MaxProofLength = 0
+		_, entries, err := generateTree(cmd.Context(), batch, merkle.Params{
+			OneSigID:     mmrOneSigID,
+			ContractAddr: mmrContractAddr,
+			LeafVersion:  mmrLeafVersion,
+			Limits:       limits,
+		})
+		if err != nil {
+			return err
+		}
+
+		first := log.MMR().LeafCount()
+		for _, entry := range entries {
+			if index, ok := log.Lookup(entry.Hash); ok {
+				return fmt.Errorf("%w: oneSigId %d nonce %d is already leaf %d of the MMR log", utils.ErrDuplicateNonce, entry.Leaf.OneSigID, entry.Leaf.Nonce, index)
+			}
+			log.Append(entry.Leaf.OneSigID, entry.Leaf.Nonce, entry.Hash)
+		}
+		if err := log.Save(); err != nil {
+			return err
+		}
+
+		fmt.Printf("Appended: %d leaves (indices %d..%d)\n", len(entries), first, log.MMR().LeafCount()-1)
+		fmt.Println("Leaf Count:", log.MMR().LeafCount())
+		fmt.Println("Peaks:", len(log.MMR().Peaks()))
+		fmt.Println("MMR Root:", utils.FormatHash(log.MMR().Root(), hashEncoding))
+		return nil
+	},
+}
+
+// mmrProveCmd writes the proof of one leaf of a Merkle mountain range log
+var mmrProveCmd = &cobra.Command{
+	Use:   "mmr-prove",
+	Short: "Write the proof of a leaf of a Merkle mountain range log",
+	Long: `Write the proof of a leaf of a Merkle mountain range log
+
+Selects the leaf by --leaf-index, or by --onesig-id and --nonce, and writes its
+proof against the current MMR root as JSON to --output, or to stdout. The proof
+holds the siblings from the leaf to the peak of its mountain and the other
+peaks, from left to right.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := utils.CheckHashEncoding(hashEncoding); err != nil {
+			return err
+		}
+		if _, err := os.Stat(mmrLogFile); err != nil {
+			return fmt.Errorf("failed to read MMR log: %w", err)
+		}
+		log, err := mmr.OpenLog(mmrLogFile, 0)
+		if err != nil {
+			return err
+		}
+		index, err := mmrSelectLeaf(cmd, log)
+		if err != nil {
+			return err
+		}
+		tree := &merkle.MountainRange{MMR: log.MMR()}
+		proof, err := tree.Proof(int(index))
+		if err != nil {
+			return err
+		}
+		// The proof lists the siblings up to the leaf's peak, then the other peaks
+		siblings := mmr.MountainHeight(index, tree.LeafCount())
+
+		leaf := log.Leaves[index]
+		hash, err := utils.ParseHash(leaf.LeafHash, utils.HashEncodingHex, "leafHash")
+		if err != nil {
+			return err
+		}
+		output := models.MMRProof{
+			MMRRoot:             utils.FormatHash(tree.Root(), hashEncoding),
+			LeafEncodingVersion: log.LeafVersion,
+			LeafCount:           tree.LeafCount(),
+			LeafIndex:           index,
+			OneSigID:            leaf.OneSigID,
+			Nonce:               leaf.Nonce,
+			LeafHash:            utils.FormatHash(hash, hashEncoding),
+			Siblings:            formatHashes(proof[:siblings]),
+			Peaks:               formatHashes(proof[siblings:]),
+		}
+		if hashEncoding != utils.HashEncodingHex {
+			output.HashEncoding = hashEncoding
+		}
+		data, err := json.MarshalIndent(output, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode MMR proof: %w", err)
+		}
+		if mmrOutput == "" {
+			fmt.Println(string(data))
+			return nil
+		}
+		if err := os.WriteFile(mmrOutput, append(data, '\n'), 0644); err != nil {
+			return fmt.Errorf("failed to write MMR proof: %w", err)
+		}
+		fmt.Println("Output:", mmrOutput)
+		return nil
+	},
+}
+
+// mmrSelectLeaf returns the index of the leaf selected with --leaf-index, or
+// with --onesig-id and --nonce
+func mmrSelectLeaf(cmd *cobra.Command, log *mmr.Log) (uint64, error) {
+	if cmd.Flags().Changed("leaf-index") {
+		if mmrLeafIndex < 0 || uint64(mmrLeafIndex) >= log.MMR().LeafCount() {
+			return 0, fmt.Errorf("--leaf-index %d out of range, the MMR log has %d leaves", mmrLeafIndex, log.MMR().LeafCount())
+		}
+		return uint64(mmrLeafIndex), nil
+	}
+	if !cmd.Flags().Changed("nonce") || !cmd.Flags().Changed("onesig-id") {
+		return 0, fmt.Errorf("either --leaf-index or --onesig-id and --nonce are required")
+	}
+	var matches []uint64
+	for i, leaf := range log.Leaves {
+		if leaf.OneSigID == mmrOneSigID && leaf.Nonce == mmrNonce {
+			matches = append(matches, uint64(i))
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return 0, fmt.Errorf("%w: no leaf of the MMR log has oneSigId %d and nonce %d", merkle.ErrLeafNotFound, mmrOneSigID, mmrNonce)
+	case 1:
+		return matches[0], nil
+	}
+	return 0, fmt.Errorf("leaves %v of the MMR log have oneSigId %d and nonce %d, select one with --leaf-index", matches, mmrOneSigID, mmrNonce)
+}
+
+// formatHashes serializes hashes with --hash-encoding
+func formatHashes(hashes [][]byte) []string {
+	formatted := make([]string, len(hashes))
+	for i, h := range hashes {
+		formatted[i] = utils.FormatHash(h, hashEncoding)
+	}
+	return formatted
+}
+
+// mmrVerifyCmd checks an MMR proof against its root
+var mmrVerifyCmd = &cobra.Command{
+	Use:   "mmr-verify <proof-file>",
+	Short: "Verify a Merkle mountain range proof",
+	Long: `Verify a Merkle mountain range proof
+
+Hashes the leaf of a proof written by mmr-prove up to its peak, bags it with
+the other peaks and compares the result with the root in the file and, if
+given, with --root. Exits with status 4 if they differ.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		data, err := os.ReadFile(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to read MMR proof: %w", err)
+		}
+		var file models.MMRProof
+		if err := json.Unmarshal(data, &file); err != nil {
+			return fmt.Errorf("failed to parse MMR proof: %w", err)
+		}
+		encoding := file.HashEncoding
+		if encoding == "" {
+			encoding = utils.HashEncodingHex
+		}
+		if err := utils.CheckHashEncoding(encoding); err != nil {
+			return err
+		}
+
+		root, err := utils.ParseHash(file.MMRRoot, encoding, "mmrRoot")
+		if err != nil {
+			return err
+		}
+		leaf, err := utils.ParseHash(file.LeafHash, encoding, "leafHash")
+		if err != nil {
+			return err
+		}
+		proof := &mmr.Proof{LeafIndex: file.LeafIndex, LeafCount: file.LeafCount}
+		if proof.Siblings, err = parseHashes(file.Siblings, encoding, "siblings"); err != nil {
+			return err
+		}
+		if proof.Peaks, err = parseHashes(file.Peaks, encoding, "peaks"); err != nil {
+			return err
+		}
+		if err := mmr.Verify(root, leaf, proof); err != nil {
+			return err
+		}
+
+		if mmrExpectedRoot != "" {
+			expected, err := parseRootFlag(mmrExpectedRoot)
+			if err != nil {
+				return err
+			}
+			if !bytes.Equal(root, expected) {
+				return withExitCode(exitVerification, fmt.Errorf("MMR root %s does not match %s", file.MMRRoot, mmrExpectedRoot))
+			}
+		}
+		fmt.Printf("Proof valid: leaf %d of %d (oneSigId %d, nonce %d)\n", file.LeafIndex, file.LeafCount, file.OneSigID, file.Nonce)
+		fmt.Println("MMR Root:", file.MMRRoot)
+		return nil
+	},
+}
+
+// parseHashes decodes the hashes of a field of an MMR proof
+func parseHashes(values []string, encoding string, field string) ([][]byte, error) {
+	hashes := make([][]byte, len(values))
+	for i, value := range values {
+		h, err := utils.ParseHash(value, encoding, fmt.Sprintf("%s[%d]", field, i))
+		if err != nil {
+			return nil, err
+		}
+		hashes[i] = h
+	}
+	return hashes, nil
+}
+
+func init() {
+	rootCmd.AddCommand(mmrAppendCmd)
+	rootCmd.AddCommand(mmrProveCmd)
+	rootCmd.AddCommand(mmrVerifyCmd)
+
+	mmrAppendCmd.Flags().StringVar(&mmrLogFile, "log", "", "JSON file holding the MMR log, created if it does not exist")
+	mmrAppendCmd.Flags().StringVarP(&mmrBatchFile, "batch-file", "f", "", "Path or URL of the transaction batch JSON file")
+	mmrAppendCmd.Flags().Uint64VarP(&mmrOneSigID, "onesig-id", "o", 0, "OneSig ID (typically chain ID)")
+	mmrAppendCmd.Flags().StringVarP(&mmrContractAddr, "contract-addr", "c", "", "OneSig contract address (defaults to 0xdEaD if not provided)")
+	mmrAppendCmd.Flags().Uint8Var(&mmrLeafVersion, "leaf-version", utils.LeafEncodingVersion, "Leaf encoding version, which must match the log's")
+	mmrAppendCmd.MarkFlagRequired("log")
+	mmrAppendCmd.MarkFlagRequired("batch-file")
+	mmrAppendCmd.MarkFlagRequired("onesig-id")
+	mmrAppendCmd.RegisterFlagCompletionFunc("leaf-version", completeLeafVersions)
+
+	mmrProveCmd.Flags().StringVar(&mmrLogFile, "log", "", "JSON file holding the MMR log")
+	mmrProveCmd.Flags().Int64Var(&mmrLeafIndex, "leaf-index", 0, "Index of the leaf in the log")
+	mmrProveCmd.Flags().Uint64VarP(&mmrOneSigID, "onesig-id", "o", 0, "OneSig ID of the leaf, with --nonce")
+	mmrProveCmd.Flags().Uint64Var(&mmrNonce, "nonce", 0, "Nonce of the leaf, with --onesig-id")
+	mmrProveCmd.Flags().StringVar(&mmrOutput, "output", "", "Write the proof to this file instead of stdout")
+	mmrProveCmd.MarkFlagRequired("log")
+	mmrProveCmd.MarkFlagsMutuallyExclusive("leaf-index", "nonce")
+
+	mmrVerifyCmd.Flags().StringVarP(&mmrExpectedRoot, "root", "r", "", "Expected MMR root to compare against")
+}
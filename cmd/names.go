@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"merkle-cli/models"
+	"merkle-cli/names"
+)
+
+var (
+	addressBookFile string
+	ensRPCURL       string
+	labelsFile      string
+
+	resolverOnce sync.Once
+	resolver     *names.Resolver
+	resolverErr  error
+)
+
+// nameResolver returns the resolver for --address-book and --ens-rpc-url,
+// shared by every batch read in the run so lookups are made once
+func nameResolver() (*names.Resolver, error) {
+	resolverOnce.Do(func() {
+		resolver = &names.Resolver{RPCURL: ensRPCURL}
+		if addressBookFile != "" {
+			resolver.Book, resolverErr = names.LoadAddressBook(addressBookFile)
+		}
+	})
+	return resolver, resolverErr
+}
+
+// addressLabels returns the labels shown for addresses in reports and
+// decoded output: the names of --address-book, then those of --labels
+func addressLabels() (names.Labels, error) {
+	r, err := nameResolver()
+	if err != nil {
+		return nil, err
+	}
+	labels := r.Book.Labels()
+	if labelsFile != "" {
+		fileLabels, err := names.LoadLabels(labelsFile)
+		if err != nil {
+			return nil, err
+		}
+		labels.Merge(fileLabels)
+	}
+	return labels, nil
+}
+
+// labelled formats an address followed by its label, if it has one
+func labelled(address string, labels names.Labels) string {
+	if label := labels.Label(address); label != "" {
+		return fmt.Sprintf("%s (%s)", address, label)
+	}
+	return address
+}
+
+// resolveNames replaces the @name and ENS name call targets of a batch with
+// their addresses, recording each name in the call's toName
+func resolveNames(batch *models.TransactionBatch) error {
+	for g := range batch.Groups {
+		group := &batch.Groups[g]
+		for i := range group.Calls {
+			call := &group.Calls[i]
+			if !names.IsName(call.To) {
+				continue
+			}
+			r, err := nameResolver()
+			if err != nil {
+				return err
+			}
+			address, err := r.Resolve(call.To)
+			if err != nil {
+				return withExitCode(exitValidation, fmt.Errorf("nonce %d calls[%d].to: %w", group.Nonce, i, err))
+			}
+			fmt.Fprintf(os.Stderr, "Resolved %s to %s\n", call.To, address.Hex())
+			call.ToName = call.To
+			call.To = address.Hex()
+		}
+	}
+	return nil
+}
+
+// resolvedBatchData returns a group-based batch with its names resolved, so
+// that it can be read without an address book or RPC endpoint. Batches
+// without names are returned unchanged.
+func resolvedBatchData(data []byte) ([]byte, error) {
+	var batch models.TransactionBatch
+	if err := json.Unmarshal(data, &batch); err != nil {
+		return nil, fmt.Errorf("failed to parse transaction batch: %w", err)
+	}
+	named := false
+	for _, group := range batch.Groups {
+		for _, call := range group.Calls {
+			named = named || names.IsName(call.To)
+		}
+	}
+	if !named {
+		return data, nil
+	}
+	if err := resolveNames(&batch); err != nil {
+		return nil, err
+	}
+	resolved, err := json.MarshalIndent(batch, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode batch: %w", err)
+	}
+	return append(resolved, '\n'), nil
+}
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&addressBookFile, "address-book", "", "JSON file of names and addresses, used as \"to\": \"@name\" in batches")
+	rootCmd.PersistentFlags().StringVar(&ensRPCURL, "ens-rpc-url", "", "Ethereum JSON-RPC endpoint used to resolve ENS names given as call targets")
+	rootCmd.PersistentFlags().StringVar(&labelsFile, "labels", "", "JSON file of address labels, plain or Etherscan-style, shown next to addresses in reports")
+}
@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+
+	"merkle-cli/merkle"
+	"merkle-cli/notify"
+	"merkle-cli/utils"
+)
+
+// notifyWebhook posts the digest of a generated tree to --notify-webhook
+func notifyWebhook(ctx context.Context, tree *merkle.MerkleTree, leaves int, batchData []byte) error {
+	summary := &notify.Summary{
+		MerkleRoot:  utils.NormalizeHex(tree.Root),
+		OneSigID:    oneSigID,
+		Leaves:      leaves,
+		InputSHA256: fmt.Sprintf("%x", sha256.Sum256(batchData)),
+		ArtifactURL: artifactURL,
+	}
+	if err := notify.Post(ctx, notifyURL, summary); err != nil {
+		return fmt.Errorf("failed to notify webhook: %w", err)
+	}
+	return nil
+}
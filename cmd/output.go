@@ -0,0 +1,368 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"merkle-cli/encryption"
+	"merkle-cli/filter"
+	"merkle-cli/merkle"
+	"merkle-cli/models"
+	"merkle-cli/proofbin"
+	"merkle-cli/proofindex"
+	"merkle-cli/utils"
+)
+
+// buildOutput converts the generated tree and its leaf entries into the JSON output format,
+// with hashes serialized in the given encoding
+func buildOutput(tree *merkle.MerkleTree, version uint8, options *models.TreeOptions, entries []merkle.Entry, encoding string) models.OutputFormat {
+	output := models.OutputFormat{
+		FormatVersion:       models.OutputFormatVersion,
+		MerkleRoot:          utils.FormatHash(tree.Root, encoding),
+		LeafEncodingVersion: version,
+		TreeOptions:         options,
+		Proofs:              make([]models.ProofEntry, 0, len(entries)),
+	}
+	if encoding != utils.HashEncodingHex {
+		output.HashEncoding = encoding
+	}
+
+	leaves := make([]models.Leaf, 0, len(entries))
+	for _, entry := range entries {
+		leaves = append(leaves, entry.Leaf)
+	}
+	output.Aggregate = models.AggregateLeaves(leaves)
+	output.TreeShape = &models.TreeShape{Depth: merkle.TreeDepthArity(len(tree.Leafs), merkle.ArityOf(options))}
+
+	for _, entry := range entries {
+		proof := make([]string, 0, len(entry.Proof))
+		for _, p := range entry.Proof {
+			proof = append(proof, utils.FormatHash(p, encoding))
+		}
+
+		output.Proofs = append(output.Proofs, models.ProofEntry{
+			Leaf:      entry.Leaf,
+			LeafIndex: entry.Index,
+			LeafHash:  utils.FormatHash(entry.Hash, encoding),
+			Proof:     proof,
+		})
+		if len(proof) > output.TreeShape.MaxProofLength {
+			output.TreeShape.MaxProofLength = len(proof)
+		}
+	}
+
+	return output
+}
+
+// Output file formats selectable with --output-format. The table format is
+// printed to the terminal instead of written to a file.
+const (
+	outputFormatJSON   = "json"
+	outputFormatBinary = "bin"
+	outputFormatTable  = "table"
+)
+
+// encodeOutput serializes the output as indented JSON or in the compact binary format
+func encodeOutput(output *models.OutputFormat, format string, redact bool) ([]byte, error) {
+	var data []byte
+	var err error
+	switch {
+	case format == outputFormatBinary:
+		data, err = proofbin.Encode(output, redact)
+	case redact:
+		data, err = json.MarshalIndent(output.Redact(), "", "  ")
+	default:
+		data, err = json.MarshalIndent(output, "", "  ")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: output: %w", utils.ErrEncoding, err)
+	}
+	if format == outputFormatJSON {
+		data = append(data, '\n')
+	}
+	return data, nil
+}
+
+// writeOutputFile writes the encoded output, encrypted if recipients are given
+func writeOutputFile(path string, output *models.OutputFormat, format string, redact bool, encrypt encryption.Options) error {
+	data, err := encodeOutput(output, format, redact)
+	if err != nil {
+		return err
+	}
+
+	if encrypt.Enabled() {
+		if data, err = encryption.Encrypt(data, encrypt); err != nil {
+			return fmt.Errorf("failed to encrypt output: %w", err)
+		}
+	}
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+
+	return nil
+}
+
+// outputEncryption returns the encryption options selected by --encrypt-to and --encrypt-gpg
+func outputEncryption() encryption.Options {
+	return encryption.Options{AgeRecipients: encryptTo, GPGRecipients: encryptGPG}
+}
+
+// readOutputFile reads a proofs file previously written with --output
+func readOutputFile(path string) (*models.OutputFormat, error) {
+	output, redacted, err := decodeOutputFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if redacted {
+		return nil, fmt.Errorf("proofs file %s is redacted and has no call data", path)
+	}
+	return output, nil
+}
+
+// decodeOutputFile reads a JSON or binary proofs file, converting its hashes
+// to hex, and reports whether it was redacted
+func decodeOutputFile(path string) (*models.OutputFormat, bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read proofs file: %w", err)
+	}
+	if encryption.IsEncrypted(data) {
+		return nil, false, fmt.Errorf("proofs file %s is encrypted, decrypt it first with the decrypt command", path)
+	}
+
+	var output models.OutputFormat
+	redacted := false
+	if proofbin.IsBinary(data) {
+		decoded, isRedacted, err := proofbin.Decode(data)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to parse proofs file: %w", err)
+		}
+		output, redacted = *decoded, isRedacted
+	} else {
+		var document struct {
+			models.OutputFormat
+			Redacted bool `json:"redacted"`
+		}
+		if err := json.Unmarshal(data, &document); err != nil {
+			return nil, false, fmt.Errorf("failed to parse proofs file: %w", err)
+		}
+		output, redacted = document.OutputFormat, document.Redacted
+	}
+
+	if err := upgradeOutput(&output); err != nil {
+		return nil, false, fmt.Errorf("invalid proofs file %s: %w", path, err)
+	}
+	if _, err := merkle.CheckTreeOptions(output.TreeOptions); err != nil {
+		return nil, false, fmt.Errorf("invalid proofs file %s: %w", path, err)
+	}
+	if err := hashesToHex(&output); err != nil {
+		return nil, false, fmt.Errorf("invalid proofs file %s: %w", path, err)
+	}
+
+	return &output, redacted, nil
+}
+
+// upgradeOutput rejects outputs written by a newer version of the CLI and
+// brings older ones up to the current format version, filling in the leaf
+// encoding version and tree options that unversioned files may lack
+func upgradeOutput(output *models.OutputFormat) error {
+	if output.FormatVersion < 0 || output.FormatVersion > models.OutputFormatVersion {
+		return fmt.Errorf("%w: output format version %d, this merkle-cli reads versions up to %d", utils.ErrUnsupportedVersion, output.FormatVersion, models.OutputFormatVersion)
+	}
+	if output.LeafEncodingVersion == 0 {
+		output.LeafEncodingVersion = 1
+	}
+	if output.TreeOptions == nil {
+		defaults := merkle.DefaultTreeOptions()
+		output.TreeOptions = &defaults
+	}
+	output.FormatVersion = models.OutputFormatVersion
+	return nil
+}
+
+// hashesToHex converts the root and proofs of an output read from disk to hex,
+// the form used throughout the CLI
+func hashesToHex(output *models.OutputFormat) error {
+	encoding := output.HashEncoding
+	if encoding == "" {
+		encoding = utils.HashEncodingHex
+	}
+	if err := utils.CheckHashEncoding(encoding); err != nil {
+		return err
+	}
+
+	var err error
+	if output.MerkleRoot, err = hashToHex(output.MerkleRoot, encoding, "merkleRoot"); err != nil {
+		return err
+	}
+	for i := range output.Subtrees {
+		s := &output.Subtrees[i]
+		if s.Root, err = hashToHex(s.Root, encoding, fmt.Sprintf("subtrees[%d].root", i)); err != nil {
+			return err
+		}
+		if s.Node == nil {
+			continue
+		}
+		for j := range s.Node.Proof {
+			if s.Node.Proof[j], err = hashToHex(s.Node.Proof[j], encoding, fmt.Sprintf("subtrees[%d].node.proof[%d]", i, j)); err != nil {
+				return err
+			}
+		}
+	}
+	for i := range output.Proofs {
+		entry := &output.Proofs[i]
+		if entry.LeafHash, err = hashToHex(entry.LeafHash, encoding, fmt.Sprintf("proofs[%d].leafHash", i)); err != nil {
+			return err
+		}
+		for j := range entry.Proof {
+			if entry.Proof[j], err = hashToHex(entry.Proof[j], encoding, fmt.Sprintf("proofs[%d].proof[%d]", i, j)); err != nil {
+				return err
+			}
+		}
+	}
+	output.HashEncoding = ""
+	return nil
+}
+
+// hashToHex re-encodes a hash given in the encoding as hex
+func hashToHex(value string, encoding string, field string) (string, error) {
+	b, err := utils.ParseHash(value, encoding, field)
+	if err != nil {
+		return "", err
+	}
+	return utils.NormalizeHex(b), nil
+}
+
+// selectEntry picks the proof entry for a nonce, using the leaf hash to disambiguate
+// nonces that have several windowed leaves
+func selectEntry(output *models.OutputFormat, nonce uint64, leafHash string) (*models.ProofEntry, error) {
+	candidates := output.EntriesForNonce(nonce)
+	if leafHash != "" {
+		for i := range candidates {
+			if candidates[i].LeafHash == leafHash {
+				return &candidates[i], nil
+			}
+		}
+		return nil, fmt.Errorf("no proof entry with nonce %d and leaf %s", nonce, leafHash)
+	}
+
+	switch len(candidates) {
+	case 0:
+		return nil, fmt.Errorf("no proof entry with nonce %d", nonce)
+	case 1:
+		return &candidates[0], nil
+	default:
+		return nil, fmt.Errorf("nonce %d has %d leaves, select one with --leaf", nonce, len(candidates))
+	}
+}
+
+// checkEntryLeafHash re-encodes the leaf of a proof entry and checks that it
+// hashes to the entry's leaf hash, so calldata is never built from a leaf
+// that differs from the one the signers approved
+func checkEntryLeafHash(entry *models.ProofEntry, version uint8) error {
+	encoded, err := utils.EncodeLeafVersion(entry.Leaf, version)
+	if err != nil {
+		return fmt.Errorf("failed to encode the leaf with nonce %d: %w", entry.Nonce, err)
+	}
+	leafHash, err := utils.HexToBytes(entry.LeafHash)
+	if err != nil {
+		return fmt.Errorf("invalid leaf hash for nonce %d: %w", entry.Nonce, err)
+	}
+	if !bytes.Equal(encoded, leafHash) {
+		return withExitCode(exitVerification, fmt.Errorf("leaf with nonce %d encodes to 0x%x, not to its leaf hash %s", entry.Nonce, encoded, entry.LeafHash))
+	}
+	return nil
+}
+
+// splitByOneSig splits an output into one output per OneSig ID, in OneSig ID
+// order, each holding the same root and the proofs and subtree root of that
+// OneSig ID
+func splitByOneSig(output *models.OutputFormat) []*models.OutputFormat {
+	var parts []*models.OutputFormat
+	byID := make(map[uint64]*models.OutputFormat)
+	for _, entry := range output.Proofs {
+		part, ok := byID[entry.OneSigID]
+		if !ok {
+			part = &models.OutputFormat{
+				FormatVersion:       output.FormatVersion,
+				MerkleRoot:          output.MerkleRoot,
+				LeafEncodingVersion: output.LeafEncodingVersion,
+				HashEncoding:        output.HashEncoding,
+				TreeOptions:         output.TreeOptions,
+				TreeShape:           output.TreeShape,
+				Aggregate:           output.Aggregate,
+			}
+			for _, s := range output.Subtrees {
+				if s.OneSigID == entry.OneSigID {
+					part.Subtrees = append(part.Subtrees, s)
+				}
+			}
+			byID[entry.OneSigID] = part
+			parts = append(parts, part)
+		}
+		part.Proofs = append(part.Proofs, entry)
+	}
+	sort.Slice(parts, func(i, j int) bool {
+		return parts[i].Proofs[0].OneSigID < parts[j].Proofs[0].OneSigID
+	})
+	return parts
+}
+
+// splitOutputPath names the file of one OneSig ID's proofs after the output
+// path, as proofs-30101.json for proofs.json
+func splitOutputPath(path string, oneSigID uint64) string {
+	ext := filepath.Ext(path)
+	return fmt.Sprintf("%s-%d%s", strings.TrimSuffix(path, ext), oneSigID, ext)
+}
+
+// filterProofs keeps the proof entries matching a --filter expression. The
+// tree and its root are unchanged.
+func filterProofs(output *models.OutputFormat, expr string) error {
+	if expr == "" {
+		return nil
+	}
+	parsed, err := filter.Parse(expr)
+	if err != nil {
+		return fmt.Errorf("invalid --filter: %w", err)
+	}
+	if output.Proofs, err = parsed.Filter(output.Proofs); err != nil {
+		return err
+	}
+	if len(output.Proofs) == 0 {
+		return fmt.Errorf("--filter matches no proof entries")
+	}
+	return nil
+}
+
+// indexPath names the index file of a proofs file, as proofs.index.json for proofs.json
+func indexPath(path string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + ".index.json"
+}
+
+// writeIndexFile indexes the JSON proofs file at path by oneSigId and nonce
+// and writes the index next to it
+func writeIndexFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read proofs file: %w", err)
+	}
+	index, err := proofindex.Build(data, filepath.Base(path))
+	if err != nil {
+		return "", err
+	}
+	encoded, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	out := indexPath(path)
+	if err := os.WriteFile(out, append(encoded, '\n'), 0644); err != nil {
+		return "", fmt.Errorf("failed to write index file: %w", err)
+	}
+	return out, nil
+}
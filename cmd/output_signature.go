@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"merkle-cli/models"
+	"merkle-cli/signer"
+	"merkle-cli/utils"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// outputSignaturePath returns the path of the host signature of a proofs file
+func outputSignaturePath(path string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + ".sig.json"
+}
+
+// signOutputFile signs the unencrypted encoding of a proofs file written to
+// path with the key of the generation host, and writes the signature next to it
+func signOutputFile(path string, output *models.OutputFormat, format string, redact bool, s signer.Signer) (string, error) {
+	data, err := encodeOutput(output, format, redact)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	signature, err := s.SignDigest(utils.PersonalMessageDigest(sum[:]))
+	if err != nil {
+		return "", fmt.Errorf("failed to sign output file: %w", err)
+	}
+	out := outputSignaturePath(path)
+	err = writeJSON(out, models.OutputSignature{
+		File:      filepath.Base(path),
+		SHA256:    utils.NormalizeHex(sum[:]),
+		Signer:    s.Address().Hex(),
+		Signature: utils.NormalizeHex(signature),
+	})
+	return out, err
+}
+
+// verifyOutputSignature checks the host signature file of a proofs file against
+// its data and returns the signer, which must be one of allowed
+func verifyOutputSignature(signaturePath string, data []byte, allowed []string) (common.Address, error) {
+	raw, err := os.ReadFile(signaturePath)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("failed to read output signature: %w", err)
+	}
+	var file models.OutputSignature
+	if err := json.Unmarshal(raw, &file); err != nil {
+		return common.Address{}, fmt.Errorf("failed to parse output signature %s: %w", signaturePath, err)
+	}
+
+	sum := sha256.Sum256(data)
+	if !strings.EqualFold(file.SHA256, utils.NormalizeHex(sum[:])) {
+		return common.Address{}, withExitCode(exitVerification, fmt.Errorf("output signature is for SHA-256 %s, the file has %s", file.SHA256, utils.NormalizeHex(sum[:])))
+	}
+	signature, err := utils.ParseHex(file.Signature, "signature", true)
+	if err != nil {
+		return common.Address{}, err
+	}
+	recovered, err := signer.RecoverAddress(utils.PersonalMessageDigest(sum[:]), signature)
+	if err != nil {
+		return common.Address{}, withExitCode(exitVerification, fmt.Errorf("invalid output signature: %w", err))
+	}
+	if !strings.EqualFold(recovered.Hex(), file.Signer) {
+		return common.Address{}, withExitCode(exitVerification, fmt.Errorf("output signature is by %s, not %s as it claims", recovered.Hex(), file.Signer))
+	}
+	for _, a := range allowed {
+		if common.IsHexAddress(a) && common.HexToAddress(a) == recovered {
+			return recovered, nil
+		}
+	}
+	return common.Address{}, withExitCode(exitVerification, fmt.Errorf("output is signed by %s, which is not an expected host signer", recovered.Hex()))
+}
@@ -0,0 +1,195 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"merkle-cli/encryption"
+	"merkle-cli/merkle"
+	"merkle-cli/plan"
+	"merkle-cli/utils"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	planInputs       []string
+	planOutputDir    string
+	planLeafVersion  uint8
+	planOutputFormat string
+	planMaxLeaves    int
+	planGroupByChain bool
+	planWindow       time.Duration
+	planStart        string
+)
+
+// planCmd splits a large set of batches into a campaign of several roots
+var planCmd = &cobra.Command{
+	Use:   "plan",
+	Short: "Split batches into a campaign of several roots",
+	Long: `Split batches into a campaign of several roots
+
+Each --input is a batch file followed by the OneSig ID and optionally the
+contract address its leaves are for, as for merge. The groups of all inputs
+are split into roots so that:
+
+  - no root holds more than --max-leaves-per-root leaves
+  - with --group-by-chain, every root holds the leaves of a single OneSig ID
+  - with --window, every root holds the leaves becoming valid (validAfter) in
+    the same window of that length after --start; its expiry is the end of
+    the window
+
+Leaves are ordered by nonce within and across the roots of a OneSig ID. A
+leaf becoming valid in an earlier window than a lower nonce is an error, as
+OneSig executes nonces in order.
+
+The proofs of every root are written to --output-dir as root-001.json,
+root-002.json and so on, in the order the roots are committed. campaign.json
+links them: it lists every root with its file, leaves, nonce range per
+OneSig ID, window, expiry and the previous root of the same OneSig IDs.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if planOutputFormat != outputFormatJSON && planOutputFormat != outputFormatBinary {
+			return fmt.Errorf("unsupported output format %q, expected %s or %s", planOutputFormat, outputFormatJSON, outputFormatBinary)
+		}
+		if err := utils.CheckHashEncoding(hashEncoding); err != nil {
+			return err
+		}
+		if planWindow > 0 && planStart == "" {
+			return fmt.Errorf("--window requires --start")
+		}
+		constraints := plan.Constraints{
+			MaxLeaves:    planMaxLeaves,
+			GroupByChain: planGroupByChain,
+			Window:       planWindow,
+		}
+		if planStart != "" {
+			start, err := parseTimestamp("--start", planStart)
+			if err != nil {
+				return err
+			}
+			constraints.Start = time.Unix(int64(start), 0)
+		}
+
+		var sources []merkle.Source
+		for _, input := range planInputs {
+			source, err := parseMergeInput(input)
+			if err != nil {
+				return err
+			}
+			if source.Batch, err = readBatchFile(cmd.Context(), source.Name); err != nil {
+				return fmt.Errorf("%s: %w", source.Name, err)
+			}
+			sources = append(sources, *source)
+		}
+
+		roots, err := plan.Partition(sources, constraints)
+		if err != nil {
+			return withExitCode(exitValidation, err)
+		}
+		if len(roots) == 0 {
+			return fmt.Errorf("the inputs hold no groups")
+		}
+
+		options, err := treeOptions()
+		if err != nil {
+			return err
+		}
+		params := merkle.Params{
+			LeafVersion: planLeafVersion,
+			Options:     &options,
+			Limits:      batchLimits(),
+			Validate:    enforcePolicy,
+			Workers:     hashWorkers(),
+		}
+		if err := applySaltFlags(&params); err != nil {
+			return err
+		}
+		module := merkle.NewMerkleModule(params)
+
+		if err := os.MkdirAll(planOutputDir, 0755); err != nil {
+			return fmt.Errorf("failed to create %s: %w", planOutputDir, err)
+		}
+		encoding := hashEncoding
+		if planOutputFormat == outputFormatBinary {
+			encoding = utils.HashEncodingHex
+		}
+
+		manifest := plan.Manifest{
+			LeafEncodingVersion: planLeafVersion,
+			TreeOptions:         &options,
+			MaxLeavesPerRoot:    planMaxLeaves,
+			GroupByChain:        planGroupByChain,
+			Window:              uint64(planWindow / time.Second),
+		}
+		// latest holds the index of the last root of every OneSig ID
+		latest := make(map[uint64]int)
+		for i, root := range roots {
+			result, err := module.GenerateMerged(cmd.Context(), root.Sources)
+			if err != nil {
+				return fmt.Errorf("root %d: %w", i+1, err)
+			}
+			output := buildOutput(result.Tree, planLeafVersion, &options, result.Entries, encoding)
+			file := fmt.Sprintf("root-%03d.%s", i+1, planOutputFormat)
+			if err := writeOutputFile(filepath.Join(planOutputDir, file), &output, planOutputFormat, false, encryption.Options{}); err != nil {
+				return err
+			}
+
+			entry := plan.ManifestRoot{
+				Index:       i + 1,
+				MerkleRoot:  output.MerkleRoot,
+				File:        file,
+				Leaves:      root.Leaves,
+				WindowStart: root.WindowStart,
+				Expiry:      root.Expiry,
+				Chains:      root.Chains(),
+			}
+			previous := -1
+			for _, chain := range entry.Chains {
+				if j, ok := latest[chain.OneSigID]; ok && j > previous {
+					previous = j
+				}
+				latest[chain.OneSigID] = i
+			}
+			if previous >= 0 {
+				entry.PreviousRoot = manifest.Roots[previous].MerkleRoot
+			}
+			manifest.Roots = append(manifest.Roots, entry)
+
+			fmt.Printf("%s %s (%d leaves", entry.MerkleRoot, file, entry.Leaves)
+			if entry.Expiry != 0 {
+				fmt.Printf(", expires %s", time.Unix(int64(entry.Expiry), 0).UTC().Format(time.RFC3339))
+			}
+			fmt.Println(")")
+			for _, chain := range entry.Chains {
+				fmt.Printf("  OneSig ID %d: nonces %d-%d\n", chain.OneSigID, chain.FirstNonce, chain.LastNonce)
+			}
+		}
+
+		manifestPath := filepath.Join(planOutputDir, plan.ManifestFile)
+		if err := writeJSON(manifestPath, manifest); err != nil {
+			return err
+		}
+		fmt.Println("Campaign:", manifestPath)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(planCmd)
+	addTreeFlags(planCmd)
+
+	planCmd.Flags().StringArrayVarP(&planInputs, "input", "i", nil, "Batch file and the instance its leaves are for, as file:oneSigId[:contractAddr] (repeatable)")
+	planCmd.MarkFlagRequired("input")
+	planCmd.Flags().StringVar(&planOutputDir, "output-dir", "", "Directory to write the proofs of every root and the campaign manifest to")
+	planCmd.MarkFlagRequired("output-dir")
+	planCmd.Flags().Uint8Var(&planLeafVersion, "leaf-version", utils.LeafEncodingVersion, "Leaf encoding version")
+	planCmd.Flags().StringVar(&planOutputFormat, "output-format", outputFormatJSON, "Format of the proofs files: json or bin")
+	planCmd.Flags().IntVar(&planMaxLeaves, "max-leaves-per-root", 0, "Maximum number of leaves of a root (0 for no maximum)")
+	planCmd.Flags().BoolVar(&planGroupByChain, "group-by-chain", false, "Give every OneSig ID its own roots")
+	planCmd.Flags().DurationVar(&planWindow, "window", 0, "Split leaves into windows of this length by validAfter, such as 168h for weekly roots")
+	planCmd.Flags().StringVar(&planStart, "start", "", "Start of the first window, as unix seconds or RFC 3339")
+	planCmd.RegisterFlagCompletionFunc("leaf-version", completeLeafVersions)
+	planCmd.RegisterFlagCompletionFunc("output-format", fixedCompletion(outputFormatJSON, outputFormatBinary))
+}
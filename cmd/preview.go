@@ -0,0 +1,193 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"merkle-cli/lint"
+	"merkle-cli/merkle"
+	"merkle-cli/models"
+	"merkle-cli/utils"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	previewOneSigID     uint64
+	previewContractAddr string
+	previewLeafVersion  uint8
+	previewDisable      []string
+)
+
+// previewResult is the line written for every document read by preview
+type previewResult struct {
+	Document    int                 `json:"document"`
+	MerkleRoot  string              `json:"merkleRoot,omitempty"`
+	Leaves      int                 `json:"leaves,omitempty"`
+	Diagnostics []previewDiagnostic `json:"diagnostics"`
+}
+
+// previewDiagnostic is an error or lint warning found in a document. Line
+// and column locate JSON errors; nonce and call locate lint warnings.
+type previewDiagnostic struct {
+	Severity string  `json:"severity"`
+	Message  string  `json:"message"`
+	Rule     string  `json:"rule,omitempty"`
+	Line     int     `json:"line,omitempty"`
+	Column   int     `json:"column,omitempty"`
+	Nonce    *uint64 `json:"nonce,omitempty"`
+	Call     *int    `json:"call,omitempty"`
+}
+
+// previewCmd reads successive batch documents from stdin and reports the root
+// and diagnostics of each, for editor integrations
+var previewCmd = &cobra.Command{
+	Use:   "preview",
+	Short: "Report the root and diagnostics of every batch document read from stdin",
+	Long: `Report the root and diagnostics of every batch document read from stdin
+
+Reads transaction batch documents from stdin until it is closed, separated by
+NUL bytes, so an editor plugin can send the buffer on every save and show the
+live root and errors. A document with a syntax error does not affect the next.
+
+For every document, writes one line of JSON to stdout holding the document
+number, the Merkle root and leaf count when the batch is valid, and its
+diagnostics: parse and validation errors, with the line and column of JSON
+errors, and the warnings of the lint rules that need no network access.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		options, err := treeOptions()
+		if err != nil {
+			return err
+		}
+		lintOpts, err := lintOptions(previewContractAddr, previewDisable)
+		if err != nil {
+			return err
+		}
+		params := merkle.Params{
+			OneSigID:     previewOneSigID,
+			ContractAddr: previewContractAddr,
+			LeafVersion:  previewLeafVersion,
+			Options:      &options,
+			Limits:       batchLimits(),
+		}
+
+		scanner := bufio.NewScanner(os.Stdin)
+		scanner.Buffer(nil, 64<<20)
+		scanner.Split(splitDocuments)
+		out := json.NewEncoder(os.Stdout)
+		for n := 1; scanner.Scan(); n++ {
+			if err := cmd.Context().Err(); err != nil {
+				return err
+			}
+			result := previewDocument(cmd, scanner.Bytes(), params, lintOpts)
+			result.Document = n
+			if err := out.Encode(result); err != nil {
+				return err
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return fmt.Errorf("failed to read stdin: %w", err)
+		}
+		return nil
+	},
+}
+
+// previewDocument parses, generates and lints one batch document
+func previewDocument(cmd *cobra.Command, data []byte, params merkle.Params, lintOpts lint.Options) previewResult {
+	result := previewResult{Diagnostics: []previewDiagnostic{}}
+	fail := func(err error) previewResult {
+		d := previewDiagnostic{Severity: "error", Message: err.Error()}
+		var syntaxErr *json.SyntaxError
+		var typeErr *json.UnmarshalTypeError
+		switch {
+		case errors.As(err, &syntaxErr):
+			d.Line, d.Column = lineColumn(data, syntaxErr.Offset)
+		case errors.As(err, &typeErr):
+			d.Line, d.Column = lineColumn(data, typeErr.Offset)
+		}
+		result.Diagnostics = append(result.Diagnostics, d)
+		return result
+	}
+
+	batch, err := parseBatch(data)
+	if err != nil {
+		return fail(err)
+	}
+	if err := utils.CheckBatchHex(batch, false); err != nil {
+		return fail(err)
+	}
+	tree, entries, err := generateTree(cmd.Context(), batch, params)
+	if err != nil {
+		return fail(err)
+	}
+	result.MerkleRoot = utils.FormatHash(tree.Root, hashEncoding)
+	result.Leaves = len(entries)
+
+	if batch.TargetFormat == models.TargetFormatBytes32 {
+		return result
+	}
+	warnings, err := lint.Batch(cmd.Context(), batch, lintOpts)
+	if err != nil {
+		return fail(err)
+	}
+	for _, w := range warnings {
+		w := w
+		result.Diagnostics = append(result.Diagnostics, previewDiagnostic{
+			Severity: "warning",
+			Message:  w.Message,
+			Rule:     w.Rule,
+			Nonce:    &w.Nonce,
+			Call:     &w.Call,
+		})
+	}
+	return result
+}
+
+// splitDocuments is a bufio.SplitFunc splitting stdin on NUL bytes. Documents
+// holding only whitespace are skipped.
+func splitDocuments(data []byte, atEOF bool) (int, []byte, error) {
+	var document []byte
+	advance := 0
+	if i := bytes.IndexByte(data, 0); i >= 0 {
+		document, advance = data[:i], i+1
+	} else if atEOF && len(data) > 0 {
+		document, advance = data, len(data)
+	} else if atEOF {
+		return 0, nil, io.EOF
+	} else {
+		return 0, nil, nil
+	}
+	if len(bytes.TrimSpace(document)) == 0 {
+		return advance, nil, nil
+	}
+	return advance, document, nil
+}
+
+// lineColumn converts a byte offset into a 1-based line and column
+func lineColumn(data []byte, offset int64) (int, int) {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	before := data[:offset]
+	line := bytes.Count(before, []byte{'\n'}) + 1
+	column := len(before) - bytes.LastIndexByte(before, '\n')
+	return line, column
+}
+
+func init() {
+	rootCmd.AddCommand(previewCmd)
+	addTreeFlags(previewCmd)
+
+	previewCmd.Flags().Uint64VarP(&previewOneSigID, "onesig-id", "o", 0, "OneSig ID (typically chain ID)")
+	previewCmd.MarkFlagRequired("onesig-id")
+	previewCmd.Flags().StringVarP(&previewContractAddr, "contract-addr", "c", "", "OneSig contract address (defaults to 0xdEaD if not provided)")
+	previewCmd.Flags().Uint8Var(&previewLeafVersion, "leaf-version", utils.LeafEncodingVersion, "Leaf encoding version")
+	previewCmd.Flags().StringSliceVar(&previewDisable, "disable", nil, "Comma separated lint rules to skip")
+	previewCmd.RegisterFlagCompletionFunc("leaf-version", completeLeafVersions)
+}
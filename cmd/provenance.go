@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"time"
+
+	"merkle-cli/models"
+	"merkle-cli/provenance"
+	"merkle-cli/signer"
+	"merkle-cli/utils"
+)
+
+var (
+	provenanceFile      string
+	provenanceBuilderID string
+)
+
+// toolVersion returns the version, VCS revision and Go version the binary was built from
+func toolVersion() map[string]string {
+	version := make(map[string]string)
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return version
+	}
+	version["merkle-cli"] = info.Main.Version
+	version["go"] = info.GoVersion
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision", "vcs.modified":
+			version[setting.Key] = setting.Value
+		}
+	}
+	return version
+}
+
+// writeProvenance writes the provenance of the --output file to --provenance,
+// signed with the host key when one is given
+func writeProvenance(started time.Time, batchData []byte, options *models.TreeOptions, s signer.Signer) error {
+	output, err := os.ReadFile(outputFile)
+	if err != nil {
+		return fmt.Errorf("failed to read output file: %w", err)
+	}
+
+	builderID := provenanceBuilderID
+	if builderID == "" {
+		host, err := os.Hostname()
+		if err != nil {
+			return fmt.Errorf("failed to read host name, set --provenance-builder-id: %w", err)
+		}
+		builderID = "urn:merkle-cli:host:" + host
+	}
+
+	parameters := map[string]interface{}{
+		"batchFile":           batchFile,
+		"inputFormat":         inputFormat,
+		"oneSigId":            oneSigID,
+		"contractAddr":        contractAddr,
+		"leafEncodingVersion": leafVersion,
+		"treeOptions":         options,
+		"limits": map[string]int{
+			"maxLeaves":        maxLeaves,
+			"maxCallDataBytes": maxCallDataBytes,
+			"maxCallsPerLeaf":  maxCallsPerLeaf,
+			"maxProofLength":   maxProofLength,
+		},
+		"hashEncoding": hashEncoding,
+		"outputFormat": outputFormat,
+		"redact":       redact,
+		"encrypted":    outputEncryption().Enabled(),
+	}
+	if filterExpr != "" {
+		parameters["filter"] = filterExpr
+	}
+	if len(templateVars) > 0 {
+		parameters["vars"] = templateVars
+	}
+
+	statement := provenance.NewStatement(
+		provenance.Resource{Name: filepath.Base(outputFile), Digest: provenance.SHA256(output)},
+		provenance.BuildDefinition{
+			BuildType:          provenance.BuildType,
+			ExternalParameters: parameters,
+			InternalParameters: map[string]interface{}{"encoderRevision": utils.EncoderRevision},
+			ResolvedDependencies: []provenance.Resource{
+				{URI: batchFile, Digest: provenance.SHA256(batchData)},
+			},
+		},
+		provenance.Builder{ID: builderID, Version: toolVersion()},
+		started,
+		time.Now(),
+	)
+	envelope, err := provenance.Seal(statement, s)
+	if err != nil {
+		return err
+	}
+	return writeJSON(provenanceFile, envelope)
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"fmt"
+
+	"merkle-cli/bundle"
+	"merkle-cli/publish"
+
+	"github.com/spf13/cobra"
+)
+
+var publishTo string
+
+// publishCmd uploads a verified bundle to an artifact store by its digest
+var publishCmd = &cobra.Command{
+	Use:   "publish <bundle>",
+	Short: "Upload a bundle to S3, GCS or an OCI registry by digest",
+	Long: `Upload a bundle to S3, GCS or an OCI registry by digest
+
+Checks the bundle written by export-bundle against its manifest and uploads it
+under a name derived from its SHA-256, then prints the content-addressed URL:
+
+  s3://bucket/prefix     uploads to prefix/sha256/<sha256>.tar.gz with the AWS CLI
+  gs://bucket/prefix     uploads to prefix/sha256/<sha256>.tar.gz with a gcloud token
+  oci://registry/repo    pushes an artifact tagged sha256-<sha256> with oras and
+                         prints registry/repo@<manifest digest>
+
+Existing objects are never replaced.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dest, err := publish.ParseDestination(publishTo)
+		if err != nil {
+			return err
+		}
+
+		manifest, _, err := bundle.Read(args[0])
+		if err != nil {
+			return err
+		}
+
+		result, err := publish.Publish(cmd.Context(), dest, args[0])
+		if err != nil {
+			return fmt.Errorf("failed to publish bundle: %w", err)
+		}
+
+		fmt.Println("Merkle Root:", manifest.MerkleRoot)
+		fmt.Println("SHA-256:", result.SHA256)
+		fmt.Println("URL:", result.URL)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(publishCmd)
+
+	publishCmd.Flags().StringVar(&publishTo, "to", "", "Destination: s3://bucket/prefix, gs://bucket/prefix or oci://registry/repository")
+	publishCmd.MarkFlagRequired("to")
+}
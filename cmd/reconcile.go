@@ -0,0 +1,257 @@
+package cmd
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	"merkle-cli/chain"
+	"merkle-cli/models"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/spf13/cobra"
+)
+
+// defaultExecutionEvent is the event OneSig emits for every executed leaf
+const defaultExecutionEvent = "TransactionExecuted(bytes32,uint256)"
+
+var (
+	reconcileProofsFile     string
+	reconcileOneSigID       uint64
+	reconcileContract       string
+	reconcileRPCURL         string
+	reconcileFromBlock      uint64
+	reconcileToBlock        uint64
+	reconcileChunkSize      uint64
+	reconcileEvent          string
+	reconcileOutput         string
+	reconcileFailOnMismatch bool
+)
+
+// reconcileReport is the reconciliation written with --output
+type reconcileReport struct {
+	Contract   string `json:"contract"`
+	OneSigID   uint64 `json:"oneSigId"`
+	MerkleRoot string `json:"merkleRoot"`
+	FromBlock  uint64 `json:"fromBlock"`
+	ToBlock    uint64 `json:"toBlock"`
+	// Matched are executions of leaves of the proofs file
+	Matched []reconcileExecution `json:"matched"`
+	// Unknown are executions of leaves the proofs file does not hold
+	Unknown []reconcileExecution `json:"unknown"`
+	// Missing are leaves of the proofs file without an execution
+	Missing []reconcileMissing `json:"missing"`
+}
+
+// reconcileExecution is an execution event found on chain
+type reconcileExecution struct {
+	Nonce           uint64   `json:"nonce"`
+	MerkleRoot      string   `json:"merkleRoot"`
+	LeafHashes      []string `json:"leafHashes,omitempty"`
+	BlockNumber     uint64   `json:"blockNumber"`
+	TransactionHash string   `json:"transactionHash"`
+}
+
+// reconcileMissing is a nonce of the proofs file that was not executed
+type reconcileMissing struct {
+	Nonce      uint64   `json:"nonce"`
+	LeafHashes []string `json:"leafHashes"`
+	// ExecutedUnder is the other root the nonce was executed under, if any
+	ExecutedUnder string `json:"executedUnder,omitempty"`
+}
+
+// reconcileCmd matches the execution events of a OneSig contract against a proofs file
+var reconcileCmd = &cobra.Command{
+	Use:   "reconcile",
+	Short: "Match the execution events of a OneSig contract against a proofs file",
+	Long: `Match the execution events of a OneSig contract against a proofs file
+
+Scans the logs of the OneSig contract between --from-block and --to-block
+(default the latest block) for its execution event, in ranges of at most
+--chunk-size blocks, and matches every execution, identified by its Merkle
+root and nonce, against the leaves of the OneSig ID in the proofs file.
+
+Reports the executions matching a leaf, executions the proofs file does not
+hold (another root, or a nonce it lacks), and leaves without an execution in
+the range. --event selects the event for deployments emitting another one; its
+first two arguments, indexed or not, must be the root and the nonce.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if reconcileChunkSize == 0 {
+			return fmt.Errorf("--chunk-size must be at least 1")
+		}
+		output, err := readOutputFile(reconcileProofsFile)
+		if err != nil {
+			return err
+		}
+
+		leaves := make(map[uint64][]models.ProofEntry)
+		target := reconcileContract
+		for _, entry := range output.Proofs {
+			if entry.OneSigID != reconcileOneSigID {
+				continue
+			}
+			leaves[entry.Nonce] = append(leaves[entry.Nonce], entry)
+			if target == "" {
+				target = entry.ContractAddr
+			}
+		}
+		if len(leaves) == 0 {
+			return fmt.Errorf("no proofs for OneSig ID %d in %s", reconcileOneSigID, reconcileProofsFile)
+		}
+		if !common.IsHexAddress(target) {
+			return fmt.Errorf("proofs file has no OneSig contract address, provide --contract-addr")
+		}
+
+		client := chain.NewClient(reconcileRPCURL)
+		toBlock := reconcileToBlock
+		if toBlock == 0 {
+			if toBlock, err = client.BlockNumber(); err != nil {
+				return err
+			}
+		}
+		if reconcileFromBlock > toBlock {
+			return fmt.Errorf("--from-block %d is after --to-block %d", reconcileFromBlock, toBlock)
+		}
+
+		report := reconcileReport{
+			Contract:   common.HexToAddress(target).Hex(),
+			OneSigID:   reconcileOneSigID,
+			MerkleRoot: output.MerkleRoot,
+			FromBlock:  reconcileFromBlock,
+			ToBlock:    toBlock,
+			Matched:    []reconcileExecution{},
+			Unknown:    []reconcileExecution{},
+			Missing:    []reconcileMissing{},
+		}
+		topic := common.BytesToHash(crypto.Keccak256([]byte(reconcileEvent)))
+		executedUnder := make(map[uint64]string)
+		for from := reconcileFromBlock; from <= toBlock; from += reconcileChunkSize {
+			to := from + reconcileChunkSize - 1
+			if to > toBlock || to < from {
+				to = toBlock
+			}
+			logs, err := client.FilterLogs(common.HexToAddress(target), topic, from, to)
+			if err != nil {
+				return fmt.Errorf("failed to read logs of blocks %d-%d: %w", from, to, err)
+			}
+			for _, log := range logs {
+				root, nonce, err := decodeExecutionLog(log)
+				if err != nil {
+					return fmt.Errorf("transaction %s: %w", log.TransactionHash.Hex(), err)
+				}
+				execution := reconcileExecution{
+					Nonce:           nonce,
+					MerkleRoot:      root.Hex(),
+					BlockNumber:     uint64(log.BlockNumber),
+					TransactionHash: log.TransactionHash.Hex(),
+				}
+				entries, ok := leaves[nonce]
+				if !ok || !strings.EqualFold(root.Hex(), output.MerkleRoot) {
+					report.Unknown = append(report.Unknown, execution)
+					if ok {
+						executedUnder[nonce] = root.Hex()
+					}
+					continue
+				}
+				execution.LeafHashes = leafHashes(entries)
+				report.Matched = append(report.Matched, execution)
+				delete(leaves, nonce)
+			}
+			if to == toBlock {
+				break
+			}
+		}
+
+		nonces := make([]uint64, 0, len(leaves))
+		for nonce := range leaves {
+			nonces = append(nonces, nonce)
+		}
+		sort.Slice(nonces, func(i, j int) bool { return nonces[i] < nonces[j] })
+		for _, nonce := range nonces {
+			report.Missing = append(report.Missing, reconcileMissing{
+				Nonce:         nonce,
+				LeafHashes:    leafHashes(leaves[nonce]),
+				ExecutedUnder: executedUnder[nonce],
+			})
+		}
+
+		fmt.Printf("Scanned blocks %d-%d of %s\n", report.FromBlock, report.ToBlock, report.Contract)
+		fmt.Printf("Matched: %d\n", len(report.Matched))
+		for _, e := range report.Matched {
+			fmt.Printf("  nonce %d in block %d (%s)\n", e.Nonce, e.BlockNumber, e.TransactionHash)
+		}
+		fmt.Printf("Executed but unknown: %d\n", len(report.Unknown))
+		for _, e := range report.Unknown {
+			fmt.Printf("  nonce %d under root %s in block %d (%s)\n", e.Nonce, e.MerkleRoot, e.BlockNumber, e.TransactionHash)
+		}
+		fmt.Printf("Expected but missing: %d\n", len(report.Missing))
+		for _, m := range report.Missing {
+			if m.ExecutedUnder != "" {
+				fmt.Printf("  nonce %d (executed under root %s)\n", m.Nonce, m.ExecutedUnder)
+			} else {
+				fmt.Printf("  nonce %d\n", m.Nonce)
+			}
+		}
+
+		if reconcileOutput != "" {
+			if err := writeJSON(reconcileOutput, report); err != nil {
+				return err
+			}
+			fmt.Println("Report:", reconcileOutput)
+		}
+		if reconcileFailOnMismatch && (len(report.Unknown) > 0 || len(report.Missing) > 0) {
+			return withExitCode(exitVerification, fmt.Errorf("%d unknown and %d missing execution(s)", len(report.Unknown), len(report.Missing)))
+		}
+		return nil
+	},
+}
+
+// decodeExecutionLog reads the root and nonce of an execution event from its
+// first two arguments, whether they are indexed topics or data words
+func decodeExecutionLog(log chain.Log) (common.Hash, uint64, error) {
+	var words [][]byte
+	for _, topic := range log.Topics[1:] {
+		words = append(words, topic.Bytes())
+	}
+	for i := 0; i+32 <= len(log.Data); i += 32 {
+		words = append(words, log.Data[i:i+32])
+	}
+	if len(words) < 2 {
+		return common.Hash{}, 0, fmt.Errorf("execution event has %d arguments, expected the root and nonce", len(words))
+	}
+	nonce := new(big.Int).SetBytes(words[1])
+	if !nonce.IsUint64() {
+		return common.Hash{}, 0, fmt.Errorf("execution event nonce %s is out of range", nonce)
+	}
+	return common.BytesToHash(words[0]), nonce.Uint64(), nil
+}
+
+// leafHashes lists the leaf hashes of proof entries
+func leafHashes(entries []models.ProofEntry) []string {
+	hashes := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		hashes = append(hashes, entry.LeafHash)
+	}
+	return hashes
+}
+
+func init() {
+	rootCmd.AddCommand(reconcileCmd)
+
+	reconcileCmd.Flags().StringVarP(&reconcileProofsFile, "proofs-file", "p", "", "Path to a proofs file written with --output")
+	reconcileCmd.MarkFlagRequired("proofs-file")
+	reconcileCmd.Flags().Uint64VarP(&reconcileOneSigID, "onesig-id", "o", 0, "OneSig ID whose leaves are reconciled")
+	reconcileCmd.MarkFlagRequired("onesig-id")
+	reconcileCmd.Flags().StringVarP(&reconcileContract, "contract-addr", "c", "", "OneSig contract address (defaults to the address in the proofs file)")
+	reconcileCmd.Flags().StringVar(&reconcileRPCURL, "rpc-url", "", "JSON-RPC endpoint of the OneSig contract")
+	reconcileCmd.MarkFlagRequired("rpc-url")
+	reconcileCmd.Flags().Uint64Var(&reconcileFromBlock, "from-block", 0, "First block to scan")
+	reconcileCmd.MarkFlagRequired("from-block")
+	reconcileCmd.Flags().Uint64Var(&reconcileToBlock, "to-block", 0, "Last block to scan (defaults to the latest block)")
+	reconcileCmd.Flags().Uint64Var(&reconcileChunkSize, "chunk-size", 5000, "Maximum number of blocks per eth_getLogs request")
+	reconcileCmd.Flags().StringVar(&reconcileEvent, "event", defaultExecutionEvent, "Signature of the execution event")
+	reconcileCmd.Flags().StringVar(&reconcileOutput, "output", "", "Write the reconciliation as JSON to this file")
+	reconcileCmd.Flags().BoolVar(&reconcileFailOnMismatch, "fail-on-mismatch", false, "Exit with an error when an execution is unknown or a leaf was not executed")
+}
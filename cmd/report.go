@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"merkle-cli/etherscan"
+	"merkle-cli/lint"
+	"merkle-cli/merkle"
+	"merkle-cli/models"
+	"merkle-cli/report"
+	"merkle-cli/utils"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Report formats selectable with --report and --export
+const (
+	reportFormatHTML     = "html"
+	exportFormatMarkdown = "markdown"
+)
+
+// buildReport collects the report data of a generated tree, including the
+// lint findings for the batch and the outcome of the selector policy. With
+// --etherscan, calls are described with the functions of verified targets.
+func buildReport(ctx context.Context, batch *models.TransactionBatch, tree *merkle.MerkleTree, entries []merkle.Entry, options *models.TreeOptions) (*report.Report, error) {
+	opts, err := lintOptions(contractAddr, nil)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Explorer, err = rootEtherscan.client(); err != nil {
+		return nil, err
+	}
+	warnings, err := lint.Batch(ctx, batch, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	policyStatus := "not configured"
+	if cfg.Policy != nil {
+		policyStatus = fmt.Sprintf("passed (%d rule(s), %d budget(s))", len(cfg.Policy.Rules), len(cfg.Policy.Budgets))
+	}
+
+	output := buildOutput(tree, leafVersion, options, entries, utils.HashEncodingHex)
+	r := &report.Report{
+		GeneratedAt:  time.Now().UTC().Format(time.RFC3339),
+		OneSigID:     oneSigID,
+		ContractAddr: opts.OneSig.Hex(),
+		MerkleRoot:   output.MerkleRoot,
+		LeafVersion:  leafVersion,
+		TreeOptions:  options,
+		Policy:       policyStatus,
+		Warnings:     warnings,
+	}
+	labels, err := addressLabels()
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range output.Proofs {
+		leaf, err := report.NewLeaf(entry)
+		if err != nil {
+			return nil, err
+		}
+		for i := range leaf.Calls {
+			call := &leaf.Calls[i]
+			if call.Label == "" {
+				call.Label = labels.Label(call.To)
+			}
+			if opts.Explorer != nil {
+				if err := describeVerified(ctx, opts.Explorer, call); err != nil {
+					return nil, err
+				}
+			}
+		}
+		r.Leaves = append(r.Leaves, leaf)
+	}
+	return r, nil
+}
+
+// describeVerified names the function of a call and, if it has no label, its
+// target from the verified source of the target
+func describeVerified(ctx context.Context, explorer *etherscan.Client, call *report.Call) error {
+	contract, signatures, err := explorer.Functions(ctx, common.HexToAddress(call.To))
+	if err != nil || contract == nil || !contract.Verified {
+		return err
+	}
+	if call.Label == "" {
+		call.Label = contract.Name
+	}
+	if call.Selector != "" {
+		call.Signature = report.NewSignatures(signatures)[call.Selector]
+	}
+	return nil
+}
+
+// writeReport renders the HTML report to path
+func writeReport(path string, r *report.Report) error {
+	var b bytes.Buffer
+	if err := report.WriteHTML(&b, r); err != nil {
+		return fmt.Errorf("failed to render report: %w", err)
+	}
+	if err := os.WriteFile(path, b.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	return nil
+}
+
+// writeMarkdownExport renders the Markdown call table to path. Functions are
+// named from a list of common signatures and those in the config policy.
+func writeMarkdownExport(path string, r *report.Report) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	var policySignatures []string
+	if cfg.Policy != nil {
+		for _, rule := range cfg.Policy.Rules {
+			policySignatures = append(policySignatures, rule.Allow...)
+			policySignatures = append(policySignatures, rule.Deny...)
+		}
+	}
+
+	tokens := make(report.Tokens)
+	for _, token := range cfg.Tokens {
+		tokens[strings.ToLower(token.Address)] = report.Token{Symbol: token.Symbol, Decimals: token.Decimals}
+	}
+
+	var b bytes.Buffer
+	if err := report.WriteMarkdown(&b, r, report.NewSignatures(report.CommonSignatures, policySignatures), tokens); err != nil {
+		return fmt.Errorf("failed to render export: %w", err)
+	}
+	if err := os.WriteFile(path, b.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+	return nil
+}
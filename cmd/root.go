@@ -1,12 +1,18 @@
 package cmd
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
+	"time"
 
+	"merkle-cli/filter"
 	"merkle-cli/merkle"
-	"merkle-cli/models"
+	"merkle-cli/notify"
+	"merkle-cli/profile"
+	"merkle-cli/signer"
 	"merkle-cli/utils"
 
 	"github.com/spf13/cobra"
@@ -17,6 +23,43 @@ var (
 	contractAddr string
 	batchFile    string
 	verbose      bool
+	leafVersion  uint8
+	outputFile   string
+	encryptTo    []string
+	encryptGPG   []string
+	redact       bool
+	strictHex    bool
+	outputFormat string
+	hashEncoding string
+	printDigests bool
+	digestSeed   string
+	// digestExpiry binds the --print-digest digest to an expiry
+	digestExpiry expiryOptions
+	pairOrder    string
+	leafOrder    string
+	arity        int
+	reportFormat string
+	reportFile   string
+	exportFormat string
+	exportFile   string
+	notifyURL    string
+	artifactURL  string
+	filterExpr   string
+	writeIndex   bool
+	// quiet prints only the proofs JSON, rootOnly only the root
+	quiet    bool
+	rootOnly bool
+	// rootEtherscan describes calls in the report and export from verified sources
+	rootEtherscan etherscanOptions
+	// hostKey signs the output file on behalf of the generation host
+	hostKey signerOptions
+
+	maxLeaves        int
+	maxCallDataBytes int
+	maxCallsPerLeaf  int
+	maxProofLength   int
+	// cacheDir stores generated trees keyed by their inputs
+	cacheDir string
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -27,123 +70,283 @@ var rootCmd = &cobra.Command{
 
 A CLI tool for generating Merkle roots for OneSig transaction batches according to the
 LayerZero OneSig specification.`,
+	// Execute prints errors, once and to stderr
+	SilenceErrors: true,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := applyFlagDefaults(cmd); err != nil {
+			return err
+		}
+		if err := applyContractVersion(cmd); err != nil {
+			return err
+		}
+		// Cobra checks these only after this hook, so run them here to
+		// report missing flags as usage errors
+		if err := cmd.ValidateRequiredFlags(); err != nil {
+			return err
+		}
+		if err := cmd.ValidateFlagGroups(); err != nil {
+			return err
+		}
+		commandStarted = true
+		// Errors from here on are not about usage, so they are printed without it
+		cmd.SilenceUsage = true
+		return nil
+	},
 	RunE: func(cmd *cobra.Command, args []string) error {
+		started := time.Now()
 		// Validate required flags
 		if batchFile == "" {
 			return fmt.Errorf("transaction batch file is required")
 		}
+		if err := outputEncryption().Validate(); err != nil {
+			return err
+		}
+		if outputEncryption().Enabled() && outputFile == "" {
+			return fmt.Errorf("--encrypt-to and --encrypt-gpg require --output")
+		}
+		switch outputFormat {
+		case outputFormatJSON, outputFormatBinary:
+		case outputFormatTable:
+			if outputFile != "" || quiet || rootOnly {
+				return fmt.Errorf("--output-format %s prints to the terminal and cannot be used with --output, --quiet or --root-only", outputFormatTable)
+			}
+		default:
+			return fmt.Errorf("unsupported output format %q, expected %s, %s or %s", outputFormat, outputFormatJSON, outputFormatBinary, outputFormatTable)
+		}
+		if err := utils.CheckHashEncoding(hashEncoding); err != nil {
+			return err
+		}
+		if redact && outputFile == "" && !quiet {
+			return fmt.Errorf("--redact requires --output or --quiet")
+		}
+		if writeIndex && (outputFile == "" || outputFormat != outputFormatJSON || redact || outputEncryption().Enabled()) {
+			return fmt.Errorf("--index requires a plain --output file in json format")
+		}
+		if provenanceFile != "" && outputFile == "" {
+			return fmt.Errorf("--provenance requires --output")
+		}
+		var hostSigner signer.Signer
+		if hostKey.enabled() {
+			if outputFile == "" {
+				return fmt.Errorf("--host-private-key, --host-kms-key and --host-keystore require --output")
+			}
+			var err error
+			if hostSigner, err = hostKey.resolve(); err != nil {
+				return err
+			}
+		}
+		if filterExpr != "" {
+			if outputFile == "" && outputFormat != outputFormatTable && !quiet {
+				return fmt.Errorf("--filter requires --output, --quiet or --output-format %s", outputFormatTable)
+			}
+			if _, err := filter.Parse(filterExpr); err != nil {
+				return fmt.Errorf("invalid --filter: %w", err)
+			}
+		}
+		if reportFormat != "" && reportFormat != reportFormatHTML {
+			return fmt.Errorf("unsupported report format %q, expected %s", reportFormat, reportFormatHTML)
+		}
+		if exportFormat != "" && exportFormat != exportFormatMarkdown {
+			return fmt.Errorf("unsupported export format %q, expected %s", exportFormat, exportFormatMarkdown)
+		}
+		if notifyURL != "" {
+			if _, err := notify.ParseURL(notifyURL); err != nil {
+				return err
+			}
+		} else if artifactURL != "" {
+			return fmt.Errorf("--artifact-url requires --notify-webhook")
+		}
 
-		// Read the transaction batch file
-		data, err := os.ReadFile(batchFile)
+		// Read and parse the transaction batch file
+		batchData, err := readBatchData(cmd.Context(), batchFile)
 		if err != nil {
-			return fmt.Errorf("failed to read transaction batch file: %w", err)
-		}
-
-		// Parse the transaction batch
-		var batch models.TransactionBatch
-		if err := json.Unmarshal(data, &batch); err != nil {
-			return fmt.Errorf("failed to parse transaction batch: %w", err)
-		}
-
-		// Handle both new (with groups) and legacy (flat transactions) formats
-		var leaves [][]byte
-		var nonceToLeaf = make(map[uint64][]byte)
-		var nonceToProof = make(map[uint64][][]byte)
-		var nonceToCalls = make(map[uint64][]models.Call)
-
-		// Process transaction groups if they exist
-		if len(batch.Groups) > 0 {
-			for _, group := range batch.Groups {
-				// Generate only one leaf for each group's nonce
-				if len(group.Calls) > 0 {
-					// Check if a leaf already exists for this nonce
-					if _, exists := nonceToLeaf[group.Nonce]; !exists {
-						// Generate leaf using all calls
-						leaf, err := utils.EncodeLeaf(oneSigID, contractAddr, group.Nonce, group.Calls)
-						if err != nil {
-							return fmt.Errorf("failed to encode leaf for group %d: %w", group.Nonce, err)
-						}
-
-						leaves = append(leaves, leaf)
-						nonceToLeaf[group.Nonce] = leaf
-						nonceToCalls[group.Nonce] = group.Calls
-					}
-				}
+			return err
+		}
+		batch, err := parseBatch(batchData)
+		if err != nil {
+			return err
+		}
+		if err := utils.CheckBatchHex(batch, strictHex); err != nil {
+			return err
+		}
+		if strictHex && contractAddr != "" {
+			if _, err := utils.ParseHexAddress(contractAddr, "--contract-addr", true); err != nil {
+				return err
 			}
-		} else {
-			return fmt.Errorf("transaction batch is empty")
 		}
 
-		// Ensure we have at least one valid leaf
-		if len(leaves) == 0 {
-			return fmt.Errorf("no valid transactions found in batch")
+		options, err := treeOptions()
+		if err != nil {
+			return err
+		}
+		tree, entries, err := generateTreeCached(cmd.Context(), batch, merkle.Params{
+			OneSigID:     oneSigID,
+			ContractAddr: contractAddr,
+			LeafVersion:  leafVersion,
+			Options:      &options,
+			Limits:       batchLimits(),
+		})
+		if err != nil {
+			return err
 		}
 
-		// Sort leaves for consistent merkle root generation
-		sortedLeaves := merkle.SortLeaves(leaves)
+		// Everything but the final root or JSON is dropped in scripting modes
+		var out io.Writer = os.Stdout
+		if quiet || rootOnly {
+			out = io.Discard
+		}
 
-		// Generate the merkle tree
-		tree, err := merkle.NewMerkleTree(sortedLeaves)
+		// Output the merkle root
+		fmt.Fprintln(out, "Merkle Root:", utils.FormatHash(tree.Root, hashEncoding))
+		if batch.Source != nil {
+			fmt.Fprintf(out, "Source: %s (revision %s)\n", batch.Source.Location, batch.Source.Revision)
+		}
+		subtrees, err := computeSubtrees(cmd.Context(), tree, entries, &options)
 		if err != nil {
-			return fmt.Errorf("failed to generate merkle tree: %w", err)
+			return err
 		}
+		printSubtrees(out, subtrees)
 
-		// Output the merkle root
-		fmt.Println("Merkle Root:", tree.GetRootHex())
+		if err := checkRotation(entries); err != nil {
+			return err
+		}
+		if err := checkLeafStore(tree.GetRootHex(), entries); err != nil {
+			return err
+		}
 
-		// Generate proof for each nonce
-		for nonce, leaf := range nonceToLeaf {
-			proof, err := tree.GenerateProof(leaf)
+		if printDigests {
+			seed, err := parseSeedFlag(digestSeed)
 			if err != nil {
-				return fmt.Errorf("failed to generate proof for nonce %d: %w", nonce, err)
+				return err
+			}
+			expiry, err := digestExpiry.parse(0)
+			if err != nil {
+				return err
+			}
+			if err := printDigest(out, tree.Root, seed, expiry); err != nil {
+				return err
 			}
-			nonceToProof[nonce] = proof
 		}
 
-		// Output the proofs if verbose mode is enabled
-		if verbose {
-			fmt.Println("\nMerkle Proofs by Nonce:")
+		// Write the proofs file if requested
+		if outputFile != "" {
+			encoding := hashEncoding
+			if outputFormat == outputFormatBinary {
+				// The binary format stores raw hashes
+				encoding = utils.HashEncodingHex
+			}
+			output := buildOutput(tree, leafVersion, &options, entries, encoding)
+			output.Subtrees = subtreeOutput(subtrees, encoding)
+			if err := filterProofs(&output, filterExpr); err != nil {
+				return err
+			}
+			if err := writeOutputFile(outputFile, &output, outputFormat, redact, outputEncryption()); err != nil {
+				return err
+			}
+			if hostSigner != nil {
+				path, err := signOutputFile(outputFile, &output, outputFormat, redact, hostSigner)
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(out, "Output signature: %s (%s)\n", path, hostSigner.Address().Hex())
+			}
+			if provenanceFile != "" {
+				if err := writeProvenance(started, batchData, &options, hostSigner); err != nil {
+					return err
+				}
+				fmt.Fprintln(out, "Provenance:", provenanceFile)
+			}
+			if writeIndex {
+				path, err := writeIndexFile(outputFile)
+				if err != nil {
+					return err
+				}
+				fmt.Fprintln(out, "Index:", path)
+			}
+		}
 
-			// Sort keys to output in nonce order
-			var nonces []uint64
-			for nonce := range nonceToLeaf {
-				nonces = append(nonces, nonce)
+		if outputFormat == outputFormatTable {
+			output := buildOutput(tree, leafVersion, &options, entries, hashEncoding)
+			if err := filterProofs(&output, filterExpr); err != nil {
+				return err
+			}
+			if err := writeProofTable(os.Stdout, &output); err != nil {
+				return err
 			}
+		}
 
-			// Sort nonces in ascending order
-			for i := 0; i < len(nonces); i++ {
-				for j := i + 1; j < len(nonces); j++ {
-					if nonces[i] > nonces[j] {
-						nonces[i], nonces[j] = nonces[j], nonces[i]
-					}
+		if reportFormat != "" || exportFormat != "" {
+			r, err := buildReport(cmd.Context(), batch, tree, entries, &options)
+			if err != nil {
+				return err
+			}
+			if reportFormat != "" {
+				if err := writeReport(reportFile, r); err != nil {
+					return err
+				}
+				fmt.Fprintln(out, "Report:", reportFile)
+			}
+			if exportFormat != "" {
+				if err := writeMarkdownExport(exportFile, r); err != nil {
+					return err
 				}
+				fmt.Fprintln(out, "Export:", exportFile)
 			}
+		}
 
-			for _, nonce := range nonces {
-				leaf := nonceToLeaf[nonce]
-				proof := nonceToProof[nonce]
-				calls := nonceToCalls[nonce]
+		// Output the proofs if verbose mode is enabled
+		if verbose {
+			fmt.Fprintln(out, "\nMerkle Proofs by Nonce:")
 
+			for _, entry := range entries {
 				// Convert proofs to hex for display
 				var proofHex []string
-				for _, p := range proof {
-					proofHex = append(proofHex, fmt.Sprintf("0x%x", p))
+				for _, p := range entry.Proof {
+					proofHex = append(proofHex, utils.FormatHash(p, hashEncoding))
 				}
 
-				fmt.Printf("\nNonce %d:\n", nonce)
-				fmt.Printf("  Calls: %d\n", len(calls))
-				fmt.Printf("  Leaf: 0x%x\n", leaf)
-				fmt.Printf("  Proof:\n")
+				fmt.Fprintf(out, "\nNonce %d:\n", entry.Leaf.Nonce)
+				fmt.Fprintf(out, "  Calls: %d\n", len(entry.Leaf.Calls))
+				if entry.Leaf.HasValidityWindow() {
+					fmt.Fprintf(out, "  Valid After: %d\n", entry.Leaf.ValidAfter)
+					fmt.Fprintf(out, "  Valid Until: %d\n", entry.Leaf.ValidUntil)
+				}
+				fmt.Fprintf(out, "  Leaf: %s\n", utils.FormatHash(entry.Hash, hashEncoding))
+				fmt.Fprintf(out, "  Proof:\n")
 				for j, p := range proofHex {
-					fmt.Printf("    %d: %s\n", j+1, p)
+					fmt.Fprintf(out, "    %d: %s\n", j+1, p)
 				}
 
 				// Verify the proof
-				isValid := merkle.VerifyProof(tree.Root, leaf, proof)
-				fmt.Printf("  Proof Valid: %v\n", isValid)
+				isValid := merkle.VerifyProofArity(tree.Root, entry.Hash, entry.Proof, entry.Index, merkle.PairOrder(options.PairOrder), merkle.ArityOf(&options))
+				fmt.Fprintf(out, "  Proof Valid: %v\n", isValid)
+			}
+		}
+
+		if notifyURL != "" {
+			if err := notifyWebhook(cmd.Context(), tree, len(entries), batchData); err != nil {
+				return err
 			}
+			fmt.Fprintln(out, "Notified webhook")
 		}
 
+		if rootOnly {
+			fmt.Println(utils.FormatHash(tree.Root, hashEncoding))
+		}
+		if quiet {
+			output := buildOutput(tree, leafVersion, &options, entries, hashEncoding)
+			output.Subtrees = subtreeOutput(subtrees, hashEncoding)
+			if err := filterProofs(&output, filterExpr); err != nil {
+				return err
+			}
+			data, err := encodeOutput(&output, outputFormatJSON, redact)
+			if err != nil {
+				return err
+			}
+			if _, err := os.Stdout.Write(data); err != nil {
+				return err
+			}
+		}
 		return nil
 	},
 }
@@ -151,13 +354,40 @@ LayerZero OneSig specification.`,
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+	// Cancel long running generations and RPC waits on Ctrl-C
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := rootCmd.ExecuteContext(ctx); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		stop()
+		os.Exit(exitCode(err))
 	}
 }
 
 func init() {
+	// Config file flag, shared by every command
+	rootCmd.PersistentFlags().StringVar(&configFile, "config", "", "Path to the JSON config file")
+	rootCmd.PersistentFlags().IntVar(&maxLeaves, "max-leaves", utils.DefaultLimits.MaxLeaves, "Maximum number of groups in a batch")
+	rootCmd.PersistentFlags().IntVar(&maxCallDataBytes, "max-calldata-bytes", utils.DefaultLimits.MaxCallDataBytes, "Maximum calldata size of a single call")
+	rootCmd.PersistentFlags().IntVar(&maxCallsPerLeaf, "max-calls-per-leaf", utils.DefaultLimits.MaxCallsPerLeaf, "Maximum number of calls in a group")
+	rootCmd.PersistentFlags().IntVar(&maxProofLength, "max-proof-length", 0, "Maximum number of hashes in a proof, as capped by the on-chain verifier (0 for no maximum)")
+	rootCmd.PersistentFlags().StringVar(&hashEncoding, "hash-encoding", utils.HashEncodingHex, "Encoding of roots and proofs: hex or base64")
+	rootCmd.PersistentFlags().StringVar(&inputFormat, "input-format", inputFormatGroups, "Format of transaction batch files: groups, legacy or xlsx")
+	rootCmd.PersistentFlags().StringVar(&emptyData, "empty-data", emptyDataWarn, "Handling of calls with missing or empty data instead of 0x: allow, warn or error (error with --strict-hex)")
+	rootCmd.PersistentFlags().BoolVar(&allowUnknownFields, "allow-unknown-fields", false, "Ignore keys of JSON transaction batches that are not batch fields instead of failing")
+	rootCmd.PersistentFlags().StringVar(&inputSheet, "sheet", "", "Sheet of XLSX transaction batch files to read (defaults to the first)")
+	rootCmd.PersistentFlags().StringVar(&expectSHA256, "expect-sha256", "", "Fail unless the transaction batch file has this SHA-256")
+	rootCmd.PersistentFlags().StringArrayVar(&templateVars, "var", nil, "Value substituted for ${NAME} in transaction batch files, as NAME=value (repeatable)")
+	rootCmd.PersistentFlags().BoolVar(&templateInput, "template", false, "Substitute ${NAME} in transaction batch files from --var and the environment")
+	rootCmd.PersistentFlags().StringVar(&cacheDir, "cache-dir", "", "Directory caching generated trees by the hash of their inputs, options and encoder revision")
+	rootCmd.PersistentFlags().BoolVar(&serialHashing, "serial", false, "Hash every level of a tree on one goroutine instead of GOMAXPROCS, for debugging")
+	rootCmd.PersistentFlags().BoolVar(&subtreeRoots, "subtree-roots", false, "Also compute the root of the leaves of every OneSig ID, and its proof when it is a node of the tree")
+	rootCmd.PersistentFlags().BoolVar(&autoSalt, "auto-salt", false, "Give every leaf without a salt one derived from its OneSig ID and nonce (leaf version 4)")
+	rootCmd.PersistentFlags().StringVar(&saltDomain, "salt-domain", "", "Domain mixed into --auto-salt salts, such as a campaign name, so leaves of different roots differ")
+	rootCmd.PersistentFlags().StringVar(&traceHashes, "trace-hashes", "", "Log every pair of nodes hashed while building trees, with the resulting hash, as JSON lines to this file")
+	rootCmd.PersistentFlags().StringVar(&contractVersion, "contract-version", "", "OneSig contract version whose leaf encoding, signing domain and functions to use (see contract-versions)")
+
 	// OneSig ID flag
 	rootCmd.Flags().Uint64VarP(&oneSigID, "onesig-id", "o", 0, "OneSig ID (typically chain ID)")
 	rootCmd.MarkFlagRequired("onesig-id")
@@ -166,8 +396,51 @@ func init() {
 	rootCmd.Flags().StringVarP(&contractAddr, "contract-addr", "c", "", "OneSig contract address (defaults to 0xdEaD if not provided)")
 
 	// Transaction batch file flag
-	rootCmd.Flags().StringVarP(&batchFile, "batch-file", "f", "", "Path to transaction batch JSON file")
+	rootCmd.Flags().StringVarP(&batchFile, "batch-file", "f", "", "Path or https://, s3://, gs:// or gsheet:// URL of the transaction batch JSON file")
 	rootCmd.MarkFlagRequired("batch-file")
 
 	rootCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Show detailed output including Merkle proofs")
+	rootCmd.Flags().BoolVarP(&quiet, "quiet", "q", false, "Print nothing but the proofs as JSON")
+	rootCmd.Flags().BoolVar(&rootOnly, "root-only", false, "Print nothing but the Merkle root")
+	rootCmd.MarkFlagsMutuallyExclusive("verbose", "quiet", "root-only")
+
+	// Output file flag
+	rootCmd.Flags().StringVar(&outputFile, "output", "", "Write the root and proofs as JSON to this file")
+	rootCmd.Flags().BoolVar(&strictHex, "strict-hex", false, "Require 0x prefixed, even length hex and valid addresses in the batch")
+	rootCmd.Flags().StringVar(&outputFormat, "output-format", outputFormatJSON, "Format of the output file: json or bin, or table to print the proofs as a table instead")
+	rootCmd.Flags().BoolVar(&printDigests, "print-digest", false, "Show the root and its EIP-712 digest as QR codes and fingerprint words")
+	rootCmd.Flags().StringVar(&digestSeed, "seed", "", "OneSig seed for the --print-digest digest (defaults to zero)")
+	digestExpiry.register(rootCmd)
+	rootCmd.Flags().StringVar(&filterExpr, "filter", "", "Write only the proofs matching this expression to the output file, e.g. 'nonce >= 50'")
+	rootCmd.Flags().BoolVar(&writeIndex, "index", false, "Also write an index locating every proof in the output file by oneSigId and nonce")
+	rootCmd.Flags().BoolVar(&redact, "redact", false, "Write only the root, leaf hashes, indices and proofs to the output file")
+	rootCmd.Flags().StringVar(&reportFormat, "report", "", "Also write a report of the tree in this format: html")
+	rootCmd.Flags().StringVar(&reportFile, "report-file", "report.html", "Path of the --report file")
+	rootCmd.Flags().StringVar(&exportFormat, "export", "", "Also export the calls of the batch in this format: markdown")
+	rootCmd.Flags().StringVar(&exportFile, "export-file", "batch.md", "Path of the --export file")
+	rootEtherscan.register(rootCmd)
+	hostKey.register(rootCmd, "host-", "generation host, to sign the output file with")
+	rootCmd.Flags().StringVar(&provenanceFile, "provenance", "", "Write an in-toto SLSA provenance statement of the output file here, signed with the host key")
+	rootCmd.Flags().StringVar(&provenanceBuilderID, "provenance-builder-id", "", "Builder ID recorded in the provenance (defaults to urn:merkle-cli:host:<hostname>)")
+	rootCmd.Flags().StringVar(&rotateReplaces, "replaces", "", "Proofs file of the root this one replaces, to show the nonces added, dropped and changed")
+	rootCmd.Flags().StringVar(&leafStorePath, "leaf-store", "", "JSON file recording the leaves of generated roots, used to warn about leaves repeating an earlier root")
+	rootCmd.Flags().StringVar(&rotateRPCURL, "rpc-url", "", "JSON-RPC endpoint of the OneSig contract, used to reject nonces it already executed")
+	rootCmd.Flags().StringVar(&notifyURL, "notify-webhook", "", "Post the root, leaf count and input hash to this Slack, Discord or generic webhook")
+	rootCmd.Flags().StringVar(&artifactURL, "artifact-url", "", "Link to the published output included in the --notify-webhook message")
+	rootCmd.Flags().StringSliceVar(&encryptTo, "encrypt-to", nil, "Encrypt the output file to these age recipients")
+	rootCmd.Flags().StringSliceVar(&encryptGPG, "encrypt-gpg", nil, "Encrypt the output file to these gpg key IDs")
+
+	// Leaf encoding version flag
+	rootCmd.Flags().Uint8Var(&leafVersion, "leaf-version", utils.LeafEncodingVersion, "Leaf encoding version (2 adds validAfter/validUntil, 3 adds call operations, 4 adds a salt)")
+
+	addTreeFlags(rootCmd)
+
+	rootCmd.RegisterFlagCompletionFunc("leaf-version", completeLeafVersions)
+	rootCmd.RegisterFlagCompletionFunc("output-format", fixedCompletion(outputFormatJSON, outputFormatBinary, outputFormatTable))
+	rootCmd.RegisterFlagCompletionFunc("report", fixedCompletion(reportFormatHTML))
+	rootCmd.RegisterFlagCompletionFunc("export", fixedCompletion(exportFormatMarkdown))
+	rootCmd.RegisterFlagCompletionFunc("empty-data", fixedCompletion(emptyDataAllow, emptyDataWarn, emptyDataError))
+	rootCmd.RegisterFlagCompletionFunc("input-format", fixedCompletion(inputFormatGroups, inputFormatLegacy, inputFormatXLSX))
+	rootCmd.RegisterFlagCompletionFunc("contract-version", fixedCompletion(profile.Names()...))
+	rootCmd.RegisterFlagCompletionFunc("hash-encoding", fixedCompletion(utils.HashEncodingHex, utils.HashEncodingBase64))
 }
@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"merkle-cli/merkle"
+	"merkle-cli/models"
+	"merkle-cli/utils"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	rootFromProofEntryFile   string
+	rootFromProofLeafHash    string
+	rootFromProofProof       []string
+	rootFromProofIndex       int
+	rootFromProofLeafVersion uint8
+	rootFromProofExpected    string
+)
+
+// rootFromProofCmd derives the root from a single proof, without the rest of the tree
+var rootFromProofCmd = &cobra.Command{
+	Use:   "root-from-proof",
+	Short: "Derive the Merkle root from a single leaf and its proof",
+	Long: `Derive the Merkle root from a single leaf and its proof
+
+Takes either one proof entry as JSON (--entry-file, in the form of an entry of
+a proofs file) or a leaf hash, its proof and its index, and hashes up to the
+root. Entries with calls are re-encoded, so the root is derived from the calls
+themselves. With --root the derived root must match, otherwise the command
+exits with status 4.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := utils.CheckHashEncoding(hashEncoding); err != nil {
+			return err
+		}
+		options, err := treeOptions()
+		if err != nil {
+			return err
+		}
+
+		leaf, proof, index, err := proofInput()
+		if err != nil {
+			return err
+		}
+
+		root, err := merkle.ComputeRootFromProof(leaf, proof, index, &options)
+		if err != nil {
+			return err
+		}
+		fmt.Println("Leaf:", utils.FormatHash(leaf, hashEncoding))
+		fmt.Println("Merkle Root:", utils.FormatHash(root, hashEncoding))
+
+		if rootFromProofExpected != "" {
+			expected, err := parseRootFlag(rootFromProofExpected)
+			if err != nil {
+				return err
+			}
+			if !bytes.Equal(root, expected) {
+				return withExitCode(exitVerification, fmt.Errorf("derived root %s does not match %s", utils.FormatHash(root, hashEncoding), rootFromProofExpected))
+			}
+			fmt.Println("Root matches")
+		}
+		return nil
+	},
+}
+
+// proofInput reads the leaf hash, proof and index from --entry-file or the
+// --leaf-hash, --proof and --index flags
+func proofInput() ([]byte, [][]byte, int, error) {
+	hashes := rootFromProofProof
+	index := rootFromProofIndex
+	var leaf []byte
+
+	if rootFromProofEntryFile != "" {
+		data, err := os.ReadFile(rootFromProofEntryFile)
+		if err != nil {
+			return nil, nil, 0, fmt.Errorf("failed to read proof entry: %w", err)
+		}
+		var entry models.ProofEntry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			return nil, nil, 0, fmt.Errorf("failed to parse proof entry: %w", err)
+		}
+		hashes, index = entry.Proof, entry.LeafIndex
+
+		if len(entry.Calls) > 0 {
+			if leaf, err = utils.EncodeLeafVersion(entry.Leaf, rootFromProofLeafVersion); err != nil {
+				return nil, nil, 0, err
+			}
+		}
+		if entry.LeafHash != "" {
+			recorded, err := utils.ParseHash(entry.LeafHash, hashEncoding, "leafHash")
+			if err != nil {
+				return nil, nil, 0, err
+			}
+			if leaf != nil && !bytes.Equal(leaf, recorded) {
+				return nil, nil, 0, withExitCode(exitVerification, fmt.Errorf("leaf hash %s does not match the encoded leaf %s", entry.LeafHash, utils.FormatHash(leaf, hashEncoding)))
+			}
+			leaf = recorded
+		}
+		if leaf == nil {
+			return nil, nil, 0, fmt.Errorf("proof entry has neither calls nor a leaf hash")
+		}
+	} else {
+		if rootFromProofLeafHash == "" {
+			return nil, nil, 0, fmt.Errorf("either --entry-file or --leaf-hash is required")
+		}
+		var err error
+		if leaf, err = utils.ParseHash(rootFromProofLeafHash, hashEncoding, "--leaf-hash"); err != nil {
+			return nil, nil, 0, err
+		}
+	}
+
+	proof := make([][]byte, 0, len(hashes))
+	for i, h := range hashes {
+		b, err := utils.ParseHash(h, hashEncoding, fmt.Sprintf("proof[%d]", i))
+		if err != nil {
+			return nil, nil, 0, err
+		}
+		proof = append(proof, b)
+	}
+	return leaf, proof, index, nil
+}
+
+func init() {
+	rootCmd.AddCommand(rootFromProofCmd)
+	addPairOrderFlags(rootFromProofCmd)
+
+	rootFromProofCmd.Flags().StringVar(&rootFromProofEntryFile, "entry-file", "", "JSON file holding a single proof entry")
+	rootFromProofCmd.Flags().StringVar(&rootFromProofLeafHash, "leaf-hash", "", "Leaf hash, if no entry file is given")
+	rootFromProofCmd.Flags().StringSliceVar(&rootFromProofProof, "proof", nil, "Comma separated proof hashes, from the leaf upwards")
+	rootFromProofCmd.Flags().IntVar(&rootFromProofIndex, "index", 0, "Leaf index (only needed for --pair-order positional)")
+	rootFromProofCmd.Flags().Uint8Var(&rootFromProofLeafVersion, "leaf-version", utils.LeafEncodingVersion, "Leaf encoding version used to re-encode the entry's calls")
+	rootFromProofCmd.Flags().StringVarP(&rootFromProofExpected, "root", "r", "", "Expected Merkle root to compare against")
+	rootFromProofCmd.MarkFlagsMutuallyExclusive("entry-file", "leaf-hash")
+	rootFromProofCmd.MarkFlagsMutuallyExclusive("entry-file", "proof")
+	rootFromProofCmd.RegisterFlagCompletionFunc("leaf-version", completeLeafVersions)
+}
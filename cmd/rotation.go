@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"merkle-cli/chain"
+	"merkle-cli/merkle"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var (
+	rotateReplaces string
+	rotateRPCURL   string
+)
+
+// checkRotation compares a replacement tree with the proofs file of the root
+// it replaces, printing the nonces added, dropped and changed, and with
+// --rpc-url rejects leaves whose nonce the contract has already executed
+func checkRotation(entries []merkle.Entry) error {
+	if rotateReplaces != "" {
+		previous, err := readOutputFile(rotateReplaces)
+		if err != nil {
+			return err
+		}
+		old := make(map[uint64]string)
+		for _, entry := range previous.Proofs {
+			if entry.OneSigID == oneSigID {
+				old[entry.Nonce] = entry.LeafHash
+			}
+		}
+
+		var added, changed, unchanged []uint64
+		current := make(map[uint64]bool)
+		for _, entry := range entries {
+			nonce := entry.Leaf.Nonce
+			current[nonce] = true
+			hash, ok := old[nonce]
+			switch {
+			case !ok:
+				added = append(added, nonce)
+			case strings.EqualFold(hash, fmt.Sprintf("0x%x", entry.Hash)):
+				unchanged = append(unchanged, nonce)
+			default:
+				changed = append(changed, nonce)
+			}
+		}
+		var dropped []uint64
+		for nonce := range old {
+			if !current[nonce] {
+				dropped = append(dropped, nonce)
+			}
+		}
+		sort.Slice(dropped, func(i, j int) bool { return dropped[i] < dropped[j] })
+
+		fmt.Println("Replaces:", previous.MerkleRoot)
+		for _, group := range []struct {
+			label  string
+			nonces []uint64
+		}{
+			{"Added", added},
+			{"Changed", changed},
+			{"Dropped", dropped},
+			{"Unchanged", unchanged},
+		} {
+			if len(group.nonces) > 0 {
+				fmt.Printf("  %s: %s\n", group.label, formatNonces(group.nonces))
+			}
+		}
+	}
+
+	if rotateRPCURL == "" {
+		return nil
+	}
+	if !common.IsHexAddress(contractAddr) {
+		return fmt.Errorf("--rpc-url requires the --contract-addr of the OneSig contract")
+	}
+	next, err := chain.NewClient(rotateRPCURL).OneSigNonce(common.HexToAddress(contractAddr))
+	if err != nil {
+		return fmt.Errorf("failed to read the executed nonces: %w", err)
+	}
+	var executed []uint64
+	for _, entry := range entries {
+		if entry.Leaf.Nonce < next {
+			executed = append(executed, entry.Leaf.Nonce)
+		}
+	}
+	if len(executed) > 0 {
+		return withExitCode(exitValidation, fmt.Errorf("oneSigId %d nonce(s) %s were already executed on chain (next nonce %d)", oneSigID, formatNonces(executed), next))
+	}
+	fmt.Printf("No executed nonces included (next nonce %d)\n", next)
+	return nil
+}
+
+// formatNonces lists nonces, collapsing consecutive runs into ranges
+func formatNonces(nonces []uint64) string {
+	var parts []string
+	for i := 0; i < len(nonces); {
+		j := i
+		for j+1 < len(nonces) && nonces[j+1] == nonces[j]+1 {
+			j++
+		}
+		if i == j {
+			parts = append(parts, fmt.Sprint(nonces[i]))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d-%d", nonces[i], nonces[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,244 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"merkle-cli/models"
+	"merkle-cli/signer"
+	"merkle-cli/utils"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	signRoot     string
+	signSeed     string
+	signKey      signerOptions
+	signOutput   string
+	signPrintDig bool
+	signExpiry   expiryOptions
+)
+
+// signCmd signs the EIP-712 digest of a Merkle root and writes a signature file
+var signCmd = &cobra.Command{
+	Use:   "sign",
+	Short: "Sign a Merkle root and write a signature file",
+	Long: `Sign a Merkle root and write a signature file
+
+Signs the OneSig EIP-712 digest of the Merkle root and writes the signature,
+signer address and digest as JSON. Collect one file per signer and combine
+them with the aggregate command.
+
+With --expiry, the root is bound to an expiry timestamp for OneSig deployments
+that support it. The expiry is part of the digest and recorded in the file; it
+must be at least --expiry-margin in the future.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		root, seed, err := parseRootAndSeed(signRoot, signSeed)
+		if err != nil {
+			return err
+		}
+
+		expiry, err := signExpiry.parse(0)
+		if err != nil {
+			return err
+		}
+
+		digest, err := rootDigest(root, seed, expiry)
+		if err != nil {
+			return err
+		}
+
+		if signPrintDig {
+			// Written to stderr so the signature file can still go to stdout
+			if err := printDigest(os.Stderr, root, seed, expiry); err != nil {
+				return err
+			}
+		}
+
+		s, err := signKey.resolve()
+		if err != nil {
+			return err
+		}
+
+		signature, err := s.SignDigest(digest)
+		if err != nil {
+			return err
+		}
+
+		file := models.SignatureFile{
+			MerkleRoot: fmt.Sprintf("0x%x", root),
+			Seed:       fmt.Sprintf("0x%x", seed),
+			Expiry:     expiry,
+			Digest:     fmt.Sprintf("0x%x", digest),
+			Signer:     s.Address().Hex(),
+			Signature:  fmt.Sprintf("0x%x", signature),
+		}
+		return writeJSON(signOutput, file)
+	},
+}
+
+// signerOptions are the flags selecting the key a signature is made with
+type signerOptions struct {
+	prefix       string
+	privateKey   string
+	kmsKey       string
+	keystore     string
+	passwordFile string
+}
+
+// register adds the key flags to cmd, named with the prefix and described as
+// the key of role
+func (o *signerOptions) register(cmd *cobra.Command, prefix string, role string) {
+	o.prefix = prefix
+	cmd.Flags().StringVar(&o.privateKey, prefix+"private-key", "", "Hex private key of the "+role)
+	cmd.Flags().StringVar(&o.keystore, prefix+"keystore", "", "Encrypted keystore JSON file of the "+role)
+	cmd.Flags().StringVar(&o.passwordFile, prefix+"password-file", "", "File holding the keystore password (prompts if not set)")
+	cmd.Flags().StringVar(&o.kmsKey, prefix+"kms-key", "", "KMS key of the "+role+", as aws:<key-id> or gcp:<key-version-resource>")
+}
+
+// enabled reports whether a key was given
+func (o *signerOptions) enabled() bool {
+	return o.privateKey != "" || o.kmsKey != "" || o.keystore != ""
+}
+
+// resolve creates the signer selected by the private key, KMS key or keystore flag
+func (o *signerOptions) resolve() (signer.Signer, error) {
+	selected := 0
+	for _, v := range []string{o.privateKey, o.kmsKey, o.keystore} {
+		if v != "" {
+			selected++
+		}
+	}
+	if selected != 1 {
+		return nil, fmt.Errorf("exactly one of --%sprivate-key, --%skms-key or --%skeystore is required", o.prefix, o.prefix, o.prefix)
+	}
+
+	switch {
+	case o.kmsKey != "":
+		return signer.NewKMSSigner(o.kmsKey)
+	case o.keystore != "":
+		password, err := readPassword(o.passwordFile)
+		if err != nil {
+			return nil, err
+		}
+		return signer.NewKeystoreSigner(o.keystore, password)
+	default:
+		return signer.NewKeySigner(o.privateKey)
+	}
+}
+
+// readPassword reads the keystore password from a file, or prompts for it on the terminal
+func readPassword(path string) (string, error) {
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read password file: %w", err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}
+
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return "", fmt.Errorf("no terminal to prompt for the keystore password, use --password-file")
+	}
+	defer tty.Close()
+
+	fmt.Fprint(tty, "Keystore password: ")
+	echo := func(flag string) {
+		stty := exec.Command("stty", flag)
+		stty.Stdin = tty
+		stty.Run()
+	}
+	echo("-echo")
+	line, err := bufio.NewReader(tty).ReadString('\n')
+	echo("echo")
+	fmt.Fprintln(tty)
+	if err != nil {
+		return "", fmt.Errorf("failed to read password: %w", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// parseRootFlag decodes a Merkle root given on the command line in the --hash-encoding
+func parseRootFlag(value string) ([]byte, error) {
+	if err := utils.CheckHashEncoding(hashEncoding); err != nil {
+		return nil, err
+	}
+	root, err := utils.ParseHash(value, hashEncoding, "--root")
+	if err != nil {
+		return nil, fmt.Errorf("invalid merkle root: %w", err)
+	}
+	return root, nil
+}
+
+// parseRootAndSeed decodes the root and optional seed flags, defaulting the seed to zero
+func parseRootAndSeed(rootHex string, seedHex string) ([]byte, []byte, error) {
+	root, err := parseRootFlag(rootHex)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(root) != 32 {
+		return nil, nil, fmt.Errorf("merkle root must be 32 bytes, got %d", len(root))
+	}
+
+	seed, err := parseSeedFlag(seedHex)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return root, seed, nil
+}
+
+// parseSeedFlag decodes an optional seed flag, defaulting to zero
+func parseSeedFlag(value string) ([]byte, error) {
+	if value == "" {
+		return make([]byte, 32), nil
+	}
+
+	seed, err := utils.ParseHash(value, hashEncoding, "--seed")
+	if err != nil {
+		return nil, fmt.Errorf("invalid seed: %w", err)
+	}
+	if len(seed) != 32 {
+		return nil, fmt.Errorf("seed must be 32 bytes, got %d", len(seed))
+	}
+	return seed, nil
+}
+
+// writeJSON writes v as indented JSON to path, or to stdout if path is empty
+func writeJSON(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode output: %w", err)
+	}
+	data = append(data, '\n')
+
+	if path == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(signCmd)
+
+	signCmd.Flags().StringVarP(&signRoot, "root", "r", "", "Merkle root to sign")
+	signCmd.MarkFlagRequired("root")
+
+	signCmd.Flags().StringVar(&signSeed, "seed", "", "OneSig seed (defaults to zero)")
+	signKey.register(signCmd, "", "signer")
+
+	signExpiry.register(signCmd)
+
+	signCmd.Flags().BoolVar(&signPrintDig, "print-digest", false, "Show the root and digest being signed as QR codes and fingerprint words")
+	signCmd.Flags().StringVar(&signOutput, "output", "", "Write the signature file here instead of stdout")
+}
@@ -0,0 +1,182 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"merkle-cli/models"
+	"merkle-cli/simulation"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/spf13/cobra"
+)
+
+var (
+	simulateProofsFile string
+	simulateOneSigID   uint64
+	simulateContract   string
+	simulateForkURL    string
+	simulateRPCURL     string
+	simulateNode       string
+	simulateNodeBinary string
+	simulatePort       int
+	simulateReport     string
+)
+
+// simulateCmd replays every leaf of a OneSig instance on a local fork
+var simulateCmd = &cobra.Command{
+	Use:   "simulate",
+	Short: "Execute the leaves of a proofs file on a local fork",
+	Long: `Execute the leaves of a proofs file on a local fork
+
+Launches an anvil (or hardhat) fork of the target chain, impersonates the OneSig
+contract and executes each leaf's calls in nonce order, so later leaves observe
+the state changes of earlier ones. Reports the status, gas, emitted events and
+state diffs of every call.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if simulateForkURL == "" && simulateRPCURL == "" {
+			return fmt.Errorf("either --fork-url or --rpc-url is required")
+		}
+
+		output, err := readOutputFile(simulateProofsFile)
+		if err != nil {
+			return err
+		}
+
+		var entries []models.ProofEntry
+		for _, entry := range output.Proofs {
+			if entry.OneSigID == simulateOneSigID {
+				entries = append(entries, entry)
+			}
+		}
+		if len(entries) == 0 {
+			return fmt.Errorf("no proofs for OneSig ID %d in %s", simulateOneSigID, simulateProofsFile)
+		}
+		sort.SliceStable(entries, func(i, j int) bool {
+			return entries[i].Nonce < entries[j].Nonce
+		})
+
+		oneSig := simulateContract
+		if oneSig == "" {
+			oneSig = entries[0].ContractAddr
+		}
+		if !common.IsHexAddress(oneSig) {
+			return fmt.Errorf("proofs have no OneSig contract address, provide --contract-addr")
+		}
+
+		nodeURL := simulateRPCURL
+		if nodeURL == "" {
+			node, err := simulation.StartNode(simulateNode, simulateNodeBinary, simulateForkURL, simulatePort)
+			if err != nil {
+				return err
+			}
+			defer node.Stop()
+			nodeURL = node.URL
+		}
+
+		simulator, err := simulation.NewSimulator(simulateNode, nodeURL, common.HexToAddress(oneSig))
+		if err != nil {
+			return err
+		}
+
+		var results []*simulation.LeafResult
+		failed := 0
+		for _, entry := range entries {
+			result, err := simulator.SimulateLeaf(entry)
+			if err != nil {
+				return fmt.Errorf("failed to simulate nonce %d: %w", entry.Nonce, err)
+			}
+			results = append(results, result)
+			printLeafResult(result)
+
+			if !result.Success {
+				failed++
+			}
+		}
+
+		if simulateReport != "" {
+			data, err := json.MarshalIndent(results, "", "  ")
+			if err != nil {
+				return fmt.Errorf("failed to encode simulation report: %w", err)
+			}
+			if err := os.WriteFile(simulateReport, append(data, '\n'), 0644); err != nil {
+				return fmt.Errorf("failed to write simulation report: %w", err)
+			}
+		}
+
+		if failed > 0 {
+			return fmt.Errorf("%d of %d leaves reverted or failed their expectations", failed, len(results))
+		}
+		return nil
+	},
+}
+
+// printLeafResult prints the outcome of a simulated leaf
+func printLeafResult(result *simulation.LeafResult) {
+	status := "OK"
+	if len(result.Failures) > 0 {
+		status = "EXPECTATIONS FAILED"
+	} else if !result.Success {
+		status = "REVERTED"
+	}
+	fmt.Printf("\nNonce %d: %s\n", result.Nonce, status)
+
+	for i, call := range result.Calls {
+		callStatus := "success"
+		if !call.Success {
+			callStatus = "reverted"
+		}
+		fmt.Printf("  Call %d -> %s: %s (gas %d)\n", i+1, call.To, callStatus, call.GasUsed)
+
+		for _, log := range call.Logs {
+			topics := make([]string, 0, len(log.Topics))
+			for _, topic := range log.Topics {
+				topics = append(topics, topic.Hex())
+			}
+			fmt.Printf("    Event from %s: topics [%s] data 0x%x\n", log.Address.Hex(), strings.Join(topics, ", "), []byte(log.Data))
+		}
+
+		for addr, diff := range call.StateDiff {
+			if diff.BalanceAfter != nil {
+				fmt.Printf("    Balance %s: %s -> %s\n", addr.Hex(), bigOrZero(diff.BalanceBefore), bigOrZero(diff.BalanceAfter))
+			}
+			if len(diff.Storage) > 0 {
+				fmt.Printf("    Storage %s: %d slot(s) changed\n", addr.Hex(), len(diff.Storage))
+			}
+		}
+	}
+
+	for _, failure := range result.Failures {
+		fmt.Printf("  FAILED: %s\n", failure)
+	}
+}
+
+// bigOrZero formats an optional hex big integer as a decimal string
+func bigOrZero(v *hexutil.Big) string {
+	if v == nil {
+		return "0"
+	}
+	return v.ToInt().String()
+}
+
+func init() {
+	rootCmd.AddCommand(simulateCmd)
+
+	simulateCmd.Flags().StringVarP(&simulateProofsFile, "proofs-file", "p", "", "Path to a proofs file written with --output")
+	simulateCmd.MarkFlagRequired("proofs-file")
+
+	simulateCmd.Flags().Uint64VarP(&simulateOneSigID, "onesig-id", "o", 0, "OneSig ID whose leaves are simulated")
+	simulateCmd.MarkFlagRequired("onesig-id")
+
+	simulateCmd.Flags().StringVarP(&simulateContract, "contract-addr", "c", "", "OneSig contract address (defaults to the address in the proofs file)")
+	simulateCmd.Flags().StringVar(&simulateForkURL, "fork-url", "", "RPC endpoint of the chain to fork")
+	simulateCmd.Flags().StringVar(&simulateRPCURL, "rpc-url", "", "Use an already running fork node instead of spawning one")
+	simulateCmd.Flags().StringVar(&simulateNode, "node", "anvil", "Development node to use ("+strings.Join(simulation.NodeKinds, "|")+")")
+	simulateCmd.Flags().StringVar(&simulateNodeBinary, "node-binary", "", "Path to the node executable (defaults to anvil or npx)")
+	simulateCmd.Flags().IntVar(&simulatePort, "port", 8545, "Port for the spawned node")
+	simulateCmd.Flags().StringVar(&simulateReport, "report", "", "Write the simulation results as JSON to this file")
+}
@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"merkle-cli/chain"
+	"merkle-cli/models"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/spf13/cobra"
+)
+
+// Execution states of a leaf reported by the status command
+const (
+	leafExecuted = "executed"
+	leafPending  = "pending"
+	leafOrphaned = "orphaned"
+)
+
+var (
+	statusProofsFile string
+	statusOneSigID   uint64
+	statusContract   string
+	statusRPCURL     string
+)
+
+// statusCmd shows which leaves of a proofs file a OneSig contract has executed
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show which leaves of a proofs file are executed, pending or orphaned",
+	Long: `Show which leaves of a proofs file are executed, pending or orphaned
+
+Reads nonce() and merkleRoot() from the OneSig contract and classifies every
+leaf of the OneSig ID in the proofs file. OneSig executes nonces in order, so:
+
+  executed  the nonce is below the contract's next nonce
+  pending   the leaf can still execute: every nonce from the next nonce up to
+            it has an unexpired leaf in the file
+  orphaned  the leaf can never execute with this root: it expired, or a lower
+            nonce is missing from the file or only has expired leaves
+
+Also reports whether the root of the proofs file is the contract's current root.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		output, err := readOutputFile(statusProofsFile)
+		if err != nil {
+			return err
+		}
+
+		var entries []models.ProofEntry
+		for _, entry := range output.Proofs {
+			if entry.OneSigID == statusOneSigID {
+				entries = append(entries, entry)
+			}
+		}
+		if len(entries) == 0 {
+			return fmt.Errorf("no proofs for OneSig ID %d in %s", statusOneSigID, statusProofsFile)
+		}
+		sort.SliceStable(entries, func(i, j int) bool {
+			return entries[i].Nonce < entries[j].Nonce
+		})
+
+		target := statusContract
+		if target == "" {
+			target = entries[0].ContractAddr
+		}
+		if !common.IsHexAddress(target) {
+			return fmt.Errorf("proofs file has no OneSig contract address, provide --contract-addr")
+		}
+		client := chain.NewClient(statusRPCURL)
+		next, err := client.OneSigNonce(common.HexToAddress(target))
+		if err != nil {
+			return fmt.Errorf("failed to read the nonce of %s: %w", target, err)
+		}
+		current, err := client.OneSigRoot(common.HexToAddress(target))
+		if err != nil {
+			return fmt.Errorf("failed to read the root of %s: %w", target, err)
+		}
+
+		fmt.Println("Contract:", common.HexToAddress(target).Hex())
+		fmt.Println("Next Nonce:", next)
+		fmt.Println("Current Root:", current.Hex())
+		if strings.EqualFold(current.Hex(), output.MerkleRoot) {
+			fmt.Println("Proofs file root is the current root")
+		} else {
+			fmt.Println("Proofs file root", output.MerkleRoot, "is not the current root")
+		}
+
+		counts := make(map[string]int)
+		for _, s := range leafStatuses(entries, next, uint64(time.Now().Unix())) {
+			counts[s.state]++
+			fmt.Printf("  nonce %d: %s", s.entry.Nonce, s.state)
+			if s.reason != "" {
+				fmt.Printf(" (%s)", s.reason)
+			}
+			fmt.Println()
+		}
+		fmt.Printf("%d executed, %d pending, %d orphaned\n", counts[leafExecuted], counts[leafPending], counts[leafOrphaned])
+		return nil
+	},
+}
+
+// leafStatus is the execution state of one proof entry
+type leafStatus struct {
+	entry  models.ProofEntry
+	state  string
+	reason string
+}
+
+// leafStatuses classifies entries sorted by nonce against the contract's next
+// nonce. A nonce can only execute once every lower nonce has, so the first
+// nonce without an unexpired leaf orphans every leaf above it.
+func leafStatuses(entries []models.ProofEntry, next uint64, now uint64) []leafStatus {
+	expired := func(entry models.ProofEntry) bool {
+		return entry.ValidUntil != 0 && entry.ValidUntil < now
+	}
+	live := make(map[uint64]bool)
+	for _, entry := range entries {
+		if entry.Nonce >= next && !expired(entry) {
+			live[entry.Nonce] = true
+		}
+	}
+
+	// reachable is the lowest nonce at or above next without a live leaf
+	reachable := next
+	for live[reachable] {
+		reachable++
+	}
+
+	statuses := make([]leafStatus, 0, len(entries))
+	for _, entry := range entries {
+		s := leafStatus{entry: entry}
+		switch {
+		case entry.Nonce < next:
+			s.state = leafExecuted
+		case expired(entry):
+			s.state = leafOrphaned
+			s.reason = fmt.Sprintf("expired at %s", time.Unix(int64(entry.ValidUntil), 0).UTC().Format(time.RFC3339))
+		case entry.Nonce > reachable:
+			s.state = leafOrphaned
+			s.reason = fmt.Sprintf("nonce %d can never execute with this root", reachable)
+		default:
+			s.state = leafPending
+		}
+		statuses = append(statuses, s)
+	}
+	return statuses
+}
+
+func init() {
+	rootCmd.AddCommand(statusCmd)
+
+	statusCmd.Flags().StringVarP(&statusProofsFile, "proofs-file", "p", "", "Path to a proofs file written with --output")
+	statusCmd.MarkFlagRequired("proofs-file")
+	statusCmd.Flags().Uint64VarP(&statusOneSigID, "onesig-id", "o", 0, "OneSig ID whose leaves are checked")
+	statusCmd.MarkFlagRequired("onesig-id")
+	statusCmd.Flags().StringVarP(&statusContract, "contract-addr", "c", "", "OneSig contract address (defaults to the address in the proofs file)")
+	statusCmd.Flags().StringVar(&statusRPCURL, "rpc-url", "", "JSON-RPC endpoint of the OneSig contract")
+	statusCmd.MarkFlagRequired("rpc-url")
+}
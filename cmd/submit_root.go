@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"strings"
+
+	"merkle-cli/chain"
+	"merkle-cli/checkpoint"
+	"merkle-cli/utils"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/spf13/cobra"
+)
+
+var (
+	submitRootContract   string
+	submitRootRoot       string
+	submitRootSignatures string
+	submitRootSigFile    string
+	submitRootMethod     string
+	submitRootCheckpoint string
+	submitRootTx         txOptions
+	submitRootExpiry     expiryOptions
+)
+
+// submitRootCmd builds and optionally broadcasts the transaction committing a signed root
+var submitRootCmd = &cobra.Command{
+	Use:   "submit-root",
+	Short: "Submit a signed Merkle root to the OneSig contract",
+	Long: `Submit a signed Merkle root to the OneSig contract
+
+Builds the root submission transaction for the OneSig contract, signs it with
+the provided key and broadcasts it through the RPC endpoint. With --dry-run
+the raw transaction is printed instead of being sent.
+
+Roots bound to an expiry are submitted with it as the second argument of the
+submission function. It is taken from --expiry or --signatures-file and must
+be at least --expiry-margin in the future.
+
+The command waits for --confirmations confirmations of the submission, up to
+--receipt-timeout, and fails if it reverts. Pass --confirmations 0 to return
+as soon as the transaction is broadcast.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !common.IsHexAddress(submitRootContract) {
+			return fmt.Errorf("invalid OneSig contract address %q", submitRootContract)
+		}
+
+		root, err := parseRootFlag(submitRootRoot)
+		if err != nil {
+			return err
+		}
+
+		var fileExpiry uint64
+		if submitRootSigFile != "" {
+			if submitRootSignatures != "" {
+				return fmt.Errorf("--signatures and --signatures-file are mutually exclusive")
+			}
+			aggregated, err := readAggregatedSignatures(submitRootSigFile, root)
+			if err != nil {
+				return err
+			}
+			submitRootSignatures, fileExpiry = aggregated.Signatures, aggregated.Expiry
+		}
+
+		expiry, err := submitRootExpiry.parse(fileExpiry)
+		if err != nil {
+			return err
+		}
+		if err := checkRootExpiry(expiry); err != nil {
+			return err
+		}
+
+		signatures, err := utils.HexToBytes(submitRootSignatures)
+		if err != nil {
+			return fmt.Errorf("invalid signatures: %w", err)
+		}
+		if len(signatures) < 65 {
+			return fmt.Errorf("signatures must hold at least one 65 byte signature, got %d bytes", len(signatures))
+		}
+
+		calldata, err := utils.EncodeRootSubmission(submitRootMethod, root, expiry, signatures)
+		if err != nil {
+			return err
+		}
+
+		rootHex := fmt.Sprintf("0x%x", root)
+		state, err := checkpoint.Load(submitRootCheckpoint, "submit-root", rootHex)
+		if err != nil {
+			return err
+		}
+		if state.Done(rootHex) {
+			fmt.Println("Root already submitted, skipping")
+			return nil
+		}
+
+		// Return right after broadcasting only when nothing waits for the receipt
+		if submitRootCheckpoint == "" && submitRootTx.confirmations == 0 {
+			tx, key, err := submitRootTx.buildTransaction(cmd, common.HexToAddress(submitRootContract), nil, calldata)
+			if err != nil {
+				return err
+			}
+			_, err = submitRootTx.sendTransaction(tx, key)
+			return err
+		}
+
+		return submitRootTx.submitAndWait(state, rootHex, func() (*chain.Transaction, *ecdsa.PrivateKey, error) {
+			return submitRootTx.buildTransaction(cmd, common.HexToAddress(submitRootContract), nil, calldata)
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(submitRootCmd)
+
+	submitRootCmd.Flags().StringVarP(&submitRootContract, "contract-addr", "c", "", "OneSig contract address")
+	submitRootCmd.MarkFlagRequired("contract-addr")
+
+	submitRootCmd.Flags().StringVarP(&submitRootRoot, "root", "r", "", "Merkle root to submit")
+	submitRootCmd.MarkFlagRequired("root")
+
+	submitRootCmd.Flags().StringVarP(&submitRootSignatures, "signatures", "s", "", "Concatenated signer signatures over the root")
+	submitRootCmd.Flags().StringVar(&submitRootSigFile, "signatures-file", "", "Aggregated signatures file written by the aggregate command")
+
+	submitRootCmd.Flags().StringVar(&submitRootMethod, "method", "setRoot", "Root submission function ("+strings.Join(utils.RootSubmissionMethods, "|")+")")
+
+	submitRootExpiry.register(submitRootCmd)
+
+	submitRootCmd.Flags().StringVar(&submitRootCheckpoint, "checkpoint", "", "Checkpoint file recording the submission, used to resume interrupted runs")
+
+	submitRootTx.register(submitRootCmd)
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"merkle-cli/merkle"
+	"merkle-cli/models"
+	"merkle-cli/utils"
+)
+
+// subtreeRoots reports the subtree root of every OneSig ID next to the root
+var subtreeRoots bool
+
+// computeSubtrees returns the subtree roots of a tree with --subtree-roots,
+// and nil otherwise
+func computeSubtrees(ctx context.Context, tree *merkle.MerkleTree, entries []merkle.Entry, options *models.TreeOptions) ([]merkle.SubtreeRoot, error) {
+	if !subtreeRoots {
+		return nil, nil
+	}
+	order, err := merkle.CheckTreeOptions(options)
+	if err != nil {
+		return nil, err
+	}
+	if merkle.ArityOf(options) != merkle.DefaultArity {
+		return nil, fmt.Errorf("--subtree-roots requires a binary tree, not arity %d", merkle.ArityOf(options))
+	}
+	subtrees, err := tree.SubtreeRoots(ctx, entries, order, hashWorkers())
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute subtree roots: %w", err)
+	}
+	return subtrees, nil
+}
+
+// printSubtrees prints the subtree root of every OneSig ID and, for those
+// that are nodes of the tree, their position
+func printSubtrees(w io.Writer, subtrees []merkle.SubtreeRoot) {
+	if len(subtrees) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "Subtree Roots:")
+	for _, s := range subtrees {
+		line := fmt.Sprintf("  OneSig ID %d: %s (%d leaves)", s.OneSigID, utils.FormatHash(s.Root, hashEncoding), s.LeafCount)
+		if s.Node != nil {
+			line += fmt.Sprintf(", node %d of level %d", s.Node.Index, s.Node.Level)
+		}
+		fmt.Fprintln(w, line)
+	}
+}
+
+// subtreeOutput converts subtree roots to their form in proofs files
+func subtreeOutput(subtrees []merkle.SubtreeRoot, encoding string) []models.SubtreeRoot {
+	if len(subtrees) == 0 {
+		return nil
+	}
+	out := make([]models.SubtreeRoot, 0, len(subtrees))
+	for _, s := range subtrees {
+		root := models.SubtreeRoot{OneSigID: s.OneSigID, Root: utils.FormatHash(s.Root, encoding), LeafCount: s.LeafCount}
+		if s.Node != nil {
+			proof := make([]string, 0, len(s.Node.Proof))
+			for _, p := range s.Node.Proof {
+				proof = append(proof, utils.FormatHash(p, encoding))
+			}
+			root.Node = &models.SubtreeNode{Level: s.Node.Level, Index: s.Node.Index, Proof: proof}
+		}
+		out = append(out, root)
+	}
+	return out
+}
+
+// verifySubtrees checks the proof of every subtree root of a proofs file that
+// is a node of its tree against the root
+func verifySubtrees(root []byte, output *models.OutputFormat, order merkle.PairOrder) []checkFailure {
+	var failures []checkFailure
+	for i, s := range output.Subtrees {
+		if s.Node == nil {
+			continue
+		}
+		path := fmt.Sprintf("subtrees[%d]", i)
+		node, err := utils.HexToBytes(s.Root)
+		if err != nil {
+			failures = append(failures, checkFailure{Message: fmt.Sprintf("%s.root is invalid: %v", path, err)})
+			continue
+		}
+		proof := make([][]byte, 0, len(s.Node.Proof))
+		for _, p := range s.Node.Proof {
+			b, err := utils.HexToBytes(p)
+			if err != nil {
+				break
+			}
+			proof = append(proof, b)
+		}
+		if len(proof) != len(s.Node.Proof) || !merkle.VerifyProofAt(root, node, proof, s.Node.Index, order) {
+			failures = append(failures, checkFailure{Message: fmt.Sprintf("%s: subtree root of OneSig ID %d does not prove against the root", path, s.OneSigID)})
+		}
+	}
+	return failures
+}
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"merkle-cli/models"
+)
+
+// leafHashPrefix is the number of characters of the leaf hash shown in a table
+const leafHashPrefix = 10
+
+// writeProofTable prints one row per proof entry with its OneSig ID, nonce,
+// target, number of calls, leaf hash prefix and proof length
+func writeProofTable(w io.Writer, output *models.OutputFormat) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ONESIG ID\tNONCE\tTARGET\tCALLS\tLEAF\tPROOF")
+	for _, entry := range output.Proofs {
+		leafHash := entry.LeafHash
+		if len(leafHash) > leafHashPrefix {
+			leafHash = leafHash[:leafHashPrefix] + ".."
+		}
+		fmt.Fprintf(tw, "%d\t%d\t%s\t%d\t%s\t%d\n", entry.OneSigID, entry.Nonce, tableTarget(entry.Calls), len(entry.Calls), leafHash, len(entry.Proof))
+	}
+	return tw.Flush()
+}
+
+// tableTarget returns the target of the first call, followed by the number of
+// other distinct targets the calls are made to
+func tableTarget(calls []models.Call) string {
+	if len(calls) == 0 {
+		return "-"
+	}
+	first := strings.ToLower(calls[0].To)
+	others := make(map[string]bool)
+	for _, call := range calls[1:] {
+		if to := strings.ToLower(call.To); to != first {
+			others[to] = true
+		}
+	}
+	if len(others) == 0 {
+		return calls[0].To
+	}
+	return fmt.Sprintf("%s (+%d)", calls[0].To, len(others))
+}
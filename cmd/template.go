@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+var (
+	// templateVars are the key=value pairs given with --var
+	templateVars []string
+	// templateInput enables substitution without any --var, from the environment only
+	templateInput bool
+)
+
+// templateVariable matches ${NAME} references and their $${ escape
+var templateVariable = regexp.MustCompile(`\$\$\{|\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// expandTemplate substitutes the ${NAME} references of a batch file when
+// --template or --var is given. Values come from --var, then the environment;
+// $${ stands for a literal ${.
+func expandTemplate(data []byte) ([]byte, error) {
+	if !templateInput && len(templateVars) == 0 {
+		return data, nil
+	}
+	if inputFormat == inputFormatXLSX {
+		return nil, fmt.Errorf("template variables are not supported in XLSX batch files")
+	}
+	vars := make(map[string]string, len(templateVars))
+	for _, v := range templateVars {
+		key, value, ok := strings.Cut(v, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("--var %q must be NAME=value", v)
+		}
+		vars[key] = value
+	}
+
+	var missing []string
+	expanded := templateVariable.ReplaceAllFunc(data, func(match []byte) []byte {
+		if string(match) == "$${" {
+			return []byte("${")
+		}
+		name := string(match[2 : len(match)-1])
+		if value, ok := vars[name]; ok {
+			return []byte(value)
+		}
+		if value, ok := os.LookupEnv(name); ok {
+			return []byte(value)
+		}
+		missing = append(missing, name)
+		return match
+	})
+	if len(missing) > 0 {
+		return nil, withExitCode(exitValidation, fmt.Errorf("template variable(s) not set: %s", strings.Join(missing, ", ")))
+	}
+	return expanded, nil
+}
@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"merkle-cli/chain"
+	"merkle-cli/models"
+	"merkle-cli/timelock"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/spf13/cobra"
+)
+
+var (
+	timelockBatchFile  string
+	timelockRPCURL     string
+	timelockMinDelay   time.Duration
+	timelockAnnotate   string
+	timelockFailOnWarn bool
+)
+
+// timelockCmd reports the timelock operations scheduled and executed by a batch
+var timelockCmd = &cobra.Command{
+	Use:   "timelock",
+	Short: "Report and check the timelock operations of a transaction batch",
+	Long: `Report and check the timelock operations of a transaction batch
+
+Decodes every call to an OpenZeppelin TimelockController (schedule,
+scheduleBatch, execute, executeBatch and cancel) and prints the id of its
+operation. A schedule in a leaf with a validAfter gets an ETA of validAfter
+plus its delay, which the leaves executing the operation inherit.
+
+Fails when a schedule requests less than the minimum delay, given with
+--min-delay or read from each timelock with --rpc-url. Warns when schedules
+conflict: an operation scheduled twice, executed before or in the same leaf as
+its schedule, executed by a leaf expiring before its ETA, executed before its
+predecessor, or cancelled in the batch that schedules it.
+
+With --annotate, writes the batch with the operation of every timelock call
+recorded in its "timelock" field, which is carried into proofs files.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		batch, err := readBatchFile(cmd.Context(), timelockBatchFile)
+		if err != nil {
+			return err
+		}
+		entries, err := timelock.Entries(batch)
+		if err != nil {
+			return withExitCode(exitValidation, err)
+		}
+
+		var minDelay func(common.Address) (uint64, error)
+		switch {
+		case timelockRPCURL != "":
+			client := chain.NewClient(timelockRPCURL)
+			delays := make(map[common.Address]uint64)
+			minDelay = func(address common.Address) (uint64, error) {
+				if delay, ok := delays[address]; ok {
+					return delay, nil
+				}
+				delay, err := timelock.MinDelay(client, address)
+				if err != nil {
+					return 0, err
+				}
+				delays[address] = delay
+				return delay, nil
+			}
+		case timelockMinDelay > 0:
+			minDelay = func(common.Address) (uint64, error) {
+				return uint64(timelockMinDelay / time.Second), nil
+			}
+		}
+		issues, err := timelock.Check(entries, minDelay)
+		if err != nil {
+			return err
+		}
+
+		for _, e := range entries {
+			fmt.Printf("nonce %d call %d: %s on %s\n", e.Nonce, e.Call, e.Op.Kind, e.Timelock.Hex())
+			fmt.Printf("  operation: %s\n", e.Op.ID.Hex())
+			if e.Op.Delay != nil {
+				fmt.Printf("  delay: %ss\n", e.Op.Delay)
+			}
+			switch {
+			case e.ETA != 0:
+				fmt.Printf("  eta: %d (%s)\n", e.ETA, time.Unix(int64(e.ETA), 0).UTC().Format(time.RFC3339))
+			case e.Op.Delay != nil:
+				fmt.Printf("  eta: execution + %ss\n", e.Op.Delay)
+			}
+		}
+
+		failed, warned := 0, 0
+		for _, issue := range issues {
+			if issue.Error {
+				failed++
+				fmt.Println("error:", issue)
+			} else {
+				warned++
+				fmt.Println("warning:", issue)
+			}
+		}
+		fmt.Printf("%d timelock call(s), %d error(s), %d warning(s)\n", len(entries), failed, warned)
+		if failed > 0 {
+			return withExitCode(exitValidation, fmt.Errorf("%d timelock call(s) would be rejected", failed))
+		}
+		if timelockFailOnWarn && warned > 0 {
+			return withExitCode(exitValidation, fmt.Errorf("found %d conflicting schedule(s)", warned))
+		}
+
+		if timelockAnnotate != "" {
+			annotateTimelock(batch, entries)
+			if err := writeJSON(timelockAnnotate, batch); err != nil {
+				return err
+			}
+			fmt.Println("Annotated batch:", timelockAnnotate)
+		}
+		return nil
+	},
+}
+
+// annotateTimelock records the operation of every timelock call of a batch
+func annotateTimelock(batch *models.TransactionBatch, entries []timelock.Entry) {
+	groups := make(map[uint64]*models.TransactionGroup)
+	for g := range batch.Groups {
+		groups[batch.Groups[g].Nonce] = &batch.Groups[g]
+	}
+	for _, e := range entries {
+		operation := &models.TimelockOperation{
+			Kind:        e.Op.Kind,
+			OperationID: e.Op.ID.Hex(),
+			ETA:         e.ETA,
+		}
+		if e.Op.Delay != nil && e.Op.Delay.IsUint64() {
+			operation.Delay = e.Op.Delay.Uint64()
+		}
+		groups[e.Nonce].Calls[e.Call].Timelock = operation
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(timelockCmd)
+
+	timelockCmd.Flags().StringVarP(&timelockBatchFile, "batch-file", "f", "", "Path or https://, s3:// or gs:// URL of the transaction batch JSON file")
+	timelockCmd.MarkFlagRequired("batch-file")
+	timelockCmd.Flags().StringVar(&timelockRPCURL, "rpc-url", "", "JSON-RPC endpoint to read the minimum delay of each timelock from")
+	timelockCmd.Flags().DurationVar(&timelockMinDelay, "min-delay", 0, "Minimum delay schedules must request, such as 48h")
+	timelockCmd.Flags().StringVar(&timelockAnnotate, "annotate", "", "Write the batch with the operation of every timelock call recorded to this file")
+	timelockCmd.Flags().BoolVar(&timelockFailOnWarn, "fail-on-warning", false, "Exit with an error when schedules conflict")
+}
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"merkle-cli/merkle"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// traceHashes is the file every pair hashed while building trees is logged to
+var traceHashes string
+
+// traceMu serializes traced generations, such as the files of encode-dir, so
+// the pairs of one tree are never interleaved with another's. traceStarted is
+// set once the trace file was created, so later trees are appended to it.
+var (
+	traceMu      sync.Mutex
+	traceStarted bool
+)
+
+// hashTraceLine is the line written to the --trace-hashes file for every pair
+// pair. Nodes of trees wider than binary log their children instead of
+// left and right.
+type hashTraceLine struct {
+	Level    int      `json:"level"`
+	Index    int      `json:"index"`
+	Left     string   `json:"left,omitempty"`
+	Right    string   `json:"right,omitempty"`
+	Children []string `json:"children,omitempty"`
+	Hash     string   `json:"hash"`
+}
+
+// traceTree makes params log every pair hashed to the --trace-hashes file, if
+// one is set. The returned function flushes and closes the file and must be
+// called once the tree is built; traced trees are built one at a time.
+func traceTree(params *merkle.Params) (func() error, error) {
+	if traceHashes == "" {
+		return func() error { return nil }, nil
+	}
+	traceMu.Lock()
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if traceStarted {
+		flags = os.O_WRONLY | os.O_APPEND
+	}
+	file, err := os.OpenFile(traceHashes, flags, 0644)
+	if err != nil {
+		traceMu.Unlock()
+		return nil, fmt.Errorf("failed to open hash trace: %w", err)
+	}
+	traceStarted = true
+
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+	var writeErr error
+	params.Trace = func(pair merkle.PairTrace) {
+		if writeErr != nil {
+			return
+		}
+		line := hashTraceLine{
+			Level: pair.Level,
+			Index: pair.Index,
+			Hash:  hexutil.Encode(pair.Hash),
+		}
+		if pair.Children != nil {
+			for _, child := range pair.Children {
+				line.Children = append(line.Children, hexutil.Encode(child))
+			}
+		} else {
+			line.Left, line.Right = hexutil.Encode(pair.Left), hexutil.Encode(pair.Right)
+		}
+		writeErr = encoder.Encode(line)
+	}
+	return func() error {
+		defer traceMu.Unlock()
+		if writeErr == nil {
+			writeErr = writer.Flush()
+		}
+		if err := file.Close(); writeErr == nil {
+			writeErr = err
+		}
+		if writeErr != nil {
+			return fmt.Errorf("failed to write hash trace: %w", writeErr)
+		}
+		return nil
+	}, nil
+}
@@ -0,0 +1,217 @@
+package cmd
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"merkle-cli/chain"
+	"merkle-cli/checkpoint"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/spf13/cobra"
+)
+
+// txOptions holds the flags shared by commands that build and send transactions
+type txOptions struct {
+	rpcURL               string
+	privateKey           string
+	chainID              uint64
+	txNonce              uint64
+	gasLimit             uint64
+	gasPrice             string
+	maxFeePerGas         string
+	maxPriorityFeePerGas string
+	dryRun               bool
+	confirmations        uint64
+	receiptTimeout       time.Duration
+
+	// nonceFixed is set once a transaction was sent, so follow-up transactions
+	// use the next nonce instead of querying the pending one again
+	nonceFixed bool
+}
+
+// register adds the transaction flags to cmd
+func (o *txOptions) register(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&o.rpcURL, "rpc-url", "", "JSON-RPC endpoint used to fill missing fields and broadcast")
+	cmd.Flags().StringVar(&o.privateKey, "private-key", "", "Hex private key of the sending account")
+	cmd.Flags().Uint64Var(&o.chainID, "chain-id", 0, "Chain ID (fetched from the RPC endpoint if not provided)")
+	cmd.Flags().Uint64Var(&o.txNonce, "tx-nonce", 0, "Sender account nonce (fetched from the RPC endpoint if not provided)")
+	cmd.Flags().Uint64Var(&o.gasLimit, "gas-limit", 0, "Gas limit (estimated via the RPC endpoint if not provided)")
+	cmd.Flags().StringVar(&o.gasPrice, "gas-price", "", "Legacy gas price in wei (sends a legacy transaction)")
+	cmd.Flags().StringVar(&o.maxFeePerGas, "max-fee-per-gas", "", "EIP-1559 max fee per gas in wei")
+	cmd.Flags().StringVar(&o.maxPriorityFeePerGas, "max-priority-fee-per-gas", "", "EIP-1559 max priority fee per gas in wei")
+	cmd.Flags().BoolVar(&o.dryRun, "dry-run", false, "Print the raw transaction instead of broadcasting it")
+	cmd.Flags().Uint64Var(&o.confirmations, "confirmations", 1, "Confirmations to wait for after broadcasting")
+	cmd.Flags().DurationVar(&o.receiptTimeout, "receipt-timeout", 5*time.Minute, "How long to wait for a transaction to be mined")
+}
+
+// buildTransaction creates a transaction from the flags, filling unset fields over RPC when available
+func (o *txOptions) buildTransaction(cmd *cobra.Command, to common.Address, value *big.Int, data []byte) (*chain.Transaction, *ecdsa.PrivateKey, error) {
+	if o.gasPrice != "" && (o.maxFeePerGas != "" || o.maxPriorityFeePerGas != "") {
+		return nil, nil, fmt.Errorf("--gas-price cannot be combined with EIP-1559 fee flags")
+	}
+	if !o.dryRun && (o.rpcURL == "" || o.privateKey == "") {
+		return nil, nil, fmt.Errorf("broadcasting requires --rpc-url and --private-key (use --dry-run to only print the transaction)")
+	}
+
+	tx := &chain.Transaction{
+		Nonce:    o.txNonce,
+		To:       to,
+		Value:    value,
+		Data:     data,
+		GasLimit: o.gasLimit,
+	}
+	if cmd.Flags().Changed("chain-id") {
+		tx.ChainID = new(big.Int).SetUint64(o.chainID)
+	}
+
+	var err error
+	if tx.GasPrice, err = parseWei("gas-price", o.gasPrice); err != nil {
+		return nil, nil, err
+	}
+	if tx.MaxFeePerGas, err = parseWei("max-fee-per-gas", o.maxFeePerGas); err != nil {
+		return nil, nil, err
+	}
+	if tx.MaxPriorityFeePerGas, err = parseWei("max-priority-fee-per-gas", o.maxPriorityFeePerGas); err != nil {
+		return nil, nil, err
+	}
+
+	var key *ecdsa.PrivateKey
+	var sender common.Address
+	if o.privateKey != "" {
+		if key, err = crypto.HexToECDSA(strings.TrimPrefix(o.privateKey, "0x")); err != nil {
+			return nil, nil, fmt.Errorf("invalid private key: %w", err)
+		}
+		sender = crypto.PubkeyToAddress(key.PublicKey)
+	}
+
+	if o.rpcURL != "" {
+		client := chain.NewClient(o.rpcURL)
+		if err := client.Fill(tx, sender, o.nonceFixed || cmd.Flags().Changed("tx-nonce")); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err := tx.Validate(); err != nil {
+		return nil, nil, fmt.Errorf("incomplete transaction (provide the missing flag or --rpc-url): %w", err)
+	}
+
+	return tx, key, nil
+}
+
+// sendTransaction prints the transaction in dry-run mode, otherwise signs and broadcasts it
+func (o *txOptions) sendTransaction(tx *chain.Transaction, key *ecdsa.PrivateKey) (common.Hash, error) {
+	if o.dryRun {
+		fmt.Printf("To: %s\n", tx.To.Hex())
+		fmt.Printf("Data: 0x%x\n", tx.Data)
+
+		if key == nil {
+			unsigned, err := tx.UnsignedBytes()
+			if err != nil {
+				return common.Hash{}, err
+			}
+			fmt.Printf("Unsigned Transaction: 0x%x\n", unsigned)
+			return common.Hash{}, nil
+		}
+
+		raw, err := tx.Sign(key)
+		if err != nil {
+			return common.Hash{}, err
+		}
+		fmt.Printf("Raw Transaction: 0x%x\n", raw)
+		fmt.Printf("Transaction Hash: %s\n", chain.TransactionHash(raw).Hex())
+		return chain.TransactionHash(raw), nil
+	}
+
+	raw, err := tx.Sign(key)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	hash, err := chain.NewClient(o.rpcURL).SendRawTransaction(raw)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("failed to broadcast transaction: %w", err)
+	}
+	fmt.Printf("Transaction Hash: %s\n", hash.Hex())
+	return hash, nil
+}
+
+// submitAndWait sends the transaction for a checkpointed step and waits for it to be mined.
+// A transaction left pending by an interrupted run is awaited instead of being sent again,
+// unless the node no longer knows about it.
+func (o *txOptions) submitAndWait(state *checkpoint.State, step string, build func() (*chain.Transaction, *ecdsa.PrivateKey, error)) error {
+	client := chain.NewClient(o.rpcURL)
+
+	if pending := state.PendingTx(step); pending != "" && !o.dryRun {
+		known, err := client.TransactionKnown(common.HexToHash(pending))
+		if err != nil {
+			return fmt.Errorf("failed to look up pending transaction %s: %w", pending, err)
+		}
+		if known {
+			fmt.Printf("Resuming pending transaction %s\n", pending)
+			return o.awaitStep(client, state, step, common.HexToHash(pending))
+		}
+		fmt.Printf("Pending transaction %s was dropped, resending\n", pending)
+		if err := state.ClearPending(step); err != nil {
+			return err
+		}
+	}
+
+	tx, key, err := build()
+	if err != nil {
+		return err
+	}
+
+	hash, err := o.sendTransaction(tx, key)
+	if err != nil {
+		return err
+	}
+	o.advanceNonce(tx)
+	if o.dryRun {
+		return nil
+	}
+
+	if err := state.MarkPending(step, hash.Hex()); err != nil {
+		return err
+	}
+	return o.awaitStep(client, state, step, hash)
+}
+
+// awaitStep waits for the step's transaction and records the outcome in the checkpoint
+func (o *txOptions) awaitStep(client *chain.Client, state *checkpoint.State, step string, hash common.Hash) error {
+	receipt, err := client.WaitForReceipt(hash, o.confirmations, o.receiptTimeout)
+	if err != nil {
+		return err
+	}
+	if !receipt.Succeeded() {
+		if err := state.ClearPending(step); err != nil {
+			return err
+		}
+		return fmt.Errorf("transaction %s reverted", hash.Hex())
+	}
+
+	fmt.Printf("  Confirmed in block %d\n", uint64(receipt.BlockNumber))
+	return state.MarkCompleted(step, hash.Hex())
+}
+
+// advanceNonce makes the next built transaction use the nonce following tx
+func (o *txOptions) advanceNonce(tx *chain.Transaction) {
+	o.txNonce = tx.Nonce + 1
+	o.nonceFixed = true
+}
+
+// parseWei parses an optional decimal wei amount from a flag
+func parseWei(flag string, value string) (*big.Int, error) {
+	if value == "" {
+		return nil, nil
+	}
+	wei, ok := new(big.Int).SetString(value, 10)
+	if !ok || wei.Sign() < 0 {
+		return nil, fmt.Errorf("invalid --%s value %q: expected a non-negative integer amount of wei", flag, value)
+	}
+	return wei, nil
+}
@@ -0,0 +1,327 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"merkle-cli/merkle"
+	"merkle-cli/models"
+	"merkle-cli/utils"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/spf13/cobra"
+)
+
+var (
+	verifyAllBatchFile    string
+	verifyAllOneSigID     uint64
+	verifyAllContractAddr string
+	verifyAllHostSigners  []string
+	verifyAllSignature    string
+	verifyAllColor        string
+)
+
+// verifyAllCmd re-verifies every proof of a proofs file as a final check before distribution
+var verifyAllCmd = &cobra.Command{
+	Use:   "verify-all <proofs-file>",
+	Short: "Verify every proof in a proofs file against its root",
+	Long: `Verify every proof in a proofs file against its root
+
+Checks each proof against the root using the tree options recorded in the
+file and, unless the file is redacted, re-encodes each leaf to confirm its hash.
+With --batch-file the tree is regenerated from the input batch and must match
+the file exactly, including its aggregate and tree shape. With
+--max-proof-length every proof must fit the on-chain verifier's cap. Subtree
+roots that are nodes of the tree must prove against its root. With
+--host-signer the file must carry a signature by one of the given generation
+hosts (<file>.sig.json, or --signature-file). Exits with status 4 if anything
+fails.
+
+Every failed leaf is shown with its path in the file, such as proofs[3], and,
+diff style, the value in the file and the computed value of each differing
+field, colored on terminals.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkColorMode(verifyAllColor); err != nil {
+			return err
+		}
+		for _, a := range verifyAllHostSigners {
+			if !common.IsHexAddress(a) {
+				return fmt.Errorf("invalid --host-signer address %q", a)
+			}
+		}
+		output, redacted, err := decodeOutputFile(args[0])
+		if err != nil {
+			return err
+		}
+		order, err := merkle.CheckTreeOptions(output.TreeOptions)
+		if err != nil {
+			return err
+		}
+		root, err := utils.HexToBytes(output.MerkleRoot)
+		if err != nil {
+			return fmt.Errorf("invalid merkle root: %w", err)
+		}
+		// An empty tree of the file's shape verifies its proofs
+		verifier, err := merkle.NewLevelTree(cmd.Context(), nil, order, merkle.ArityOf(output.TreeOptions))
+		if err != nil {
+			return err
+		}
+
+		var failures []checkFailure
+		for i, entry := range output.Proofs {
+			path := fmt.Sprintf("proofs[%d]", i)
+			if failure := verifyProofEntry(root, entry, path, verifier, output.TreeOptions, output.LeafEncodingVersion, redacted); failure != nil {
+				failures = append(failures, *failure)
+			}
+			if maxProofLength > 0 && len(entry.Proof) > maxProofLength {
+				failures = append(failures, checkFailure{
+					Message: fmt.Sprintf("proof has %d hashes, exceeding the limit of %d (--max-proof-length)", len(entry.Proof), maxProofLength),
+					Path:    path,
+					Entry:   &output.Proofs[i],
+				})
+			}
+		}
+
+		failures = append(failures, verifySubtrees(root, output, order)...)
+
+		if verifyAllBatchFile != "" {
+			mismatches, err := compareWithBatch(cmd, output, redacted)
+			if err != nil {
+				return err
+			}
+			failures = append(failures, mismatches...)
+		}
+
+		var hostSigner common.Address
+		if len(verifyAllHostSigners) > 0 {
+			data, err := os.ReadFile(args[0])
+			if err != nil {
+				return fmt.Errorf("failed to read proofs file: %w", err)
+			}
+			signaturePath := verifyAllSignature
+			if signaturePath == "" {
+				signaturePath = outputSignaturePath(args[0])
+			}
+			if hostSigner, err = verifyOutputSignature(signaturePath, data, verifyAllHostSigners); err != nil {
+				failures = append(failures, checkFailure{Message: err.Error()})
+			}
+		}
+
+		if len(failures) > 0 {
+			color := useColor(verifyAllColor, os.Stderr)
+			for _, failure := range failures {
+				printFailure(os.Stderr, color, failure)
+			}
+			return withExitCode(exitVerification, fmt.Errorf("%d check(s) failed for %s", len(failures), args[0]))
+		}
+
+		fmt.Println("Merkle Root:", output.MerkleRoot)
+		fmt.Printf("Verified %d proof(s)\n", len(output.Proofs))
+		if redacted {
+			fmt.Println("File is redacted, leaf hashes were not re-encoded")
+		}
+		if verifyAllBatchFile != "" {
+			fmt.Println("Matches batch:", verifyAllBatchFile)
+		}
+		if len(verifyAllHostSigners) > 0 {
+			fmt.Println("Signed by host:", hostSigner.Hex())
+		}
+		return nil
+	},
+}
+
+// verifyProofEntry checks a proof against the root with a tree of the shape
+// given by options and, for files with leaf contents, that the leaf hash
+// matches its encoding. It returns the problem found with the entry at path,
+// or nil if the entry is valid.
+func verifyProofEntry(root []byte, entry models.ProofEntry, path string, tree merkle.Tree, options *models.TreeOptions, version uint8, redacted bool) *checkFailure {
+	fail := func(message string, diffs ...fieldDiff) *checkFailure {
+		return &checkFailure{
+			Message: fmt.Sprintf("%s (leaf index %d): %s", path, entry.LeafIndex, message),
+			Path:    path,
+			Entry:   &entry,
+			Diffs:   diffs,
+		}
+	}
+
+	leafHash, err := utils.HexToBytes(entry.LeafHash)
+	if err != nil {
+		return fail(fmt.Sprintf("invalid leaf hash: %v", err))
+	}
+	proof := make([][]byte, 0, len(entry.Proof))
+	for _, p := range entry.Proof {
+		b, err := utils.HexToBytes(p)
+		if err != nil {
+			return fail(fmt.Sprintf("invalid proof hash: %v", err))
+		}
+		proof = append(proof, b)
+	}
+
+	if !redacted {
+		encoded, err := utils.EncodeLeafVersion(entry.Leaf, version)
+		if err != nil {
+			return fail(fmt.Sprintf("failed to encode leaf: %v", err))
+		}
+		if !bytes.Equal(encoded, leafHash) {
+			return fail("leaf hash does not match the encoded leaf",
+				fieldDiff{Path: path + ".leafHash", File: entry.LeafHash, Computed: fmt.Sprintf("0x%x", encoded)})
+		}
+	}
+
+	if !tree.Verify(root, leafHash, proof, entry.LeafIndex) {
+		derived, err := merkle.ComputeRootFromProof(leafHash, proof, entry.LeafIndex, options)
+		if err != nil {
+			return fail("proof does not verify against the root")
+		}
+		return fail("proof does not verify against the root",
+			fieldDiff{Path: "merkleRoot", File: fmt.Sprintf("0x%x", root), Computed: fmt.Sprintf("0x%x (from %s.proof)", derived, path)})
+	}
+	return nil
+}
+
+// compareWithBatch regenerates the tree from --batch-file with the parameters
+// of the proofs file and reports every difference
+func compareWithBatch(cmd *cobra.Command, output *models.OutputFormat, redacted bool) ([]checkFailure, error) {
+	batch, err := readBatchFile(cmd.Context(), verifyAllBatchFile)
+	if err != nil {
+		return nil, err
+	}
+
+	// Proofs over --max-proof-length are reported as failures, not refused
+	limits := batchLimits()
+	limits.MaxProofLength = 0
+	params := merkle.Params{
+		OneSigID:     verifyAllOneSigID,
+		ContractAddr: verifyAllContractAddr,
+		LeafVersion:  output.LeafEncodingVersion,
+		Options:      output.TreeOptions,
+		Limits:       limits,
+	}
+	if !redacted && len(output.Proofs) > 0 {
+		if !cmd.Flags().Changed("onesig-id") {
+			params.OneSigID = output.Proofs[0].OneSigID
+		}
+		if !cmd.Flags().Changed("contract-addr") {
+			params.ContractAddr = output.Proofs[0].ContractAddr
+		}
+	} else if !cmd.Flags().Changed("onesig-id") {
+		return nil, withExitCode(exitValidation, fmt.Errorf("--onesig-id is required to regenerate a redacted proofs file"))
+	}
+
+	tree, entries, err := generateTree(cmd.Context(), batch, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to regenerate tree: %w", err)
+	}
+	expected := buildOutput(tree, output.LeafEncodingVersion, output.TreeOptions, entries, utils.HashEncodingHex)
+
+	var mismatches []checkFailure
+	if expected.MerkleRoot != output.MerkleRoot {
+		mismatches = append(mismatches, checkFailure{
+			Message: "merkle root does not match the batch root",
+			Diffs:   []fieldDiff{{Path: "merkleRoot", File: output.MerkleRoot, Computed: expected.MerkleRoot}},
+		})
+	}
+	if output.Aggregate != nil && !sameJSON(output.Aggregate, expected.Aggregate) {
+		file, _ := json.Marshal(output.Aggregate)
+		computed, _ := json.Marshal(expected.Aggregate)
+		mismatches = append(mismatches, checkFailure{
+			Message: "aggregate does not match the leaves of the batch",
+			Diffs:   []fieldDiff{{Path: "aggregate", File: string(file), Computed: string(computed)}},
+		})
+	}
+	if output.TreeShape != nil && *output.TreeShape != *expected.TreeShape {
+		mismatches = append(mismatches, checkFailure{
+			Message: "tree shape does not match the batch tree",
+			Diffs: []fieldDiff{
+				{Path: "treeShape.depth", File: fmt.Sprint(output.TreeShape.Depth), Computed: fmt.Sprint(expected.TreeShape.Depth)},
+				{Path: "treeShape.maxProofLength", File: fmt.Sprint(output.TreeShape.MaxProofLength), Computed: fmt.Sprint(expected.TreeShape.MaxProofLength)},
+			},
+		})
+	}
+	if len(output.Subtrees) > 0 {
+		order, err := merkle.CheckTreeOptions(output.TreeOptions)
+		if err != nil {
+			return nil, err
+		}
+		subtrees, err := tree.SubtreeRoots(cmd.Context(), entries, order, hashWorkers())
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute subtree roots: %w", err)
+		}
+		if computed := subtreeOutput(subtrees, utils.HashEncodingHex); !sameJSON(output.Subtrees, computed) {
+			file, _ := json.Marshal(output.Subtrees)
+			regenerated, _ := json.Marshal(computed)
+			mismatches = append(mismatches, checkFailure{
+				Message: "subtree roots do not match the batch tree",
+				Diffs:   []fieldDiff{{Path: "subtrees", File: string(file), Computed: string(regenerated)}},
+			})
+		}
+	}
+	if len(expected.Proofs) != len(output.Proofs) {
+		mismatches = append(mismatches, checkFailure{Message: fmt.Sprintf("file has %d proofs, the batch has %d leaves", len(output.Proofs), len(expected.Proofs))})
+	}
+
+	byHash := make(map[string]int, len(output.Proofs))
+	for i, entry := range output.Proofs {
+		byHash[entry.LeafHash] = i
+	}
+	for _, want := range expected.Proofs {
+		i, ok := byHash[want.LeafHash]
+		if !ok {
+			mismatches = append(mismatches, checkFailure{Message: fmt.Sprintf("leaf %s (nonce %d) is missing from the file", want.LeafHash, want.Nonce)})
+			continue
+		}
+		got := output.Proofs[i]
+		path := fmt.Sprintf("proofs[%d]", i)
+		var diffs []fieldDiff
+		if got.LeafIndex != want.LeafIndex {
+			diffs = append(diffs, fieldDiff{Path: path + ".leafIndex", File: fmt.Sprint(got.LeafIndex), Computed: fmt.Sprint(want.LeafIndex)})
+		}
+		diffs = append(diffs, proofDiffs(path, got.Proof, want.Proof)...)
+		if len(diffs) > 0 {
+			mismatches = append(mismatches, checkFailure{
+				Message: fmt.Sprintf("leaf %s (nonce %d) has a different index or proof than regenerated", want.LeafHash, want.Nonce),
+				Path:    path,
+				Entry:   &got,
+				Diffs:   diffs,
+			})
+		}
+	}
+	return mismatches, nil
+}
+
+// proofDiffs returns the differing hashes of a proof in the file and the
+// regenerated proof, and their lengths if they differ
+func proofDiffs(path string, file, computed []string) []fieldDiff {
+	if len(file) != len(computed) {
+		return []fieldDiff{{Path: path + ".proof length", File: fmt.Sprint(len(file)), Computed: fmt.Sprint(len(computed))}}
+	}
+	var diffs []fieldDiff
+	for i := range file {
+		if file[i] != computed[i] {
+			diffs = append(diffs, fieldDiff{Path: fmt.Sprintf("%s.proof[%d]", path, i), File: file[i], Computed: computed[i]})
+		}
+	}
+	return diffs
+}
+
+// sameJSON reports whether two values have the same JSON encoding
+func sameJSON(a, b interface{}) bool {
+	x, errX := json.Marshal(a)
+	y, errY := json.Marshal(b)
+	return errX == nil && errY == nil && bytes.Equal(x, y)
+}
+
+func init() {
+	rootCmd.AddCommand(verifyAllCmd)
+
+	verifyAllCmd.Flags().StringVarP(&verifyAllBatchFile, "batch-file", "f", "", "Input batch to regenerate the tree from and compare against")
+	verifyAllCmd.Flags().Uint64VarP(&verifyAllOneSigID, "onesig-id", "o", 0, "OneSig ID for --batch-file (defaults to the one in the proofs file)")
+	verifyAllCmd.Flags().StringVarP(&verifyAllContractAddr, "contract-addr", "c", "", "OneSig contract address for --batch-file (defaults to the one in the proofs file)")
+	verifyAllCmd.Flags().StringSliceVar(&verifyAllHostSigners, "host-signer", nil, "Require the file to be signed by one of these generation host addresses")
+	verifyAllCmd.Flags().StringVar(&verifyAllSignature, "signature-file", "", "Host signature of the file (defaults to <file>.sig.json)")
+	verifyAllCmd.Flags().StringVar(&verifyAllColor, "color", colorAuto, "Color the failures: auto (on terminals), always or never")
+	verifyAllCmd.RegisterFlagCompletionFunc("color", fixedCompletion(colorAuto, colorAlways, colorNever))
+}
@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"fmt"
+
+	"merkle-cli/signer"
+	"merkle-cli/utils"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	verifySigsRoot      string
+	verifySigsSeed      string
+	verifySigsPayload   string
+	verifySigsFile      string
+	verifySigsSigners   []string
+	verifySigsThreshold int
+	verifySigsRPCURL    string
+	verifySigsExpiry    expiryOptions
+)
+
+// verifySignaturesCmd checks a signature payload against the configured signer set
+var verifySignaturesCmd = &cobra.Command{
+	Use:   "verify-signatures",
+	Short: "Verify a signature payload against the signer set",
+	Long: `Verify a signature payload against the signer set
+
+Recovers every signer of a concatenated signature payload and fails if a
+signature comes from an unknown signer, a signer appears twice, signatures are
+not sorted by ascending signer address or the threshold is not met. Run it
+before submit-root to avoid submitting an invalid signature bundle.
+
+Roots bound to an expiry are verified with --expiry, or with the expiry
+recorded in --signatures-file.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		root, seed, err := parseRootAndSeed(verifySigsRoot, verifySigsSeed)
+		if err != nil {
+			return err
+		}
+
+		var fileExpiry uint64
+		if verifySigsFile != "" {
+			if verifySigsPayload != "" {
+				return fmt.Errorf("--signatures and --signatures-file are mutually exclusive")
+			}
+			aggregated, err := readAggregatedSignatures(verifySigsFile, root)
+			if err != nil {
+				return err
+			}
+			verifySigsPayload, fileExpiry = aggregated.Signatures, aggregated.Expiry
+		}
+		if verifySigsPayload == "" {
+			return fmt.Errorf("one of --signatures or --signatures-file is required")
+		}
+
+		payload, err := utils.HexToBytes(verifySigsPayload)
+		if err != nil {
+			return fmt.Errorf("invalid signatures: %w", err)
+		}
+
+		quorum, hasSignerSet, err := resolveQuorum(cmd, verifySigsSigners, verifySigsThreshold)
+		if err != nil {
+			return err
+		}
+		if !hasSignerSet {
+			return fmt.Errorf("no signer set configured, declare one in the config file or pass --signers")
+		}
+
+		expiry, err := verifySigsExpiry.parse(fileExpiry)
+		if err != nil {
+			return err
+		}
+
+		digest, err := rootDigest(root, seed, expiry)
+		if err != nil {
+			return err
+		}
+
+		signers, err := signer.VerifyPayload(digest, payload, quorum, contractVerifier(verifySigsRPCURL))
+		if err != nil {
+			return withExitCode(exitVerification, fmt.Errorf("signature verification failed: %w", err))
+		}
+
+		fmt.Printf("Signatures valid: %d of %d required signers\n", len(signers), quorum.Threshold)
+		for _, s := range signerStrings(signers) {
+			fmt.Println("  " + s)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(verifySignaturesCmd)
+
+	verifySignaturesCmd.Flags().StringVarP(&verifySigsRoot, "root", "r", "", "Merkle root the signatures approve")
+	verifySignaturesCmd.MarkFlagRequired("root")
+
+	verifySignaturesCmd.Flags().StringVar(&verifySigsSeed, "seed", "", "OneSig seed (defaults to zero)")
+	verifySignaturesCmd.Flags().StringVarP(&verifySigsPayload, "signatures", "s", "", "Concatenated signature payload")
+	verifySignaturesCmd.Flags().StringVar(&verifySigsFile, "signatures-file", "", "Aggregated signatures file written by the aggregate command")
+	verifySignaturesCmd.Flags().StringSliceVar(&verifySigsSigners, "signers", nil, "Comma separated addresses of the allowed signers (overrides the config signer set)")
+	verifySignaturesCmd.RegisterFlagCompletionFunc("signers", completeSigners)
+	verifySignaturesCmd.Flags().IntVar(&verifySigsThreshold, "threshold", 1, "Number of distinct signers required (overrides the config signer set)")
+	verifySignaturesCmd.Flags().StringVar(&verifySigsRPCURL, "rpc-url", "", "JSON-RPC endpoint used to verify EIP-1271 contract signers")
+	verifySigsExpiry.register(verifySignaturesCmd)
+}
@@ -0,0 +1,312 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"merkle-cli/merkle"
+	"merkle-cli/models"
+	"merkle-cli/utils"
+
+	"github.com/spf13/cobra"
+)
+
+// Visualization formats selectable with --format
+const (
+	visualizeFormatDot     = "dot"
+	visualizeFormatMermaid = "mermaid"
+)
+
+var (
+	visualizeFormat   string
+	visualizeNonce    uint64
+	visualizeLeaf     string
+	visualizeMaxNodes int
+	visualizeOutput   string
+)
+
+// visualizeCmd renders the tree of a proofs file as a GraphViz or Mermaid graph
+var visualizeCmd = &cobra.Command{
+	Use:   "visualize <proofs-file>",
+	Short: "Render the Merkle tree as a GraphViz or Mermaid graph",
+	Long: `Render the Merkle tree as a GraphViz or Mermaid graph
+
+Rebuilds the tree from the leaf hashes of a proofs file and renders every node
+with its hash truncated. Selecting a leaf with --nonce or --leaf highlights its
+proof path; if the whole tree has more than --max-nodes nodes only the path and
+its siblings are rendered.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if visualizeFormat != visualizeFormatDot && visualizeFormat != visualizeFormatMermaid {
+			return fmt.Errorf("unsupported format %q, expected %s or %s", visualizeFormat, visualizeFormatDot, visualizeFormatMermaid)
+		}
+
+		output, _, err := decodeOutputFile(args[0])
+		if err != nil {
+			return err
+		}
+		order, err := merkle.CheckTreeOptions(output.TreeOptions)
+		if err != nil {
+			return err
+		}
+
+		graph, err := newTreeGraph(cmd, output, order)
+		if err != nil {
+			return err
+		}
+
+		var b bytes.Buffer
+		if visualizeFormat == visualizeFormatDot {
+			err = graph.writeDot(&b)
+		} else {
+			err = graph.writeMermaid(&b)
+		}
+		if err != nil {
+			return err
+		}
+
+		if visualizeOutput == "" {
+			_, err = os.Stdout.Write(b.Bytes())
+			return err
+		}
+		if err := os.WriteFile(visualizeOutput, b.Bytes(), 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", visualizeOutput, err)
+		}
+		return nil
+	},
+}
+
+// treeGraph is the set of tree nodes selected for rendering
+type treeGraph struct {
+	levels  [][][]byte
+	arity   int
+	leaves  map[int]models.ProofEntry
+	visible map[[2]int]bool
+	path    map[[2]int]bool
+	sibling map[[2]int]bool
+}
+
+// newTreeGraph rebuilds the tree of a proofs file and selects the nodes to render
+func newTreeGraph(cmd *cobra.Command, output *models.OutputFormat, order merkle.PairOrder) (*treeGraph, error) {
+	if len(output.Proofs) == 0 {
+		return nil, fmt.Errorf("proofs file has no entries")
+	}
+
+	g := &treeGraph{
+		leaves:  make(map[int]models.ProofEntry, len(output.Proofs)),
+		visible: make(map[[2]int]bool),
+		path:    make(map[[2]int]bool),
+		sibling: make(map[[2]int]bool),
+		arity:   merkle.ArityOf(output.TreeOptions),
+	}
+	hashes := make([][]byte, len(output.Proofs))
+	for _, entry := range output.Proofs {
+		if entry.LeafIndex < 0 || entry.LeafIndex >= len(hashes) || hashes[entry.LeafIndex] != nil {
+			return nil, fmt.Errorf("proofs file has an invalid or repeated leaf index %d", entry.LeafIndex)
+		}
+		hash, err := utils.HexToBytes(entry.LeafHash)
+		if err != nil {
+			return nil, fmt.Errorf("invalid leaf hash: %w", err)
+		}
+		hashes[entry.LeafIndex] = hash
+		g.leaves[entry.LeafIndex] = entry
+	}
+
+	levels, err := merkle.BuildLevelsArity(cmd.Context(), hashes, order, g.arity, hashWorkers())
+	if err != nil {
+		return nil, err
+	}
+	if root := utils.NormalizeHex(levels[len(levels)-1][0]); root != output.MerkleRoot {
+		return nil, withExitCode(exitVerification, fmt.Errorf("leaf hashes hash to %s, not to the file's root %s", root, output.MerkleRoot))
+	}
+	g.levels = levels
+
+	total := 0
+	for _, nodes := range levels {
+		total += len(nodes)
+	}
+
+	selected := -1
+	if cmd.Flags().Changed("nonce") || visualizeLeaf != "" {
+		entry, err := findVisualizedEntry(cmd, output)
+		if err != nil {
+			return nil, err
+		}
+		selected = entry.LeafIndex
+		index := selected
+		for level := range levels {
+			g.path[[2]int{level, index}] = true
+			first := index - index%g.arity
+			for sibling := first; level < len(levels)-1 && sibling < first+g.arity && sibling < len(levels[level]); sibling++ {
+				if sibling != index {
+					g.sibling[[2]int{level, sibling}] = true
+				}
+			}
+			index /= g.arity
+		}
+	}
+
+	switch {
+	case total <= visualizeMaxNodes:
+		for level, nodes := range levels {
+			for i := range nodes {
+				g.visible[[2]int{level, i}] = true
+			}
+		}
+	case selected >= 0:
+		for node := range g.path {
+			g.visible[node] = true
+		}
+		for node := range g.sibling {
+			g.visible[node] = true
+		}
+	default:
+		return nil, fmt.Errorf("tree has %d nodes, more than --max-nodes %d; select a leaf with --nonce or --leaf to render its path", total, visualizeMaxNodes)
+	}
+	return g, nil
+}
+
+// findVisualizedEntry selects the leaf given with --nonce and --leaf
+func findVisualizedEntry(cmd *cobra.Command, output *models.OutputFormat) (*models.ProofEntry, error) {
+	if cmd.Flags().Changed("nonce") {
+		return selectEntry(output, visualizeNonce, visualizeLeaf)
+	}
+	for i := range output.Proofs {
+		if strings.EqualFold(output.Proofs[i].LeafHash, visualizeLeaf) {
+			return &output.Proofs[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no proof entry with leaf %s", visualizeLeaf)
+}
+
+// nodes returns the visible nodes in level then index order, root first
+func (g *treeGraph) nodes() [][2]int {
+	var nodes [][2]int
+	for node := range g.visible {
+		nodes = append(nodes, node)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		if nodes[i][0] != nodes[j][0] {
+			return nodes[i][0] > nodes[j][0]
+		}
+		return nodes[i][1] < nodes[j][1]
+	})
+	return nodes
+}
+
+// edges returns the visible parent to child edges. The last odd node is
+// paired with itself, and a short last group filled up with its last node,
+// which is drawn as a single edge.
+func (g *treeGraph) edges() [][2][2]int {
+	var edges [][2][2]int
+	for _, node := range g.nodes() {
+		level, index := node[0], node[1]
+		if level == 0 {
+			continue
+		}
+		for child := g.arity * index; child < g.arity*(index+1); child++ {
+			c := [2]int{level - 1, child}
+			if child < len(g.levels[level-1]) && g.visible[c] {
+				edges = append(edges, [2][2]int{node, c})
+			}
+		}
+	}
+	return edges
+}
+
+// label describes a node by its role and truncated hash
+func (g *treeGraph) label(node [2]int) string {
+	hash := shortHash(g.levels[node[0]][node[1]])
+	switch {
+	case node[0] == len(g.levels)-1:
+		return "root " + hash
+	case node[0] == 0:
+		entry := g.leaves[node[1]]
+		if len(entry.Calls) == 0 {
+			return fmt.Sprintf("leaf %d %s", node[1], hash)
+		}
+		return fmt.Sprintf("leaf %d nonce %d %s", node[1], entry.Nonce, hash)
+	default:
+		return hash
+	}
+}
+
+// shortHash truncates a hash to its first and last bytes
+func shortHash(hash []byte) string {
+	h := utils.NormalizeHex(hash)
+	if len(h) <= 14 {
+		return h
+	}
+	return h[:8] + ".." + h[len(h)-4:]
+}
+
+// nodeID names a node in the rendered graph
+func nodeID(node [2]int) string {
+	return fmt.Sprintf("n%d_%d", node[0], node[1])
+}
+
+// writeDot renders the graph in GraphViz dot syntax
+func (g *treeGraph) writeDot(w io.Writer) error {
+	var b bytes.Buffer
+	b.WriteString("digraph merkle {\n")
+	b.WriteString("  node [shape=box, fontname=\"monospace\"];\n")
+	for _, node := range g.nodes() {
+		style := ""
+		switch {
+		case g.path[node]:
+			style = ", style=filled, fillcolor=\"#cfe2ff\""
+		case g.sibling[node]:
+			style = ", style=filled, fillcolor=\"#fff3cd\""
+		}
+		fmt.Fprintf(&b, "  %s [label=%q%s];\n", nodeID(node), g.label(node), style)
+	}
+	for _, edge := range g.edges() {
+		fmt.Fprintf(&b, "  %s -> %s;\n", nodeID(edge[0]), nodeID(edge[1]))
+	}
+	b.WriteString("}\n")
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// writeMermaid renders the graph as a Mermaid flowchart
+func (g *treeGraph) writeMermaid(w io.Writer) error {
+	var b bytes.Buffer
+	b.WriteString("graph TD\n")
+	var path, siblings []string
+	for _, node := range g.nodes() {
+		fmt.Fprintf(&b, "  %s[\"%s\"]\n", nodeID(node), g.label(node))
+		if g.path[node] {
+			path = append(path, nodeID(node))
+		} else if g.sibling[node] {
+			siblings = append(siblings, nodeID(node))
+		}
+	}
+	for _, edge := range g.edges() {
+		fmt.Fprintf(&b, "  %s --> %s\n", nodeID(edge[0]), nodeID(edge[1]))
+	}
+	if len(path) > 0 {
+		b.WriteString("  classDef path fill:#cfe2ff\n")
+		b.WriteString("  classDef sibling fill:#fff3cd\n")
+		fmt.Fprintf(&b, "  class %s path\n", strings.Join(path, ","))
+		if len(siblings) > 0 {
+			fmt.Fprintf(&b, "  class %s sibling\n", strings.Join(siblings, ","))
+		}
+	}
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+func init() {
+	rootCmd.AddCommand(visualizeCmd)
+
+	visualizeCmd.Flags().StringVar(&visualizeFormat, "format", visualizeFormatDot, "Graph format: dot or mermaid")
+	visualizeCmd.Flags().Uint64Var(&visualizeNonce, "nonce", 0, "Highlight the proof path of the leaf with this nonce")
+	visualizeCmd.Flags().StringVar(&visualizeLeaf, "leaf", "", "Highlight the proof path of the leaf with this hash")
+	visualizeCmd.Flags().IntVar(&visualizeMaxNodes, "max-nodes", 256, "Render only the selected proof path when the tree has more nodes than this")
+	visualizeCmd.Flags().StringVar(&visualizeOutput, "output", "", "Write the graph to this file instead of stdout")
+	visualizeCmd.RegisterFlagCompletionFunc("format", fixedCompletion(visualizeFormatDot, visualizeFormatMermaid))
+}
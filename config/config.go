@@ -0,0 +1,271 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+
+	"merkle-cli/models"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// Config is the registry of deployment settings shared by the CLI commands
+type Config struct {
+	SignerSet *SignerSet `json:"signerSet,omitempty"`
+	Lint      *Lint      `json:"lint,omitempty"`
+	Policy    *Policy    `json:"policy,omitempty"`
+	Tokens    []Token    `json:"tokens,omitempty"`
+	// Flags holds default flag values by flag name, used when a flag is not
+	// given on the command line or in the environment
+	Flags map[string]json.RawMessage `json:"flags,omitempty"`
+}
+
+// PolicyVersion is the only supported policy rules version
+const PolicyVersion = 1
+
+// Policy restricts which functions a batch may call on each target and how
+// much it may spend
+type Policy struct {
+	Version int          `json:"version"`
+	Rules   []PolicyRule `json:"rules"`
+	Budgets []Budget     `json:"budgets,omitempty"`
+}
+
+// NativeToken names the chain's native currency in budgets
+const NativeToken = "native"
+
+// Budget caps the total a batch may spend of the native currency or of a
+// declared token, on one OneSig ID or, without one, across the whole batch.
+// Max is in whole tokens, or ether for the native currency.
+type Budget struct {
+	OneSigID *uint64 `json:"oneSigId,omitempty"`
+	Token    string  `json:"token"`
+	Max      string  `json:"max"`
+}
+
+// PolicyRule allows or denies selectors on a target address or 32 byte id,
+// or on every target when Target is "*". Selectors are 4 byte hex values or function
+// signatures such as "transfer(address,uint256)".
+type PolicyRule struct {
+	Target string   `json:"target"`
+	Allow  []string `json:"allow,omitempty"`
+	Deny   []string `json:"deny,omitempty"`
+	// AllowDelegateCall permits delegatecalls to the target, which are
+	// otherwise rejected
+	AllowDelegateCall bool `json:"allowDelegatecall,omitempty"`
+}
+
+// Token declares an ERC-20 token so its amounts can be given and shown in
+// whole tokens rather than base units
+type Token struct {
+	Address  string `json:"address"`
+	Symbol   string `json:"symbol"`
+	Decimals int    `json:"decimals"`
+}
+
+// Token returns the declared token with an address or symbol
+func (c *Config) Token(name string) (Token, bool) {
+	for _, token := range c.Tokens {
+		if strings.EqualFold(token.Address, name) || strings.EqualFold(token.Symbol, name) {
+			return token, true
+		}
+	}
+	return Token{}, false
+}
+
+// BudgetCap resolves the token of a budget and converts its cap to base
+// units. The native currency is returned with an empty address.
+func (c *Config) BudgetCap(b Budget) (Token, *big.Int, error) {
+	token := Token{Symbol: NativeToken, Decimals: 18}
+	if !strings.EqualFold(b.Token, NativeToken) {
+		var ok bool
+		if token, ok = c.Token(b.Token); !ok {
+			return Token{}, nil, fmt.Errorf("token %q is neither %s nor declared in tokens", b.Token, NativeToken)
+		}
+	}
+	max, err := models.ParseUnits(b.Max, token.Decimals)
+	if err != nil {
+		return Token{}, nil, fmt.Errorf("invalid max: %w", err)
+	}
+	return token, max, nil
+}
+
+// Lint configures the batch lint rules
+type Lint struct {
+	Disabled []string `json:"disabled"`
+}
+
+// SignerSet declares the addresses allowed to sign roots and how many must sign
+type SignerSet struct {
+	Signers   []string `json:"signers"`
+	Threshold int      `json:"threshold"`
+}
+
+// Load reads and validates the config file at path
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+
+	return &cfg, nil
+}
+
+// Validate checks every section of the config
+func (c *Config) Validate() error {
+	if c.SignerSet != nil {
+		if _, err := c.SignerSet.Addresses(); err != nil {
+			return fmt.Errorf("signerSet: %w", err)
+		}
+	}
+	if c.Policy != nil {
+		if err := c.Policy.Validate(); err != nil {
+			return fmt.Errorf("policy: %w", err)
+		}
+	}
+	seen := make(map[string]bool)
+	for i, token := range c.Tokens {
+		if !common.IsHexAddress(token.Address) {
+			return fmt.Errorf("tokens[%d]: invalid address %q", i, token.Address)
+		}
+		if token.Symbol == "" || strings.ContainsAny(token.Symbol, " .") || strings.EqualFold(token.Symbol, NativeToken) {
+			return fmt.Errorf("tokens[%d]: invalid symbol %q", i, token.Symbol)
+		}
+		if token.Decimals < 0 || token.Decimals > 77 {
+			return fmt.Errorf("tokens[%d]: decimals must be between 0 and 77", i)
+		}
+		address, symbol := strings.ToLower(token.Address), strings.ToUpper(token.Symbol)
+		if seen[address] || seen[symbol] {
+			return fmt.Errorf("tokens[%d]: %s is declared twice", i, token.Symbol)
+		}
+		seen[address], seen[symbol] = true, true
+	}
+	if c.Policy != nil {
+		for i, budget := range c.Policy.Budgets {
+			if _, _, err := c.BudgetCap(budget); err != nil {
+				return fmt.Errorf("policy: budgets[%d]: %w", i, err)
+			}
+		}
+	}
+	for name := range c.Flags {
+		if _, _, err := c.FlagValue(name); err != nil {
+			return fmt.Errorf("flags: %w", err)
+		}
+	}
+	return nil
+}
+
+// FlagValue returns the default of a flag from the flags section in the form
+// accepted on the command line. Arrays are joined with commas.
+func (c *Config) FlagValue(name string) (string, bool, error) {
+	raw, ok := c.Flags[name]
+	if !ok {
+		return "", false, nil
+	}
+
+	var list []json.RawMessage
+	if err := json.Unmarshal(raw, &list); err == nil {
+		values := make([]string, 0, len(list))
+		for _, item := range list {
+			value, err := scalarFlagValue(item)
+			if err != nil {
+				return "", false, fmt.Errorf("%s: %w", name, err)
+			}
+			values = append(values, value)
+		}
+		return strings.Join(values, ","), true, nil
+	}
+
+	value, err := scalarFlagValue(raw)
+	if err != nil {
+		return "", false, fmt.Errorf("%s: %w", name, err)
+	}
+	return value, true, nil
+}
+
+// scalarFlagValue formats a JSON string, number or bool as a flag value
+func scalarFlagValue(raw json.RawMessage) (string, error) {
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return "", err
+	}
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	default:
+		return "", fmt.Errorf("value must be a string, number, bool or an array of them")
+	}
+}
+
+// Validate checks the policy version and rule targets
+func (p *Policy) Validate() error {
+	if p.Version != PolicyVersion {
+		return fmt.Errorf("unsupported policy version %d, expected %d", p.Version, PolicyVersion)
+	}
+	for i, rule := range p.Rules {
+		if rule.Target != "*" && !isRuleTarget(rule.Target) {
+			return fmt.Errorf("rules[%d]: invalid target %q", i, rule.Target)
+		}
+		if len(rule.Allow) == 0 && len(rule.Deny) == 0 && !rule.AllowDelegateCall {
+			return fmt.Errorf("rules[%d]: rule has neither allow nor deny selectors nor allowDelegatecall", i)
+		}
+	}
+	return nil
+}
+
+// isRuleTarget reports whether a rule target is an address or a 32 byte id
+func isRuleTarget(target string) bool {
+	if common.IsHexAddress(target) {
+		return true
+	}
+	id, err := hexutil.Decode(target)
+	return err == nil && len(id) == common.HashLength
+}
+
+// Addresses validates the signer set and returns its signer addresses
+func (s *SignerSet) Addresses() ([]common.Address, error) {
+	if len(s.Signers) == 0 {
+		return nil, fmt.Errorf("no signers declared")
+	}
+
+	seen := make(map[common.Address]bool)
+	addresses := make([]common.Address, 0, len(s.Signers))
+	for _, signer := range s.Signers {
+		if !common.IsHexAddress(signer) {
+			return nil, fmt.Errorf("invalid signer address %q", signer)
+		}
+		addr := common.HexToAddress(signer)
+		if seen[addr] {
+			return nil, fmt.Errorf("signer %s is declared twice", addr.Hex())
+		}
+		seen[addr] = true
+		addresses = append(addresses, addr)
+	}
+
+	if s.Threshold < 1 || s.Threshold > len(addresses) {
+		return nil, fmt.Errorf("threshold %d must be between 1 and the number of signers (%d)", s.Threshold, len(addresses))
+	}
+
+	return addresses, nil
+}
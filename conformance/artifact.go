@@ -0,0 +1,61 @@
+// Package conformance checks the leaf encoder against a reference OneSig
+// contract deployed on a local development node, so a release can be
+// certified to hash leaves exactly like the contract that verifies them.
+package conformance
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"merkle-cli/utils"
+)
+
+// LoadBytecode reads the creation bytecode of a contract from a Foundry or
+// Hardhat build artifact, or from a file holding only the bytecode as hex
+func LoadBytecode(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read artifact: %w", err)
+	}
+
+	text := strings.TrimSpace(string(data))
+	if !strings.HasPrefix(text, "{") {
+		return decodeBytecode(text, path)
+	}
+
+	// Foundry nests the bytecode in an object, Hardhat stores it as a string
+	var artifact struct {
+		Bytecode json.RawMessage `json:"bytecode"`
+	}
+	if err := json.Unmarshal(data, &artifact); err != nil {
+		return nil, fmt.Errorf("failed to parse artifact %s: %w", path, err)
+	}
+	var hardhat string
+	if err := json.Unmarshal(artifact.Bytecode, &hardhat); err == nil {
+		return decodeBytecode(hardhat, path)
+	}
+	var foundry struct {
+		Object string `json:"object"`
+	}
+	if err := json.Unmarshal(artifact.Bytecode, &foundry); err != nil || foundry.Object == "" {
+		return nil, fmt.Errorf("artifact %s has no bytecode", path)
+	}
+	return decodeBytecode(foundry.Object, path)
+}
+
+// decodeBytecode decodes hex bytecode, rejecting unlinked library placeholders
+func decodeBytecode(text string, path string) ([]byte, error) {
+	if strings.Contains(text, "__") {
+		return nil, fmt.Errorf("bytecode of %s has unlinked library references", path)
+	}
+	code, err := utils.ParseHex(text, "bytecode", false)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bytecode in %s: %w", path, err)
+	}
+	if len(code) == 0 {
+		return nil, fmt.Errorf("artifact %s has empty bytecode, it may be an interface or abstract contract", path)
+	}
+	return code, nil
+}
@@ -0,0 +1,110 @@
+package conformance_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"merkle-cli/conformance"
+	"merkle-cli/utils"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// cases is the number of leaves compared per leaf version
+const cases = 32
+
+// oneSigID is the OneSig ID the reference encoders are constructed with
+const oneSigID = 40161
+
+// constructorArgs are the ABI-encoded constructor arguments of the reference encoders
+var constructorArgs = hexutil.Encode(common.BigToHash(big.NewInt(oneSigID)).Bytes())
+
+// TestConformanceCommand runs the conformance command against the reference
+// leaf encoder of every supported leaf encoding version, deployed from
+// testdata on an in-process dev node
+func TestConformanceCommand(t *testing.T) {
+	bin := buildCLI(t)
+	url := startDevNode(t)
+
+	for _, version := range utils.SupportedLeafEncodingVersions() {
+		t.Run(fmt.Sprintf("v%d", version), func(t *testing.T) {
+			artifact := filepath.Join("testdata", fmt.Sprintf("leaf_encoder_v%d.hex", version))
+			if _, err := conformance.LoadBytecode(artifact); err != nil {
+				t.Fatalf("missing reference encoder, run go run leafencoder.go in testdata: %v", err)
+			}
+
+			out, err := exec.Command(bin, "conformance",
+				"--artifact", artifact,
+				"--constructor-args", constructorArgs,
+				"--leaf-version", fmt.Sprint(version),
+				"--cases", fmt.Sprint(cases),
+				"--rpc-url", url,
+			).CombinedOutput()
+			if err != nil {
+				t.Fatalf("conformance failed: %v\n%s", err, out)
+			}
+			for _, want := range []string{
+				fmt.Sprintf("with OneSig ID %d", oneSigID),
+				fmt.Sprintf("All %d leaves (leaf version %d,", cases, version),
+			} {
+				if !strings.Contains(string(out), want) {
+					t.Fatalf("conformance output lacks %q:\n%s", want, out)
+				}
+			}
+		})
+	}
+}
+
+// TestConformanceCommandMismatch checks that a contract packing another leaf
+// encoding version fails the command with the verification exit code
+func TestConformanceCommandMismatch(t *testing.T) {
+	bin := buildCLI(t)
+	code, err := conformance.LoadBytecode(filepath.Join("testdata", "leaf_encoder_v1.hex"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The encodeLeaf entry point starts with PUSH1 <version> PUSH1 0 MSTORE8
+	entry := []byte{0x5b, 0x60, utils.LeafEncodingVersion, 0x60, 0x00, 0x53}
+	if bytes.Count(code, entry) != 1 {
+		t.Fatal("reference encoder has no single encodeLeaf entry point")
+	}
+	tampered := bytes.Replace(code, entry, []byte{0x5b, 0x60, utils.LeafEncodingVersionWindowed, 0x60, 0x00, 0x53}, 1)
+	artifact := filepath.Join(t.TempDir(), "tampered.hex")
+	if err := os.WriteFile(artifact, []byte(hexutil.Encode(tampered)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := exec.Command(bin, "conformance",
+		"--artifact", artifact,
+		"--constructor-args", constructorArgs,
+		"--cases", "4",
+		"--rpc-url", startDevNode(t),
+	).CombinedOutput()
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 4 {
+		t.Fatalf("conformance against a tampered encoder: %v, want exit status 4\n%s", err, out)
+	}
+	if !strings.Contains(string(out), "4 of 4 leaves are hashed differently") {
+		t.Fatalf("conformance output lacks the mismatch count:\n%s", out)
+	}
+}
+
+// buildCLI builds merkle-cli into a temporary directory
+func buildCLI(t *testing.T) string {
+	bin := filepath.Join(t.TempDir(), "merkle-cli")
+	build := exec.Command("go", "build", "-o", bin, ".")
+	build.Dir = ".."
+	if out, err := build.CombinedOutput(); err != nil {
+		t.Fatalf("failed to build merkle-cli: %v\n%s", err, out)
+	}
+	return bin
+}
@@ -0,0 +1,254 @@
+package conformance_test
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// maxMemory bounds the memory a contract may use, as the interpreter charges no gas
+const maxMemory = 1 << 20
+
+// errReverted is returned when the executed code reverts
+var errReverted = errors.New("execution reverted")
+
+// wordModulus is 2^256, which arithmetic wraps at
+var wordModulus = new(big.Int).Lsh(big.NewInt(1), 256)
+
+// contract is the code and storage of an account of the dev node
+type contract struct {
+	code    []byte
+	storage map[common.Hash]common.Hash
+}
+
+// frame is one execution of code, interpreting the opcodes the reference
+// leaf encoders use; any other opcode fails the execution
+type frame struct {
+	contract *contract
+	code     []byte
+	address  common.Address
+	input    []byte
+	stack    []*big.Int
+	memory   []byte
+}
+
+// run executes code in the context of c at address with the given input and
+// returns the data it returns
+func run(c *contract, code []byte, address common.Address, input []byte) ([]byte, error) {
+	f := &frame{contract: c, code: code, address: address, input: input}
+	dests := jumpDests(code)
+
+	for pc := 0; pc < len(code); pc++ {
+		op := code[pc]
+		switch {
+		case op >= 0x60 && op <= 0x7f: // PUSH1..PUSH32
+			n := int(op-0x60) + 1
+			f.push(new(big.Int).SetBytes(padRight(code[pc+1:], n)))
+			pc += n
+			continue
+		case op >= 0x80 && op <= 0x8f: // DUP1..DUP16
+			n := int(op-0x80) + 1
+			if len(f.stack) < n {
+				return nil, fmt.Errorf("stack underflow at %d", pc)
+			}
+			f.push(new(big.Int).Set(f.stack[len(f.stack)-n]))
+			continue
+		case op >= 0x90 && op <= 0x9f: // SWAP1..SWAP16
+			n := int(op-0x90) + 1
+			if len(f.stack) <= n {
+				return nil, fmt.Errorf("stack underflow at %d", pc)
+			}
+			top := len(f.stack) - 1
+			f.stack[top], f.stack[top-n] = f.stack[top-n], f.stack[top]
+			continue
+		}
+
+		args, ok := opArgs[op]
+		if !ok {
+			return nil, fmt.Errorf("unsupported opcode 0x%02x at %d", op, pc)
+		}
+		if len(f.stack) < args {
+			return nil, fmt.Errorf("stack underflow at %d", pc)
+		}
+		in := make([]*big.Int, args)
+		for i := range in {
+			in[i] = f.stack[len(f.stack)-1-i]
+		}
+		f.stack = f.stack[:len(f.stack)-args]
+
+		switch op {
+		case 0x00: // STOP
+			return nil, nil
+		case 0x01: // ADD
+			f.push(wrap(new(big.Int).Add(in[0], in[1])))
+		case 0x03: // SUB
+			f.push(wrap(new(big.Int).Sub(in[0], in[1])))
+		case 0x10: // LT
+			f.push(boolWord(in[0].Cmp(in[1]) < 0))
+		case 0x14: // EQ
+			f.push(boolWord(in[0].Cmp(in[1]) == 0))
+		case 0x1b: // SHL
+			f.push(wrap(new(big.Int).Lsh(in[1], uint(min64(in[0], 256)))))
+		case 0x1c: // SHR
+			f.push(new(big.Int).Rsh(in[1], uint(min64(in[0], 256))))
+		case 0x20: // KECCAK256
+			data, err := f.read(in[0], in[1])
+			if err != nil {
+				return nil, err
+			}
+			f.push(new(big.Int).SetBytes(crypto.Keccak256(data)))
+		case 0x30: // ADDRESS
+			f.push(new(big.Int).SetBytes(f.address.Bytes()))
+		case 0x35: // CALLDATALOAD
+			f.push(new(big.Int).SetBytes(slice(f.input, in[0], big.NewInt(32))))
+		case 0x36: // CALLDATASIZE
+			f.push(big.NewInt(int64(len(f.input))))
+		case 0x37: // CALLDATACOPY
+			if err := f.write(in[0], slice(f.input, in[1], in[2])); err != nil {
+				return nil, err
+			}
+		case 0x38: // CODESIZE
+			f.push(big.NewInt(int64(len(f.code))))
+		case 0x39: // CODECOPY
+			if err := f.write(in[0], slice(f.code, in[1], in[2])); err != nil {
+				return nil, err
+			}
+		case 0x50: // POP
+		case 0x51: // MLOAD
+			data, err := f.read(in[0], big.NewInt(32))
+			if err != nil {
+				return nil, err
+			}
+			f.push(new(big.Int).SetBytes(data))
+		case 0x52: // MSTORE
+			if err := f.write(in[0], common.BigToHash(in[1]).Bytes()); err != nil {
+				return nil, err
+			}
+		case 0x53: // MSTORE8
+			if err := f.write(in[0], []byte{byte(in[1].Uint64())}); err != nil {
+				return nil, err
+			}
+		case 0x54: // SLOAD
+			f.push(f.contract.storage[common.BigToHash(in[0])].Big())
+		case 0x55: // SSTORE
+			f.contract.storage[common.BigToHash(in[0])] = common.BigToHash(in[1])
+		case 0x56, 0x57: // JUMP, JUMPI
+			if op == 0x57 && in[1].Sign() == 0 {
+				break
+			}
+			if !in[0].IsInt64() || !dests[int(in[0].Int64())] {
+				return nil, fmt.Errorf("invalid jump destination %s at %d", in[0], pc)
+			}
+			pc = int(in[0].Int64())
+		case 0x5b: // JUMPDEST
+		case 0xf3, 0xfd: // RETURN, REVERT
+			data, err := f.read(in[0], in[1])
+			if err != nil {
+				return nil, err
+			}
+			if op == 0xfd {
+				return data, errReverted
+			}
+			return data, nil
+		}
+	}
+	return nil, nil
+}
+
+// opArgs is the number of stack items each supported opcode takes
+var opArgs = map[byte]int{
+	0x00: 0, 0x01: 2, 0x03: 2, 0x10: 2, 0x14: 2, 0x1b: 2, 0x1c: 2, 0x20: 2,
+	0x30: 0, 0x35: 1, 0x36: 0, 0x37: 3, 0x38: 0, 0x39: 3,
+	0x50: 1, 0x51: 1, 0x52: 2, 0x53: 2, 0x54: 1, 0x55: 2, 0x56: 1, 0x57: 2, 0x5b: 0,
+	0xf3: 2, 0xfd: 2,
+}
+
+func (f *frame) push(v *big.Int) {
+	f.stack = append(f.stack, v)
+}
+
+// grow extends memory to cover size bytes from offset
+func (f *frame) grow(offset, size *big.Int) (int, int, error) {
+	if size.Sign() == 0 {
+		return 0, 0, nil
+	}
+	end := new(big.Int).Add(offset, size)
+	if !end.IsInt64() || end.Int64() > maxMemory {
+		return 0, 0, fmt.Errorf("memory access beyond %d bytes", maxMemory)
+	}
+	if n := int((end.Int64() + 31) / 32 * 32); n > len(f.memory) {
+		f.memory = append(f.memory, make([]byte, n-len(f.memory))...)
+	}
+	return int(offset.Int64()), int(size.Int64()), nil
+}
+
+func (f *frame) read(offset, size *big.Int) ([]byte, error) {
+	o, n, err := f.grow(offset, size)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{}, f.memory[o:o+n]...), nil
+}
+
+func (f *frame) write(offset *big.Int, data []byte) error {
+	o, n, err := f.grow(offset, big.NewInt(int64(len(data))))
+	if err != nil {
+		return err
+	}
+	copy(f.memory[o:o+n], data)
+	return nil
+}
+
+// jumpDests returns the JUMPDEST positions of code outside push data
+func jumpDests(code []byte) map[int]bool {
+	dests := map[int]bool{}
+	for pc := 0; pc < len(code); pc++ {
+		switch op := code[pc]; {
+		case op == 0x5b:
+			dests[pc] = true
+		case op >= 0x60 && op <= 0x7f:
+			pc += int(op - 0x5f)
+		}
+	}
+	return dests
+}
+
+// slice returns size bytes of data from offset, zero-padded past its end
+func slice(data []byte, offset, size *big.Int) []byte {
+	if !size.IsInt64() || size.Int64() > maxMemory {
+		return make([]byte, maxMemory+1)
+	}
+	out := make([]byte, size.Int64())
+	if offset.IsInt64() && offset.Int64() < int64(len(data)) {
+		copy(out, data[offset.Int64():])
+	}
+	return out
+}
+
+// padRight returns the first n bytes of data, zero-padded past its end
+func padRight(data []byte, n int) []byte {
+	out := make([]byte, n)
+	copy(out, data)
+	return out
+}
+
+func wrap(v *big.Int) *big.Int {
+	return v.Mod(v, wordModulus)
+}
+
+func boolWord(b bool) *big.Int {
+	if b {
+		return big.NewInt(1)
+	}
+	return new(big.Int)
+}
+
+func min64(v *big.Int, limit int64) int64 {
+	if !v.IsInt64() || v.Int64() > limit {
+		return limit
+	}
+	return v.Int64()
+}
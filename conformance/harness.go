@@ -0,0 +1,116 @@
+package conformance
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	"merkle-cli/chain"
+	"merkle-cli/models"
+	"merkle-cli/simulation"
+	"merkle-cli/utils"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// deployTimeout bounds how long the deployment of the reference contract may take
+const deployTimeout = time.Minute
+
+// Harness is a reference OneSig contract deployed on a development node
+type Harness struct {
+	client   *chain.Client
+	prefix   string
+	deployer common.Address
+
+	// Contract is the address of the deployed contract and OneSigID the ID it
+	// was constructed with; every checked leaf is built for them
+	Contract common.Address
+	OneSigID uint64
+}
+
+// Mismatch is a leaf the contract hashes differently from the encoder
+type Mismatch struct {
+	Nonce    uint64 `json:"nonce"`
+	Expected string `json:"expected"`
+	Contract string `json:"contract"`
+}
+
+// Deploy deploys the creation bytecode followed by its ABI-encoded constructor
+// arguments from the first unlocked account of the node of the given kind at url
+func Deploy(kind string, url string, code []byte, constructorArgs []byte) (*Harness, error) {
+	client := chain.NewClient(url)
+	accounts, err := client.Accounts()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list node accounts: %w", err)
+	}
+	if len(accounts) == 0 {
+		return nil, fmt.Errorf("node at %s has no unlocked account to deploy from", url)
+	}
+
+	creation := append(append([]byte{}, code...), constructorArgs...)
+	hash, err := client.Deploy(accounts[0], creation)
+	if err != nil {
+		return nil, fmt.Errorf("failed to deploy reference contract: %w", err)
+	}
+	receipt, err := client.WaitForReceipt(hash, 1, deployTimeout)
+	if err != nil {
+		return nil, err
+	}
+	if !receipt.Succeeded() || receipt.ContractAddress == nil {
+		return nil, fmt.Errorf("deployment of the reference contract reverted (transaction %s), check the constructor arguments", hash.Hex())
+	}
+
+	h := &Harness{client: client, prefix: simulation.MethodPrefix(kind), deployer: accounts[0], Contract: *receipt.ContractAddress}
+	if h.OneSigID, err = client.OneSigID(h.Contract); err != nil {
+		return nil, fmt.Errorf("failed to read the OneSig ID of the reference contract: %w", err)
+	}
+	return h, nil
+}
+
+// EncodeLeaf hashes a leaf with the encodeLeaf view of the contract
+func (h *Harness) EncodeLeaf(leaf models.Leaf, version byte) ([]byte, error) {
+	calldata, err := utils.EncodeLeafCall(leaf, version)
+	if err != nil {
+		return nil, err
+	}
+	result, err := h.client.CallContract(chain.CallMsg{From: h.deployer, To: h.Contract, Data: calldata})
+	if err != nil {
+		return nil, fmt.Errorf("encodeLeaf reverted for nonce %d: %w", leaf.Nonce, err)
+	}
+	if len(result) != 32 {
+		return nil, fmt.Errorf("encodeLeaf returned %d bytes for nonce %d", len(result), leaf.Nonce)
+	}
+	return result, nil
+}
+
+// CheckBatch builds the leaves of a batch for the deployed contract and
+// compares the hash of each computed by the encoder and by the contract
+func (h *Harness) CheckBatch(ctx context.Context, batch *models.TransactionBatch, version byte) ([]Mismatch, error) {
+	var mismatches []Mismatch
+	for _, group := range batch.Groups {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		leaf := group.Leaf(h.OneSigID, h.Contract.Hex())
+		leaf.TargetFormat = batch.TargetFormat
+
+		expected, err := utils.EncodeLeafVersion(leaf, version)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode nonce %d: %w", leaf.Nonce, err)
+		}
+		got, err := h.EncodeLeaf(leaf, version)
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(expected, got) {
+			mismatches = append(mismatches, Mismatch{
+				Nonce:    leaf.Nonce,
+				Expected: hexutil.Encode(expected),
+				Contract: hexutil.Encode(got),
+			})
+		}
+	}
+	return mismatches, nil
+}
@@ -0,0 +1,141 @@
+package conformance_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// devAccount is the unlocked account of the dev node
+var devAccount = common.HexToAddress("0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266")
+
+// devNode is an in-process development node serving the JSON-RPC methods the
+// conformance command uses to deploy a contract and call its views. Each
+// transaction is mined in its own block.
+type devNode struct {
+	mu        sync.Mutex
+	nonce     uint64
+	contracts map[common.Address]*contract
+	receipts  map[common.Hash]map[string]interface{}
+}
+
+// rpcMessage is the transaction or call of an eth_sendTransaction or eth_call request
+type rpcMessage struct {
+	From common.Address  `json:"from"`
+	To   *common.Address `json:"to"`
+	Data hexutil.Bytes   `json:"data"`
+}
+
+// startDevNode serves a dev node until the test ends and returns its URL
+func startDevNode(t *testing.T) string {
+	node := &devNode{contracts: map[common.Address]*contract{}, receipts: map[common.Hash]map[string]interface{}{}}
+	server := httptest.NewServer(http.HandlerFunc(node.serve))
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+func (n *devNode) serve(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID     json.RawMessage   `json:"id"`
+		Method string            `json:"method"`
+		Params []json.RawMessage `json:"params"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	n.mu.Lock()
+	result, err := n.handle(req.Method, req.Params)
+	n.mu.Unlock()
+
+	resp := map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": result}
+	if err != nil {
+		resp = map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "error": map[string]interface{}{"code": 3, "message": err.Error()}}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (n *devNode) handle(method string, params []json.RawMessage) (interface{}, error) {
+	switch method {
+	case "eth_accounts":
+		return []common.Address{devAccount}, nil
+	case "eth_blockNumber":
+		return hexutil.Uint64(n.nonce), nil
+	case "eth_sendTransaction":
+		msg, err := message(params)
+		if err != nil {
+			return nil, err
+		}
+		return n.deploy(msg)
+	case "eth_getTransactionReceipt":
+		var hash common.Hash
+		if len(params) == 0 || json.Unmarshal(params[0], &hash) != nil {
+			return nil, errors.New("invalid transaction hash")
+		}
+		return n.receipts[hash], nil
+	case "eth_call":
+		msg, err := message(params)
+		if err != nil {
+			return nil, err
+		}
+		if msg.To == nil {
+			return nil, errors.New("eth_call needs a target")
+		}
+		c, ok := n.contracts[*msg.To]
+		if !ok {
+			return hexutil.Bytes{}, nil
+		}
+		out, err := run(c, c.code, *msg.To, msg.Data)
+		return hexutil.Bytes(out), err
+	}
+	return nil, fmt.Errorf("method %s is not supported", method)
+}
+
+// deploy mines a contract creation, recording a failed receipt if it reverts
+func (n *devNode) deploy(msg rpcMessage) (common.Hash, error) {
+	if msg.To != nil {
+		return common.Hash{}, errors.New("only contract creations are supported")
+	}
+	address := crypto.CreateAddress(msg.From, n.nonce)
+	hash := crypto.Keccak256Hash(address.Bytes())
+	n.nonce++
+
+	c := &contract{storage: map[common.Hash]common.Hash{}}
+	receipt := map[string]interface{}{
+		"transactionHash": hash,
+		"blockNumber":     hexutil.Uint64(n.nonce),
+		"status":          hexutil.Uint64(0),
+		"gasUsed":         hexutil.Uint64(0),
+		"logs":            []interface{}{},
+		"contractAddress": nil,
+	}
+	if code, err := run(c, msg.Data, address, nil); err == nil {
+		c.code = code
+		n.contracts[address] = c
+		receipt["status"] = hexutil.Uint64(1)
+		receipt["contractAddress"] = address
+	}
+	n.receipts[hash] = receipt
+	return hash, nil
+}
+
+func message(params []json.RawMessage) (rpcMessage, error) {
+	var msg rpcMessage
+	if len(params) == 0 {
+		return msg, errors.New("missing transaction")
+	}
+	if err := json.Unmarshal(params[0], &msg); err != nil {
+		return msg, fmt.Errorf("invalid transaction: %w", err)
+	}
+	return msg, nil
+}
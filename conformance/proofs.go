@@ -0,0 +1,160 @@
+package conformance
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"time"
+
+	"merkle-cli/chain"
+	"merkle-cli/merkle"
+	"merkle-cli/models"
+	"merkle-cli/signer"
+	"merkle-cli/utils"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// executeTimeout bounds how long a transaction sent to the contract may take to be mined
+const executeTimeout = time.Minute
+
+// contractBalance funds the contract for the values of the executed calls
+var contractBalance = new(big.Int).Lsh(big.NewInt(1), 128)
+
+// ProofFailure is a leaf whose proof the contract did not handle as expected
+type ProofFailure struct {
+	Nonce  uint64 `json:"nonce"`
+	Reason string `json:"reason"`
+}
+
+// DigestFunc returns the EIP-712 digest signers sign to approve a root for
+// the seed of the contract
+type DigestFunc func(root []byte, seed []byte) ([]byte, error)
+
+// VerifyProofs builds the tree of the batch for the deployed contract, sets
+// its root, bound to expiry unless it is 0, with signatures of signers over
+// digest, which must be the contract's signers, and executes every leaf with its proof in nonce order from the deploying
+// account. Before each execution, the leaf is also submitted with a corrupted
+// proof, which the contract must reject. Validity windows are opened to every
+// timestamp, as the leaves are executed at once.
+func (h *Harness) VerifyProofs(ctx context.Context, batch *models.TransactionBatch, version byte, signers []signer.Signer, digest DigestFunc, expiry uint64) ([]byte, []ProofFailure, error) {
+	open := *batch
+	open.Groups = make([]models.TransactionGroup, len(batch.Groups))
+	for i, group := range batch.Groups {
+		if version >= utils.LeafEncodingVersionWindowed {
+			group.ValidAfter, group.ValidUntil = 0, math.MaxUint64
+		}
+		open.Groups[i] = group
+	}
+
+	options := merkle.DefaultTreeOptions()
+	result, err := merkle.NewMerkleModule(merkle.Params{
+		OneSigID:     h.OneSigID,
+		ContractAddr: h.Contract.Hex(),
+		LeafVersion:  version,
+		Options:      &options,
+		Limits:       utils.DefaultLimits,
+	}).Generate(ctx, &open)
+	if err != nil {
+		return nil, nil, err
+	}
+	root := result.Tree.Root
+
+	if err := h.setRoot(root, signers, digest, expiry); err != nil {
+		return nil, nil, err
+	}
+	if err := h.client.Call(nil, h.prefix+"_setBalance", h.Contract, (*hexutil.Big)(contractBalance)); err != nil {
+		return nil, nil, fmt.Errorf("failed to fund the reference contract: %w", err)
+	}
+
+	var failures []ProofFailure
+	for _, entry := range result.Entries {
+		if err := ctx.Err(); err != nil {
+			return nil, nil, err
+		}
+		proof := make([]string, len(entry.Proof))
+		for i, node := range entry.Proof {
+			proof[i] = hexutil.Encode(node)
+		}
+		proofEntry := models.ProofEntry{Leaf: entry.Leaf, LeafIndex: entry.Index, LeafHash: hexutil.Encode(entry.Hash), Proof: proof}
+
+		if len(entry.Proof) > 0 {
+			corrupted := proofEntry
+			corrupted.Proof = append([]string{}, proof...)
+			node := append([]byte{}, entry.Proof[0]...)
+			node[len(node)-1] ^= 1
+			corrupted.Proof[0] = hexutil.Encode(node)
+			calldata, err := utils.EncodeExecute(corrupted, version)
+			if err != nil {
+				return nil, nil, err
+			}
+			if _, err := h.client.CallContract(chain.CallMsg{From: h.deployer, To: h.Contract, Data: calldata}); err == nil {
+				failures = append(failures, ProofFailure{Nonce: entry.Leaf.Nonce, Reason: "accepted a corrupted proof"})
+			}
+		}
+
+		calldata, err := utils.EncodeExecute(proofEntry, version)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := h.send(calldata); err != nil {
+			failures = append(failures, ProofFailure{Nonce: entry.Leaf.Nonce, Reason: err.Error()})
+		}
+	}
+	return root, failures, nil
+}
+
+// setRoot signs the digest of the root for the seed of the contract with
+// every signer and submits it with setRoot
+func (h *Harness) setRoot(root []byte, signers []signer.Signer, rootDigest DigestFunc, expiry uint64) error {
+	seed, err := h.client.OneSigSeed(h.Contract)
+	if err != nil {
+		return fmt.Errorf("failed to read the seed of the reference contract: %w", err)
+	}
+	digest, err := rootDigest(root, seed.Bytes())
+	if err != nil {
+		return err
+	}
+
+	signatures := make([]signer.RecoveredSignature, 0, len(signers))
+	for _, s := range signers {
+		sig, err := s.SignDigest(digest)
+		if err != nil {
+			return err
+		}
+		signatures = append(signatures, signer.RecoveredSignature{Signer: s.Address(), Signature: sig})
+	}
+	// The contract requires signatures ordered by ascending signer address
+	sort.Slice(signatures, func(i, j int) bool {
+		return bytes.Compare(signatures[i].Signer.Bytes(), signatures[j].Signer.Bytes()) < 0
+	})
+
+	calldata, err := utils.EncodeRootSubmission("setRoot", root, expiry, signer.Concat(signatures))
+	if err != nil {
+		return err
+	}
+	if err := h.send(calldata); err != nil {
+		return fmt.Errorf("failed to set the root on the reference contract, check that the signer keys are its signers: %w", err)
+	}
+	return nil
+}
+
+// send sends a transaction to the contract from the deploying account and
+// fails if it reverts
+func (h *Harness) send(calldata []byte) error {
+	hash, err := h.client.SendTransaction(chain.CallMsg{From: h.deployer, To: h.Contract, Data: calldata})
+	if err != nil {
+		return err
+	}
+	receipt, err := h.client.WaitForReceipt(hash, 1, executeTimeout)
+	if err != nil {
+		return err
+	}
+	if !receipt.Succeeded() {
+		return fmt.Errorf("transaction %s reverted", hash.Hex())
+	}
+	return nil
+}
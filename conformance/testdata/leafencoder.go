@@ -0,0 +1,265 @@
+//go:build ignore
+
+// Command leafencoder assembles the reference leaf encoders the conformance
+// test deploys, writing the creation bytecode of each leaf encoding version to
+// leaf_encoder_v<version>.hex. Run it from this directory with
+//
+//	go run leafencoder.go
+//
+// The encoders are hand-assembled, so they are regenerated without a Solidity
+// compiler. Each stores the OneSig ID it is constructed with and
+// answers ONE_SIG_ID() and encodeLeaf(...) for address and bytes32 targets:
+//
+//	keccak256(keccak256(abi.encodePacked(
+//		LEAF_ENCODING_VERSION, ONE_SIG_ID, bytes32(uint256(uint160(address(this)))),
+//		_nonce, [_validAfter, _validUntil], [_salt], abi.encode(_calls))))
+//
+// abi.encode(_calls) is the 0x20 offset word followed by the calls copied from
+// calldata, which equals re-encoding them for canonical calldata.
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// The opcodes the encoders use
+const (
+	opAdd          = 0x01
+	opSub          = 0x03
+	opLt           = 0x10
+	opEq           = 0x14
+	opShl          = 0x1b
+	opShr          = 0x1c
+	opKeccak256    = 0x20
+	opAddress      = 0x30
+	opCalldataload = 0x35
+	opCalldatasize = 0x36
+	opCalldatacopy = 0x37
+	opCodesize     = 0x38
+	opCodecopy     = 0x39
+	opMload        = 0x51
+	opMstore       = 0x52
+	opMstore8      = 0x53
+	opSload        = 0x54
+	opSstore       = 0x55
+	opJumpi        = 0x57
+	opJumpdest     = 0x5b
+	opPush1        = 0x60
+	opPush2        = 0x61
+	opPush4        = 0x63
+	opDup1         = 0x80
+	opSwap2        = 0x91
+	opReturn       = 0xf3
+	opRevert       = 0xfd
+)
+
+// encoder describes the encodeLeaf signature of a leaf encoding version
+type encoder struct {
+	version byte
+	// fields are the ABI types of the arguments between the nonce and the calls
+	fields string
+	// call is the tuple type of a call with an address target, after the target
+	call string
+	// packed are the packed sizes of the nonce and the fields
+	packed []int
+}
+
+var encoders = []encoder{
+	{1, "", "uint256,bytes", []int{8}},
+	{2, "uint64,uint64,", "uint256,bytes", []int{8, 8, 8}},
+	{3, "uint64,uint64,", "uint256,bytes,uint8", []int{8, 8, 8}},
+	{4, "uint64,uint64,bytes32,", "uint256,bytes,uint8", []int{8, 8, 8, 32}},
+}
+
+// assembler emits bytecode with two byte jump labels patched at the end
+type assembler struct {
+	code   []byte
+	labels map[string]int
+	refs   map[int]string
+}
+
+func newAssembler() *assembler {
+	return &assembler{labels: map[string]int{}, refs: map[int]string{}}
+}
+
+func (a *assembler) op(ops ...byte) {
+	a.code = append(a.code, ops...)
+}
+
+// push emits the smallest PUSH of n
+func (a *assembler) push(n int) {
+	if n < 0x100 {
+		a.op(opPush1, byte(n))
+		return
+	}
+	a.op(opPush2, byte(n>>8), byte(n))
+}
+
+func (a *assembler) pushLabel(label string) {
+	a.refs[len(a.code)+1] = label
+	a.op(opPush2, 0, 0)
+}
+
+func (a *assembler) label(label string) {
+	a.labels[label] = len(a.code)
+	a.op(opJumpdest)
+}
+
+func (a *assembler) bytes() []byte {
+	for at, label := range a.refs {
+		pc, ok := a.labels[label]
+		if !ok {
+			panic("undefined label " + label)
+		}
+		a.code[at], a.code[at+1] = byte(pc>>8), byte(pc)
+	}
+	return a.code
+}
+
+// selector returns the four byte selector of a function signature
+func selector(signature string) []byte {
+	return crypto.Keccak256([]byte(signature))[:4]
+}
+
+// runtime assembles the deployed code of an encoder
+func runtime(e encoder) []byte {
+	a := newAssembler()
+
+	// Dispatch on the selector
+	a.push(0)
+	a.op(opCalldataload)
+	a.push(0xe0)
+	a.op(opShr)
+	for _, target := range []string{"address", "bytes32"} {
+		a.op(opDup1, opPush4)
+		a.op(selector(fmt.Sprintf("encodeLeaf(uint64,%s(%s,%s)[])", e.fields, target, e.call))...)
+		a.op(opEq)
+		a.pushLabel("encodeLeaf")
+		a.op(opJumpi)
+	}
+	a.op(opPush4)
+	a.op(selector("ONE_SIG_ID()")...)
+	a.op(opEq)
+	a.pushLabel("oneSigID")
+	a.op(opJumpi)
+	a.label("revert")
+	a.push(0)
+	a.op(opDup1, opRevert)
+
+	// ONE_SIG_ID() returns storage slot 0
+	a.label("oneSigID")
+	a.push(0)
+	a.op(opSload)
+	a.push(0)
+	a.op(opMstore)
+	a.push(32)
+	a.push(0)
+	a.op(opReturn)
+
+	// encodeLeaf packs the leaf from memory offset 0
+	a.label("encodeLeaf")
+	a.push(int(e.version))
+	a.push(0)
+	a.op(opMstore8)
+	a.push(0)
+	a.op(opSload)
+	a.push(192)
+	a.op(opShl)
+	a.push(1)
+	a.op(opMstore)
+	a.op(opAddress)
+	a.push(9)
+	a.op(opMstore)
+
+	// The nonce and window are packed as eight bytes, the salt as a word
+	offset, arg := 41, 4
+	for _, size := range e.packed {
+		a.push(arg)
+		a.op(opCalldataload)
+		if size == 8 {
+			a.push(192)
+			a.op(opShl)
+		}
+		a.push(offset)
+		a.op(opMstore)
+		offset += size
+		arg += 32
+	}
+
+	// abi.encode(_calls): the offset word, then the calls from calldata
+	a.push(32)
+	a.push(offset)
+	a.op(opMstore)
+	a.push(arg)
+	a.op(opCalldataload)
+	a.push(4)
+	a.op(opAdd)
+	a.op(opDup1, opCalldatasize, opLt)
+	a.pushLabel("revert")
+	a.op(opJumpi)
+	a.op(opDup1, opCalldatasize, opSub)
+	a.op(opDup1, opSwap2)
+	a.push(offset + 32)
+	a.op(opCalldatacopy)
+	a.push(offset + 32)
+	a.op(opAdd)
+
+	// keccak256(keccak256(packed))
+	a.push(0)
+	a.op(opKeccak256)
+	a.push(0)
+	a.op(opMstore)
+	a.push(32)
+	a.push(0)
+	a.op(opKeccak256)
+	a.push(0)
+	a.op(opMstore)
+	a.push(32)
+	a.push(0)
+	a.op(opReturn)
+
+	return a.bytes()
+}
+
+// creation assembles the constructor, which stores the OneSig ID appended to
+// the code as a word and returns the runtime code
+func creation(runtime []byte) []byte {
+	a := newAssembler()
+	a.push(32)
+	a.push(32)
+	a.op(opCodesize, opSub)
+	a.push(0)
+	a.op(opCodecopy)
+	a.push(0)
+	a.op(opMload)
+	a.push(0)
+	a.op(opSstore)
+
+	// Copy the runtime, which follows the constructor, and return it
+	a.op(opPush2, byte(len(runtime)>>8), byte(len(runtime)))
+	a.op(opDup1, opPush2)
+	at := len(a.code)
+	a.op(0, 0)
+	a.push(0)
+	a.op(opCodecopy)
+	a.push(0)
+	a.op(opReturn)
+	a.code[at], a.code[at+1] = byte(len(a.code)>>8), byte(len(a.code))
+	return append(a.bytes(), runtime...)
+}
+
+func main() {
+	for _, e := range encoders {
+		code := creation(runtime(e))
+		name := fmt.Sprintf("leaf_encoder_v%d.hex", e.version)
+		if err := os.WriteFile(name, []byte("0x"+hex.EncodeToString(code)+"\n"), 0o644); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Printf("Wrote %s (%d bytes)\n", name, len(code))
+	}
+}
@@ -0,0 +1,94 @@
+package encryption
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+var (
+	ageBinaryHeader = []byte("age-encryption.org/v1")
+	ageArmorHeader  = []byte("-----BEGIN AGE ENCRYPTED FILE-----")
+	pgpArmorHeader  = []byte("-----BEGIN PGP MESSAGE-----")
+)
+
+// Options selects the recipients that output files are encrypted to
+type Options struct {
+	AgeRecipients []string
+	GPGRecipients []string
+}
+
+// Enabled reports whether any recipient was configured
+func (o Options) Enabled() bool {
+	return len(o.AgeRecipients) > 0 || len(o.GPGRecipients) > 0
+}
+
+// Validate rejects mixing age and gpg recipients
+func (o Options) Validate() error {
+	if len(o.AgeRecipients) > 0 && len(o.GPGRecipients) > 0 {
+		return fmt.Errorf("age and gpg recipients cannot be combined")
+	}
+	return nil
+}
+
+// Encrypt encrypts data to the configured recipients with the age or gpg binary
+func Encrypt(data []byte, opts Options) ([]byte, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	if len(opts.AgeRecipients) > 0 {
+		args := []string{"--encrypt", "--armor"}
+		for _, r := range opts.AgeRecipients {
+			args = append(args, "--recipient", r)
+		}
+		return run(data, "age", args...)
+	}
+
+	args := []string{"--batch", "--yes", "--armor", "--encrypt", "--trust-model", "always"}
+	for _, r := range opts.GPGRecipients {
+		args = append(args, "--recipient", r)
+	}
+	return run(data, "gpg", args...)
+}
+
+// Decrypt decrypts age or gpg encrypted data. identity is the age identity file;
+// gpg uses the keys in its keyring.
+func Decrypt(data []byte, identity string) ([]byte, error) {
+	switch {
+	case isAge(data):
+		if identity == "" {
+			return nil, fmt.Errorf("an age identity file is required to decrypt")
+		}
+		return run(data, "age", "--decrypt", "--identity", identity)
+	case IsEncrypted(data):
+		return run(data, "gpg", "--batch", "--quiet", "--decrypt")
+	default:
+		return nil, fmt.Errorf("data is not age or gpg encrypted")
+	}
+}
+
+// IsEncrypted reports whether data looks like an age file or an armored PGP message
+func IsEncrypted(data []byte) bool {
+	return isAge(data) || bytes.HasPrefix(bytes.TrimSpace(data), pgpArmorHeader)
+}
+
+func isAge(data []byte) bool {
+	data = bytes.TrimSpace(data)
+	return bytes.HasPrefix(data, ageBinaryHeader) || bytes.HasPrefix(data, ageArmorHeader)
+}
+
+// run pipes data through a command and returns its standard output
+func run(data []byte, name string, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	command := exec.Command(name, args...)
+	command.Stdin = bytes.NewReader(data)
+	command.Stdout = &stdout
+	command.Stderr = &stderr
+
+	if err := command.Run(); err != nil {
+		return nil, fmt.Errorf("%s failed: %w: %s", name, err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
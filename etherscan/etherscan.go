@@ -0,0 +1,277 @@
+// Package etherscan looks up the verified source metadata of contracts on
+// Etherscan-compatible explorer APIs, caching verified contracts on disk.
+package etherscan
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// DefaultURL is the Etherscan multichain API endpoint
+const DefaultURL = "https://api.etherscan.io/v2/api"
+
+// Contract is the verified source metadata of a contract
+type Contract struct {
+	Address  string          `json:"address"`
+	Verified bool            `json:"verified"`
+	Name     string          `json:"name,omitempty"`
+	ABI      json.RawMessage `json:"abi,omitempty"`
+	// Implementation is the implementation of a proxy, as detected by the explorer
+	Implementation string `json:"implementation,omitempty"`
+}
+
+// Client queries an Etherscan-compatible API. Verified contracts are cached
+// under CacheDir and never fetched again; in Offline mode only the cache is
+// used. A Client is safe for concurrent use.
+type Client struct {
+	URL      string
+	APIKey   string
+	ChainID  uint64
+	CacheDir string
+	Offline  bool
+
+	mu         sync.Mutex
+	contracts  map[common.Address]*Contract
+	httpClient *http.Client
+}
+
+// sourceCodeResponse is the response of the getsourcecode action
+type sourceCodeResponse struct {
+	Status  string          `json:"status"`
+	Message string          `json:"message"`
+	Result  json.RawMessage `json:"result"`
+}
+
+// sourceCode is an entry of a getsourcecode result
+type sourceCode struct {
+	ABI            string `json:"ABI"`
+	ContractName   string `json:"ContractName"`
+	Proxy          string `json:"Proxy"`
+	Implementation string `json:"Implementation"`
+}
+
+// Lookup returns the metadata of the contract at address. In offline mode a
+// contract missing from the cache is returned as nil.
+func (c *Client) Lookup(ctx context.Context, address common.Address) (*Contract, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if contract, ok := c.contracts[address]; ok {
+		return contract, nil
+	}
+
+	contract, err := c.readCache(address)
+	if err != nil {
+		return nil, err
+	}
+	if contract == nil && !c.Offline {
+		if contract, err = c.fetch(ctx, address); err != nil {
+			return nil, fmt.Errorf("failed to look up %s on the explorer: %w", address.Hex(), err)
+		}
+		if contract.Verified {
+			if err := c.writeCache(contract); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if c.contracts == nil {
+		c.contracts = make(map[common.Address]*Contract)
+	}
+	c.contracts[address] = contract
+	return contract, nil
+}
+
+// cachePath returns the cache file of a contract
+func (c *Client) cachePath(address common.Address) string {
+	return filepath.Join(c.CacheDir, strconv.FormatUint(c.ChainID, 10), strings.ToLower(address.Hex())+".json")
+}
+
+// readCache reads a cached contract, returning nil if it is not cached
+func (c *Client) readCache(address common.Address) (*Contract, error) {
+	if c.CacheDir == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(c.cachePath(address))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read explorer cache: %w", err)
+	}
+	var contract Contract
+	if err := json.Unmarshal(data, &contract); err != nil {
+		return nil, fmt.Errorf("failed to parse explorer cache %s: %w", c.cachePath(address), err)
+	}
+	return &contract, nil
+}
+
+// writeCache stores a contract in the cache
+func (c *Client) writeCache(contract *Contract) error {
+	if c.CacheDir == "" {
+		return nil
+	}
+	path := c.cachePath(common.HexToAddress(contract.Address))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create explorer cache: %w", err)
+	}
+	data, err := json.MarshalIndent(contract, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write explorer cache: %w", err)
+	}
+	return nil
+}
+
+// fetch queries the getsourcecode action of the API
+func (c *Client) fetch(ctx context.Context, address common.Address) (*Contract, error) {
+	query := url.Values{}
+	query.Set("module", "contract")
+	query.Set("action", "getsourcecode")
+	query.Set("address", address.Hex())
+	if c.ChainID != 0 {
+		query.Set("chainid", strconv.FormatUint(c.ChainID, 10))
+	}
+	if c.APIKey != "" {
+		query.Set("apikey", c.APIKey)
+	}
+	endpoint := c.URL
+	if endpoint == "" {
+		endpoint = DefaultURL
+	}
+	sep := "?"
+	if strings.Contains(endpoint, "?") {
+		sep = "&"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+sep+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.httpClient == nil {
+		c.httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 32<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("explorer API returned %s", resp.Status)
+	}
+
+	var response sourceCodeResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("invalid explorer API response: %w", err)
+	}
+	var results []sourceCode
+	if response.Status != "1" || json.Unmarshal(response.Result, &results) != nil || len(results) == 0 {
+		var reason string
+		if json.Unmarshal(response.Result, &reason) != nil || reason == "" {
+			reason = response.Message
+		}
+		return nil, fmt.Errorf("explorer API error: %s", reason)
+	}
+
+	result := results[0]
+	contract := &Contract{Address: address.Hex()}
+	// Unverified contracts carry a message instead of an ABI
+	if strings.HasPrefix(strings.TrimSpace(result.ABI), "[") {
+		contract.Verified = true
+		contract.Name = result.ContractName
+		contract.ABI = json.RawMessage(result.ABI)
+		if result.Proxy == "1" && common.IsHexAddress(result.Implementation) {
+			contract.Implementation = common.HexToAddress(result.Implementation).Hex()
+		}
+	}
+	return contract, nil
+}
+
+// abiEntry is an entry of a contract ABI
+type abiEntry struct {
+	Type   string     `json:"type"`
+	Name   string     `json:"name"`
+	Inputs []abiParam `json:"inputs"`
+}
+
+// abiParam is a parameter of an ABI entry
+type abiParam struct {
+	Type       string     `json:"type"`
+	Components []abiParam `json:"components"`
+}
+
+// Signatures returns the canonical signatures of the functions in the
+// contract's ABI, such as "transfer(address,uint256)"
+func (c *Contract) Signatures() ([]string, error) {
+	if !c.Verified {
+		return nil, nil
+	}
+	var entries []abiEntry
+	if err := json.Unmarshal(c.ABI, &entries); err != nil {
+		return nil, fmt.Errorf("invalid ABI of %s: %w", c.Address, err)
+	}
+	var signatures []string
+	for _, entry := range entries {
+		if entry.Type == "function" {
+			signatures = append(signatures, entry.Name+"("+canonicalTypes(entry.Inputs)+")")
+		}
+	}
+	return signatures, nil
+}
+
+// canonicalTypes joins parameter types, expanding tuples into their components
+func canonicalTypes(params []abiParam) string {
+	types := make([]string, len(params))
+	for i, p := range params {
+		types[i] = p.Type
+		if suffix, ok := strings.CutPrefix(p.Type, "tuple"); ok {
+			types[i] = "(" + canonicalTypes(p.Components) + ")" + suffix
+		}
+	}
+	return strings.Join(types, ",")
+}
+
+// Functions returns the contract at address and the signatures of the
+// functions callable on it, including those of a proxy's implementation.
+// The contract is nil if it is unknown in offline mode.
+func (c *Client) Functions(ctx context.Context, address common.Address) (*Contract, []string, error) {
+	contract, err := c.Lookup(ctx, address)
+	if err != nil || contract == nil {
+		return nil, nil, err
+	}
+	signatures, err := contract.Signatures()
+	if err != nil {
+		return nil, nil, err
+	}
+	if contract.Implementation != "" {
+		implementation, err := c.Lookup(ctx, common.HexToAddress(contract.Implementation))
+		if err != nil {
+			return nil, nil, err
+		}
+		if implementation != nil {
+			more, err := implementation.Signatures()
+			if err != nil {
+				return nil, nil, err
+			}
+			signatures = append(signatures, more...)
+		}
+	}
+	return contract, signatures, nil
+}
@@ -0,0 +1,187 @@
+// Package filter parses and evaluates expressions selecting proof entries,
+// such as oneSigId == 30101 && nonce >= 50.
+package filter
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"merkle-cli/models"
+	"merkle-cli/utils"
+)
+
+// Field types
+const (
+	numberField = iota
+	stringField
+)
+
+// fields are the entry fields an expression can compare, with their types.
+// to and selector match if any call of the entry matches.
+var fields = map[string]int{
+	"oneSigId":     numberField,
+	"nonce":        numberField,
+	"leafIndex":    numberField,
+	"validAfter":   numberField,
+	"validUntil":   numberField,
+	"calls":        numberField,
+	"value":        numberField,
+	"contractAddr": stringField,
+	"leafHash":     stringField,
+	"to":           stringField,
+	"selector":     stringField,
+}
+
+// Fields returns the names of the fields an expression can compare
+func Fields() []string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	return names
+}
+
+// Expr is a parsed filter expression
+type Expr struct {
+	root node
+}
+
+// node is a boolean expression over a proof entry
+type node interface {
+	match(entry *models.ProofEntry) (bool, error)
+}
+
+type andNode struct{ left, right node }
+type orNode struct{ left, right node }
+type notNode struct{ operand node }
+
+// compareNode compares a field with a literal
+type compareNode struct {
+	field  string
+	op     string
+	number *big.Int
+	text   string
+}
+
+func (n *andNode) match(entry *models.ProofEntry) (bool, error) {
+	ok, err := n.left.match(entry)
+	if err != nil || !ok {
+		return false, err
+	}
+	return n.right.match(entry)
+}
+
+func (n *orNode) match(entry *models.ProofEntry) (bool, error) {
+	ok, err := n.left.match(entry)
+	if err != nil || ok {
+		return ok, err
+	}
+	return n.right.match(entry)
+}
+
+func (n *notNode) match(entry *models.ProofEntry) (bool, error) {
+	ok, err := n.operand.match(entry)
+	return !ok, err
+}
+
+func (n *compareNode) match(entry *models.ProofEntry) (bool, error) {
+	if fields[n.field] == stringField {
+		values, err := stringValues(entry, n.field)
+		if err != nil {
+			return false, err
+		}
+		for _, value := range values {
+			if strings.EqualFold(value, n.text) == (n.op == "==") {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	value := numberValue(entry, n.field)
+	c := value.Cmp(n.number)
+	switch n.op {
+	case "==":
+		return c == 0, nil
+	case "!=":
+		return c != 0, nil
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	case ">":
+		return c > 0, nil
+	default:
+		return c >= 0, nil
+	}
+}
+
+// numberValue returns a numeric field of an entry
+func numberValue(entry *models.ProofEntry, field string) *big.Int {
+	switch field {
+	case "oneSigId":
+		return new(big.Int).SetUint64(entry.OneSigID)
+	case "nonce":
+		return new(big.Int).SetUint64(entry.Nonce)
+	case "leafIndex":
+		return big.NewInt(int64(entry.LeafIndex))
+	case "validAfter":
+		return new(big.Int).SetUint64(entry.ValidAfter)
+	case "validUntil":
+		return new(big.Int).SetUint64(entry.ValidUntil)
+	case "calls":
+		return big.NewInt(int64(len(entry.Calls)))
+	default:
+		return models.TotalValue(entry.Calls)
+	}
+}
+
+// stringValues returns the values of a string field of an entry, one per
+// call for the call fields
+func stringValues(entry *models.ProofEntry, field string) ([]string, error) {
+	switch field {
+	case "contractAddr":
+		return []string{entry.ContractAddr}, nil
+	case "leafHash":
+		return []string{entry.LeafHash}, nil
+	case "to":
+		values := make([]string, len(entry.Calls))
+		for i, call := range entry.Calls {
+			values[i] = call.To
+		}
+		return values, nil
+	default:
+		values := make([]string, 0, len(entry.Calls))
+		for i, call := range entry.Calls {
+			data, err := utils.HexToBytes(call.Data)
+			if err != nil {
+				return nil, fmt.Errorf("nonce %d calls[%d].data: %w", entry.Nonce, i, err)
+			}
+			if len(data) >= 4 {
+				values = append(values, fmt.Sprintf("0x%x", data[:4]))
+			}
+		}
+		return values, nil
+	}
+}
+
+// Match reports whether an entry satisfies the expression
+func (e *Expr) Match(entry *models.ProofEntry) (bool, error) {
+	return e.root.match(entry)
+}
+
+// Filter returns the entries satisfying the expression, in their original order
+func (e *Expr) Filter(entries []models.ProofEntry) ([]models.ProofEntry, error) {
+	var matched []models.ProofEntry
+	for i := range entries {
+		ok, err := e.Match(&entries[i])
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			matched = append(matched, entries[i])
+		}
+	}
+	return matched, nil
+}
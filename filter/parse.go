@@ -0,0 +1,204 @@
+package filter
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// token is a lexical token of an expression
+type token struct {
+	kind  string // "ident", "number", "string", "op" or "eof"
+	text  string
+	start int
+}
+
+// Parse parses a filter expression. Comparisons of a field with a literal
+// (==, !=, <, <=, >, >=) are combined with &&, || and !, and grouped with
+// parentheses. String fields only support == and !=.
+func Parse(expr string) (*Expr, error) {
+	tokens, err := lex(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	root, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != "eof" {
+		return nil, fmt.Errorf("unexpected %q at position %d", t.text, t.start+1)
+	}
+	return &Expr{root: root}, nil
+}
+
+// lex splits an expression into tokens
+func lex(expr string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(expr); {
+		c := rune(expr[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '"' || c == '\'':
+			end := strings.IndexRune(expr[i+1:], c)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string at position %d", i+1)
+			}
+			tokens = append(tokens, token{"string", expr[i+1 : i+1+end], i})
+			i += end + 2
+		case unicode.IsDigit(c):
+			j := i
+			for j < len(expr) && (unicode.IsDigit(rune(expr[j])) || unicode.IsLetter(rune(expr[j]))) {
+				j++
+			}
+			tokens = append(tokens, token{"number", expr[i:j], i})
+			i = j
+		case unicode.IsLetter(c):
+			j := i
+			for j < len(expr) && (unicode.IsLetter(rune(expr[j])) || unicode.IsDigit(rune(expr[j]))) {
+				j++
+			}
+			tokens = append(tokens, token{"ident", expr[i:j], i})
+			i = j
+		default:
+			op := ""
+			for _, candidate := range []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!", "(", ")"} {
+				if strings.HasPrefix(expr[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected %q at position %d", c, i+1)
+			}
+			tokens = append(tokens, token{"op", op, i})
+			i += len(op)
+		}
+	}
+	return append(tokens, token{"eof", "end of expression", len(expr)}), nil
+}
+
+// parser is a recursive descent parser over the tokens of an expression
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != "eof" {
+		p.pos++
+	}
+	return t
+}
+
+// accept consumes the next token if it is the given operator
+func (p *parser) accept(op string) bool {
+	if t := p.peek(); t.kind == "op" && t.text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// or parses: and ("||" and)*
+func (p *parser) or() (node, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("||") {
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = &orNode{left, right}
+	}
+	return left, nil
+}
+
+// and parses: unary ("&&" unary)*
+func (p *parser) and() (node, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("&&") {
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		left = &andNode{left, right}
+	}
+	return left, nil
+}
+
+// unary parses: "!" unary | "(" or ")" | comparison
+func (p *parser) unary() (node, error) {
+	if p.accept("!") {
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{operand}, nil
+	}
+	if p.accept("(") {
+		inner, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(")") {
+			t := p.peek()
+			return nil, fmt.Errorf("expected ) at position %d, found %q", t.start+1, t.text)
+		}
+		return inner, nil
+	}
+	return p.comparison()
+}
+
+// comparison parses: field operator literal
+func (p *parser) comparison() (node, error) {
+	t := p.next()
+	if t.kind != "ident" {
+		return nil, fmt.Errorf("expected a field at position %d, found %q", t.start+1, t.text)
+	}
+	kind, ok := fields[t.text]
+	if !ok {
+		names := Fields()
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown field %q, expected one of %s", t.text, strings.Join(names, ", "))
+	}
+
+	op := p.next()
+	switch op.text {
+	case "==", "!=":
+	case "<", "<=", ">", ">=":
+		if kind == stringField {
+			return nil, fmt.Errorf("%s is compared with == or != only", t.text)
+		}
+	default:
+		return nil, fmt.Errorf("expected a comparison operator after %s at position %d", t.text, op.start+1)
+	}
+
+	literal := p.next()
+	if literal.kind != "number" && literal.kind != "string" {
+		return nil, fmt.Errorf("expected a value after %s %s at position %d", t.text, op.text, literal.start+1)
+	}
+	n := &compareNode{field: t.text, op: op.text, text: literal.text}
+	if kind == numberField {
+		// Numbers may be quoted, as in oneSigId == "30101"
+		number, ok := new(big.Int).SetString(literal.text, 0)
+		if !ok || number.Sign() < 0 {
+			return nil, fmt.Errorf("%s must be compared with a number, not %q", t.text, literal.text)
+		}
+		n.number = number
+	}
+	return n, nil
+}
@@ -3,8 +3,11 @@ module merkle-cli
 go 1.21
 
 require (
+	github.com/cloudflare/circl v1.3.7
 	github.com/ethereum/go-ethereum v1.13.14
 	github.com/spf13/cobra v1.8.0
+	github.com/spf13/pflag v1.0.5
+	golang.org/x/crypto v0.17.0
 )
 
 require (
@@ -12,7 +15,5 @@ require (
 	github.com/decred/dcrd/dcrec/secp256k1/v4 v4.0.1 // indirect
 	github.com/holiman/uint256 v1.2.4 // indirect
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
-	github.com/spf13/pflag v1.0.5 // indirect
-	golang.org/x/crypto v0.17.0 // indirect
 	golang.org/x/sys v0.16.0 // indirect
 )
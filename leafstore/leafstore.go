@@ -0,0 +1,103 @@
+// Package leafstore records the leaves of generated roots in a JSON file, so
+// a later batch repeating an action of an earlier root can be flagged.
+package leafstore
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"merkle-cli/models"
+	"merkle-cli/utils"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Record locates the first leaf seen with a fingerprint
+type Record struct {
+	MerkleRoot string `json:"merkleRoot"`
+	OneSigID   uint64 `json:"oneSigId"`
+	Nonce      uint64 `json:"nonce"`
+	LeafHash   string `json:"leafHash"`
+}
+
+// Store maps leaf fingerprints to the first leaf recorded with them
+type Store struct {
+	path   string
+	Leaves map[string]Record `json:"leaves"`
+}
+
+// Open reads the store at path, or returns an empty store if it does not exist yet
+func Open(path string) (*Store, error) {
+	s := &Store{path: path, Leaves: make(map[string]Record)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read leaf store: %w", err)
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("failed to parse leaf store %s: %w", path, err)
+	}
+	if s.Leaves == nil {
+		s.Leaves = make(map[string]Record)
+	}
+	return s, nil
+}
+
+// Fingerprint hashes the encoded leaf with its nonce, validity window and salt
+// cleared, so leaves repeating the same calls on the same OneSig instance
+// share a fingerprint
+func Fingerprint(leaf models.Leaf, version byte) (string, error) {
+	leaf.Nonce = 0
+	leaf.ValidAfter = 0
+	leaf.ValidUntil = 0
+	leaf.Salt = ""
+	data, err := utils.EncodeLeafData(leaf, version)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(crypto.Keccak256(data)), nil
+}
+
+// Lookup returns the first leaf recorded with the fingerprint
+func (s *Store) Lookup(fingerprint string) (Record, bool) {
+	record, ok := s.Leaves[fingerprint]
+	return record, ok
+}
+
+// Add records a leaf unless its fingerprint is already known
+func (s *Store) Add(fingerprint string, record Record) {
+	if _, ok := s.Leaves[fingerprint]; !ok {
+		s.Leaves[fingerprint] = record
+	}
+}
+
+// Save writes the store back to its file
+func (s *Store) Save() error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode leaf store: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write leaf store: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write leaf store: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write leaf store: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to write leaf store: %w", err)
+	}
+	return nil
+}
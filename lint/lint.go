@@ -0,0 +1,230 @@
+package lint
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"merkle-cli/chain"
+	"merkle-cli/etherscan"
+	"merkle-cli/models"
+	"merkle-cli/utils"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Lint rule names, used to report warnings and to disable rules
+const (
+	RuleEmptyCall          = "empty-call"
+	RuleZeroAddress        = "zero-address"
+	RuleNonPayableTransfer = "non-payable-transfer"
+	RuleSelfCall           = "self-call"
+	RuleUnverifiedTarget   = "unverified-target"
+	RuleUnknownSelector    = "unknown-selector"
+	RuleImplementation     = "implementation-changed"
+)
+
+// Rules lists every lint rule
+var Rules = []string{RuleEmptyCall, RuleZeroAddress, RuleNonPayableTransfer, RuleSelfCall, RuleUnverifiedTarget, RuleUnknownSelector, RuleImplementation}
+
+// Warning is a suspicious call found in a batch
+type Warning struct {
+	Rule    string `json:"rule"`
+	Group   int    `json:"group"`
+	Nonce   uint64 `json:"nonce"`
+	Call    int    `json:"call"`
+	To      string `json:"to"`
+	Message string `json:"message"`
+}
+
+func (w Warning) String() string {
+	return fmt.Sprintf("[%s] group %d (nonce %d) call %d to %s: %s", w.Rule, w.Group, w.Nonce, w.Call, w.To, w.Message)
+}
+
+// Options configures which rules run and what they check against
+type Options struct {
+	// OneSig is the OneSig contract address, used to detect self-calls
+	OneSig common.Address
+	// Disabled holds the names of rules that are skipped
+	Disabled map[string]bool
+	// Client enables the rules that need chain state; they are skipped when nil
+	Client *chain.Client
+	// Explorer enables the rules that need verified sources; they are skipped when nil
+	Explorer *etherscan.Client
+}
+
+// CheckRules rejects unknown rule names
+func CheckRules(names []string) error {
+	for _, name := range names {
+		known := false
+		for _, rule := range Rules {
+			known = known || rule == name
+		}
+		if !known {
+			return fmt.Errorf("unknown lint rule %q", name)
+		}
+	}
+	return nil
+}
+
+// Batch returns a warning for every suspicious call in the batch
+func Batch(ctx context.Context, batch *models.TransactionBatch, opts Options) ([]Warning, error) {
+	var warnings []Warning
+	codeCache := make(map[common.Address][]byte)
+	proxyCache := make(map[common.Address]common.Address)
+
+	for g, group := range batch.Groups {
+		for c, call := range group.Calls {
+			warn := func(rule string, format string, args ...interface{}) {
+				if !opts.Disabled[rule] {
+					warnings = append(warnings, Warning{
+						Rule:    rule,
+						Group:   g,
+						Nonce:   group.Nonce,
+						Call:    c,
+						To:      call.To,
+						Message: fmt.Sprintf(format, args...),
+					})
+				}
+			}
+
+			data, err := utils.ParseHex(call.Data, fmt.Sprintf("groups[%d].calls[%d].data", g, c), false)
+			if err != nil {
+				return nil, err
+			}
+			to := common.HexToAddress(call.To)
+			hasValue := call.Value != nil && call.Value.Sign() > 0
+
+			if len(data) == 0 && !hasValue {
+				warn(RuleEmptyCall, "call has no data and no value")
+			}
+			if to == (common.Address{}) {
+				warn(RuleZeroAddress, "call targets the zero address")
+			}
+			if to == opts.OneSig {
+				warn(RuleSelfCall, "call targets the OneSig contract itself")
+			}
+
+			if hasValue && len(data) == 0 && opts.Client != nil && !opts.Disabled[RuleNonPayableTransfer] {
+				message, err := checkPayable(opts, codeCache, to, call.Value)
+				if err != nil {
+					return nil, fmt.Errorf("failed to check group %d call %d: %w", g, c, err)
+				}
+				if message != "" {
+					warn(RuleNonPayableTransfer, "%s", message)
+				}
+			}
+
+			// Calls to proxies are checked against their implementation
+			var implementation common.Address
+			if opts.Client != nil && (call.Implementation != "" || (len(data) >= 4 && opts.Explorer != nil)) {
+				if implementation, err = proxyImplementation(opts.Client, proxyCache, to); err != nil {
+					return nil, fmt.Errorf("failed to check group %d call %d: %w", g, c, err)
+				}
+				if message := CheckImplementation(call.Implementation, implementation); message != "" {
+					warn(RuleImplementation, "%s", message)
+				}
+			}
+
+			if len(data) >= 4 && opts.Explorer != nil {
+				rule, message, err := checkSelector(ctx, opts.Explorer, to, implementation, data[:4])
+				if err != nil {
+					return nil, fmt.Errorf("failed to check group %d call %d: %w", g, c, err)
+				}
+				if message != "" {
+					warn(rule, "%s", message)
+				}
+			}
+		}
+	}
+
+	return warnings, nil
+}
+
+// proxyImplementation returns the implementation of an EIP-1967 proxy, or
+// the zero address if the target is not a proxy
+func proxyImplementation(client *chain.Client, cache map[common.Address]common.Address, to common.Address) (common.Address, error) {
+	if implementation, ok := cache[to]; ok {
+		return implementation, nil
+	}
+	implementation, err := client.ProxyImplementation(to)
+	if err != nil {
+		return common.Address{}, err
+	}
+	cache[to] = implementation
+	return implementation, nil
+}
+
+// CheckImplementation compares the implementation pinned in a call with the
+// one the target has now, returning the problem or an empty string
+func CheckImplementation(pinned string, implementation common.Address) string {
+	if pinned == "" {
+		return ""
+	}
+	if !common.IsHexAddress(pinned) {
+		return fmt.Sprintf("pinned implementation %q is not an address", pinned)
+	}
+	if implementation == (common.Address{}) {
+		return "target pins an implementation but is not an EIP-1967 proxy"
+	}
+	if common.HexToAddress(pinned) != implementation {
+		return fmt.Sprintf("proxy implementation changed to %s since the batch was prepared against %s", implementation.Hex(), common.HexToAddress(pinned).Hex())
+	}
+	return ""
+}
+
+// checkSelector reports a call to a target without verified source, or to a
+// function missing from its verified ABI. Calls to a proxy are checked
+// against the ABI of its implementation as well as its own. Targets unknown
+// to an offline explorer are not checked.
+func checkSelector(ctx context.Context, explorer *etherscan.Client, to common.Address, implementation common.Address, selector []byte) (string, string, error) {
+	contract, signatures, err := explorer.Functions(ctx, to)
+	if err != nil || contract == nil {
+		return "", "", err
+	}
+	if implementation != (common.Address{}) {
+		target, more, err := explorer.Functions(ctx, implementation)
+		if err != nil || target == nil {
+			return "", "", err
+		}
+		if !target.Verified {
+			return RuleUnverifiedTarget, fmt.Sprintf("call with data targets a proxy whose implementation %s has no verified source", implementation.Hex()), nil
+		}
+		contract = target
+		signatures = append(signatures, more...)
+	} else if !contract.Verified {
+		return RuleUnverifiedTarget, "call with data targets a contract without verified source", nil
+	}
+	for _, sig := range signatures {
+		if string(crypto.Keccak256([]byte(sig))[:4]) == string(selector) {
+			return "", "", nil
+		}
+	}
+	return RuleUnknownSelector, fmt.Sprintf("selector 0x%x is not a function of the verified %s ABI", selector, contract.Name), nil
+}
+
+// checkPayable reports why a plain value transfer to a contract would revert,
+// or an empty string if the target is not a contract or accepts the transfer
+func checkPayable(opts Options, codeCache map[common.Address][]byte, to common.Address, value *big.Int) (string, error) {
+	code, ok := codeCache[to]
+	if !ok {
+		var err error
+		if code, err = opts.Client.Code(to); err != nil {
+			return "", err
+		}
+		codeCache[to] = code
+	}
+	if len(code) == 0 {
+		return "", nil
+	}
+
+	_, err := opts.Client.CallContract(chain.CallMsg{From: opts.OneSig, To: to, Value: (*hexutil.Big)(value)})
+	var rpcErr *chain.RPCError
+	if errors.As(err, &rpcErr) {
+		return fmt.Sprintf("value transfer to contract reverts (%s); it may have no payable receive or fallback function", rpcErr.Message), nil
+	}
+	return "", err
+}
@@ -0,0 +1,166 @@
+package merkle
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"merkle-cli/models"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// DefaultArity is the number of children of every node of OneSig trees
+const DefaultArity = 2
+
+// MaxArity is the largest number of children of a node
+const MaxArity = 64
+
+// CheckArity validates the number of children of every node of a tree
+func CheckArity(arity int) error {
+	if arity < 2 || arity > MaxArity {
+		return fmt.Errorf("unsupported arity %d, expected 2 to %d", arity, MaxArity)
+	}
+	return nil
+}
+
+// ArityOf returns the arity of trees built with opts, which must have passed
+// CheckTreeOptions. Nil options and options without one are binary.
+func ArityOf(opts *models.TreeOptions) int {
+	if opts == nil || opts.Arity == 0 {
+		return DefaultArity
+	}
+	return opts.Arity
+}
+
+// TreeDepthArity returns the number of levels above the leaves of a tree of
+// the given arity and number of leaves
+func TreeDepthArity(leaves, arity int) int {
+	depth := 0
+	for n := leaves; n > 1; n = (n + arity - 1) / arity {
+		depth++
+	}
+	return depth
+}
+
+// ProofLength returns the number of hashes in every proof of a tree of the
+// given arity and number of leaves: arity-1 siblings per level
+func ProofLength(leaves, arity int) int {
+	return TreeDepthArity(leaves, arity) * (arity - 1)
+}
+
+// groupAt returns the children of the i-th node of the next level in buf. A
+// short last group is filled up with its last node, as the last odd node of
+// a binary tree is paired with itself.
+func groupAt(nodes [][]byte, i, arity int, buf [][]byte) [][]byte {
+	children := buf[:arity]
+	for j := range children {
+		k := i*arity + j
+		if k >= len(nodes) {
+			k = len(nodes) - 1
+		}
+		children[j] = nodes[k]
+	}
+	return children
+}
+
+// arrangeGroup orders children in place the way they are hashed: ascending
+// for sorted pair order, as they are for positional. Groups are small, so an
+// insertion sort does without the allocations of sort.Slice.
+func (o PairOrder) arrangeGroup(children [][]byte) {
+	if o == PairOrderPositional {
+		return
+	}
+	for i := 1; i < len(children); i++ {
+		for j := i; j > 0 && bytes.Compare(children[j-1], children[j]) > 0; j-- {
+			children[j-1], children[j] = children[j], children[j-1]
+		}
+	}
+}
+
+// hashGroups hashes the groups start to end of nodes into their slots
+func hashGroups(ctx context.Context, nodes, next [][]byte, slots []byte, order PairOrder, arity, start, end int) error {
+	state := crypto.NewKeccakState()
+	buf := make([][]byte, arity)
+	for i := start; i < end; i++ {
+		if (i-start)%cancelCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+		children := groupAt(nodes, i, arity, buf)
+		order.arrangeGroup(children)
+		parent := slots[i*hashSize : (i+1)*hashSize : (i+1)*hashSize]
+		state.Reset()
+		for _, child := range children {
+			state.Write(child)
+		}
+		state.Read(parent)
+		next[i] = parent
+	}
+	return nil
+}
+
+// hashGroup hashes the children of a node, in the order they are hashed
+func hashGroup(children [][]byte) []byte {
+	return crypto.Keccak256(children...)
+}
+
+// BuildLevelsArity is BuildLevelsWorkers for trees whose nodes have arity children
+func BuildLevelsArity(ctx context.Context, leaves [][]byte, order PairOrder, arity, workers int) ([][][]byte, error) {
+	if len(leaves) == 0 {
+		return nil, fmt.Errorf("cannot build tree with no leaves")
+	}
+	if err := CheckArity(arity); err != nil {
+		return nil, err
+	}
+	return buildLevels(ctx, leaves, order, arity, nil, workers)
+}
+
+// VerifyProofArity verifies a proof of the leaf at index in a tree of the
+// given pair order and arity
+func VerifyProofArity(root, leaf []byte, proof [][]byte, index int, order PairOrder, arity int) bool {
+	if arity <= DefaultArity {
+		return VerifyProofAt(root, leaf, proof, index, order)
+	}
+	if len(proof)%(arity-1) != 0 {
+		return false
+	}
+	return bytes.Equal(rootFromSteps(leaf, TraceProofArity(leaf, proof, index, order, arity)), root)
+}
+
+// TraceProofArity is TraceProof for trees of the given arity. Each round of
+// a wider tree combines the node with the next arity-1 siblings of the proof,
+// inserted at the node's position for positional pair order; a trailing
+// partial round is left out.
+func TraceProofArity(leaf []byte, proof [][]byte, index int, order PairOrder, arity int) []ProofStep {
+	if arity <= DefaultArity {
+		return TraceProof(leaf, proof, index, order)
+	}
+	steps := make([]ProofStep, 0, len(proof)/(arity-1))
+	currentHash := leaf
+	for len(proof) >= arity-1 {
+		siblings := proof[:arity-1]
+		proof = proof[arity-1:]
+		position := index % arity
+		children := make([][]byte, 0, arity)
+		children = append(children, siblings[:position]...)
+		children = append(children, currentHash)
+		children = append(children, siblings[position:]...)
+		order.arrangeGroup(children)
+		parent := hashGroup(children)
+		steps = append(steps, ProofStep{Node: currentHash, Siblings: siblings, Children: children, Parent: parent})
+		currentHash = parent
+		index /= arity
+	}
+	return steps
+}
+
+// rootFromSteps returns the parent of the last round of a proof, or the leaf
+// of a proof without rounds
+func rootFromSteps(leaf []byte, steps []ProofStep) []byte {
+	if len(steps) == 0 {
+		return leaf
+	}
+	return steps[len(steps)-1].Parent
+}
@@ -0,0 +1,6 @@
+package merkle
+
+import "errors"
+
+// ErrLeafNotFound is returned when a proof is requested for a leaf that is not in the tree
+var ErrLeafNotFound = errors.New("leaf not found in tree")
@@ -0,0 +1,20 @@
+//go:build !keccak_circl
+
+package merkle
+
+import "github.com/ethereum/go-ethereum/crypto"
+
+// KeccakBackend names the keccak implementation trees are built with. Build
+// with -tags keccak_circl for the vectorized backend.
+const KeccakBackend = "go-ethereum"
+
+// keccakBackendActive reports whether the backend is usable on this CPU
+func keccakBackendActive() bool {
+	return true
+}
+
+// newPairHasher returns a pair hasher using go-ethereum's keccak, which is
+// assembly accelerated on amd64 and arm64
+func newPairHasher() pairHasher {
+	return &scalarHasher{state: crypto.NewKeccakState()}
+}
@@ -0,0 +1,68 @@
+//go:build keccak_circl
+
+package merkle
+
+import (
+	"encoding/binary"
+
+	"github.com/cloudflare/circl/simd/keccakf1600"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// KeccakBackend names the keccak implementation trees are built with
+const KeccakBackend = "circl"
+
+// keccakRateLanes is the number of 64-bit lanes absorbed per keccak-256 block
+const keccakRateLanes = 17
+
+// keccakBackendActive reports whether the CPU supports the four-way permutation
+func keccakBackendActive() bool {
+	return keccakf1600.IsEnabledX4()
+}
+
+// newPairHasher returns a pair hasher running four keccak permutations at once
+// with AVX2, or go-ethereum's keccak on CPUs without it
+func newPairHasher() pairHasher {
+	scalar := &scalarHasher{state: crypto.NewKeccakState()}
+	if !keccakBackendActive() {
+		return scalar
+	}
+	return &vectorHasher{scalar: scalar}
+}
+
+// vectorHasher hashes four pairs of 32-byte nodes with one four-way
+// permutation. Two nodes fit in one block, so each hash is a single permutation.
+type vectorHasher struct {
+	state  keccakf1600.StateX4
+	scalar *scalarHasher
+}
+
+func (h *vectorHasher) hash(dst, first, second *[pairLanes][]byte, n int) {
+	for i := 0; i < n; i++ {
+		if len(first[i]) != hashSize || len(second[i]) != hashSize {
+			h.scalar.hash(dst, first, second, n)
+			return
+		}
+	}
+
+	// Lane k of state i is a[4*k+i]
+	a := h.state.Initialize(false)
+	for k := range a {
+		a[k] = 0
+	}
+	for i := 0; i < n; i++ {
+		for k := 0; k < 4; k++ {
+			a[4*k+i] = binary.LittleEndian.Uint64(first[i][8*k:])
+			a[4*(k+4)+i] = binary.LittleEndian.Uint64(second[i][8*k:])
+		}
+		// Legacy keccak padding of the 64-byte message
+		a[4*8+i] = 0x01
+		a[4*(keccakRateLanes-1)+i] = 0x80 << 56
+	}
+	h.state.Permute()
+	for i := 0; i < n; i++ {
+		for k := 0; k < 4; k++ {
+			binary.LittleEndian.PutUint64(dst[i][8*k:], a[4*k+i])
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package merkle
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// pairLanes is the largest number of pairs a pairHasher hashes in one call
+const pairLanes = 4
+
+// pairHasher hashes batches of up to pairLanes pairs of nodes. A pairHasher is
+// used by a single goroutine.
+type pairHasher interface {
+	// hash hashes first[i] followed by second[i] into dst[i] for every i below n
+	hash(dst, first, second *[pairLanes][]byte, n int)
+}
+
+// scalarHasher hashes one pair after the other, reusing a keccak state
+type scalarHasher struct {
+	state crypto.KeccakState
+}
+
+func (h *scalarHasher) hash(dst, first, second *[pairLanes][]byte, n int) {
+	for i := 0; i < n; i++ {
+		h.state.Reset()
+		h.state.Write(first[i])
+		h.state.Write(second[i])
+		h.state.Read(dst[i])
+	}
+}
+
+// KeccakBackendStatus describes the keccak backend in use, noting when it
+// falls back to go-ethereum on this CPU
+func KeccakBackendStatus() string {
+	if !keccakBackendActive() {
+		return KeccakBackend + " (unsupported CPU, using go-ethereum)"
+	}
+	return KeccakBackend
+}
+
+// CheckPairHasher hashes consecutive pairs of leaves with the keccak backend,
+// in batches of every size up to pairLanes, and checks that every hash matches
+// go-ethereum's. It returns the number of pairs compared.
+func CheckPairHasher(leaves [][]byte) (int, error) {
+	backend := newPairHasher()
+	scalar := &scalarHasher{state: crypto.NewKeccakState()}
+	var first, second, got, want [pairLanes][]byte
+	for i := range got {
+		got[i] = make([]byte, hashSize)
+		want[i] = make([]byte, hashSize)
+	}
+
+	pairs := 0
+	for start, n := 0, 1; start+2*n <= len(leaves); start, n = start+2*n, n%pairLanes+1 {
+		for i := 0; i < n; i++ {
+			first[i], second[i] = leaves[start+2*i], leaves[start+2*i+1]
+		}
+		backend.hash(&got, &first, &second, n)
+		scalar.hash(&want, &first, &second, n)
+		for i := 0; i < n; i++ {
+			if !bytes.Equal(got[i], want[i]) {
+				return pairs, fmt.Errorf("%s keccak hashes leaves %d and %d to 0x%x, go-ethereum to 0x%x",
+					KeccakBackend, start+2*i, start+2*i+1, got[i], want[i])
+			}
+		}
+		pairs += n
+	}
+	return pairs, nil
+}
@@ -0,0 +1,96 @@
+package merkle
+
+import (
+	"fmt"
+	"sort"
+
+	"merkle-cli/models"
+)
+
+// LeafOrder selects the order in which leaves feed the tree. It only decides
+// the positions of the leaves; pairs are still ordered by the PairOrder.
+type LeafOrder string
+
+const (
+	// LeafOrderSorted orders leaves by their hash, as OneSig trees are built
+	LeafOrderSorted LeafOrder = "sorted"
+	// LeafOrderInput keeps the leaves in the order of their batch, or batches
+	LeafOrderInput LeafOrder = "input"
+	// LeafOrderByNonce orders leaves by nonce, then OneSig ID
+	LeafOrderByNonce LeafOrder = "by-nonce"
+	// LeafOrderByOneSigNonce orders leaves by OneSig ID, then nonce, so the
+	// leaves of one OneSig instance share a subtree
+	LeafOrderByOneSigNonce LeafOrder = "by-onesig-nonce"
+)
+
+// DefaultLeafOrder is the leaf order used unless one is chosen explicitly
+const DefaultLeafOrder = LeafOrderSorted
+
+// LeafOrders lists every leaf order, the default first
+var LeafOrders = []LeafOrder{LeafOrderSorted, LeafOrderInput, LeafOrderByNonce, LeafOrderByOneSigNonce}
+
+// ParseLeafOrder validates a leaf order name, treating "" as the default
+func ParseLeafOrder(s string) (LeafOrder, error) {
+	if s == "" {
+		return DefaultLeafOrder, nil
+	}
+	for _, order := range LeafOrders {
+		if LeafOrder(s) == order {
+			return order, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported leaf order %q, expected %s, %s, %s or %s", s, LeafOrderSorted, LeafOrderInput, LeafOrderByNonce, LeafOrderByOneSigNonce)
+}
+
+// LeafOrderOf returns the leaf order of tree options already checked with
+// CheckTreeOptions. Nil options and options without one use the default.
+func LeafOrderOf(opts *models.TreeOptions) LeafOrder {
+	if opts == nil || opts.LeafOrder == "" {
+		return DefaultLeafOrder
+	}
+	return LeafOrder(opts.LeafOrder)
+}
+
+// arrange returns the leaf hashes in the order they feed the tree. Leaves
+// that tie, such as the windows of one nonce, keep their order in the input.
+func (o LeafOrder) arrange(leaves []models.Leaf, hashes [][]byte) [][]byte {
+	if o == LeafOrderSorted {
+		return SortLeaves(hashes)
+	}
+
+	positions := make([]int, len(leaves))
+	for i := range positions {
+		positions[i] = i
+	}
+	less := func(a, b models.Leaf) bool {
+		if a.Nonce != b.Nonce {
+			return a.Nonce < b.Nonce
+		}
+		if a.OneSigID != b.OneSigID {
+			return a.OneSigID < b.OneSigID
+		}
+		return a.ValidAfter < b.ValidAfter
+	}
+	if o == LeafOrderByOneSigNonce {
+		less = func(a, b models.Leaf) bool {
+			if a.OneSigID != b.OneSigID {
+				return a.OneSigID < b.OneSigID
+			}
+			if a.Nonce != b.Nonce {
+				return a.Nonce < b.Nonce
+			}
+			return a.ValidAfter < b.ValidAfter
+		}
+	}
+	if o != LeafOrderInput {
+		sort.SliceStable(positions, func(i, j int) bool {
+			return less(leaves[positions[i]], leaves[positions[j]])
+		})
+	}
+
+	ordered := make([][]byte, len(positions))
+	for i, position := range positions {
+		ordered[i] = hashes[position]
+	}
+	return ordered
+}
@@ -0,0 +1,490 @@
+package merkle
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"runtime"
+	"sort"
+	"sync"
+
+	"merkle-cli/models"
+	"merkle-cli/utils"
+)
+
+// hashSize is the size of every node hash
+const hashSize = 32
+
+// cancelCheckInterval is how many leaves or nodes are processed between context checks
+const cancelCheckInterval = 1024
+
+// Params are the deployment settings a batch is merklized with
+type Params struct {
+	OneSigID     uint64
+	ContractAddr string
+	LeafVersion  uint8
+	// Options defaults to DefaultTreeOptions when nil
+	Options *models.TreeOptions
+	Limits  utils.Limits
+	// Validate, if set, is called with every leaf before encoding, e.g. to enforce a policy
+	Validate func(leaves []models.Leaf) error
+	// Trace, if set, is called with every pair of nodes hashed while building the tree
+	Trace func(pair PairTrace)
+	// Workers is the number of goroutines hashing each level of the tree:
+	// 0 uses GOMAXPROCS and 1 hashes serially. The tree is the same either way.
+	Workers int
+	// AutoSalt gives every leaf without a salt the one derived from its OneSig
+	// ID, nonce and SaltDomain with utils.DeriveSalt
+	AutoSalt   bool
+	SaltDomain string
+}
+
+// Entry is a leaf of a generated tree together with its hash, position and proof
+type Entry struct {
+	Leaf  models.Leaf
+	Hash  []byte
+	Index int
+	Proof [][]byte
+}
+
+// Result is a generated tree and its entries, sorted by nonce and validity
+// window. Results are immutable: the hashes of the tree and of the entries'
+// proofs share memory, and entries reference the calls of the input batch, so
+// none of them may be modified. Use Clone for a copy that may be.
+type Result struct {
+	Tree    *MerkleTree
+	Entries []Entry
+}
+
+// Clone returns a deep copy of the hashes of a result, sharing nothing with it
+// but the leaves of the entries
+func (r *Result) Clone() *Result {
+	clone := &Result{
+		Tree:    &MerkleTree{Root: bytes.Clone(r.Tree.Root), Leafs: cloneHashes(r.Tree.Leafs), Order: r.Tree.Order, Arity: r.Tree.Arity},
+		Entries: make([]Entry, len(r.Entries)),
+	}
+	for i, entry := range r.Entries {
+		entry.Hash = bytes.Clone(entry.Hash)
+		entry.Proof = cloneHashes(entry.Proof)
+		clone.Entries[i] = entry
+	}
+	return clone
+}
+
+// MerkleModule turns transaction batches into Merkle trees with proofs. A
+// module holds no mutable state: Generate and GenerateMerged may be called
+// from many goroutines at once, each call building its own Result. The
+// Validate and Trace callbacks run on the calling goroutine, so a module
+// shared between goroutines needs callbacks that are safe for concurrent use.
+type MerkleModule struct {
+	params Params
+}
+
+// NewMerkleModule creates a module generating trees with the given parameters.
+// The tree options are copied, so later changes to them do not affect it.
+func NewMerkleModule(params Params) *MerkleModule {
+	if params.Options != nil {
+		options := *params.Options
+		params.Options = &options
+	}
+	return &MerkleModule{params: params}
+}
+
+// Source is a batch together with the OneSig instance its leaves are for,
+// one of the inputs of a merged tree
+type Source struct {
+	// Name identifies the source in errors, e.g. its file name
+	Name         string
+	OneSigID     uint64
+	ContractAddr string
+	Batch        *models.TransactionBatch
+}
+
+// Generate builds the tree of a batch and the proof of every leaf. It stops
+// with the context's error as soon as ctx is cancelled.
+func (m *MerkleModule) Generate(ctx context.Context, batch *models.TransactionBatch) (*Result, error) {
+	if err := utils.CheckLimits(batch, m.params.Limits); err != nil {
+		return nil, err
+	}
+
+	leaves, err := m.candidates(batch, m.params.OneSigID, m.params.ContractAddr)
+	if err != nil {
+		return nil, err
+	}
+	return m.build(ctx, leaves)
+}
+
+// GenerateMerged builds a single tree over the leaves of several batches, each
+// for its own OneSig instance; the instance in the params is ignored. Two
+// sources holding the same nonce for the same OneSig ID are rejected.
+func (m *MerkleModule) GenerateMerged(ctx context.Context, sources []Source) (*Result, error) {
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("no batches to merge")
+	}
+
+	type nonceKey struct {
+		oneSigID uint64
+		nonce    uint64
+	}
+	owners := make(map[nonceKey]int)
+	groups := 0
+	var leaves []models.Leaf
+	for i, source := range sources {
+		if err := utils.CheckLimits(source.Batch, m.params.Limits); err != nil {
+			return nil, fmt.Errorf("%s: %w", source.Name, err)
+		}
+		groups += len(source.Batch.Groups)
+		if maxLeaves := m.params.Limits.MaxLeaves; maxLeaves > 0 && groups > maxLeaves {
+			return nil, fmt.Errorf("%w: merged batches have more than %d groups (--max-leaves)", utils.ErrLimitExceeded, maxLeaves)
+		}
+
+		candidates, err := m.candidates(source.Batch, source.OneSigID, source.ContractAddr)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", source.Name, err)
+		}
+		for _, leaf := range candidates {
+			key := nonceKey{oneSigID: leaf.OneSigID, nonce: leaf.Nonce}
+			if owner, ok := owners[key]; ok && owner != i {
+				return nil, fmt.Errorf("%w: oneSigId %d nonce %d appears in both %s and %s", utils.ErrDuplicateNonce, leaf.OneSigID, leaf.Nonce, sources[owner].Name, source.Name)
+			}
+			owners[key] = i
+		}
+		leaves = append(leaves, candidates...)
+	}
+	return m.build(ctx, leaves)
+}
+
+// build validates the leaves, then builds their tree and the proof of every leaf
+func (m *MerkleModule) build(ctx context.Context, leaves []models.Leaf) (*Result, error) {
+	order, err := CheckTreeOptions(m.params.Options)
+	if err != nil {
+		return nil, err
+	}
+	if err := utils.ValidateValidityWindows(leaves); err != nil {
+		return nil, err
+	}
+	if err := utils.ValidateDependencies(leaves); err != nil {
+		return nil, err
+	}
+	if err := utils.ValidateTargetFormats(leaves); err != nil {
+		return nil, err
+	}
+	if m.params.Validate != nil {
+		if err := m.params.Validate(leaves); err != nil {
+			return nil, err
+		}
+	}
+
+	hashes, err := utils.EncodeLeavesContext(ctx, leaves, m.params.LeafVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	arity := ArityOf(m.params.Options)
+	if err := CheckProofLength(len(hashes), arity, m.params.Limits.MaxProofLength); err != nil {
+		return nil, err
+	}
+
+	leafOrder := LeafOrderOf(m.params.Options)
+	levelTree, err := newLevelTree(ctx, leafOrder.arrange(leaves, hashes), order, arity, m.params.Trace, m.params.Workers)
+	if err != nil {
+		return nil, err
+	}
+	tree := &MerkleTree{Root: levelTree.Root(), Leafs: levelTree.levels[0], Order: order}
+	if arity != DefaultArity {
+		tree.Arity = arity
+	}
+
+	index := make(map[string]int, len(tree.Leafs))
+	for i, leaf := range tree.Leafs {
+		index[string(leaf)] = i
+	}
+
+	entries := make([]Entry, 0, len(leaves))
+	for i, leaf := range leaves {
+		if i%cancelCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		position := index[string(hashes[i])]
+		entries = append(entries, Entry{
+			Leaf:  leaf,
+			Hash:  hashes[i],
+			Index: position,
+			Proof: levelTree.proof(position),
+		})
+	}
+
+	// Sort entries to output in OneSig ID and nonce order
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Leaf.OneSigID != entries[j].Leaf.OneSigID {
+			return entries[i].Leaf.OneSigID < entries[j].Leaf.OneSigID
+		}
+		if entries[i].Leaf.Nonce != entries[j].Leaf.Nonce {
+			return entries[i].Leaf.Nonce < entries[j].Leaf.Nonce
+		}
+		return entries[i].Leaf.ValidAfter < entries[j].Leaf.ValidAfter
+	})
+
+	return &Result{Tree: tree, Entries: entries}, nil
+}
+
+// candidates selects the leaves of a batch for a OneSig instance
+func (m *MerkleModule) candidates(batch *models.TransactionBatch, oneSigID uint64, contractAddr string) ([]models.Leaf, error) {
+	if len(batch.Groups) == 0 {
+		return nil, fmt.Errorf("transaction batch is empty")
+	}
+
+	var leaves []models.Leaf
+	seenNonces := make(map[uint64]bool)
+	for _, group := range batch.Groups {
+		// Generate only one leaf for each group's nonce
+		if len(group.Calls) == 0 {
+			continue
+		}
+		// Windowed leaves may share a nonce as long as their windows are disjoint
+		if seenNonces[group.Nonce] && m.params.LeafVersion < utils.LeafEncodingVersionWindowed {
+			continue
+		}
+		seenNonces[group.Nonce] = true
+		leaf := group.Leaf(oneSigID, contractAddr)
+		leaf.TargetFormat = batch.TargetFormat
+		if m.params.AutoSalt && leaf.Salt == "" {
+			leaf.Salt = utils.DeriveSalt(m.params.SaltDomain, oneSigID, leaf.Nonce)
+		}
+		leaves = append(leaves, leaf)
+	}
+
+	// Ensure we have at least one valid leaf
+	if len(leaves) == 0 {
+		return nil, fmt.Errorf("no valid transactions found in batch")
+	}
+	return leaves, nil
+}
+
+// buildLevels hashes the leaves, in the order they feed the tree, level by level
+// up to the root, the same way buildTree does for sorted pairs and leaves,
+// keeping every level for proof generation. Nodes of trees wider than binary
+// hash arity children at once.
+// trace, if not nil, is called with every pair hashed, in order.
+//
+// The nodes above the leaves are hashed into slots of a single arena, so a
+// build makes a few allocations per level instead of one per node, which
+// keeps the garbage collector out of the way of million-leaf trees. The pairs
+// of large levels are hashed by up to workers goroutines, GOMAXPROCS when
+// workers is 0; every node has a fixed slot, so the result does not depend on
+// the number of workers.
+func buildLevels(ctx context.Context, leaves [][]byte, order PairOrder, arity int, trace func(PairTrace), workers int) ([][][]byte, error) {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	arena := newNodeArena(len(leaves), arity)
+	levels := [][][]byte{leaves}
+	for len(levels[len(levels)-1]) > 1 {
+		nodes := levels[len(levels)-1]
+		next := make([][]byte, (len(nodes)+arity-1)/arity)
+		slots := arena.take(len(next))
+		hash := func(start, end int) error {
+			return hashPairs(ctx, nodes, next, slots, order, start, end)
+		}
+		if arity > DefaultArity {
+			hash = func(start, end int) error {
+				return hashGroups(ctx, nodes, next, slots, order, arity, start, end)
+			}
+		}
+		if err := hashLevel(len(next), workers, hash); err != nil {
+			return nil, err
+		}
+		if trace != nil {
+			buf := make([][]byte, arity)
+			for i, parent := range next {
+				pair := PairTrace{Level: len(levels) - 1, Index: i, Hash: parent}
+				if arity > DefaultArity {
+					children := groupAt(nodes, i, arity, buf)
+					order.arrangeGroup(children)
+					pair.Children = append([][]byte(nil), children...)
+				} else {
+					pair.Left, pair.Right = order.arrange(pairAt(nodes, i))
+				}
+				trace(pair)
+			}
+		}
+		levels = append(levels, next)
+	}
+	return levels, nil
+}
+
+// parallelChunkPairs is the least number of pairs a goroutine hashes; smaller
+// levels are hashed on the calling goroutine
+const parallelChunkPairs = 1024
+
+// hashLevel hashes the n nodes of the next level with hash, split into
+// chunks hashed by up to workers goroutines
+func hashLevel(n, workers int, hash func(start, end int) error) error {
+	chunks := n / parallelChunkPairs
+	if chunks > workers {
+		chunks = workers
+	}
+	if chunks <= 1 {
+		return hash(0, n)
+	}
+
+	size := (n + chunks - 1) / chunks
+	errs := make([]error, chunks)
+	var wg sync.WaitGroup
+	for c := 0; c < chunks; c++ {
+		start, end := c*size, (c+1)*size
+		if end > n {
+			end = n
+		}
+		wg.Add(1)
+		go func(c, start, end int) {
+			defer wg.Done()
+			errs[c] = hash(start, end)
+		}(c, start, end)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hashPairs hashes the pairs start to end of nodes into their slots, in
+// batches of pairLanes pairs for backends that hash several at once
+func hashPairs(ctx context.Context, nodes, next [][]byte, slots []byte, order PairOrder, start, end int) error {
+	hasher := newPairHasher()
+	var dst, first, second [pairLanes][]byte
+	for i := start; i < end; i += pairLanes {
+		if (i-start)%cancelCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+		n := end - i
+		if n > pairLanes {
+			n = pairLanes
+		}
+		for j := 0; j < n; j++ {
+			// Capping the capacity keeps appends to a node out of the next slot
+			parent := slots[(i+j)*hashSize : (i+j+1)*hashSize : (i+j+1)*hashSize]
+			first[j], second[j] = order.arrange(pairAt(nodes, i+j))
+			dst[j] = parent
+			next[i+j] = parent
+		}
+		hasher.hash(&dst, &first, &second, n)
+	}
+	return nil
+}
+
+// pairAt returns the children of the i-th node of the next level. The last
+// odd node is paired with itself.
+func pairAt(nodes [][]byte, i int) ([]byte, []byte) {
+	left := nodes[2*i]
+	if 2*i+1 < len(nodes) {
+		return left, nodes[2*i+1]
+	}
+	return left, left
+}
+
+// nodeArena hands out the hash slots of the nodes of a tree from one allocation
+type nodeArena struct {
+	buf []byte
+}
+
+// newNodeArena allocates the slots of every node above the leaves of a tree
+// with the given number of leaves and arity
+func newNodeArena(leaves, arity int) *nodeArena {
+	nodes := 0
+	for n := leaves; n > 1; n = (n + arity - 1) / arity {
+		nodes += (n + arity - 1) / arity
+	}
+	return &nodeArena{buf: make([]byte, nodes*hashSize)}
+}
+
+// take returns the slots of the next n nodes
+func (a *nodeArena) take(n int) []byte {
+	slots := a.buf[: n*hashSize : n*hashSize]
+	a.buf = a.buf[n*hashSize:]
+	return slots
+}
+
+// BuildLevels hashes leaves, in the order given, level by level, returning every
+// level from the leaves up to the single root node. OneSig trees feed it leaves
+// sorted with SortLeaves. Large levels are hashed in parallel
+// by GOMAXPROCS goroutines.
+func BuildLevels(ctx context.Context, leaves [][]byte, order PairOrder) ([][][]byte, error) {
+	return BuildLevelsWorkers(ctx, leaves, order, 0)
+}
+
+// BuildLevelsWorkers is BuildLevels with the pairs of each level hashed by up
+// to workers goroutines: 1 hashes serially and 0 uses GOMAXPROCS
+func BuildLevelsWorkers(ctx context.Context, leaves [][]byte, order PairOrder, workers int) ([][][]byte, error) {
+	if len(leaves) == 0 {
+		return nil, fmt.Errorf("cannot build tree with no leaves")
+	}
+	return buildLevels(ctx, leaves, order, DefaultArity, nil, workers)
+}
+
+// TreeDepth returns the number of levels above the leaves of a tree with the
+// given number of leaves. Every proof holds one sibling per level, the last odd
+// node being its own sibling, so it is also the length of every proof.
+func TreeDepth(leaves int) int {
+	return TreeDepthArity(leaves, DefaultArity)
+}
+
+// CheckProofLength rejects trees of the given number of leaves and arity whose
+// proofs would exceed maxLength hashes. A maxLength of 0 accepts any tree.
+func CheckProofLength(leaves, arity, maxLength int) error {
+	if maxLength <= 0 {
+		return nil
+	}
+	if length := ProofLength(leaves, arity); length > maxLength {
+		return fmt.Errorf("%w: a tree of %d leaves has proofs of %d hashes, exceeding the limit of %d (--max-proof-length); split the batch into trees of at most %d leaves", utils.ErrLimitExceeded, leaves, length, maxLength, maxTreeLeaves(maxLength, arity))
+	}
+	return nil
+}
+
+// maxTreeLeaves returns the largest number of leaves of a tree of the given
+// arity whose proofs hold at most maxLength hashes
+func maxTreeLeaves(maxLength, arity int) uint64 {
+	leaves := uint64(1)
+	for depth := maxLength / (arity - 1); depth > 0; depth-- {
+		if leaves > (1<<63)/uint64(arity) {
+			return 1 << 63
+		}
+		leaves *= uint64(arity)
+	}
+	return leaves
+}
+
+// proofFromLevels collects the siblings of the node at index on every level,
+// in the order of their group for trees wider than binary
+func proofFromLevels(levels [][][]byte, index int, arity int) [][]byte {
+	proof := make([][]byte, 0, (len(levels)-1)*(arity-1))
+	for _, nodes := range levels[:len(levels)-1] {
+		if arity > DefaultArity {
+			first := index - index%arity
+			for k := first; k < first+arity; k++ {
+				if k == index {
+					continue
+				}
+				// A short last group is filled up with its last node
+				proof = append(proof, nodes[min(k, len(nodes)-1)])
+			}
+			index /= arity
+			continue
+		}
+		sibling := index ^ 1
+		if sibling >= len(nodes) {
+			// The last odd node is paired with itself
+			sibling = index
+		}
+		proof = append(proof, nodes[sibling])
+		index /= 2
+	}
+	return proof
+}
@@ -0,0 +1,72 @@
+package merkle
+
+import (
+	"fmt"
+
+	"merkle-cli/models"
+)
+
+// HashKeccak256 is the node hash of every tree
+const HashKeccak256 = "keccak256"
+
+// DefaultTreeOptions returns the options OneSig trees are built with: leaves
+// sorted by hash, sorted pairs, the last odd node paired with itself and keccak256
+func DefaultTreeOptions() models.TreeOptions {
+	return TreeOptionsFor(DefaultPairOrder)
+}
+
+// TreeOptionsFor returns the default tree options with the given pair order
+func TreeOptionsFor(order PairOrder) models.TreeOptions {
+	return TreeOptionsWith(order, DefaultLeafOrder)
+}
+
+// TreeOptionsWith returns the default tree options with the given pair and
+// leaf orders. The leaf order is only recorded for unsorted leaves, so the
+// options of sorted trees are those written before leaf orders existed.
+func TreeOptionsWith(order PairOrder, leafOrder LeafOrder) models.TreeOptions {
+	options := models.TreeOptions{
+		PairOrder:    string(order),
+		SortLeaves:   leafOrder == LeafOrderSorted,
+		DuplicateOdd: true,
+		Hash:         HashKeccak256,
+	}
+	if leafOrder != LeafOrderSorted {
+		options.LeafOrder = string(leafOrder)
+	}
+	return options
+}
+
+// CheckTreeOptions validates tree options and returns their pair order. Nil
+// options, as in proofs files written before options were recorded, are the
+// defaults.
+func CheckTreeOptions(opts *models.TreeOptions) (PairOrder, error) {
+	if opts == nil {
+		return DefaultPairOrder, nil
+	}
+	order, err := ParsePairOrder(opts.PairOrder)
+	if err != nil {
+		return "", err
+	}
+	leafOrder, err := ParseLeafOrder(opts.LeafOrder)
+	if err != nil {
+		return "", err
+	}
+	if opts.SortLeaves != (leafOrder == LeafOrderSorted) {
+		if !opts.SortLeaves && opts.LeafOrder == "" {
+			return "", fmt.Errorf("unsupported tree options: unsorted leaves without a leaf order")
+		}
+		return "", fmt.Errorf("unsupported tree options: sortLeaves %t with leaf order %s", opts.SortLeaves, leafOrder)
+	}
+	if !opts.DuplicateOdd {
+		return "", fmt.Errorf("unsupported tree options: odd nodes must be paired with themselves")
+	}
+	if opts.Hash != HashKeccak256 {
+		return "", fmt.Errorf("unsupported tree options: hash %q", opts.Hash)
+	}
+	if opts.Arity != 0 {
+		if err := CheckArity(opts.Arity); err != nil {
+			return "", fmt.Errorf("unsupported tree options: %w", err)
+		}
+	}
+	return order, nil
+}
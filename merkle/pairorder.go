@@ -0,0 +1,139 @@
+package merkle
+
+import (
+	"bytes"
+	"fmt"
+
+	"merkle-cli/models"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// PairOrder selects how two sibling nodes are ordered before they are hashed
+type PairOrder string
+
+const (
+	// PairOrderSorted hashes the smaller node first. This is the order the
+	// OneSig contract verifies proofs with.
+	PairOrderSorted PairOrder = "sorted"
+	// PairOrderPositional hashes the left node first, so proofs depend on the
+	// leaf index. Roots built this way cannot be verified by OneSig.
+	PairOrderPositional PairOrder = "positional"
+)
+
+// DefaultPairOrder is the pair order used unless one is chosen explicitly
+const DefaultPairOrder = PairOrderSorted
+
+// ParsePairOrder validates a pair order name, treating "" as the default
+func ParsePairOrder(s string) (PairOrder, error) {
+	switch PairOrder(s) {
+	case "":
+		return DefaultPairOrder, nil
+	case PairOrderSorted, PairOrderPositional:
+		return PairOrder(s), nil
+	default:
+		return "", fmt.Errorf("unsupported pair order %q, expected %s or %s", s, PairOrderSorted, PairOrderPositional)
+	}
+}
+
+// hash hashes the left and right children of a node
+func (o PairOrder) hash(left, right []byte) []byte {
+	if o == PairOrderPositional {
+		concat := make([]byte, 0, len(left)+len(right))
+		concat = append(concat, left...)
+		return crypto.Keccak256(append(concat, right...))
+	}
+	return hashPair(left, right)
+}
+
+// arrange returns two children in the order they are hashed
+func (o PairOrder) arrange(left, right []byte) ([]byte, []byte) {
+	if o != PairOrderPositional && bytes.Compare(left, right) > 0 {
+		return right, left
+	}
+	return left, right
+}
+
+// PairTrace is one pair of nodes hashed while building a tree. Level is the
+// level of the children, 0 for the leaves, and Index the position of their
+// parent on the next level. Left and Right are in the order they are hashed;
+// in trees wider than binary Children holds every child in that order instead.
+type PairTrace struct {
+	Level    int
+	Index    int
+	Left     []byte
+	Right    []byte
+	Children [][]byte
+	Hash     []byte
+}
+
+// VerifyProofAt verifies a proof of the leaf at index using the given pair
+// order. For sorted pairs the index is ignored, as in VerifyProof.
+func VerifyProofAt(root, leaf []byte, proof [][]byte, index int, order PairOrder) bool {
+	return bytes.Equal(order.rootFromProof(leaf, proof, index), root)
+}
+
+// ComputeRootFromProof derives the root of a tree from one leaf hash, its
+// proof and its index, without the rest of the tree. Options are those
+// recorded with the proof, or nil for the defaults; the index only matters
+// for positional pair order.
+func ComputeRootFromProof(leaf []byte, proof [][]byte, index int, options *models.TreeOptions) ([]byte, error) {
+	order, err := CheckTreeOptions(options)
+	if err != nil {
+		return nil, err
+	}
+	if index < 0 {
+		return nil, fmt.Errorf("invalid leaf index %d", index)
+	}
+	arity := ArityOf(options)
+	if arity == DefaultArity {
+		return order.rootFromProof(leaf, proof, index), nil
+	}
+	if len(proof)%(arity-1) != 0 {
+		return nil, fmt.Errorf("proof of %d hashes is not a whole number of levels of %d siblings", len(proof), arity-1)
+	}
+	return rootFromSteps(leaf, TraceProofArity(leaf, proof, index, order, arity)), nil
+}
+
+// rootFromProof hashes the leaf with each sibling of its proof in turn
+func (o PairOrder) rootFromProof(leaf []byte, proof [][]byte, index int) []byte {
+	currentHash := leaf
+	for _, step := range TraceProof(leaf, proof, index, o) {
+		currentHash = step.Parent
+	}
+	return currentHash
+}
+
+// ProofStep is one hash round of a proof: the current node combined with its
+// sibling, in the order they are hashed. Rounds of trees wider than binary
+// hold the siblings and every child in hashing order instead.
+type ProofStep struct {
+	Node     []byte
+	Sibling  []byte
+	Left     []byte
+	Right    []byte
+	Siblings [][]byte
+	Children [][]byte
+	Parent   []byte
+}
+
+// TraceProof returns every hash round of deriving the root from a leaf and its proof
+func TraceProof(leaf []byte, proof [][]byte, index int, order PairOrder) []ProofStep {
+	steps := make([]ProofStep, 0, len(proof))
+	currentHash := leaf
+	for _, sibling := range proof {
+		left, right := currentHash, sibling
+		swap := bytes.Compare(left, right) > 0
+		if order == PairOrderPositional {
+			swap = index%2 == 1
+		}
+		if swap {
+			left, right = right, left
+		}
+		parent := order.hash(left, right)
+		steps = append(steps, ProofStep{Node: currentHash, Sibling: sibling, Left: left, Right: right, Parent: parent})
+		currentHash = parent
+		index /= 2
+	}
+	return steps
+}
@@ -0,0 +1,86 @@
+package merkle
+
+import (
+	"bytes"
+	"context"
+	"sort"
+)
+
+// SubtreeRoot is the root of the tree of the leaves of one OneSig ID, built
+// with the pair order of the whole tree from its leaves in tree order
+type SubtreeRoot struct {
+	OneSigID  uint64
+	Root      []byte
+	LeafCount int
+	// Node locates the root in the whole tree when the leaves of the OneSig ID
+	// form one of its subtrees, and is nil otherwise
+	Node *SubtreeNode
+}
+
+// SubtreeNode is a node of a tree and its proof against the root. Level is 0
+// for the leaves and Index the position of the node on its level.
+type SubtreeNode struct {
+	Level int
+	Index int
+	Proof [][]byte
+}
+
+// SubtreeRoots computes the subtree root of every OneSig ID of the entries of
+// the tree, in OneSig ID order. Leaves ordered by-onesig-nonce are contiguous,
+// so the subtree roots of OneSig IDs starting at a multiple of their subtree
+// width are nodes of the tree, proven against its root by their Node.
+func (m *MerkleTree) SubtreeRoots(ctx context.Context, entries []Entry, order PairOrder, workers int) ([]SubtreeRoot, error) {
+	levels, err := buildLevels(ctx, m.Leafs, order, DefaultArity, nil, workers)
+	if err != nil {
+		return nil, err
+	}
+
+	positions := make(map[uint64][]int)
+	var ids []uint64
+	for _, entry := range entries {
+		id := entry.Leaf.OneSigID
+		if _, ok := positions[id]; !ok {
+			ids = append(ids, id)
+		}
+		positions[id] = append(positions[id], entry.Index)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	subtrees := make([]SubtreeRoot, 0, len(ids))
+	for _, id := range ids {
+		indices := positions[id]
+		sort.Ints(indices)
+		leaves := make([][]byte, len(indices))
+		for i, index := range indices {
+			leaves[i] = levels[0][index]
+		}
+		sub, err := buildLevels(ctx, leaves, order, DefaultArity, nil, workers)
+		if err != nil {
+			return nil, err
+		}
+		subtree := SubtreeRoot{OneSigID: id, Root: sub[len(sub)-1][0], LeafCount: len(leaves)}
+		if node := subtreeNode(levels, indices); node != nil && bytes.Equal(levels[node.Level][node.Index], subtree.Root) {
+			subtree.Node = node
+		}
+		subtrees = append(subtrees, subtree)
+	}
+	return subtrees, nil
+}
+
+// subtreeNode returns the node of the tree whose leaves are exactly those at
+// the sorted positions, or nil if there is none
+func subtreeNode(levels [][][]byte, positions []int) *SubtreeNode {
+	start, count := positions[0], len(positions)
+	if positions[count-1]-start+1 != count {
+		return nil
+	}
+	level := TreeDepth(count)
+	width := 1 << level
+	// The last subtree of a tree may be short of leaves: its odd nodes are
+	// paired with themselves, as in a tree of its own
+	if start%width != 0 || (count != width && start+count != len(levels[0])) {
+		return nil
+	}
+	index := start >> level
+	return &SubtreeNode{Level: level, Index: index, Proof: proofFromLevels(levels[level:], index, DefaultArity)}
+}
@@ -2,6 +2,7 @@ package merkle
 
 import (
 	"bytes"
+	"context"
 	"encoding/hex"
 	"fmt"
 	"sort"
@@ -9,10 +10,17 @@ import (
 	"github.com/ethereum/go-ethereum/crypto"
 )
 
-// MerkleTree implements a binary Merkle tree
+// MerkleTree implements a binary or k-ary Merkle tree. A tree is immutable
+// once built: its methods only read it and may be called from many goroutines
+// at once, as long as nobody modifies its fields. Slices returned by its
+// methods are copies owned by the caller.
 type MerkleTree struct {
 	Root  []byte
 	Leafs [][]byte
+	// Order and Arity are the pair order and arity the tree was built with,
+	// sorted and binary when empty
+	Order PairOrder `json:",omitempty"`
+	Arity int       `json:",omitempty"`
 }
 
 // NewMerkleTree creates a new Merkle tree from a set of leaves
@@ -76,12 +84,14 @@ func hashPair(left, right []byte) []byte {
 		left, right = right, left
 	}
 
-	// Concatenate and hash
-	concat := append(left, right...)
-	return crypto.Keccak256(concat)
+	// Hash the concatenation without appending to left, which may have spare
+	// capacity shared with other nodes. For 32 byte nodes this is both
+	// abi.encodePacked and abi.encode of the pair.
+	return crypto.Keccak256(left, right)
 }
 
-// VerifyProof verifies a Merkle proof for a specific leaf
+// VerifyProof verifies a Merkle proof for a specific leaf of a sorted binary
+// tree. Proofs of other trees are verified with MerkleTree.Verify.
 func VerifyProof(root []byte, leaf []byte, proof [][]byte) bool {
 	currentHash := leaf
 
@@ -92,22 +102,54 @@ func VerifyProof(root []byte, leaf []byte, proof [][]byte) bool {
 	return bytes.Equal(currentHash, root)
 }
 
-// GenerateProof generates a Merkle proof for a specific leaf
+// GenerateProof generates a Merkle proof for a specific leaf, in the pair
+// order and arity of the tree
 func (m *MerkleTree) GenerateProof(leaf []byte) ([][]byte, error) {
-	// Find the leaf index
-	leafIndex := -1
-	for i, l := range m.Leafs {
-		if bytes.Equal(l, leaf) {
-			leafIndex = i
-			break
-		}
+	leafIndex := m.IndexOf(leaf)
+	if leafIndex == -1 {
+		return nil, ErrLeafNotFound
 	}
 
-	if leafIndex == -1 {
-		return nil, fmt.Errorf("leaf not found in tree")
+	if m.order() == PairOrderSorted && m.arity() == DefaultArity {
+		return cloneHashes(generateProofHelper(m.Leafs, leafIndex)), nil
+	}
+	levels, err := buildLevels(context.Background(), m.Leafs, m.order(), m.arity(), nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	return cloneHashes(proofFromLevels(levels, leafIndex, m.arity())), nil
+}
+
+// Verify verifies the proof of the leaf at index against the root of the
+// tree, in the pair order and arity of the tree
+func (m *MerkleTree) Verify(leaf []byte, proof [][]byte, index int) bool {
+	return VerifyProofArity(m.Root, leaf, proof, index, m.order(), m.arity())
+}
+
+// order returns the pair order of the tree
+func (m *MerkleTree) order() PairOrder {
+	if m.Order == "" {
+		return PairOrderSorted
+	}
+	return m.Order
+}
+
+// arity returns the arity of the tree
+func (m *MerkleTree) arity() int {
+	if m.Arity == 0 {
+		return DefaultArity
 	}
+	return m.Arity
+}
 
-	return generateProofHelper(m.Leafs, leafIndex), nil
+// IndexOf returns the position of a leaf in the tree, or -1 if it is not present
+func (m *MerkleTree) IndexOf(leaf []byte) int {
+	for i, l := range m.Leafs {
+		if bytes.Equal(l, leaf) {
+			return i
+		}
+	}
+	return -1
 }
 
 // generateProofHelper recursively builds the proof for a leaf at a given index
@@ -146,6 +188,15 @@ func generateProofHelper(nodes [][]byte, index int) [][]byte {
 	return append(proof, generateProofHelper(nextLevel, nextIndex)...)
 }
 
+// cloneHashes deep copies a list of hashes
+func cloneHashes(hashes [][]byte) [][]byte {
+	clones := make([][]byte, len(hashes))
+	for i, hash := range hashes {
+		clones[i] = bytes.Clone(hash)
+	}
+	return clones
+}
+
 // GetRootHex returns the root hash as a hexadecimal string
 func (m *MerkleTree) GetRootHex() string {
 	return "0x" + hex.EncodeToString(m.Root)
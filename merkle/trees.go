@@ -0,0 +1,165 @@
+package merkle
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"merkle-cli/mmr"
+)
+
+// Tree is a construction committing to a growing list of leaf hashes, so
+// callers can build, prove and verify without knowing which one they hold.
+// It is implemented by LevelTree, for binary and k-ary trees, and by
+// MountainRange.
+type Tree interface {
+	// Root returns the root of the leaves appended so far, nil without leaves
+	Root() []byte
+	// Proof returns the proof of the leaf at index
+	Proof(index int) ([][]byte, error)
+	// Verify reports whether proof shows that leaf is the leaf at index of a
+	// tree of the same kind and size with the given root
+	Verify(root, leaf []byte, proof [][]byte, index int) bool
+	// Append adds a leaf and returns its index
+	Append(leaf []byte) int
+}
+
+var (
+	_ Tree = (*LevelTree)(nil)
+	_ Tree = (*MountainRange)(nil)
+)
+
+// LevelTree is a binary or k-ary tree keeping every level, fed with leaves in
+// the order they are appended. OneSig trees append leaves sorted with
+// SortLeaves. Appending rehashes the last node of every level only.
+type LevelTree struct {
+	order  PairOrder
+	arity  int
+	levels [][][]byte
+}
+
+// NewLevelTree builds the tree of leaves with the given pair order and arity.
+// Without leaves it is an empty tree, which still verifies proofs.
+func NewLevelTree(ctx context.Context, leaves [][]byte, order PairOrder, arity int) (*LevelTree, error) {
+	if err := CheckArity(arity); err != nil {
+		return nil, err
+	}
+	return newLevelTree(ctx, cloneHashes(leaves), order, arity, nil, 0)
+}
+
+// newLevelTree builds the tree of leaves, which it takes ownership of, tracing
+// and hashing large levels like buildLevels. Generation builds its trees here.
+func newLevelTree(ctx context.Context, leaves [][]byte, order PairOrder, arity int, trace func(PairTrace), workers int) (*LevelTree, error) {
+	t := &LevelTree{order: order, arity: arity}
+	if len(leaves) > 0 {
+		levels, err := buildLevels(ctx, leaves, order, arity, trace, workers)
+		if err != nil {
+			return nil, err
+		}
+		t.levels = levels
+	}
+	return t, nil
+}
+
+// Root implements Tree
+func (t *LevelTree) Root() []byte {
+	if len(t.levels) == 0 {
+		return nil
+	}
+	return bytes.Clone(t.levels[len(t.levels)-1][0])
+}
+
+// Proof implements Tree
+func (t *LevelTree) Proof(index int) ([][]byte, error) {
+	if len(t.levels) == 0 || index < 0 || index >= len(t.levels[0]) {
+		return nil, fmt.Errorf("%w: leaf index %d", ErrLeafNotFound, index)
+	}
+	return cloneHashes(t.proof(index)), nil
+}
+
+// proof returns the proof of the leaf at index, sharing the tree's nodes
+func (t *LevelTree) proof(index int) [][]byte {
+	return proofFromLevels(t.levels, index, t.arity)
+}
+
+// Verify implements Tree
+func (t *LevelTree) Verify(root, leaf []byte, proof [][]byte, index int) bool {
+	return VerifyProofArity(root, leaf, proof, index, t.order, t.arity)
+}
+
+// Append implements Tree
+func (t *LevelTree) Append(leaf []byte) int {
+	if len(t.levels) == 0 {
+		t.levels = [][][]byte{{}}
+	}
+	t.levels[0] = append(t.levels[0], bytes.Clone(leaf))
+	buf := make([][]byte, t.arity)
+	for level := 0; len(t.levels[level]) > 1; level++ {
+		if level+1 == len(t.levels) {
+			t.levels = append(t.levels, nil)
+		}
+		// Only the group of the last node changes, or a new one starts
+		nodes := t.levels[level]
+		parent := (len(nodes) - 1) / t.arity
+		var hash []byte
+		if t.arity > DefaultArity {
+			children := groupAt(nodes, parent, t.arity, buf)
+			t.order.arrangeGroup(children)
+			hash = hashGroup(children)
+		} else {
+			hash = t.order.hash(t.order.arrange(pairAt(nodes, parent)))
+		}
+		if parent < len(t.levels[level+1]) {
+			t.levels[level+1][parent] = hash
+		} else {
+			t.levels[level+1] = append(t.levels[level+1], hash)
+		}
+	}
+	return len(t.levels[0]) - 1
+}
+
+// MountainRange adapts an mmr.MMR to Tree. Its proofs are the siblings of the
+// leaf up to the peak of its mountain followed by the other peaks, and verify
+// against an MMR of the same number of leaves.
+type MountainRange struct {
+	*mmr.MMR
+}
+
+// NewMountainRange returns an empty Merkle mountain range
+func NewMountainRange() *MountainRange {
+	return &MountainRange{MMR: &mmr.MMR{}}
+}
+
+// Proof implements Tree
+func (m *MountainRange) Proof(index int) ([][]byte, error) {
+	if index < 0 {
+		return nil, fmt.Errorf("%w: leaf index %d", ErrLeafNotFound, index)
+	}
+	proof, err := m.Prove(uint64(index))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrLeafNotFound, err)
+	}
+	return append(cloneHashes(proof.Siblings), cloneHashes(proof.Peaks)...), nil
+}
+
+// Verify implements Tree
+func (m *MountainRange) Verify(root, leaf []byte, proof [][]byte, index int) bool {
+	if index < 0 {
+		return false
+	}
+	siblings := mmr.MountainHeight(uint64(index), m.LeafCount())
+	if siblings < 0 || siblings > len(proof) {
+		return false
+	}
+	return mmr.Verify(root, leaf, &mmr.Proof{
+		LeafIndex: uint64(index),
+		LeafCount: m.LeafCount(),
+		Siblings:  proof[:siblings],
+		Peaks:     proof[siblings:],
+	}) == nil
+}
+
+// Append implements Tree
+func (m *MountainRange) Append(leaf []byte) int {
+	return int(m.MMR.Append(bytes.Clone(leaf)))
+}
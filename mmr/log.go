@@ -0,0 +1,128 @@
+package mmr
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math/bits"
+	"os"
+	"path/filepath"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// LogFormatVersion is the version of the log file layout
+const LogFormatVersion = 1
+
+// LogLeaf records the operation a leaf of the log authorizes
+type LogLeaf struct {
+	OneSigID uint64 `json:"oneSigId"`
+	Nonce    uint64 `json:"nonce"`
+	LeafHash string `json:"leafHash"`
+}
+
+// Log is an MMR kept in a JSON file, with the operation of every leaf. All
+// leaves of a log share one leaf encoding version.
+type Log struct {
+	path          string
+	mmr           *MMR
+	index         map[string]uint64
+	FormatVersion int       `json:"formatVersion"`
+	LeafVersion   uint8     `json:"leafVersion"`
+	Leaves        []LogLeaf `json:"leaves"`
+	Nodes         []string  `json:"nodes"`
+}
+
+// OpenLog reads the log at path, or returns an empty log for leafVersion if
+// it does not exist yet. The nodes are rehashed, so a tampered log is rejected.
+func OpenLog(path string, leafVersion uint8) (*Log, error) {
+	l := &Log{path: path, mmr: &MMR{}, index: make(map[string]uint64), FormatVersion: LogFormatVersion, LeafVersion: leafVersion}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read MMR log: %w", err)
+	}
+	if err := json.Unmarshal(data, l); err != nil {
+		return nil, fmt.Errorf("failed to parse MMR log %s: %w", path, err)
+	}
+	if l.FormatVersion != LogFormatVersion {
+		return nil, fmt.Errorf("unsupported MMR log format version %d in %s", l.FormatVersion, path)
+	}
+
+	nodes := make([][]byte, len(l.Nodes))
+	for i, node := range l.Nodes {
+		if nodes[i], err = hexutil.Decode(node); err != nil {
+			return nil, fmt.Errorf("invalid node %d of MMR log %s: %w", i, path, err)
+		}
+	}
+	if l.mmr, err = New(nodes); err != nil {
+		return nil, fmt.Errorf("invalid MMR log %s: %w", path, err)
+	}
+	if uint64(len(l.Leaves)) != l.mmr.LeafCount() {
+		return nil, fmt.Errorf("invalid MMR log %s: %d leaves recorded for %d leaf nodes", path, len(l.Leaves), l.mmr.LeafCount())
+	}
+	for i, leaf := range l.Leaves {
+		node := hexutil.Encode(nodes[leafPosition(uint64(i))])
+		if leaf.LeafHash != node {
+			return nil, fmt.Errorf("invalid MMR log %s: leaf %d is recorded as %s but its node is %s", path, i, leaf.LeafHash, node)
+		}
+		l.index[node] = uint64(i)
+	}
+	return l, nil
+}
+
+// MMR returns the MMR of the log
+func (l *Log) MMR() *MMR {
+	return l.mmr
+}
+
+// Lookup returns the index of the leaf with the given hash
+func (l *Log) Lookup(hash []byte) (uint64, bool) {
+	index, ok := l.index[hexutil.Encode(hash)]
+	return index, ok
+}
+
+// Append adds a leaf to the log and returns its leaf index
+func (l *Log) Append(oneSigID, nonce uint64, hash []byte) uint64 {
+	start := len(l.mmr.Nodes())
+	index := l.mmr.Append(hash)
+	for _, node := range l.mmr.Nodes()[start:] {
+		l.Nodes = append(l.Nodes, hexutil.Encode(node))
+	}
+	leafHash := hexutil.Encode(hash)
+	l.Leaves = append(l.Leaves, LogLeaf{OneSigID: oneSigID, Nonce: nonce, LeafHash: leafHash})
+	l.index[leafHash] = index
+	return index
+}
+
+// Save writes the log back to its file
+func (l *Log) Save() error {
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode MMR log: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(l.path), filepath.Base(l.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write MMR log: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write MMR log: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write MMR log: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), l.path); err != nil {
+		return fmt.Errorf("failed to write MMR log: %w", err)
+	}
+	return nil
+}
+
+// leafPosition returns the postorder position of the leaf at index
+func leafPosition(index uint64) uint64 {
+	return 2*index - uint64(bits.OnesCount64(index))
+}
@@ -0,0 +1,229 @@
+// Package mmr builds Merkle mountain ranges: append-only accumulators over a
+// growing log of leaf hashes. An MMR is a list of perfect binary trees, the
+// mountains, of strictly decreasing height; appending a leaf merges the
+// mountains of equal height on its left, so earlier nodes never change.
+//
+// Nodes are kept in postorder, parents are keccak256(left || right), and the
+// root bags the peaks from right to left and commits to the number of leaves:
+//
+//	bag  = keccak256(peak[0] || keccak256(peak[1] || ... peak[n-1]))
+//	root = keccak256(uint64 leafCount || bag)
+package mmr
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/bits"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// ErrInvalidProof is returned when an MMR proof does not lead to the expected root
+var ErrInvalidProof = errors.New("invalid MMR proof")
+
+// MMR is a Merkle mountain range over leaf hashes
+type MMR struct {
+	nodes  [][]byte
+	leaves uint64
+}
+
+// Proof shows a leaf is the leaf at LeafIndex of an MMR of LeafCount leaves.
+// Siblings lead from the leaf to the peak of its mountain, Peaks are the
+// other peaks from left to right.
+type Proof struct {
+	LeafIndex uint64
+	LeafCount uint64
+	Siblings  [][]byte
+	Peaks     [][]byte
+}
+
+// New returns an MMR holding the given nodes in postorder, as returned by Nodes
+func New(nodes [][]byte) (*MMR, error) {
+	m := &MMR{}
+	for pos := uint64(0); pos < uint64(len(nodes)); {
+		// A leaf is followed by one parent per mountain it merges with
+		next := pos + uint64(bits.TrailingZeros64(^m.leaves)) + 1
+		if next > uint64(len(nodes)) {
+			return nil, fmt.Errorf("%d nodes do not form a Merkle mountain range", len(nodes))
+		}
+		m.Append(nodes[pos])
+		for i := pos + 1; i < next; i++ {
+			if !bytes.Equal(m.nodes[i], nodes[i]) {
+				return nil, fmt.Errorf("node %d is not the hash of its children", i)
+			}
+		}
+		pos = next
+	}
+	return m, nil
+}
+
+// Nodes returns every node in postorder
+func (m *MMR) Nodes() [][]byte {
+	return m.nodes
+}
+
+// LeafCount returns the number of leaves appended
+func (m *MMR) LeafCount() uint64 {
+	return m.leaves
+}
+
+// Append adds a leaf hash and returns its leaf index
+func (m *MMR) Append(leaf []byte) uint64 {
+	index := m.leaves
+	m.nodes = append(m.nodes, leaf)
+	// Merge with one mountain per trailing one bit of the index, lowest first
+	for height := 0; height < bits.TrailingZeros64(^index); height++ {
+		right := m.nodes[len(m.nodes)-1]
+		left := m.nodes[len(m.nodes)-1-(1<<(height+1)-1)]
+		m.nodes = append(m.nodes, hashPair(left, right))
+	}
+	m.leaves++
+	return index
+}
+
+// Peaks returns the peaks of the mountains from left to right
+func (m *MMR) Peaks() [][]byte {
+	var peaks [][]byte
+	var pos uint64
+	for _, height := range mountains(m.leaves) {
+		pos += 1<<(height+1) - 1
+		peaks = append(peaks, m.nodes[pos-1])
+	}
+	return peaks
+}
+
+// Root returns the root of the MMR, or nil if it is empty
+func (m *MMR) Root() []byte {
+	return bagPeaks(m.Peaks(), m.leaves)
+}
+
+// Prove returns the proof of the leaf at index
+func (m *MMR) Prove(index uint64) (*Proof, error) {
+	if index >= m.leaves {
+		return nil, fmt.Errorf("leaf index %d out of range, the MMR has %d leaves", index, m.leaves)
+	}
+	proof := &Proof{LeafIndex: index, LeafCount: m.leaves}
+	var pos, first uint64
+	for _, height := range mountains(m.leaves) {
+		size := uint64(1)<<(height+1) - 1
+		width := uint64(1) << height
+		if index < first || index >= first+width {
+			proof.Peaks = append(proof.Peaks, m.nodes[pos+size-1])
+			pos, first = pos+size, first+width
+			continue
+		}
+		// Descend from the peak, collecting the sibling at every level
+		base, local := pos, index-first
+		siblings := make([][]byte, height)
+		for h := height; h > 0; h-- {
+			half := uint64(1) << (h - 1)
+			leftSize := uint64(1)<<h - 1
+			if local < half {
+				siblings[h-1] = m.nodes[base+2*leftSize-1]
+			} else {
+				siblings[h-1] = m.nodes[base+leftSize-1]
+				base += leftSize
+				local -= half
+			}
+		}
+		proof.Siblings = siblings
+		pos, first = pos+size, first+width
+	}
+	return proof, nil
+}
+
+// Verify checks that leaf and proof lead to root
+func Verify(root, leaf []byte, proof *Proof) error {
+	computed, err := ComputeRoot(leaf, proof)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(computed, root) {
+		return fmt.Errorf("%w: leaf %d leads to root 0x%x, expected 0x%x", ErrInvalidProof, proof.LeafIndex, computed, root)
+	}
+	return nil
+}
+
+// ComputeRoot returns the root that leaf and proof lead to
+func ComputeRoot(leaf []byte, proof *Proof) ([]byte, error) {
+	if proof.LeafIndex >= proof.LeafCount {
+		return nil, fmt.Errorf("%w: leaf index %d out of range of %d leaves", ErrInvalidProof, proof.LeafIndex, proof.LeafCount)
+	}
+	heights := mountains(proof.LeafCount)
+	if len(proof.Peaks) != len(heights)-1 {
+		return nil, fmt.Errorf("%w: %d other peaks, expected %d for %d leaves", ErrInvalidProof, len(proof.Peaks), len(heights)-1, proof.LeafCount)
+	}
+
+	var first uint64
+	for i, height := range heights {
+		width := uint64(1) << height
+		if proof.LeafIndex >= first+width {
+			first += width
+			continue
+		}
+		if len(proof.Siblings) != height {
+			return nil, fmt.Errorf("%w: %d siblings, expected %d for leaf %d", ErrInvalidProof, len(proof.Siblings), height, proof.LeafIndex)
+		}
+		node, local := leaf, proof.LeafIndex-first
+		for level, sibling := range proof.Siblings {
+			if local>>level&1 == 1 {
+				node = hashPair(sibling, node)
+			} else {
+				node = hashPair(node, sibling)
+			}
+		}
+		peaks := make([][]byte, 0, len(heights))
+		peaks = append(peaks, proof.Peaks[:i]...)
+		peaks = append(peaks, node)
+		peaks = append(peaks, proof.Peaks[i:]...)
+		return bagPeaks(peaks, proof.LeafCount), nil
+	}
+	return nil, fmt.Errorf("%w: leaf %d is in no mountain", ErrInvalidProof, proof.LeafIndex)
+}
+
+// MountainHeight returns the height of the mountain holding the leaf at index
+// of an MMR of count leaves, which is the number of siblings in its proof, or
+// -1 if the index is out of range
+func MountainHeight(index, count uint64) int {
+	var first uint64
+	for _, height := range mountains(count) {
+		first += 1 << height
+		if index < first {
+			return height
+		}
+	}
+	return -1
+}
+
+// mountains returns the heights of the mountains of an MMR of n leaves, from
+// left to right: the set bits of n, highest first
+func mountains(n uint64) []int {
+	var heights []int
+	for height := 63; height >= 0; height-- {
+		if n>>height&1 == 1 {
+			heights = append(heights, height)
+		}
+	}
+	return heights
+}
+
+// bagPeaks folds the peaks from right to left and commits to the leaf count
+func bagPeaks(peaks [][]byte, leaves uint64) []byte {
+	if len(peaks) == 0 {
+		return nil
+	}
+	bag := peaks[len(peaks)-1]
+	for i := len(peaks) - 2; i >= 0; i-- {
+		bag = hashPair(peaks[i], bag)
+	}
+	var count [8]byte
+	binary.BigEndian.PutUint64(count[:], leaves)
+	return crypto.Keccak256(count[:], bag)
+}
+
+// hashPair returns keccak256(left || right)
+func hashPair(left, right []byte) []byte {
+	return crypto.Keccak256(left, right)
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"math/big"
+	"sort"
+	"strings"
+)
+
+// Aggregate summarizes the leaves of a batch, so dashboards can read its
+// totals without walking every leaf
+type Aggregate struct {
+	LeafCount int `json:"leafCount"`
+	CallCount int `json:"callCount"`
+	// TotalNativeValuePerOneSigID sums the value of the calls of each OneSig
+	// instance, keyed by its OneSig ID
+	TotalNativeValuePerOneSigID map[uint64]*big.Int `json:"totalNativeValuePerOneSigId"`
+	// Targets counts the calls to every distinct target, ordered by target
+	Targets []TargetCount `json:"targets"`
+}
+
+// TargetCount is the number of calls made to one target. Targets are compared
+// in lower case.
+type TargetCount struct {
+	Target string `json:"target"`
+	Calls  int    `json:"calls"`
+}
+
+// AggregateLeaves computes the aggregate of the given leaves
+func AggregateLeaves(leaves []Leaf) *Aggregate {
+	aggregate := &Aggregate{
+		LeafCount:                   len(leaves),
+		TotalNativeValuePerOneSigID: make(map[uint64]*big.Int),
+		Targets:                     []TargetCount{},
+	}
+	calls := make(map[string]int)
+	for _, leaf := range leaves {
+		total, ok := aggregate.TotalNativeValuePerOneSigID[leaf.OneSigID]
+		if !ok {
+			total = new(big.Int)
+			aggregate.TotalNativeValuePerOneSigID[leaf.OneSigID] = total
+		}
+		aggregate.CallCount += len(leaf.Calls)
+		for _, call := range leaf.Calls {
+			if call.Value != nil {
+				total.Add(total, call.Value)
+			}
+			calls[strings.ToLower(call.To)]++
+		}
+	}
+	for target, count := range calls {
+		aggregate.Targets = append(aggregate.Targets, TargetCount{Target: target, Calls: count})
+	}
+	sort.Slice(aggregate.Targets, func(i, j int) bool {
+		return aggregate.Targets[i].Target < aggregate.Targets[j].Target
+	})
+	return aggregate
+}
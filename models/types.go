@@ -4,11 +4,60 @@ import (
 	"math/big"
 )
 
+// Annotation is descriptive metadata for reviewers. It is carried through to
+// proofs files and reports but never encoded into a leaf.
+type Annotation struct {
+	Description string   `json:"description,omitempty"`
+	Labels      []string `json:"labels,omitempty"`
+}
+
+// IsZero reports whether the annotation is empty
+func (a Annotation) IsZero() bool {
+	return a.Description == "" && len(a.Labels) == 0
+}
+
 // Call represents a single call to be executed
 type Call struct {
 	To    string   `json:"to"`
 	Value *big.Int `json:"value"`
 	Data  string   `json:"data"`
+	// Operation is OperationCall or OperationDelegateCall; empty means a call.
+	// It is encoded from leaf encoding version 3.
+	Operation string `json:"operation,omitempty"`
+	// ToName is the address book or ENS name To was resolved from, if any
+	ToName string `json:"toName,omitempty"`
+	// Implementation is the implementation a proxy target had when the batch
+	// was prepared, checked by lint. It is never encoded.
+	Implementation string `json:"implementation,omitempty"`
+	// CodeHash pins the extcodehash of the target; EOA marks a transfer to an
+	// account without code. Both are checked against the chain, never encoded.
+	CodeHash string `json:"codeHash,omitempty"`
+	EOA      bool   `json:"eoa,omitempty"`
+	// Timelock is the timelock operation the call schedules or executes, as
+	// computed by the timelock command. It is never encoded.
+	Timelock *TimelockOperation `json:"timelock,omitempty"`
+	Annotation
+}
+
+// TimelockOperation describes a call to a TimelockController. Delay is the
+// delay requested by a schedule; ETA is the earliest time the operation can
+// execute, known when the scheduling leaf has a validAfter.
+type TimelockOperation struct {
+	Kind        string `json:"kind"`
+	OperationID string `json:"operationId"`
+	Delay       uint64 `json:"delay,omitempty"`
+	ETA         uint64 `json:"eta,omitempty"`
+}
+
+// Call operations
+const (
+	OperationCall         = "call"
+	OperationDelegateCall = "delegatecall"
+)
+
+// IsDelegateCall reports whether the call is made with delegatecall
+func (c Call) IsDelegateCall() bool {
+	return c.Operation == OperationDelegateCall
 }
 
 // Transaction represents a batch of calls to be executed atomically
@@ -17,13 +66,313 @@ type Transaction struct {
 	Calls []Call `json:"calls"`
 }
 
+// LegacyBatch is the transaction list format that preceded groups. Every
+// transaction carries its own nonce; transactions sharing a nonce form one leaf.
+type LegacyBatch struct {
+	Transactions []Transaction `json:"transactions"`
+}
+
+// Batch converts the legacy transactions into groups, concatenating the calls
+// of transactions that share a nonce in the order they appear
+func (b LegacyBatch) Batch() *TransactionBatch {
+	batch := &TransactionBatch{}
+	groups := make(map[uint64]int)
+	for _, tx := range b.Transactions {
+		if i, ok := groups[tx.Nonce]; ok {
+			batch.Groups[i].Calls = append(batch.Groups[i].Calls, tx.Calls...)
+			continue
+		}
+		groups[tx.Nonce] = len(batch.Groups)
+		batch.Groups = append(batch.Groups, TransactionGroup{
+			Nonce: tx.Nonce,
+			Calls: append([]Call(nil), tx.Calls...),
+		})
+	}
+	return batch
+}
+
 // TransactionGroup represents a group of calls that share the same nonce
 type TransactionGroup struct {
-	Nonce uint64 `json:"nonce"`
-	Calls []Call `json:"calls"`
+	Nonce      uint64        `json:"nonce"`
+	Calls      []Call        `json:"calls"`
+	ValidAfter uint64        `json:"validAfter,omitempty"`
+	ValidUntil uint64        `json:"validUntil,omitempty"`
+	Salt       string        `json:"salt,omitempty"`
+	Expect     *Expectations `json:"expect,omitempty"`
+	// DependsOn lists the nonces of groups that must execute before this one
+	DependsOn []uint64 `json:"dependsOn,omitempty"`
+	Annotation
 }
 
+// Format versions of the batch and output JSON documents. Documents without a
+// formatVersion predate versioning: batches listing transactions instead of
+// groups, and outputs that may lack leafEncodingVersion and treeOptions.
+const (
+	BatchFormatVersion  = 1
+	OutputFormatVersion = 1
+)
+
+// Call target formats. Address targets are 20 byte EVM addresses, left padded
+// to a word when encoded; bytes32 targets are 32 byte ids, such as the program
+// ids of non-EVM endpoints, encoded as they are.
+const (
+	TargetFormatAddress = "address"
+	TargetFormatBytes32 = "bytes32"
+)
+
 // TransactionBatch represents a collection of transaction groups to be merklized
 type TransactionBatch struct {
-	Groups []TransactionGroup `json:"groups"`
+	FormatVersion int `json:"formatVersion,omitempty"`
+	// TargetFormat is the format of every call target, address when empty
+	TargetFormat string             `json:"targetFormat,omitempty"`
+	Groups       []TransactionGroup `json:"groups"`
+	// Source records the spreadsheet the batch was read from. It is never encoded.
+	Source *BatchSource `json:"source,omitempty"`
+}
+
+// BatchSource identifies the spreadsheet and the revision of it a batch was read from
+type BatchSource struct {
+	Location string `json:"location"`
+	Revision string `json:"revision"`
+}
+
+// TotalValue returns the sum of the values of all calls
+func TotalValue(calls []Call) *big.Int {
+	total := new(big.Int)
+	for _, call := range calls {
+		if call.Value != nil {
+			total.Add(total, call.Value)
+		}
+	}
+	return total
+}
+
+// Leaf holds every field that is committed to by a single Merkle leaf.
+// Fields introduced by newer encoding versions are ignored by older ones,
+// so adding a field here never changes the hash of an existing leaf.
+// Expect is carried along for simulation and, like DependsOn and the
+// annotation, is never encoded.
+type Leaf struct {
+	OneSigID     uint64        `json:"oneSigId"`
+	ContractAddr string        `json:"contractAddr,omitempty"`
+	Nonce        uint64        `json:"nonce"`
+	Calls        []Call        `json:"calls"`
+	ValidAfter   uint64        `json:"validAfter,omitempty"`
+	ValidUntil   uint64        `json:"validUntil,omitempty"`
+	Salt         string        `json:"salt,omitempty"`
+	Expect       *Expectations `json:"expect,omitempty"`
+	DependsOn    []uint64      `json:"dependsOn,omitempty"`
+	// TargetFormat is the format of the call targets, address when empty.
+	// It selects how To is encoded.
+	TargetFormat string `json:"targetFormat,omitempty"`
+	Annotation
+}
+
+// Expectations describes the outcome a leaf must produce when simulated
+type Expectations struct {
+	Events   []ExpectedEvent   `json:"events,omitempty"`
+	Balances []ExpectedBalance `json:"balances,omitempty"`
+}
+
+// ExpectedEvent matches an event log emitted by one of the leaf's calls.
+// Signature, when set, is hashed into the first topic; empty topics match anything.
+type ExpectedEvent struct {
+	Address   string   `json:"address,omitempty"`
+	Signature string   `json:"signature,omitempty"`
+	Topics    []string `json:"topics,omitempty"`
+	Data      string   `json:"data,omitempty"`
+}
+
+// ExpectedBalance is the balance an account must hold once the leaf has executed.
+// Token selects an ERC-20 balance instead of the native balance.
+type ExpectedBalance struct {
+	Address string   `json:"address"`
+	Token   string   `json:"token,omitempty"`
+	Balance *big.Int `json:"balance"`
+}
+
+// HasBytes32Targets reports whether the leaf's calls target 32 byte ids
+func (l Leaf) HasBytes32Targets() bool {
+	return l.TargetFormat == TargetFormatBytes32
+}
+
+// HasValidityWindow reports whether the leaf restricts when it may be executed
+func (l Leaf) HasValidityWindow() bool {
+	return l.ValidAfter != 0 || l.ValidUntil != 0
+}
+
+// Leaf converts the group into a leaf for the given OneSig instance
+func (g TransactionGroup) Leaf(oneSigID uint64, contractAddr string) Leaf {
+	return Leaf{
+		OneSigID:     oneSigID,
+		ContractAddr: contractAddr,
+		Nonce:        g.Nonce,
+		Calls:        g.Calls,
+		ValidAfter:   g.ValidAfter,
+		ValidUntil:   g.ValidUntil,
+		Salt:         g.Salt,
+		Expect:       g.Expect,
+		DependsOn:    g.DependsOn,
+		Annotation:   g.Annotation,
+	}
+}
+
+// ProofEntry holds a leaf together with its hash and Merkle proof
+type ProofEntry struct {
+	Leaf
+	LeafIndex int      `json:"leafIndex"`
+	LeafHash  string   `json:"leafHash"`
+	Proof     []string `json:"proof"`
+}
+
+// RedactedProofEntry is a proof entry without the leaf's calls and metadata
+type RedactedProofEntry struct {
+	LeafIndex int      `json:"leafIndex"`
+	LeafHash  string   `json:"leafHash"`
+	Proof     []string `json:"proof"`
+}
+
+// TreeOptions records how a Merkle tree was built, so its proofs can be
+// verified without guessing
+type TreeOptions struct {
+	PairOrder    string `json:"pairOrder"`
+	SortLeaves   bool   `json:"sortLeaves"`
+	DuplicateOdd bool   `json:"duplicateOdd"`
+	Hash         string `json:"hash"`
+	// LeafOrder is the order of unsorted leaves, empty for sorted ones
+	LeafOrder string `json:"leafOrder,omitempty"`
+	// Arity is the number of children of every node, empty for binary trees
+	Arity int `json:"arity,omitempty"`
+}
+
+// OutputFormat is the JSON document describing a generated Merkle tree.
+// Files without tree options were built with the defaults. The aggregate
+// covers every leaf of the batch, also when only some proofs are written.
+type OutputFormat struct {
+	FormatVersion       int          `json:"formatVersion,omitempty"`
+	MerkleRoot          string       `json:"merkleRoot"`
+	LeafEncodingVersion uint8        `json:"leafEncodingVersion"`
+	HashEncoding        string       `json:"hashEncoding,omitempty"`
+	TreeOptions         *TreeOptions `json:"treeOptions,omitempty"`
+	TreeShape           *TreeShape   `json:"treeShape,omitempty"`
+	Aggregate           *Aggregate   `json:"aggregate,omitempty"`
+	// Subtrees holds the subtree root of every OneSig ID, when requested
+	Subtrees []SubtreeRoot `json:"subtrees,omitempty"`
+	Proofs   []ProofEntry  `json:"proofs"`
+}
+
+// SubtreeRoot is the root of the tree of the leaves of one OneSig ID. Node is
+// set when that tree is a subtree of the whole tree.
+type SubtreeRoot struct {
+	OneSigID  uint64       `json:"oneSigId"`
+	Root      string       `json:"root"`
+	LeafCount int          `json:"leafCount"`
+	Node      *SubtreeNode `json:"node,omitempty"`
+}
+
+// SubtreeNode locates a subtree root in the tree, Level 0 being the leaves,
+// and proves it against the root
+type SubtreeNode struct {
+	Level int      `json:"level"`
+	Index int      `json:"index"`
+	Proof []string `json:"proof"`
+}
+
+// TreeShape is the depth of a tree and the length of its longest proof, which
+// on-chain verifiers capping proof arrays must accept
+type TreeShape struct {
+	Depth          int `json:"depth"`
+	MaxProofLength int `json:"maxProofLength"`
+}
+
+// MMRProof proves a leaf of a Merkle mountain range log against its root.
+// Siblings lead from the leaf to the peak of its mountain, Peaks are the
+// other peaks from left to right.
+type MMRProof struct {
+	MMRRoot             string   `json:"mmrRoot"`
+	LeafEncodingVersion uint8    `json:"leafEncodingVersion"`
+	HashEncoding        string   `json:"hashEncoding,omitempty"`
+	LeafCount           uint64   `json:"leafCount"`
+	LeafIndex           uint64   `json:"leafIndex"`
+	OneSigID            uint64   `json:"oneSigId"`
+	Nonce               uint64   `json:"nonce"`
+	LeafHash            string   `json:"leafHash"`
+	Siblings            []string `json:"siblings"`
+	Peaks               []string `json:"peaks"`
+}
+
+// RedactedOutput is the output format stripped down to the root, leaf hashes and proofs
+type RedactedOutput struct {
+	FormatVersion       int                  `json:"formatVersion,omitempty"`
+	MerkleRoot          string               `json:"merkleRoot"`
+	LeafEncodingVersion uint8                `json:"leafEncodingVersion"`
+	HashEncoding        string               `json:"hashEncoding,omitempty"`
+	TreeOptions         *TreeOptions         `json:"treeOptions,omitempty"`
+	TreeShape           *TreeShape           `json:"treeShape,omitempty"`
+	Redacted            bool                 `json:"redacted"`
+	Proofs              []RedactedProofEntry `json:"proofs"`
+}
+
+// Redact drops everything but the root, leaf indices, leaf hashes and proofs
+func (o *OutputFormat) Redact() RedactedOutput {
+	redacted := RedactedOutput{
+		FormatVersion:       o.FormatVersion,
+		MerkleRoot:          o.MerkleRoot,
+		LeafEncodingVersion: o.LeafEncodingVersion,
+		HashEncoding:        o.HashEncoding,
+		TreeOptions:         o.TreeOptions,
+		TreeShape:           o.TreeShape,
+		Redacted:            true,
+		Proofs:              make([]RedactedProofEntry, 0, len(o.Proofs)),
+	}
+	for _, entry := range o.Proofs {
+		redacted.Proofs = append(redacted.Proofs, RedactedProofEntry{
+			LeafIndex: entry.LeafIndex,
+			LeafHash:  entry.LeafHash,
+			Proof:     entry.Proof,
+		})
+	}
+	return redacted
+}
+
+// EntriesForNonce returns every proof entry with the given nonce
+func (o *OutputFormat) EntriesForNonce(nonce uint64) []ProofEntry {
+	var entries []ProofEntry
+	for _, entry := range o.Proofs {
+		if entry.Nonce == nonce {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// SignatureFile holds one signer's signature over a Merkle root digest.
+// Expiry is the timestamp the root is bound to, 0 for roots without one.
+type SignatureFile struct {
+	MerkleRoot string `json:"merkleRoot"`
+	Seed       string `json:"seed"`
+	Expiry     uint64 `json:"expiry,omitempty"`
+	Digest     string `json:"digest"`
+	Signer     string `json:"signer"`
+	Signature  string `json:"signature"`
+}
+
+// OutputSignature is the signature of the generation host over a proofs
+// file, made with its own key rather than a root signer's. The signature is an
+// EIP-191 personal_sign of the 32 byte SHA-256 of the file.
+type OutputSignature struct {
+	File      string `json:"file"`
+	SHA256    string `json:"sha256"`
+	Signer    string `json:"signer"`
+	Signature string `json:"signature"`
+}
+
+// AggregatedSignatures is the combined signature payload for a Merkle root
+type AggregatedSignatures struct {
+	MerkleRoot string   `json:"merkleRoot"`
+	Seed       string   `json:"seed"`
+	Expiry     uint64   `json:"expiry,omitempty"`
+	Digest     string   `json:"digest"`
+	Signers    []string `json:"signers"`
+	Signatures string   `json:"signatures"`
 }
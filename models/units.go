@@ -0,0 +1,169 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// EtherUnits are the unit names accepted in call values, with their decimals
+var EtherUnits = map[string]int{
+	"wei":    0,
+	"kwei":   3,
+	"mwei":   6,
+	"gwei":   9,
+	"szabo":  12,
+	"finney": 15,
+	"ether":  18,
+}
+
+// ParseValue parses a call value given in wei, as a decimal or 0x prefixed hex
+// integer, or as a decimal amount followed by a unit, such as "1.5 ether" or
+// "3000 gwei". The conversion is exact: amounts with more fractional digits
+// than the unit has decimals are rejected. Wei amounts in scientific notation,
+// such as 1e18, are converted exactly when they are whole numbers.
+func ParseValue(s string) (*big.Int, error) {
+	s = strings.TrimSpace(s)
+	if amount, unit, ok := strings.Cut(s, " "); ok {
+		decimals, known := EtherUnits[strings.ToLower(strings.TrimSpace(unit))]
+		if !known {
+			return nil, fmt.Errorf("unknown unit %q in value %q, expected wei, gwei or ether", strings.TrimSpace(unit), s)
+		}
+		value, err := ParseUnits(amount, decimals)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value %q: %w", s, err)
+		}
+		return value, nil
+	}
+
+	value, err := parseWei(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value %q: %w", s, err)
+	}
+	return value, nil
+}
+
+// parseWei parses an amount of wei without a unit. Signs, leading zeros, which
+// big.Int would read as octal, other bases and digit separators are rejected.
+func parseWei(s string) (*big.Int, error) {
+	switch {
+	case s == "":
+		return nil, fmt.Errorf("value is empty")
+	case s[0] == '-':
+		return nil, fmt.Errorf("value is negative")
+	case s[0] == '+':
+		return nil, fmt.Errorf("value has a sign")
+	case strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X"):
+		if strings.HasPrefix(s[2:], "-") || strings.HasPrefix(s[2:], "+") {
+			return nil, fmt.Errorf("value has a sign")
+		}
+		value, ok := new(big.Int).SetString(s[2:], 16)
+		if !ok {
+			return nil, fmt.Errorf("not a hex integer")
+		}
+		return value, nil
+	}
+
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		mantissa, exponent := s[:i], strings.TrimPrefix(s[i+1:], "+")
+		if strings.HasPrefix(exponent, "-") {
+			return nil, fmt.Errorf("negative exponents are not whole amounts of wei")
+		}
+		e, err := strconv.ParseUint(exponent, 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exponent %q", s[i+1:])
+		}
+		value, err := ParseUnits(mantissa, int(e))
+		if err != nil {
+			return nil, fmt.Errorf("not a whole amount of wei: %w", err)
+		}
+		return value, nil
+	}
+
+	if strings.Contains(s, ".") {
+		return nil, fmt.Errorf("fractional values need a unit, as in %q", s+" ether")
+	}
+	if strings.Trim(s, "0123456789") != "" {
+		return nil, fmt.Errorf("not a decimal integer")
+	}
+	if len(s) > 1 && s[0] == '0' {
+		return nil, fmt.Errorf("leading zeros are not allowed")
+	}
+	value, _ := new(big.Int).SetString(s, 10)
+	return value, nil
+}
+
+// ParseUnits converts a decimal amount such as "1.5" into base units of a
+// token or unit with the given decimals, without floating point
+func ParseUnits(amount string, decimals int) (*big.Int, error) {
+	whole, fraction, _ := strings.Cut(amount, ".")
+	if whole == "" && fraction == "" {
+		return nil, fmt.Errorf("missing amount")
+	}
+	if len(fraction) > decimals {
+		return nil, fmt.Errorf("%s has more than %d decimal places", amount, decimals)
+	}
+	digits := whole + fraction + strings.Repeat("0", decimals-len(fraction))
+	for _, c := range digits {
+		if c < '0' || c > '9' {
+			return nil, fmt.Errorf("%q is not a decimal amount", amount)
+		}
+	}
+	value, _ := new(big.Int).SetString(digits, 10)
+	return value, nil
+}
+
+// FormatUnits formats base units as a decimal amount with the given decimals,
+// without trailing zeros
+func FormatUnits(value *big.Int, decimals int) string {
+	if decimals == 0 {
+		return value.String()
+	}
+	digits := new(big.Int).Abs(value).String()
+	if len(digits) <= decimals {
+		digits = strings.Repeat("0", decimals-len(digits)+1) + digits
+	}
+	whole, fraction := digits[:len(digits)-decimals], strings.TrimRight(digits[len(digits)-decimals:], "0")
+	if value.Sign() < 0 {
+		whole = "-" + whole
+	}
+	if fraction == "" {
+		return whole
+	}
+	return whole + "." + fraction
+}
+
+// UnmarshalJSON accepts the value of a call as a JSON number or as a string
+// parsed by ParseValue
+func (c *Call) UnmarshalJSON(data []byte) error {
+	type plain Call
+	var call struct {
+		plain
+		Value json.RawMessage `json:"value"`
+	}
+	if err := json.Unmarshal(data, &call); err != nil {
+		return err
+	}
+	*c = Call(call.plain)
+	c.Value = nil
+
+	raw := bytes.TrimSpace(call.Value)
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil
+	}
+	text := string(raw)
+	if raw[0] == '"' {
+		if err := json.Unmarshal(raw, &text); err != nil {
+			return err
+		}
+	}
+	value, err := ParseValue(text)
+	if err != nil {
+		return err
+	}
+	c.Value = value
+	return nil
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+)
+
+func TestParseValue(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string // decimal wei, empty when the input is rejected
+	}{
+		{"1e18", "1000000000000000000"},
+		{"1.5e18", "1500000000000000000"},
+		{"1.5", ""},
+		{"1.5 ether", "1500000000000000000"},
+		{"3000 gwei", "3000000000000"},
+		{"-1", ""},
+		{"+1", ""},
+		{"007", ""},
+		{"0", "0"},
+		{"0x10", "16"},
+		{"0xzz", ""},
+		{"0x-10", ""},
+		{"0x+10", ""},
+		{"1_000", ""},
+		{"1e-3", ""},
+		{"1.0000000000000000001 ether", ""},
+		{"1 wie", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseValue(tt.input)
+			if tt.want == "" {
+				if err == nil {
+					t.Fatalf("ParseValue(%q) = %s, want an error", tt.input, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseValue(%q): %v", tt.input, err)
+			}
+			if got.String() != tt.want {
+				t.Fatalf("ParseValue(%q) = %s, want %s", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCallValueJSON(t *testing.T) {
+	var call Call
+	if err := json.Unmarshal([]byte(`{"to": "0x1111111111111111111111111111111111111111", "value": 1e18, "data": "0x"}`), &call); err != nil {
+		t.Fatal(err)
+	}
+	if want := new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil); call.Value.Cmp(want) != 0 {
+		t.Fatalf("value = %s, want %s", call.Value, want)
+	}
+	if err := json.Unmarshal([]byte(`{"value": "1_000"}`), &call); err == nil {
+		t.Fatal("value 1_000 was accepted")
+	}
+}
@@ -0,0 +1,84 @@
+package names
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Labels maps addresses to the names shown for them to reviewers
+type Labels map[common.Address]string
+
+// etherscanLabel is an entry of an Etherscan-style labels export
+type etherscanLabel struct {
+	Name   string   `json:"name"`
+	Labels []string `json:"labels"`
+}
+
+// LoadLabels reads a labels file holding a JSON object keyed by address.
+// Values are either the label itself or an Etherscan-style entry such as
+// {"name": "Uniswap V3: Router", "labels": ["uniswap"]}, labelled by its name.
+func LoadLabels(path string) (Labels, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read labels file: %w", err)
+	}
+	var entries map[string]json.RawMessage
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse labels file: %w", err)
+	}
+
+	labels := make(Labels, len(entries))
+	for address, raw := range entries {
+		if !common.IsHexAddress(address) {
+			return nil, fmt.Errorf("labels file: invalid address %q", address)
+		}
+		var label string
+		if err := json.Unmarshal(raw, &label); err != nil {
+			var entry etherscanLabel
+			if err := json.Unmarshal(raw, &entry); err != nil {
+				return nil, fmt.Errorf("labels file: %s: expected a string or an object with a name", address)
+			}
+			label = entry.Name
+			if label == "" {
+				label = strings.Join(entry.Labels, ", ")
+			}
+		}
+		if label = strings.TrimSpace(label); label != "" {
+			labels[common.HexToAddress(address)] = label
+		}
+	}
+	return labels, nil
+}
+
+// Labels returns the names of the address book as @name labels
+func (b AddressBook) Labels() Labels {
+	labels := make(Labels, len(b))
+	// Sorted, so an address listed under several names gets the first
+	for _, name := range b.Names() {
+		if _, ok := labels[b[name]]; !ok {
+			labels[b[name]] = "@" + name
+		}
+	}
+	return labels
+}
+
+// Merge adds the labels of other for addresses without a label
+func (l Labels) Merge(other Labels) {
+	for address, label := range other {
+		if _, ok := l[address]; !ok {
+			l[address] = label
+		}
+	}
+}
+
+// Label returns the label of an address given as hex, or an empty string
+func (l Labels) Label(address string) string {
+	if !common.IsHexAddress(address) {
+		return ""
+	}
+	return l[common.HexToAddress(address)]
+}
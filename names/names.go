@@ -0,0 +1,173 @@
+// Package names resolves the names a batch may give instead of call target
+// addresses: @entries of a local address book and ENS names.
+package names
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"merkle-cli/chain"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// ENSRegistry is the address of the ENS registry on Ethereum mainnet and its testnets
+var ENSRegistry = common.HexToAddress("0x00000000000C2E074eC69A0dFb2997BA6C7d2e1e")
+
+var (
+	// resolverSelector is resolver(bytes32) on the ENS registry
+	resolverSelector = crypto.Keccak256([]byte("resolver(bytes32)"))[:4]
+	// addrSelector is addr(bytes32) on an ENS resolver
+	addrSelector = crypto.Keccak256([]byte("addr(bytes32)"))[:4]
+)
+
+// AddressBook maps names, referenced as @name in batches, to addresses
+type AddressBook map[string]common.Address
+
+// LoadAddressBook reads an address book file holding a JSON object of names
+// and addresses, such as {"ops-multisig": "0x1234..."}
+func LoadAddressBook(path string) (AddressBook, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read address book: %w", err)
+	}
+	var entries map[string]string
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse address book: %w", err)
+	}
+
+	book := make(AddressBook, len(entries))
+	for name, address := range entries {
+		if name == "" || strings.HasPrefix(name, "@") {
+			return nil, fmt.Errorf("address book name %q must be given without @", name)
+		}
+		if !common.IsHexAddress(address) {
+			return nil, fmt.Errorf("address book entry %s: invalid address %q", name, address)
+		}
+		book[name] = common.HexToAddress(address)
+	}
+	return book, nil
+}
+
+// Names returns the names in the book in sorted order
+func (b AddressBook) Names() []string {
+	names := make([]string, 0, len(b))
+	for name := range b {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// IsName reports whether a call target is a name rather than an address:
+// an @name of the address book or a dotted ENS name
+func IsName(to string) bool {
+	if common.IsHexAddress(to) || strings.HasPrefix(to, "0x") {
+		return false
+	}
+	return strings.HasPrefix(to, "@") || strings.Contains(to, ".")
+}
+
+// Namehash computes the ENS namehash of a name. Names are lowercased but not
+// otherwise normalized.
+func Namehash(name string) common.Hash {
+	var node common.Hash
+	if name == "" {
+		return node
+	}
+	labels := strings.Split(strings.ToLower(name), ".")
+	for i := len(labels) - 1; i >= 0; i-- {
+		copy(node[:], crypto.Keccak256(node[:], crypto.Keccak256([]byte(labels[i]))))
+	}
+	return node
+}
+
+// Resolver resolves names with an address book and, for ENS names, an RPC
+// endpoint. Results are cached; a Resolver is safe for concurrent use.
+type Resolver struct {
+	Book   AddressBook
+	RPCURL string
+
+	mu    sync.Mutex
+	cache map[string]common.Address
+}
+
+// Resolve returns the address a name stands for
+func (r *Resolver) Resolve(name string) (common.Address, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if address, ok := r.cache[name]; ok {
+		return address, nil
+	}
+
+	var address common.Address
+	var err error
+	if entry, ok := strings.CutPrefix(name, "@"); ok {
+		address, err = r.lookup(entry)
+	} else {
+		address, err = r.resolveENS(name)
+	}
+	if err != nil {
+		return common.Address{}, err
+	}
+	if r.cache == nil {
+		r.cache = make(map[string]common.Address)
+	}
+	r.cache[name] = address
+	return address, nil
+}
+
+// lookup finds a name in the address book
+func (r *Resolver) lookup(name string) (common.Address, error) {
+	if r.Book == nil {
+		return common.Address{}, fmt.Errorf("@%s needs an address book", name)
+	}
+	address, ok := r.Book[name]
+	if !ok {
+		return common.Address{}, fmt.Errorf("@%s is not in the address book", name)
+	}
+	return address, nil
+}
+
+// resolveENS asks the ENS registry for the resolver of a name, then the
+// resolver for its address
+func (r *Resolver) resolveENS(name string) (common.Address, error) {
+	if r.RPCURL == "" {
+		return common.Address{}, fmt.Errorf("ENS name %s needs an RPC endpoint to resolve", name)
+	}
+	client := chain.NewClient(r.RPCURL)
+	node := Namehash(name)
+
+	resolver, err := callAddress(client, ENSRegistry, resolverSelector, node)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("failed to look up the resolver of %s: %w", name, err)
+	}
+	if resolver == (common.Address{}) {
+		return common.Address{}, fmt.Errorf("ENS name %s has no resolver", name)
+	}
+	address, err := callAddress(client, resolver, addrSelector, node)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("failed to resolve %s: %w", name, err)
+	}
+	if address == (common.Address{}) {
+		return common.Address{}, fmt.Errorf("ENS name %s does not resolve to an address", name)
+	}
+	return address, nil
+}
+
+// callAddress calls a function taking a node and returning an address
+func callAddress(client *chain.Client, to common.Address, selector []byte, node common.Hash) (common.Address, error) {
+	result, err := client.CallContract(chain.CallMsg{To: to, Data: append(append([]byte{}, selector...), node[:]...)})
+	if err != nil {
+		return common.Address{}, err
+	}
+	if len(result) != 32 {
+		return common.Address{}, fmt.Errorf("unexpected %d byte result", len(result))
+	}
+	return common.BytesToAddress(result), nil
+}
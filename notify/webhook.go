@@ -0,0 +1,91 @@
+// Package notify posts a digest of a generated tree to chat webhooks, so the
+// signer group is pinged with values they can verify independently.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Summary is the digest of a generation posted to the webhook
+type Summary struct {
+	MerkleRoot string `json:"merkleRoot"`
+	OneSigID   uint64 `json:"oneSigId"`
+	Leaves     int    `json:"leaves"`
+	// InputSHA256 is the SHA-256 of the transaction batch file
+	InputSHA256 string `json:"inputSha256"`
+	ArtifactURL string `json:"artifactUrl,omitempty"`
+}
+
+// Text formats the summary as a chat message
+func (s *Summary) Text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "OneSig Merkle root generated for OneSig ID %d\n", s.OneSigID)
+	fmt.Fprintf(&b, "Root: %s\n", s.MerkleRoot)
+	fmt.Fprintf(&b, "Leaves: %d\n", s.Leaves)
+	fmt.Fprintf(&b, "Input SHA-256: %s", s.InputSHA256)
+	if s.ArtifactURL != "" {
+		fmt.Fprintf(&b, "\nArtifact: %s", s.ArtifactURL)
+	}
+	return b.String()
+}
+
+// payload builds the request body for the webhook at u. Slack and Discord
+// webhooks get a chat message, any other URL the summary as JSON.
+func payload(u *url.URL, s *Summary) interface{} {
+	host := strings.ToLower(u.Hostname())
+	switch {
+	case host == "hooks.slack.com":
+		return map[string]string{"text": "```" + s.Text() + "```"}
+	case (host == "discord.com" || host == "discordapp.com") && strings.HasPrefix(u.Path, "/api/webhooks/"):
+		return map[string]string{"content": "```\n" + s.Text() + "\n```"}
+	default:
+		return s
+	}
+}
+
+// ParseURL checks that rawURL is an http or https webhook URL
+func ParseURL(rawURL string) (*url.URL, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return nil, fmt.Errorf("invalid webhook URL %q", rawURL)
+	}
+	return u, nil
+}
+
+// Post sends the summary to the webhook at rawURL
+func Post(ctx context.Context, rawURL string, s *Summary) error {
+	u, err := ParseURL(rawURL)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(payload(u, s))
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post to webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("webhook returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package plan
+
+import (
+	"sort"
+
+	"merkle-cli/models"
+)
+
+// ManifestFile is the name of the campaign manifest written next to the roots
+const ManifestFile = "campaign.json"
+
+// Manifest links the roots of a campaign in the order they are committed
+type Manifest struct {
+	LeafEncodingVersion uint8               `json:"leafEncodingVersion"`
+	TreeOptions         *models.TreeOptions `json:"treeOptions"`
+	MaxLeavesPerRoot    int                 `json:"maxLeavesPerRoot,omitempty"`
+	GroupByChain        bool                `json:"groupByChain,omitempty"`
+	Window              uint64              `json:"window,omitempty"`
+	Roots               []ManifestRoot      `json:"roots"`
+}
+
+// ManifestRoot describes one root of a campaign. PreviousRoot is the last
+// earlier root holding leaves of the same OneSig IDs, which must be committed
+// and executed first.
+type ManifestRoot struct {
+	Index        int          `json:"index"`
+	MerkleRoot   string       `json:"merkleRoot"`
+	PreviousRoot string       `json:"previousRoot,omitempty"`
+	File         string       `json:"file"`
+	Leaves       int          `json:"leaves"`
+	WindowStart  uint64       `json:"windowStart,omitempty"`
+	Expiry       uint64       `json:"expiry,omitempty"`
+	Chains       []ChainRange `json:"chains"`
+}
+
+// ChainRange is the nonces of one OneSig ID committed to by a root
+type ChainRange struct {
+	OneSigID   uint64 `json:"oneSigId"`
+	FirstNonce uint64 `json:"firstNonce"`
+	LastNonce  uint64 `json:"lastNonce"`
+	Leaves     int    `json:"leaves"`
+}
+
+// Chains summarises the nonces of every OneSig ID of a root, in OneSig ID order
+func (r Root) Chains() []ChainRange {
+	var chains []ChainRange
+	index := make(map[uint64]int)
+	for _, source := range r.Sources {
+		for _, group := range source.Batch.Groups {
+			i, ok := index[source.OneSigID]
+			if !ok {
+				i = len(chains)
+				index[source.OneSigID] = i
+				chains = append(chains, ChainRange{OneSigID: source.OneSigID, FirstNonce: group.Nonce, LastNonce: group.Nonce})
+			}
+			c := &chains[i]
+			if group.Nonce < c.FirstNonce {
+				c.FirstNonce = group.Nonce
+			}
+			if group.Nonce > c.LastNonce {
+				c.LastNonce = group.Nonce
+			}
+			c.Leaves++
+		}
+	}
+	sort.Slice(chains, func(i, j int) bool {
+		return chains[i].OneSigID < chains[j].OneSigID
+	})
+	return chains
+}
@@ -0,0 +1,163 @@
+// Package plan splits a large set of transaction groups into several roots,
+// for campaigns that roll out a root per week or per chain.
+package plan
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"merkle-cli/merkle"
+	"merkle-cli/models"
+)
+
+// Constraints decide how the groups of a campaign are split into roots
+type Constraints struct {
+	// MaxLeaves is the maximum number of leaves of a root, 0 for no maximum
+	MaxLeaves int
+	// GroupByChain gives every OneSig ID its own roots
+	GroupByChain bool
+	// Window, when set, assigns every group to the window of length Window
+	// after Start it becomes valid in, and gives every window its own roots
+	Window time.Duration
+	Start  time.Time
+}
+
+// Root is one planned root: the part of every input batch it commits to
+type Root struct {
+	Sources []merkle.Source
+	Leaves  int
+	// WindowStart and Expiry bound the window of the root, 0 without windows
+	WindowStart uint64
+	Expiry      uint64
+}
+
+// item is a group of an input batch together with where it is planned
+type item struct {
+	source int
+	group  int
+	id     uint64
+	nonce  uint64
+	window uint64
+}
+
+// bucket identifies the groups that may share a root
+type bucket struct {
+	window uint64
+	chain  uint64
+}
+
+// Partition splits the groups of the sources into roots satisfying the
+// constraints, in the order they should be committed. Within a root, and
+// across the roots of a OneSig ID, groups are ordered by nonce; a group
+// becoming valid in an earlier window than a lower nonce is an error, as
+// OneSig executes nonces in order.
+func Partition(sources []merkle.Source, c Constraints) ([]Root, error) {
+	if c.MaxLeaves < 0 {
+		return nil, fmt.Errorf("maximum leaves per root must not be negative")
+	}
+	if c.Window < 0 || (c.Window > 0 && c.Window < time.Second) {
+		return nil, fmt.Errorf("window must be at least one second")
+	}
+	start := uint64(0)
+	if c.Window > 0 {
+		if c.Start.Unix() <= 0 {
+			return nil, fmt.Errorf("windows require a start time")
+		}
+		start = uint64(c.Start.Unix())
+	}
+	window := uint64(c.Window / time.Second)
+
+	var items []item
+	for s, source := range sources {
+		for g, group := range source.Batch.Groups {
+			it := item{source: s, group: g, id: source.OneSigID, nonce: group.Nonce}
+			if window > 0 {
+				if group.ValidUntil != 0 && group.ValidUntil <= start {
+					return nil, fmt.Errorf("%s: nonce %d expires at %d, before the campaign starts at %d", source.Name, group.Nonce, group.ValidUntil, start)
+				}
+				if group.ValidAfter > start {
+					it.window = (group.ValidAfter - start) / window
+				}
+			}
+			items = append(items, it)
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].id != items[j].id {
+			return items[i].id < items[j].id
+		}
+		return items[i].nonce < items[j].nonce
+	})
+	for i := 1; i < len(items); i++ {
+		prev, it := items[i-1], items[i]
+		if prev.id == it.id && prev.window > it.window {
+			return nil, fmt.Errorf("oneSigId %d nonce %d becomes valid in window %d, before nonce %d in window %d", it.id, it.nonce, it.window, prev.nonce, prev.window)
+		}
+	}
+
+	buckets := make(map[bucket][]item)
+	var keys []bucket
+	for _, it := range items {
+		key := bucket{window: it.window}
+		if c.GroupByChain {
+			key.chain = it.id
+		}
+		if _, ok := buckets[key]; !ok {
+			keys = append(keys, key)
+		}
+		buckets[key] = append(buckets[key], it)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].window != keys[j].window {
+			return keys[i].window < keys[j].window
+		}
+		return keys[i].chain < keys[j].chain
+	})
+
+	var roots []Root
+	for _, key := range keys {
+		planned := buckets[key]
+		for len(planned) > 0 {
+			n := len(planned)
+			if c.MaxLeaves > 0 && n > c.MaxLeaves {
+				n = c.MaxLeaves
+			}
+			root := newRoot(sources, planned[:n])
+			if window > 0 {
+				root.WindowStart = start + key.window*window
+				root.Expiry = root.WindowStart + window
+			}
+			roots = append(roots, root)
+			planned = planned[n:]
+		}
+	}
+	return roots, nil
+}
+
+// newRoot builds the sources of a root holding the planned groups, keeping
+// the order of the inputs
+func newRoot(sources []merkle.Source, planned []item) Root {
+	bySource := make(map[int][]item)
+	for _, it := range planned {
+		bySource[it.source] = append(bySource[it.source], it)
+	}
+	root := Root{Leaves: len(planned)}
+	for s, source := range sources {
+		its, ok := bySource[s]
+		if !ok {
+			continue
+		}
+		batch := &models.TransactionBatch{
+			FormatVersion: source.Batch.FormatVersion,
+			TargetFormat:  source.Batch.TargetFormat,
+		}
+		for _, it := range its {
+			batch.Groups = append(batch.Groups, source.Batch.Groups[it.group])
+		}
+		part := source
+		part.Batch = batch
+		root.Sources = append(root.Sources, part)
+	}
+	return root
+}
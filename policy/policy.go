@@ -0,0 +1,292 @@
+package policy
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"merkle-cli/config"
+	"merkle-cli/models"
+	"merkle-cli/utils"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Violation is a call that breaks a policy rule
+type Violation struct {
+	Nonce    uint64 `json:"nonce"`
+	Call     int    `json:"call"`
+	To       string `json:"to"`
+	Selector string `json:"selector"`
+	Reason   string `json:"reason"`
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("nonce %d call %d to %s: selector %s %s", v.Nonce, v.Call, v.To, v.Selector, v.Reason)
+}
+
+// Overspend is a budget the batch exceeds
+type Overspend struct {
+	OneSigID *uint64 `json:"oneSigId,omitempty"`
+	Token    string  `json:"token"`
+	Spent    string  `json:"spent"`
+	Max      string  `json:"max"`
+}
+
+func (o Overspend) String() string {
+	scope := "the batch"
+	if o.OneSigID != nil {
+		scope = fmt.Sprintf("OneSig ID %d", *o.OneSigID)
+	}
+	return fmt.Sprintf("%s spends %s %s, over its budget of %s", scope, o.Spent, o.Token, o.Max)
+}
+
+// rule is a policy rule with its selectors resolved to 4 byte values
+type rule struct {
+	allow             map[[4]byte]bool
+	deny              map[[4]byte]bool
+	allowDelegateCall bool
+}
+
+// budget is a budget with its token resolved and its cap in base units
+type budget struct {
+	oneSigID *uint64
+	token    config.Token
+	max      *big.Int
+}
+
+// Engine evaluates calls against the selector rules and budgets of a policy
+type Engine struct {
+	wildcard []rule
+	byTarget map[string][]rule
+	budgets  []budget
+}
+
+// New resolves the selectors of every rule and the caps of every budget in
+// the config policy. Without a policy the engine only rejects delegatecalls.
+func New(cfg *config.Config) (*Engine, error) {
+	p := cfg.Policy
+	if p == nil {
+		p = &config.Policy{Version: config.PolicyVersion}
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
+	e := &Engine{byTarget: make(map[string][]rule)}
+	for i, b := range p.Budgets {
+		token, max, err := cfg.BudgetCap(b)
+		if err != nil {
+			return nil, fmt.Errorf("budgets[%d]: %w", i, err)
+		}
+		e.budgets = append(e.budgets, budget{oneSigID: b.OneSigID, token: token, max: max})
+	}
+	for i, r := range p.Rules {
+		allow, err := parseSelectors(r.Allow)
+		if err != nil {
+			return nil, fmt.Errorf("rules[%d].allow: %w", i, err)
+		}
+		deny, err := parseSelectors(r.Deny)
+		if err != nil {
+			return nil, fmt.Errorf("rules[%d].deny: %w", i, err)
+		}
+
+		resolved := rule{allow: allow, deny: deny, allowDelegateCall: r.AllowDelegateCall}
+		if r.Target == "*" {
+			e.wildcard = append(e.wildcard, resolved)
+		} else {
+			target := ruleTarget(r.Target)
+			e.byTarget[target] = append(e.byTarget[target], resolved)
+		}
+	}
+	return e, nil
+}
+
+// Check returns every call in the leaves that a rule denies, and every
+// delegatecall to a target no rule allows delegatecalls to. Calls without
+// data carry no selector and are not subject to selector rules.
+func (e *Engine) Check(leaves []models.Leaf) ([]Violation, error) {
+	var violations []Violation
+
+	for _, leaf := range leaves {
+		for i, call := range leaf.Calls {
+			data, err := utils.ParseHex(call.Data, fmt.Sprintf("nonce %d calls[%d].data", leaf.Nonce, i), false)
+			if err != nil {
+				return nil, err
+			}
+			target, err := callTarget(leaf, call, fmt.Sprintf("nonce %d calls[%d].to", leaf.Nonce, i))
+			if err != nil {
+				return nil, err
+			}
+			rules := append(append([]rule{}, e.wildcard...), e.byTarget[target]...)
+
+			var reason string
+			if call.IsDelegateCall() && !allowsDelegateCall(rules) {
+				reason = "is a delegatecall, which no policy rule allows for this target"
+			}
+			if len(data) >= 4 && reason == "" {
+				var selector [4]byte
+				copy(selector[:], data[:4])
+				reason = evaluate(rules, selector)
+			}
+			if reason != "" {
+				violations = append(violations, Violation{
+					Nonce:    leaf.Nonce,
+					Call:     i,
+					To:       call.To,
+					Selector: fmt.Sprintf("0x%x", data[:min(len(data), 4)]),
+					Reason:   reason,
+				})
+			}
+		}
+	}
+
+	return violations, nil
+}
+
+// allowsDelegateCall reports whether any of the rules allows delegatecalls
+func allowsDelegateCall(rules []rule) bool {
+	for _, r := range rules {
+		if r.allowDelegateCall {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckBudgets returns every budget the leaves exceed. The native currency
+// spent is the value of every call; a token is spent by transfer and
+// transferFrom calls to its contract.
+func (e *Engine) CheckBudgets(leaves []models.Leaf) ([]Overspend, error) {
+	var overspends []Overspend
+	for _, b := range e.budgets {
+		spent := new(big.Int)
+		for _, leaf := range leaves {
+			if b.oneSigID != nil && leaf.OneSigID != *b.oneSigID {
+				continue
+			}
+			for i, call := range leaf.Calls {
+				amount, err := Spend(leaf, call, b.token.Address)
+				if err != nil {
+					return nil, fmt.Errorf("nonce %d calls[%d]: %w", leaf.Nonce, i, err)
+				}
+				spent.Add(spent, amount)
+			}
+		}
+		if spent.Cmp(b.max) > 0 {
+			overspends = append(overspends, Overspend{
+				OneSigID: b.oneSigID,
+				Token:    b.token.Symbol,
+				Spent:    models.FormatUnits(spent, b.token.Decimals),
+				Max:      models.FormatUnits(b.max, b.token.Decimals),
+			})
+		}
+	}
+	return overspends, nil
+}
+
+// Token transfer selectors and the argument word holding their amount
+var transferSelectors = map[[4]byte]int{
+	{0xa9, 0x05, 0x9c, 0xbb}: 1, // transfer(address,uint256)
+	{0x23, 0xb8, 0x72, 0xdd}: 2, // transferFrom(address,address,uint256)
+}
+
+// Spend returns the amount of a token a call of the leaf transfers, in base
+// units. An empty token address stands for the native currency, spent as call
+// value.
+func Spend(leaf models.Leaf, call models.Call, token string) (*big.Int, error) {
+	if token == "" {
+		if call.Value == nil {
+			return new(big.Int), nil
+		}
+		return new(big.Int).Set(call.Value), nil
+	}
+	target, err := callTarget(leaf, call, "to")
+	if err != nil {
+		return nil, err
+	}
+	if target != ruleTarget(token) {
+		return new(big.Int), nil
+	}
+
+	data, err := utils.ParseHex(call.Data, "data", false)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 4 {
+		return new(big.Int), nil
+	}
+	var selector [4]byte
+	copy(selector[:], data[:4])
+	word, ok := transferSelectors[selector]
+	if !ok {
+		return new(big.Int), nil
+	}
+	start := 4 + 32*word
+	if len(data) < start+32 {
+		return nil, fmt.Errorf("transfer calldata is too short to hold an amount")
+	}
+	return new(big.Int).SetBytes(data[start : start+32]), nil
+}
+
+// callTarget returns the target of a call in the target format of its leaf:
+// the lower case address, or the whole 32 byte id for bytes32 targets, so ids
+// never match a rule on their last 20 bytes
+func callTarget(leaf models.Leaf, call models.Call, field string) (string, error) {
+	if leaf.HasBytes32Targets() {
+		id, err := utils.ParseBytes32Target(call.To, field)
+		if err != nil {
+			return "", err
+		}
+		return utils.NormalizeHex(id[:]), nil
+	}
+	if !common.IsHexAddress(call.To) {
+		return "", fmt.Errorf("%s %q is not an address", field, call.To)
+	}
+	return strings.ToLower(common.HexToAddress(call.To).Hex()), nil
+}
+
+// ruleTarget returns a validated rule or token target as callTarget does: a
+// lower case address or 32 byte id
+func ruleTarget(target string) string {
+	if common.IsHexAddress(target) {
+		return strings.ToLower(common.HexToAddress(target).Hex())
+	}
+	return strings.ToLower(target)
+}
+
+// evaluate returns why the rules reject a selector, or an empty string if they allow it
+func evaluate(rules []rule, selector [4]byte) string {
+	for _, r := range rules {
+		if r.deny[selector] {
+			return "is denied"
+		}
+		if len(r.allow) > 0 && !r.allow[selector] {
+			return "is not in the allow list"
+		}
+	}
+	return ""
+}
+
+// parseSelectors resolves 4 byte hex selectors and function signatures
+func parseSelectors(values []string) (map[[4]byte]bool, error) {
+	selectors := make(map[[4]byte]bool, len(values))
+	for _, value := range values {
+		var selector [4]byte
+		if strings.Contains(value, "(") {
+			copy(selector[:], crypto.Keccak256([]byte(strings.ReplaceAll(value, " ", "")))[:4])
+		} else {
+			b, err := utils.ParseHex(value, "selector", false)
+			if err != nil {
+				return nil, err
+			}
+			if len(b) != 4 {
+				return nil, fmt.Errorf("selector %q must be 4 bytes", value)
+			}
+			copy(selector[:], b)
+		}
+		selectors[selector] = true
+	}
+	return selectors, nil
+}
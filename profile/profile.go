@@ -0,0 +1,142 @@
+// Package profile describes the OneSig contract versions the CLI targets, so
+// a deployment is targeted by naming its version instead of setting the leaf
+// encoding, signing domain and contract functions one by one. Profiles are
+// not pinned to contract artifacts: Execute and RootMethod only document the
+// functions the encoding expects, so certify a deployment with the
+// conformance command before relying on a profile.
+package profile
+
+import (
+	"fmt"
+	"strings"
+
+	"merkle-cli/merkle"
+	"merkle-cli/utils"
+)
+
+// Profile bundles what the CLI must match for one OneSig contract version
+type Profile struct {
+	Name        string
+	Description string
+	// LeafVersion is the leaf encoding version the contract rebuilds leaves with
+	LeafVersion uint8
+	// Domain is the EIP-712 domain root signatures are verified in
+	Domain utils.EIP712Domain
+	// Execute is the signature of the function leaves are executed with
+	Execute string
+	// RootMethod is the function a signed root is submitted with
+	RootMethod string
+	// RootExpiry is set when signed roots are bound to an expiry timestamp
+	RootExpiry bool
+	// PairOrder is how the contract orders siblings when verifying a proof
+	PairOrder merkle.PairOrder
+}
+
+// Execute function signatures of the leaf encoding versions
+const (
+	executeV1 = "execute(bytes32[],(address,uint256,bytes)[],uint64)"
+	executeV2 = "execute(bytes32[],(address,uint256,bytes)[],uint64,uint64,uint64)"
+	executeV3 = "execute(bytes32[],(address,uint256,bytes,uint8)[],uint64,uint64,uint64)"
+	executeV4 = "execute(bytes32[],(address,uint256,bytes,uint8)[],uint64,uint64,uint64,bytes32)"
+)
+
+// Profiles lists every contract version profile, oldest first
+var Profiles = []Profile{
+	{
+		Name:        "onesig-v1",
+		Description: "Leaves of calls and a nonce, roots without expiry",
+		LeafVersion: utils.LeafEncodingVersion,
+		Domain:      utils.DefaultDomain,
+		Execute:     executeV1,
+		RootMethod:  "setRoot",
+		PairOrder:   merkle.PairOrderSorted,
+	},
+	{
+		Name:        "onesig-v1-expiry",
+		Description: "Leaves of calls and a nonce, roots bound to an expiry",
+		LeafVersion: utils.LeafEncodingVersion,
+		Domain:      utils.DefaultDomain,
+		Execute:     executeV1,
+		RootMethod:  "setRoot",
+		RootExpiry:  true,
+		PairOrder:   merkle.PairOrderSorted,
+	},
+	{
+		Name:        "onesig-v2",
+		Description: "Leaves with a validity window, roots without expiry",
+		LeafVersion: utils.LeafEncodingVersionWindowed,
+		Domain:      utils.DefaultDomain,
+		Execute:     executeV2,
+		RootMethod:  "setRoot",
+		PairOrder:   merkle.PairOrderSorted,
+	},
+	{
+		Name:        "onesig-v3",
+		Description: "Leaves with a validity window and call operations, roots without expiry",
+		LeafVersion: utils.LeafEncodingVersionOperation,
+		Domain:      utils.DefaultDomain,
+		Execute:     executeV3,
+		RootMethod:  "setRoot",
+		PairOrder:   merkle.PairOrderSorted,
+	},
+	{
+		Name:        "onesig-v4",
+		Description: "Salted leaves with a validity window and call operations, roots without expiry",
+		LeafVersion: utils.LeafEncodingVersionSalted,
+		Domain:      utils.DefaultDomain,
+		Execute:     executeV4,
+		RootMethod:  "setRoot",
+		PairOrder:   merkle.PairOrderSorted,
+	},
+}
+
+// Names lists the names of every profile
+func Names() []string {
+	names := make([]string, len(Profiles))
+	for i, p := range Profiles {
+		names[i] = p.Name
+	}
+	return names
+}
+
+// ForLeafVersion lists the names of the profiles executing a leaf encoding version
+func ForLeafVersion(version uint8) []string {
+	var names []string
+	for _, p := range Profiles {
+		if p.LeafVersion == version {
+			names = append(names, p.Name)
+		}
+	}
+	return names
+}
+
+// Lookup returns the profile of a contract version
+func Lookup(name string) (Profile, error) {
+	for _, p := range Profiles {
+		if p.Name == name {
+			return p, nil
+		}
+	}
+	return Profile{}, fmt.Errorf("unknown contract version %q, expected one of %s", name, strings.Join(Names(), ", "))
+}
+
+// CheckExpiry checks that a root is bound to an expiry exactly when the
+// contract version binds roots to one. An expiry of 0 means none.
+func (p Profile) CheckExpiry(expiry uint64) error {
+	if p.RootExpiry && expiry == 0 {
+		return fmt.Errorf("contract version %s binds roots to an expiry, set --expiry", p.Name)
+	}
+	if !p.RootExpiry && expiry != 0 {
+		return fmt.Errorf("contract version %s does not bind roots to an expiry, remove --expiry", p.Name)
+	}
+	return nil
+}
+
+// FlagDefaults returns the value of every flag the profile determines, by flag name
+func (p Profile) FlagDefaults() map[string]string {
+	return map[string]string{
+		"leaf-version": fmt.Sprint(p.LeafVersion),
+		"pair-order":   string(p.PairOrder),
+		"method":       p.RootMethod,
+	}
+}
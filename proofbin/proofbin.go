@@ -0,0 +1,698 @@
+// Package proofbin implements a compact binary serialization of the proofs
+// output. Hashes and addresses are stored as raw bytes and integers as
+// varints, which makes files for large batches far smaller than JSON.
+//
+// Layout:
+//
+//	magic "OSPF" | format version | leaf encoding version | flags | root (32)
+//	  flags: 1 redacted, 2 tree options recorded, 4 positional pair order,
+//	         8 annotations recorded, 16 leaf order recorded, 32 salts recorded,
+//	         64 arity recorded, 128 extended flags follow
+//	extended flags (only with the extended flags flag):
+//	         1 call operations recorded, 2 call targets length prefixed,
+//	         4 subtree roots recorded
+//	bytes leaf order (only with the leaf order flag)
+//	uvarint arity (only with the arity flag)
+//	subtree roots (only with the subtrees flag): uvarint count, then for each:
+//	  uvarint oneSigId | root (32) | uvarint leaf count | node present (1)
+//	  node (only if present): uvarint level | uvarint index | uvarint proof length | proof hashes (32 each)
+//	uvarint entry count, then for each entry:
+//	  uvarint leaf index | leaf hash (32) | uvarint proof length | proof hashes (32 each)
+//	  leaf (omitted when the redacted flag is set):
+//	    uvarint oneSigId | uvarint nonce | uvarint validAfter | uvarint validUntil
+//	    bytes contractAddr | uvarint call count
+//	    calls: to (20, or bytes with the targets flag) | bytes value (big endian) | bytes data
+//	           | operation (only with the operations flag)
+//	    bytes expect (JSON, empty if none)
+//	    bytes salt (only with the salts flag, empty if none)
+//	    bytes annotations (JSON, only with the annotations flag, empty if none)
+//
+// where "bytes" is a uvarint length followed by that many bytes.
+package proofbin
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"slices"
+
+	"merkle-cli/merkle"
+	"merkle-cli/models"
+	"merkle-cli/utils"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// FormatVersion is the version of the binary layout
+const FormatVersion byte = 1
+
+// Header flags
+const (
+	// flagRedacted marks files that carry no leaf contents
+	flagRedacted byte = 1 << iota
+	// flagTreeOptions marks files that record their tree options, which are
+	// the defaults apart from the pair order
+	flagTreeOptions
+	// flagPositional marks trees built with positional pair order
+	flagPositional
+	// flagAnnotations marks files whose leaves carry their annotations
+	flagAnnotations
+	// flagLeafOrder marks trees of unsorted leaves, whose leaf order follows
+	// the root
+	flagLeafOrder
+	// flagSalts marks files whose leaves carry their salts
+	flagSalts
+	// flagArity marks trees wider than binary, whose arity follows the leaf order
+	flagArity
+	// flagExtended marks files with a second flags byte, holding the
+	// extended flags below
+	flagExtended
+)
+
+// Extended flags
+const (
+	// extOperations marks files whose calls carry their operation
+	extOperations byte = 1 << iota
+	// extTargets marks files whose call targets are length prefixed, as some
+	// of them are 32 byte ids
+	extTargets
+	// extSubtrees marks files recording the subtree root of every OneSig ID
+	extSubtrees
+)
+
+// operations lists the call operations in the order of their operation byte
+var operations = []string{"", models.OperationCall, models.OperationDelegateCall}
+
+// annotations holds the annotations of a leaf and its calls, the nonces the
+// leaf depends on and the format of its call targets
+type annotations struct {
+	models.Annotation
+	DependsOn    []uint64          `json:"dependsOn,omitempty"`
+	TargetFormat string            `json:"targetFormat,omitempty"`
+	Calls        []callAnnotations `json:"calls,omitempty"`
+}
+
+// callAnnotations holds the annotations of a call, the name its target was
+// resolved from, what it pins about its target and its timelock operation
+type callAnnotations struct {
+	models.Annotation
+	ToName         string                    `json:"toName,omitempty"`
+	Implementation string                    `json:"implementation,omitempty"`
+	CodeHash       string                    `json:"codeHash,omitempty"`
+	EOA            bool                      `json:"eoa,omitempty"`
+	Timelock       *models.TimelockOperation `json:"timelock,omitempty"`
+}
+
+var magic = []byte("OSPF")
+
+// IsBinary reports whether data starts with the binary proofs file magic
+func IsBinary(data []byte) bool {
+	return bytes.HasPrefix(data, magic)
+}
+
+// Encode serializes the output. When redacted is set only the root, leaf
+// indices, leaf hashes and proofs are written.
+func Encode(output *models.OutputFormat, redacted bool) ([]byte, error) {
+	w := &writer{}
+	w.buf.Write(magic)
+	var flags byte
+	if redacted {
+		flags |= flagRedacted
+	}
+	if output.TreeOptions != nil {
+		order, err := merkle.CheckTreeOptions(output.TreeOptions)
+		if err != nil {
+			return nil, err
+		}
+		flags |= flagTreeOptions
+		if order == merkle.PairOrderPositional {
+			flags |= flagPositional
+		}
+		if output.TreeOptions.LeafOrder != "" {
+			flags |= flagLeafOrder
+		}
+		if output.TreeOptions.Arity != 0 {
+			flags |= flagArity
+		}
+	}
+	if !redacted && hasSalts(output) {
+		// Files without salts keep the layout that predates them
+		flags |= flagSalts
+		w.salts = true
+	}
+	if !redacted && hasAnnotations(output) {
+		// Files without annotations keep the layout that predates them
+		flags |= flagAnnotations
+		w.annotations = true
+	}
+	var extended byte
+	if !redacted && hasOperations(output) {
+		// Files without operations keep the layout that predates them
+		extended |= extOperations
+		w.operations = true
+	}
+	if !redacted && hasBytes32Targets(output) {
+		// Files with address targets only keep the layout that predates ids
+		extended |= extTargets
+		w.targets = true
+	}
+	if !redacted && len(output.Subtrees) > 0 {
+		// Subtree roots name the OneSig IDs, which redacted files leave out
+		extended |= extSubtrees
+	}
+	if extended != 0 {
+		flags |= flagExtended
+	}
+	w.buf.Write([]byte{FormatVersion, output.LeafEncodingVersion, flags})
+	if extended != 0 {
+		w.buf.WriteByte(extended)
+	}
+	if err := w.hash(output.MerkleRoot, "merkleRoot"); err != nil {
+		return nil, err
+	}
+	if flags&flagLeafOrder != 0 {
+		w.bytes([]byte(output.TreeOptions.LeafOrder))
+	}
+	if flags&flagArity != 0 {
+		w.uvarint(uint64(output.TreeOptions.Arity))
+	}
+	if extended&extSubtrees != 0 {
+		if err := w.subtrees(output.Subtrees); err != nil {
+			return nil, err
+		}
+	}
+
+	w.uvarint(uint64(len(output.Proofs)))
+	for i, entry := range output.Proofs {
+		path := fmt.Sprintf("proofs[%d]", i)
+		w.uvarint(uint64(entry.LeafIndex))
+		if err := w.hash(entry.LeafHash, path+".leafHash"); err != nil {
+			return nil, err
+		}
+		w.uvarint(uint64(len(entry.Proof)))
+		for j, p := range entry.Proof {
+			if err := w.hash(p, fmt.Sprintf("%s.proof[%d]", path, j)); err != nil {
+				return nil, err
+			}
+		}
+		if !redacted {
+			if err := w.leaf(entry.Leaf, path); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return w.buf.Bytes(), nil
+}
+
+// Decode parses a binary proofs file and reports whether it was redacted.
+// Redacted files decode to entries with empty leaves.
+func Decode(data []byte) (*models.OutputFormat, bool, error) {
+	if !IsBinary(data) {
+		return nil, false, fmt.Errorf("not a binary proofs file")
+	}
+	r := &reader{data: data[len(magic):]}
+
+	header := r.next(3)
+	if r.err != nil {
+		return nil, false, r.err
+	}
+	if header[0] != FormatVersion {
+		return nil, false, fmt.Errorf("%w: binary proofs format version %d", utils.ErrUnsupportedVersion, header[0])
+	}
+	var extended byte
+	if header[2]&flagExtended != 0 {
+		if b := r.next(1); r.err == nil {
+			extended = b[0]
+		}
+	}
+	redacted := header[2]&flagRedacted != 0
+	r.annotations = header[2]&flagAnnotations != 0
+	r.salts = header[2]&flagSalts != 0
+	r.operations = extended&extOperations != 0
+	r.targets = extended&extTargets != 0
+
+	output := &models.OutputFormat{
+		LeafEncodingVersion: header[1],
+		MerkleRoot:          r.hash(),
+	}
+	if header[2]&flagTreeOptions != 0 {
+		order := merkle.PairOrderSorted
+		if header[2]&flagPositional != 0 {
+			order = merkle.PairOrderPositional
+		}
+		leafOrder := merkle.DefaultLeafOrder
+		if header[2]&flagLeafOrder != 0 {
+			leafOrder = merkle.LeafOrder(r.bytes())
+		}
+		options := merkle.TreeOptionsWith(order, leafOrder)
+		if header[2]&flagArity != 0 {
+			arity := r.uvarint()
+			if arity > merkle.MaxArity {
+				return nil, false, fmt.Errorf("corrupt binary proofs file: arity %d", arity)
+			}
+			options.Arity = int(arity)
+		}
+		output.TreeOptions = &options
+	}
+	if extended&extSubtrees != 0 {
+		output.Subtrees = r.subtrees()
+	}
+
+	count := r.uvarint()
+	if count > uint64(len(r.data)) {
+		return nil, false, fmt.Errorf("corrupt binary proofs file: %d entries declared", count)
+	}
+	output.Proofs = make([]models.ProofEntry, 0, count)
+	for i := uint64(0); i < count && r.err == nil; i++ {
+		var entry models.ProofEntry
+		entry.LeafIndex = int(r.uvarint())
+		entry.LeafHash = r.hash()
+		proofLen := r.uvarint()
+		if proofLen > uint64(len(r.data))/32 {
+			return nil, false, fmt.Errorf("corrupt binary proofs file: proof of %d hashes declared", proofLen)
+		}
+		entry.Proof = make([]string, 0, proofLen)
+		for j := uint64(0); j < proofLen; j++ {
+			entry.Proof = append(entry.Proof, r.hash())
+		}
+		if !redacted {
+			entry.Leaf = r.leaf()
+		}
+		output.Proofs = append(output.Proofs, entry)
+	}
+
+	if r.err != nil {
+		return nil, false, fmt.Errorf("corrupt binary proofs file: %w", r.err)
+	}
+	if len(r.data) != 0 {
+		return nil, false, fmt.Errorf("corrupt binary proofs file: %d trailing bytes", len(r.data))
+	}
+	return output, redacted, nil
+}
+
+// hasSalts reports whether any leaf of the output carries a salt
+func hasSalts(output *models.OutputFormat) bool {
+	for _, entry := range output.Proofs {
+		if entry.Salt != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// hasOperations reports whether any call of the output names its operation
+func hasOperations(output *models.OutputFormat) bool {
+	for _, entry := range output.Proofs {
+		for _, call := range entry.Calls {
+			if call.Operation != "" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// hasBytes32Targets reports whether any leaf of the output targets 32 byte ids
+func hasBytes32Targets(output *models.OutputFormat) bool {
+	for _, entry := range output.Proofs {
+		if entry.HasBytes32Targets() {
+			return true
+		}
+	}
+	return false
+}
+
+// hasAnnotations reports whether any leaf or call of the output carries
+// metadata that is not encoded in its leaf
+func hasAnnotations(output *models.OutputFormat) bool {
+	for _, entry := range output.Proofs {
+		if !entry.Annotation.IsZero() || len(entry.DependsOn) > 0 || entry.TargetFormat != "" || hasCallAnnotations(entry.Calls) {
+			return true
+		}
+	}
+	return false
+}
+
+type writer struct {
+	buf         bytes.Buffer
+	annotations bool
+	salts       bool
+	operations  bool
+	targets     bool
+}
+
+func (w *writer) uvarint(v uint64) {
+	w.buf.Write(binary.AppendUvarint(nil, v))
+}
+
+func (w *writer) bytes(b []byte) {
+	w.uvarint(uint64(len(b)))
+	w.buf.Write(b)
+}
+
+func (w *writer) hash(value string, field string) error {
+	b, err := utils.ParseHex(value, field, false)
+	if err != nil {
+		return err
+	}
+	if len(b) != 32 {
+		return fmt.Errorf("%s must be 32 bytes, got %d", field, len(b))
+	}
+	w.buf.Write(b)
+	return nil
+}
+
+func (w *writer) leaf(leaf models.Leaf, path string) error {
+	w.uvarint(leaf.OneSigID)
+	w.uvarint(leaf.Nonce)
+	w.uvarint(leaf.ValidAfter)
+	w.uvarint(leaf.ValidUntil)
+
+	var contract []byte
+	if leaf.ContractAddr != "" {
+		if !common.IsHexAddress(leaf.ContractAddr) {
+			return fmt.Errorf("%s.contractAddr %q is not an address", path, leaf.ContractAddr)
+		}
+		contract = common.HexToAddress(leaf.ContractAddr).Bytes()
+	}
+	w.bytes(contract)
+
+	w.uvarint(uint64(len(leaf.Calls)))
+	for i, call := range leaf.Calls {
+		data, err := utils.ParseHex(call.Data, fmt.Sprintf("%s.calls[%d].data", path, i), false)
+		if err != nil {
+			return err
+		}
+		if call.Value != nil && call.Value.Sign() < 0 {
+			return fmt.Errorf("%s.calls[%d].value is negative", path, i)
+		}
+		to, err := w.target(leaf, call, fmt.Sprintf("%s.calls[%d].to", path, i))
+		if err != nil {
+			return err
+		}
+		operation := slices.Index(operations, call.Operation)
+		if operation < 0 {
+			return fmt.Errorf("%s.calls[%d].operation %q must be %q or %q", path, i, call.Operation, models.OperationCall, models.OperationDelegateCall)
+		}
+
+		if w.targets {
+			w.bytes(to)
+		} else {
+			w.buf.Write(to)
+		}
+		var value []byte
+		if call.Value != nil {
+			value = call.Value.Bytes()
+		}
+		w.bytes(value)
+		w.bytes(data)
+		if w.operations {
+			w.buf.WriteByte(byte(operation))
+		}
+	}
+
+	var expect []byte
+	if leaf.Expect != nil {
+		var err error
+		if expect, err = json.Marshal(leaf.Expect); err != nil {
+			return fmt.Errorf("failed to encode %s.expect: %w", path, err)
+		}
+	}
+	w.bytes(expect)
+
+	if w.salts {
+		var salt []byte
+		if leaf.Salt != "" {
+			var err error
+			if salt, err = utils.ParseSalt(leaf.Salt, path+".salt", false); err != nil {
+				return err
+			}
+		}
+		w.bytes(salt)
+	}
+
+	if w.annotations {
+		var encoded []byte
+		a := annotations{Annotation: leaf.Annotation, DependsOn: leaf.DependsOn, TargetFormat: leaf.TargetFormat}
+		for _, call := range leaf.Calls {
+			a.Calls = append(a.Calls, callAnnotations{
+				Annotation:     call.Annotation,
+				ToName:         call.ToName,
+				Implementation: call.Implementation,
+				CodeHash:       call.CodeHash,
+				EOA:            call.EOA,
+				Timelock:       call.Timelock,
+			})
+		}
+		if !a.Annotation.IsZero() || len(a.DependsOn) > 0 || a.TargetFormat != "" || hasCallAnnotations(leaf.Calls) {
+			var err error
+			if encoded, err = json.Marshal(a); err != nil {
+				return fmt.Errorf("failed to encode %s annotations: %w", path, err)
+			}
+		}
+		w.bytes(encoded)
+	}
+	return nil
+}
+
+// subtrees writes the subtree roots and the proofs of those that are nodes of the tree
+func (w *writer) subtrees(subtrees []models.SubtreeRoot) error {
+	w.uvarint(uint64(len(subtrees)))
+	for i, s := range subtrees {
+		path := fmt.Sprintf("subtrees[%d]", i)
+		w.uvarint(s.OneSigID)
+		if err := w.hash(s.Root, path+".root"); err != nil {
+			return err
+		}
+		w.uvarint(uint64(s.LeafCount))
+		if s.Node == nil {
+			w.buf.WriteByte(0)
+			continue
+		}
+		w.buf.WriteByte(1)
+		w.uvarint(uint64(s.Node.Level))
+		w.uvarint(uint64(s.Node.Index))
+		w.uvarint(uint64(len(s.Node.Proof)))
+		for j, p := range s.Node.Proof {
+			if err := w.hash(p, fmt.Sprintf("%s.node.proof[%d]", path, j)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// target returns the call target in the format of the leaf, a 32 byte id for
+// bytes32 targets and a 20 byte address otherwise
+func (w *writer) target(leaf models.Leaf, call models.Call, field string) ([]byte, error) {
+	if leaf.HasBytes32Targets() {
+		id, err := utils.ParseBytes32Target(call.To, field)
+		if err != nil {
+			return nil, err
+		}
+		return id[:], nil
+	}
+	if !common.IsHexAddress(call.To) {
+		return nil, fmt.Errorf("%s %q is not an address", field, call.To)
+	}
+	return common.HexToAddress(call.To).Bytes(), nil
+}
+
+// hasCallAnnotations reports whether any of the calls is annotated
+func hasCallAnnotations(calls []models.Call) bool {
+	for _, call := range calls {
+		if !call.Annotation.IsZero() || call.ToName != "" || call.Implementation != "" || call.CodeHash != "" || call.EOA || call.Timelock != nil {
+			return true
+		}
+	}
+	return false
+}
+
+type reader struct {
+	data        []byte
+	err         error
+	annotations bool
+	salts       bool
+	operations  bool
+	targets     bool
+}
+
+func (r *reader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n > len(r.data) {
+		r.err = fmt.Errorf("unexpected end of data")
+		return nil
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+func (r *reader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(r.data)
+	if n <= 0 {
+		r.err = fmt.Errorf("invalid varint")
+		return 0
+	}
+	r.data = r.data[n:]
+	return v
+}
+
+func (r *reader) bytes() []byte {
+	n := r.uvarint()
+	if n > uint64(len(r.data)) {
+		if r.err == nil {
+			r.err = fmt.Errorf("unexpected end of data")
+		}
+		return nil
+	}
+	return r.next(int(n))
+}
+
+func (r *reader) hash() string {
+	return fmt.Sprintf("0x%x", r.next(32))
+}
+
+func (r *reader) subtrees() []models.SubtreeRoot {
+	count := r.uvarint()
+	if count > uint64(len(r.data)) {
+		if r.err == nil {
+			r.err = fmt.Errorf("%d subtree roots declared", count)
+		}
+		return nil
+	}
+	subtrees := make([]models.SubtreeRoot, 0, count)
+	for i := uint64(0); i < count && r.err == nil; i++ {
+		s := models.SubtreeRoot{
+			OneSigID:  r.uvarint(),
+			Root:      r.hash(),
+			LeafCount: int(r.uvarint()),
+		}
+		if present := r.next(1); r.err == nil && present[0] != 0 {
+			node := &models.SubtreeNode{
+				Level: int(r.uvarint()),
+				Index: int(r.uvarint()),
+			}
+			proofLen := r.uvarint()
+			if proofLen > uint64(len(r.data))/32 {
+				if r.err == nil {
+					r.err = fmt.Errorf("subtree proof of %d hashes declared", proofLen)
+				}
+				return nil
+			}
+			node.Proof = make([]string, 0, proofLen)
+			for j := uint64(0); j < proofLen; j++ {
+				node.Proof = append(node.Proof, r.hash())
+			}
+			s.Node = node
+		}
+		subtrees = append(subtrees, s)
+	}
+	return subtrees
+}
+
+// target reads a call target, an address or, in files with length prefixed
+// targets, a 32 byte id
+func (r *reader) target() string {
+	if !r.targets {
+		return common.BytesToAddress(r.next(common.AddressLength)).Hex()
+	}
+	switch to := r.bytes(); len(to) {
+	case common.AddressLength:
+		return common.BytesToAddress(to).Hex()
+	case common.HashLength:
+		return utils.NormalizeHex(to)
+	default:
+		if r.err == nil {
+			r.err = fmt.Errorf("call target of %d bytes", len(to))
+		}
+		return ""
+	}
+}
+
+func (r *reader) leaf() models.Leaf {
+	leaf := models.Leaf{
+		OneSigID:   r.uvarint(),
+		Nonce:      r.uvarint(),
+		ValidAfter: r.uvarint(),
+		ValidUntil: r.uvarint(),
+	}
+	if contract := r.bytes(); len(contract) > 0 {
+		leaf.ContractAddr = common.BytesToAddress(contract).Hex()
+	}
+
+	count := r.uvarint()
+	if count > uint64(len(r.data)) {
+		r.err = fmt.Errorf("%d calls declared", count)
+		return leaf
+	}
+	leaf.Calls = make([]models.Call, 0, count)
+	for i := uint64(0); i < count && r.err == nil; i++ {
+		to := r.target()
+		value := new(big.Int).SetBytes(r.bytes())
+		data := r.bytes()
+		call := models.Call{
+			To:    to,
+			Value: value,
+			Data:  fmt.Sprintf("0x%x", data),
+		}
+		if r.operations {
+			if b := r.next(1); r.err == nil {
+				if int(b[0]) >= len(operations) {
+					r.err = fmt.Errorf("unknown call operation %d", b[0])
+					return leaf
+				}
+				call.Operation = operations[b[0]]
+			}
+		}
+		leaf.Calls = append(leaf.Calls, call)
+	}
+
+	if expect := r.bytes(); len(expect) > 0 && r.err == nil {
+		leaf.Expect = &models.Expectations{}
+		if err := json.Unmarshal(expect, leaf.Expect); err != nil {
+			r.err = fmt.Errorf("invalid expect: %w", err)
+		}
+	}
+
+	if r.salts {
+		if salt := r.bytes(); len(salt) > 0 {
+			leaf.Salt = utils.NormalizeHex(salt)
+		}
+	}
+
+	if !r.annotations {
+		return leaf
+	}
+	if encoded := r.bytes(); len(encoded) > 0 && r.err == nil {
+		var a annotations
+		if err := json.Unmarshal(encoded, &a); err != nil {
+			r.err = fmt.Errorf("invalid annotations: %w", err)
+			return leaf
+		}
+		if len(a.Calls) > len(leaf.Calls) {
+			r.err = fmt.Errorf("annotations for %d calls declared", len(a.Calls))
+			return leaf
+		}
+		leaf.Annotation = a.Annotation
+		leaf.DependsOn = a.DependsOn
+		leaf.TargetFormat = a.TargetFormat
+		for i := range a.Calls {
+			leaf.Calls[i].Annotation = a.Calls[i].Annotation
+			leaf.Calls[i].ToName = a.Calls[i].ToName
+			leaf.Calls[i].Implementation = a.Calls[i].Implementation
+			leaf.Calls[i].CodeHash = a.Calls[i].CodeHash
+			leaf.Calls[i].EOA = a.Calls[i].EOA
+			leaf.Calls[i].Timelock = a.Calls[i].Timelock
+		}
+	}
+	return leaf
+}
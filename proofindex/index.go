@@ -0,0 +1,144 @@
+// Package proofindex builds and reads index files locating every proof entry
+// of a JSON proofs file by byte offset, so a single proof can be read without
+// parsing the whole file.
+package proofindex
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"merkle-cli/models"
+)
+
+// FormatVersion is the version of the index layout
+const FormatVersion = 1
+
+// Location is the byte range of one proof entry in the proofs file
+type Location struct {
+	LeafIndex int   `json:"leafIndex"`
+	Offset    int64 `json:"offset"`
+	Length    int64 `json:"length"`
+}
+
+// Index maps oneSigId:nonce keys to the locations of their proof entries.
+// Windowed leaves may share a nonce, so a key can locate several entries.
+type Index struct {
+	FormatVersion int                   `json:"formatVersion"`
+	MerkleRoot    string                `json:"merkleRoot"`
+	File          string                `json:"file"`
+	FileSHA256    string                `json:"fileSha256"`
+	Entries       map[string][]Location `json:"entries"`
+}
+
+// Key returns the index key of a OneSig ID and nonce
+func Key(oneSigID uint64, nonce uint64) string {
+	return strconv.FormatUint(oneSigID, 10) + ":" + strconv.FormatUint(nonce, 10)
+}
+
+// Build indexes the proof entries of an encoded JSON proofs file
+func Build(data []byte, file string) (*Index, error) {
+	sum := sha256.Sum256(data)
+	index := &Index{
+		FormatVersion: FormatVersion,
+		File:          file,
+		FileSHA256:    hex.EncodeToString(sum[:]),
+		Entries:       make(map[string][]Location),
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if err := expectDelim(dec, '{'); err != nil {
+		return nil, err
+	}
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return nil, fmt.Errorf("failed to index proofs file: %w", err)
+		}
+		switch token {
+		case "merkleRoot":
+			if err := dec.Decode(&index.MerkleRoot); err != nil {
+				return nil, fmt.Errorf("failed to index proofs file: %w", err)
+			}
+		case "proofs":
+			if err := index.addProofs(dec, data); err != nil {
+				return nil, err
+			}
+		default:
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return nil, fmt.Errorf("failed to index proofs file: %w", err)
+			}
+		}
+	}
+	return index, nil
+}
+
+// addProofs records the location of every element of the proofs array
+func (ix *Index) addProofs(dec *json.Decoder, data []byte) error {
+	if err := expectDelim(dec, '['); err != nil {
+		return err
+	}
+	for dec.More() {
+		start := dec.InputOffset()
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return fmt.Errorf("failed to index proofs file: %w", err)
+		}
+		end := dec.InputOffset()
+		// The decoder's offset precedes the separator and indentation
+		start = end - int64(len(raw))
+		if !bytes.Equal(data[start:end], raw) {
+			return fmt.Errorf("failed to index proofs file: entry at offset %d is not contiguous", start)
+		}
+
+		var entry models.ProofEntry
+		if err := json.Unmarshal(raw, &entry); err != nil {
+			return fmt.Errorf("failed to index proofs file: %w", err)
+		}
+		key := Key(entry.OneSigID, entry.Nonce)
+		ix.Entries[key] = append(ix.Entries[key], Location{LeafIndex: entry.LeafIndex, Offset: start, Length: end - start})
+	}
+	return expectDelim(dec, ']')
+}
+
+// expectDelim consumes the next token, which must be the delimiter
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	token, err := dec.Token()
+	if err != nil {
+		return fmt.Errorf("failed to index proofs file: %w", err)
+	}
+	if token != delim {
+		return fmt.Errorf("failed to index proofs file: expected %v, found %v", delim, token)
+	}
+	return nil
+}
+
+// Lookup reads the proof entries of a OneSig ID and nonce from the proofs file
+func (ix *Index) Lookup(r io.ReaderAt, oneSigID uint64, nonce uint64) ([]models.ProofEntry, error) {
+	locations, ok := ix.Entries[Key(oneSigID, nonce)]
+	if !ok {
+		return nil, fmt.Errorf("no proof entry for oneSigId %d nonce %d", oneSigID, nonce)
+	}
+
+	entries := make([]models.ProofEntry, 0, len(locations))
+	for _, loc := range locations {
+		raw := make([]byte, loc.Length)
+		if _, err := r.ReadAt(raw, loc.Offset); err != nil {
+			return nil, fmt.Errorf("failed to read proof entry at offset %d: %w", loc.Offset, err)
+		}
+		var entry models.ProofEntry
+		if err := json.Unmarshal(raw, &entry); err != nil {
+			return nil, fmt.Errorf("index does not match the proofs file: %w", err)
+		}
+		if entry.OneSigID != oneSigID || entry.Nonce != nonce || entry.LeafIndex != loc.LeafIndex {
+			return nil, fmt.Errorf("index does not match the proofs file at offset %d", loc.Offset)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
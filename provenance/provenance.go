@@ -0,0 +1,140 @@
+// Package provenance writes in-toto statements carrying SLSA provenance for
+// generated proofs files, wrapped in DSSE envelopes, so artifact verification
+// can use the same supply-chain policy checks as other build outputs.
+package provenance
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"merkle-cli/signer"
+)
+
+// Types identifying the statement, its predicate and the envelope payload
+const (
+	StatementType = "https://in-toto.io/Statement/v1"
+	PredicateType = "https://slsa.dev/provenance/v1"
+	PayloadType   = "application/vnd.in-toto+json"
+	BuildType     = "https://github.com/Zellic/lz-onesig-merkleproof-generator/generate/v1"
+)
+
+// Statement is an in-toto statement about the generated artifacts
+type Statement struct {
+	Type          string     `json:"_type"`
+	Subject       []Resource `json:"subject"`
+	PredicateType string     `json:"predicateType"`
+	Predicate     Predicate  `json:"predicate"`
+}
+
+// Resource is an artifact identified by its digests
+type Resource struct {
+	Name   string            `json:"name,omitempty"`
+	URI    string            `json:"uri,omitempty"`
+	Digest map[string]string `json:"digest"`
+}
+
+// Predicate is a SLSA v1 provenance predicate
+type Predicate struct {
+	BuildDefinition BuildDefinition `json:"buildDefinition"`
+	RunDetails      RunDetails      `json:"runDetails"`
+}
+
+// BuildDefinition holds the inputs of the generation: its parameters and the
+// batch it read
+type BuildDefinition struct {
+	BuildType            string                 `json:"buildType"`
+	ExternalParameters   map[string]interface{} `json:"externalParameters"`
+	InternalParameters   map[string]interface{} `json:"internalParameters,omitempty"`
+	ResolvedDependencies []Resource             `json:"resolvedDependencies"`
+}
+
+// RunDetails identifies the host that generated the artifacts and when
+type RunDetails struct {
+	Builder  Builder  `json:"builder"`
+	Metadata Metadata `json:"metadata"`
+}
+
+// Builder identifies the generation host and the tool version it ran
+type Builder struct {
+	ID      string            `json:"id"`
+	Version map[string]string `json:"version,omitempty"`
+}
+
+// Metadata records when the generation ran
+type Metadata struct {
+	StartedOn  string `json:"startedOn"`
+	FinishedOn string `json:"finishedOn"`
+}
+
+// Envelope is a DSSE envelope holding a statement and its signatures
+type Envelope struct {
+	PayloadType string      `json:"payloadType"`
+	Payload     string      `json:"payload"`
+	Signatures  []Signature `json:"signatures"`
+}
+
+// Signature is a DSSE signature. KeyID is the address of the signing key.
+type Signature struct {
+	KeyID string `json:"keyid"`
+	Sig   string `json:"sig"`
+}
+
+// NewStatement creates a statement about one artifact
+func NewStatement(subject Resource, definition BuildDefinition, builder Builder, started, finished time.Time) Statement {
+	return Statement{
+		Type:          StatementType,
+		Subject:       []Resource{subject},
+		PredicateType: PredicateType,
+		Predicate: Predicate{
+			BuildDefinition: definition,
+			RunDetails: RunDetails{
+				Builder: builder,
+				Metadata: Metadata{
+					StartedOn:  started.UTC().Format(time.RFC3339),
+					FinishedOn: finished.UTC().Format(time.RFC3339),
+				},
+			},
+		},
+	}
+}
+
+// SHA256 returns the digest map of data
+func SHA256(data []byte) map[string]string {
+	return map[string]string{"sha256": fmt.Sprintf("%x", sha256.Sum256(data))}
+}
+
+// PAE is the DSSE pre-authentication encoding of a payload, the message
+// every signature of the envelope signs
+func PAE(payloadType string, payload []byte) []byte {
+	return []byte(fmt.Sprintf("DSSEv1 %d %s %d %s", len(payloadType), payloadType, len(payload), payload))
+}
+
+// Seal wraps the statement in an envelope, signed with s when it is not nil.
+// The signature is a 65 byte secp256k1 signature of the SHA-256 of the PAE.
+func Seal(statement Statement, s signer.Signer) (*Envelope, error) {
+	payload, err := json.Marshal(statement)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode provenance statement: %w", err)
+	}
+	envelope := &Envelope{
+		PayloadType: PayloadType,
+		Payload:     base64.StdEncoding.EncodeToString(payload),
+		Signatures:  []Signature{},
+	}
+	if s == nil {
+		return envelope, nil
+	}
+	digest := sha256.Sum256(PAE(PayloadType, payload))
+	sig, err := s.SignDigest(digest[:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign provenance statement: %w", err)
+	}
+	envelope.Signatures = append(envelope.Signatures, Signature{
+		KeyID: s.Address().Hex(),
+		Sig:   base64.StdEncoding.EncodeToString(sig),
+	})
+	return envelope, nil
+}
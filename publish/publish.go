@@ -0,0 +1,193 @@
+// Package publish uploads bundles to artifact stores under content-addressed
+// names, so consumers fetch them by digest instead of by a mutable path.
+package publish
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// BundleMediaType is the OCI media type of a published bundle layer
+const BundleMediaType = "application/vnd.layerzero.onesig.bundle.v1.tar+gzip"
+
+// gcsUploadEndpoint is the base URL of the Google Cloud Storage upload API
+const gcsUploadEndpoint = "https://storage.googleapis.com/upload/storage/v1/b/"
+
+// Result describes a published bundle
+type Result struct {
+	// SHA256 is the hex SHA-256 of the bundle file
+	SHA256 string
+	// URL is the content-addressed location of the bundle
+	URL string
+}
+
+// Destination is a parsed s3://, gs:// or oci:// publishing target
+type Destination struct {
+	Scheme string
+	// Host is the bucket, or the registry for OCI
+	Host string
+	// Path is the key prefix, or the repository for OCI
+	Path string
+}
+
+// ParseDestination parses a publishing target such as s3://bucket/prefix,
+// gs://bucket/prefix or oci://registry/repository
+func ParseDestination(raw string) (*Destination, error) {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid destination %q, expected s3://, gs:// or oci:// URL", raw)
+	}
+	d := &Destination{Scheme: u.Scheme, Host: u.Host, Path: strings.Trim(u.Path, "/")}
+	switch d.Scheme {
+	case "s3", "gs":
+	case "oci":
+		if d.Path == "" {
+			return nil, fmt.Errorf("invalid destination %q, expected oci://registry/repository", raw)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported destination scheme %q, expected s3, gs or oci", d.Scheme)
+	}
+	return d, nil
+}
+
+// objectName returns the content-addressed object key of a bundle
+func (d *Destination) objectName(digest string) string {
+	return path.Join(d.Path, "sha256", digest+".tar.gz")
+}
+
+// Publish uploads the bundle at file to the destination
+func Publish(ctx context.Context, d *Destination, file string) (*Result, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bundle: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	digest := hex.EncodeToString(sum[:])
+
+	switch d.Scheme {
+	case "s3":
+		return publishS3(ctx, d, file, digest)
+	case "gs":
+		return publishGCS(ctx, d, data, digest)
+	default:
+		return publishOCI(ctx, d, file, digest)
+	}
+}
+
+// publishS3 uploads through the AWS CLI, reusing its credential configuration.
+// The object is only created if the key does not exist yet.
+func publishS3(ctx context.Context, d *Destination, file string, digest string) (*Result, error) {
+	key := d.objectName(digest)
+	sum, err := hex.DecodeString(digest)
+	if err != nil {
+		return nil, err
+	}
+	_, err = run(ctx, "", "aws", "s3api", "put-object",
+		"--bucket", d.Host,
+		"--key", key,
+		"--body", file,
+		"--content-type", "application/gzip",
+		"--checksum-sha256", base64.StdEncoding.EncodeToString(sum),
+		"--if-none-match", "*",
+		"--output", "json",
+	)
+	// A failed precondition means the same content is already published
+	if err != nil && !strings.Contains(err.Error(), "PreconditionFailed") {
+		return nil, err
+	}
+	return &Result{SHA256: digest, URL: "s3://" + d.Host + "/" + key}, nil
+}
+
+// publishGCS uploads through the Cloud Storage JSON API with a gcloud access
+// token. The upload fails rather than replace an existing object.
+func publishGCS(ctx context.Context, d *Destination, data []byte, digest string) (*Result, error) {
+	token := os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN")
+	if token == "" {
+		out, err := exec.CommandContext(ctx, "gcloud", "auth", "print-access-token").Output()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get a gcloud access token: %w", err)
+		}
+		token = strings.TrimSpace(string(out))
+	}
+
+	name := d.objectName(digest)
+	query := url.Values{"uploadType": {"media"}, "name": {name}, "ifGenerationMatch": {"0"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, gcsUploadEndpoint+url.PathEscape(d.Host)+"/o?"+query.Encode(), bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/gzip")
+
+	resp, err := (&http.Client{Timeout: 5 * time.Minute}).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload to cloud storage: %w", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	switch {
+	case resp.StatusCode == http.StatusPreconditionFailed:
+		// The same content is already published under this name
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("cloud storage returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return &Result{SHA256: digest, URL: "gs://" + d.Host + "/" + name}, nil
+}
+
+// publishOCI pushes the bundle as a single layer artifact with the oras CLI,
+// which handles registry authentication. The artifact is addressed by the
+// digest of its manifest.
+func publishOCI(ctx context.Context, d *Destination, file string, digest string) (*Result, error) {
+	repository := d.Host + "/" + d.Path
+	// oras names the layer after the path it is given, so push from the
+	// bundle's directory to keep local paths out of the artifact
+	out, err := run(ctx, filepath.Dir(file), "oras", "push",
+		"--format", "json",
+		"--artifact-type", BundleMediaType,
+		repository+":sha256-"+digest,
+		filepath.Base(file)+":"+BundleMediaType,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Digest string `json:"digest"`
+	}
+	if err := json.Unmarshal(out, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse oras output: %w", err)
+	}
+	if !strings.HasPrefix(result.Digest, "sha256:") {
+		return nil, fmt.Errorf("oras returned no manifest digest")
+	}
+	return &Result{SHA256: digest, URL: repository + "@" + result.Digest}, nil
+}
+
+// run runs a command in dir, or the working directory if empty, and returns
+// its standard output
+func run(ctx context.Context, dir string, name string, args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	command := exec.CommandContext(ctx, name, args...)
+	command.Dir = dir
+	command.Stderr = &stderr
+
+	out, err := command.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s %s failed: %w: %s", name, args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
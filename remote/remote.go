@@ -0,0 +1,123 @@
+// Package remote reads input files from HTTP(S) URLs, S3 and Google Cloud
+// Storage, so pipelines can pass object locations in place of local paths.
+package remote
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// MaxSize bounds the size of a remote file so a bad URL cannot exhaust memory
+const MaxSize = 256 << 20
+
+// gcsDownloadEndpoint is the base URL of the Google Cloud Storage JSON API
+const gcsDownloadEndpoint = "https://storage.googleapis.com/storage/v1/b/"
+
+// IsRemote reports whether location is a URL rather than a local path
+func IsRemote(location string) bool {
+	for _, scheme := range []string{"https://", "http://", "s3://", "gs://"} {
+		if strings.HasPrefix(location, scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+// Read downloads the file at an https://, http://, s3:// or gs:// location
+func Read(ctx context.Context, location string) ([]byte, error) {
+	u, err := url.Parse(location)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid URL %q", location)
+	}
+
+	switch u.Scheme {
+	case "https", "http":
+		return get(ctx, location, "")
+	case "s3":
+		return readS3(ctx, u)
+	case "gs":
+		return readGCS(ctx, u)
+	default:
+		return nil, fmt.Errorf("unsupported URL scheme %q", u.Scheme)
+	}
+}
+
+// readS3 downloads an object through the AWS CLI, reusing its credential configuration
+func readS3(ctx context.Context, u *url.URL) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	command := exec.CommandContext(ctx, "aws", "s3", "cp", u.String(), "-")
+	command.Stdout = &limitedBuffer{buf: &stdout, remaining: MaxSize}
+	command.Stderr = &stderr
+	if err := command.Run(); err != nil {
+		return nil, fmt.Errorf("aws s3 cp failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// readGCS downloads an object through the Cloud Storage JSON API with a
+// gcloud access token
+func readGCS(ctx context.Context, u *url.URL) ([]byte, error) {
+	token := os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN")
+	if token == "" {
+		out, err := exec.CommandContext(ctx, "gcloud", "auth", "print-access-token").Output()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get a gcloud access token: %w", err)
+		}
+		token = strings.TrimSpace(string(out))
+	}
+
+	object := strings.TrimPrefix(u.Path, "/")
+	return get(ctx, gcsDownloadEndpoint+url.PathEscape(u.Host)+"/o/"+url.PathEscape(object)+"?alt=media", token)
+}
+
+// get fetches a URL, with a bearer token if one is given
+func get(ctx context.Context, rawURL string, token string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := (&http.Client{Timeout: 5 * time.Minute}).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("download returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, MaxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download: %w", err)
+	}
+	if len(data) > MaxSize {
+		return nil, fmt.Errorf("remote file is larger than %d bytes", MaxSize)
+	}
+	return data, nil
+}
+
+// limitedBuffer fails writes past its remaining size
+type limitedBuffer struct {
+	buf       *bytes.Buffer
+	remaining int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if len(p) > b.remaining {
+		return 0, fmt.Errorf("remote file is larger than %d bytes", MaxSize)
+	}
+	b.remaining -= len(p)
+	return b.buf.Write(p)
+}
@@ -0,0 +1,221 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+
+	"merkle-cli/models"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// CommonSignatures are function signatures named in exports without configuration
+var CommonSignatures = []string{
+	"transfer(address,uint256)",
+	"transferFrom(address,address,uint256)",
+	"approve(address,uint256)",
+	"mint(address,uint256)",
+	"burn(uint256)",
+	"transferOwnership(address)",
+	"renounceOwnership()",
+	"acceptOwnership()",
+	"grantRole(bytes32,address)",
+	"revokeRole(bytes32,address)",
+	"pause()",
+	"unpause()",
+	"upgradeTo(address)",
+	"setPeer(uint32,bytes32)",
+	"setDelegate(address)",
+	"setEnforcedOptions((uint32,uint16,bytes)[])",
+	"setConfig(address,(uint32,uint32,bytes)[])",
+	"setSendLibrary(address,uint32,address)",
+	"setReceiveLibrary(address,uint32,address,uint256)",
+}
+
+// tokenAmountSignatures are the token functions whose integer arguments are
+// amounts in the token's base units
+var tokenAmountSignatures = map[string]bool{
+	"transfer(address,uint256)":             true,
+	"transferFrom(address,address,uint256)": true,
+	"approve(address,uint256)":              true,
+	"mint(address,uint256)":                 true,
+	"burn(uint256)":                         true,
+}
+
+// Token is the symbol and decimals of a token contract
+type Token struct {
+	Symbol   string
+	Decimals int
+}
+
+// Tokens maps lowercase token addresses to their symbol and decimals
+type Tokens map[string]Token
+
+// Signatures maps 4 byte selectors, as 0x prefixed hex, to function signatures
+type Signatures map[string]string
+
+// NewSignatures indexes function signatures by selector. Values that are not
+// signatures, such as plain selectors, are skipped.
+func NewSignatures(signatures ...[]string) Signatures {
+	index := make(Signatures)
+	for _, list := range signatures {
+		for _, sig := range list {
+			sig = strings.ReplaceAll(sig, " ", "")
+			if !strings.Contains(sig, "(") {
+				continue
+			}
+			index[fmt.Sprintf("0x%x", crypto.Keccak256([]byte(sig))[:4])] = sig
+		}
+	}
+	return index
+}
+
+// WriteMarkdown renders the batch as a Markdown table of its calls, for
+// governance posts announcing an execution. Amounts passed to known tokens
+// are shown in whole tokens.
+func WriteMarkdown(w io.Writer, r *Report, signatures Signatures, tokens Tokens) error {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "## OneSig batch `%s`\n\n", r.MerkleRoot)
+	fmt.Fprintf(&b, "- OneSig ID: %d\n", r.OneSigID)
+	fmt.Fprintf(&b, "- OneSig contract: `%s`\n", r.ContractAddr)
+	stats := r.Stats()
+	fmt.Fprintf(&b, "- %d transaction(s), %d call(s), %s wei in total\n\n", stats.Leaves, stats.Calls, stats.TotalValue)
+
+	b.WriteString("| Nonce | Target | Function | Arguments | Value (wei) | Description |\n")
+	b.WriteString("|---|---|---|---|---|---|\n")
+	for _, leaf := range r.Leaves {
+		nonce := fmt.Sprintf("%d", leaf.Nonce)
+		if leaf.ValidAfter != 0 || leaf.ValidUntil != 0 {
+			nonce = fmt.Sprintf("%d (valid %d to %d)", leaf.Nonce, leaf.ValidAfter, leaf.ValidUntil)
+		}
+		for i, call := range leaf.Calls {
+			function, args := describeCall(call, signatures, tokens)
+			if call.DelegateCall {
+				function = "delegatecall " + function
+			}
+			// The description of the transaction heads its first call
+			var description []string
+			if i == 0 {
+				description = appendAnnotation(description, leaf.Description, leaf.Labels)
+			}
+			description = appendAnnotation(description, call.Description, call.Labels)
+			if t := call.Timelock; t != nil {
+				line := fmt.Sprintf("%s `%s`", t.Kind, t.OperationID)
+				if t.ETA != 0 {
+					line += fmt.Sprintf(", eta %d", t.ETA)
+				}
+				description = append(description, line)
+			}
+			target := "`" + call.To + "`"
+			if call.Label != "" {
+				target += " (" + call.Label + ")"
+			}
+			fmt.Fprintf(&b, "| %s | %s | %s | %s | %s | %s |\n", nonce, markdownCell(target), markdownCell(function), markdownCell(args), call.Value,
+				markdownCell(strings.Join(description, "<br>")))
+		}
+	}
+
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// describeCall names a call's function and formats its arguments, decoding
+// static arguments when the signature is known
+func describeCall(call Call, signatures Signatures, tokens Tokens) (string, string) {
+	if call.Selector == "" {
+		if call.Tail == "" {
+			return "(value transfer)", ""
+		}
+		return "(raw data)", "`" + call.Tail + "`"
+	}
+
+	sig, ok := call.Signature, call.Signature != ""
+	if !ok {
+		sig, ok = signatures[call.Selector]
+	}
+	if !ok {
+		return "`" + call.Selector + "`", rawArgs(call)
+	}
+
+	types := signatureTypes(sig)
+	if types == nil || len(types) != len(call.Args) || call.Tail != "" {
+		return "`" + sig + "`", rawArgs(call)
+	}
+	token, isToken := tokens[strings.ToLower(call.To)]
+	formatted := make([]string, len(types))
+	for i, t := range types {
+		formatted[i] = formatWord(t, call.Args[i])
+		if isToken && tokenAmountSignatures[sig] && strings.HasPrefix(t, "uint") {
+			amount := new(big.Int).SetBytes(common.FromHex(call.Args[i]))
+			formatted[i] = fmt.Sprintf("%s %s (%s)", models.FormatUnits(amount, token.Decimals), token.Symbol, amount)
+		}
+	}
+	return "`" + sig + "`", strings.Join(formatted, ", ")
+}
+
+// signatureTypes returns the parameter types of a signature, or nil if any is
+// not a static single word type
+func signatureTypes(sig string) []string {
+	open := strings.Index(sig, "(")
+	params := strings.TrimSuffix(sig[open+1:], ")")
+	if params == "" {
+		return []string{}
+	}
+	types := strings.Split(params, ",")
+	for _, t := range types {
+		static := t == "address" || t == "bool" || strings.HasPrefix(t, "uint") || strings.HasPrefix(t, "int") ||
+			(strings.HasPrefix(t, "bytes") && t != "bytes")
+		if !static || strings.ContainsAny(t, "[(") {
+			return nil
+		}
+	}
+	return types
+}
+
+// formatWord formats a 32 byte argument word as the given static type
+func formatWord(t string, word string) string {
+	b := common.FromHex(word)
+	switch {
+	case t == "address":
+		return "`" + common.BytesToAddress(b).Hex() + "`"
+	case t == "bool":
+		return fmt.Sprintf("%v", new(big.Int).SetBytes(b).Sign() != 0)
+	case strings.HasPrefix(t, "uint"):
+		return new(big.Int).SetBytes(b).String()
+	default:
+		return "`" + word + "`"
+	}
+}
+
+// rawArgs lists the argument words of a call undecoded
+func rawArgs(call Call) string {
+	var args []string
+	for _, word := range call.Args {
+		args = append(args, "`"+word+"`")
+	}
+	if call.Tail != "" {
+		args = append(args, "`"+call.Tail+"`")
+	}
+	return strings.Join(args, ", ")
+}
+
+// appendAnnotation appends a description and its labels, if any, to lines
+func appendAnnotation(lines []string, description string, labels []string) []string {
+	if len(labels) > 0 {
+		description = strings.TrimSpace(description + " [" + strings.Join(labels, ", ") + "]")
+	}
+	if description == "" {
+		return lines
+	}
+	return append(lines, description)
+}
+
+// markdownCell escapes text for a Markdown table cell
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(s, "\n", " ")
+	return strings.ReplaceAll(s, "|", "\\|")
+}
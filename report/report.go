@@ -0,0 +1,201 @@
+// Package report renders standalone HTML reports and Markdown exports of a
+// generated tree for approvers who will not read the JSON output.
+package report
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"math/big"
+	"strings"
+
+	"merkle-cli/lint"
+	"merkle-cli/models"
+	"merkle-cli/utils"
+)
+
+// Report holds everything shown in the HTML report
+type Report struct {
+	GeneratedAt  string
+	OneSigID     uint64
+	ContractAddr string
+	MerkleRoot   string
+	LeafVersion  uint8
+	TreeOptions  *models.TreeOptions
+	// Policy describes the outcome of the selector policy check
+	Policy   string
+	Warnings []lint.Warning
+	Leaves   []Leaf
+}
+
+// Leaf is one leaf of the tree with its decoded calls and proof
+type Leaf struct {
+	Index      int
+	Nonce      uint64
+	ValidAfter uint64
+	ValidUntil uint64
+	Hash       string
+	Proof      []string
+	Calls      []Call
+	// Description and Labels are carried over from the batch, unhashed
+	Description string
+	Labels      []string
+}
+
+// Call is a call split into its selector and 32 byte argument words
+type Call struct {
+	To       string
+	Value    string
+	Selector string
+	Args     []string
+	// Tail holds calldata bytes after the last full word, if any
+	Tail        string
+	Description string
+	Labels      []string
+	// Label names the target for reviewers, such as "Uniswap V3 Router"
+	Label string
+	// Signature is the function called, when known from the target's verified source
+	Signature string
+	// Timelock is the timelock operation the call schedules or executes, if annotated
+	Timelock *models.TimelockOperation
+	// DelegateCall marks calls made with delegatecall
+	DelegateCall bool
+}
+
+// Stats summarizes the batch
+type Stats struct {
+	Leaves     int
+	Calls      int
+	Targets    int
+	TotalValue string
+	Depth      int
+}
+
+// NewLeaf decodes the calls of a proof entry for the report
+func NewLeaf(entry models.ProofEntry) (Leaf, error) {
+	leaf := Leaf{
+		Index:      entry.LeafIndex,
+		Nonce:      entry.Nonce,
+		ValidAfter: entry.ValidAfter,
+		ValidUntil: entry.ValidUntil,
+		Hash:       entry.LeafHash,
+		Proof:      entry.Proof,
+
+		Description: entry.Description,
+		Labels:      entry.Labels,
+	}
+	for i, c := range entry.Calls {
+		data, err := utils.HexToBytes(c.Data)
+		if err != nil {
+			return Leaf{}, fmt.Errorf("nonce %d call %d: %w", entry.Nonce, i, err)
+		}
+		call := Call{To: c.To, Value: "0", Description: c.Description, Labels: c.Labels, Label: c.ToName, Timelock: c.Timelock, DelegateCall: c.IsDelegateCall()}
+		if c.Value != nil {
+			call.Value = c.Value.String()
+		}
+		if len(data) >= 4 {
+			call.Selector = fmt.Sprintf("0x%x", data[:4])
+			args := data[4:]
+			for len(args) >= 32 {
+				call.Args = append(call.Args, fmt.Sprintf("0x%x", args[:32]))
+				args = args[32:]
+			}
+			if len(args) > 0 {
+				call.Tail = fmt.Sprintf("0x%x", args)
+			}
+		} else if len(data) > 0 {
+			call.Tail = fmt.Sprintf("0x%x", data)
+		}
+		leaf.Calls = append(leaf.Calls, call)
+	}
+	return leaf, nil
+}
+
+// Stats computes the summary statistics of the report
+func (r *Report) Stats() Stats {
+	stats := Stats{Leaves: len(r.Leaves)}
+	total := new(big.Int)
+	targets := make(map[string]bool)
+	for _, leaf := range r.Leaves {
+		stats.Calls += len(leaf.Calls)
+		if len(leaf.Proof) > stats.Depth {
+			stats.Depth = len(leaf.Proof)
+		}
+		for _, call := range leaf.Calls {
+			targets[strings.ToLower(call.To)] = true
+			if v, ok := new(big.Int).SetString(call.Value, 10); ok {
+				total.Add(total, v)
+			}
+		}
+	}
+	stats.Targets = len(targets)
+	stats.TotalValue = total.String()
+	return stats
+}
+
+// WriteHTML renders the report as a single HTML page without external resources
+func WriteHTML(w io.Writer, r *Report) error {
+	return htmlTemplate.Execute(w, r)
+}
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{"join": strings.Join}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>OneSig Merkle Root Report {{.MerkleRoot}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+code, .hash { font-family: monospace; word-break: break-all; }
+table { border-collapse: collapse; margin: 0.5em 0 1.5em; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.6em; text-align: left; vertical-align: top; }
+th { background: #f3f3f3; }
+.root { font-size: 1.2em; padding: 0.6em; background: #eef4ff; border: 1px solid #9bb; }
+.ok { color: #1a7f37; }
+.warn { color: #b35900; }
+details { margin: 0.3em 0; }
+</style>
+</head>
+<body>
+<h1>OneSig Merkle Root Report</h1>
+<p class="root">Merkle root: <code>{{.MerkleRoot}}</code></p>
+<table>
+<tr><th>OneSig ID</th><td>{{.OneSigID}}</td></tr>
+<tr><th>OneSig contract</th><td><code>{{.ContractAddr}}</code></td></tr>
+<tr><th>Leaf encoding version</th><td>{{.LeafVersion}}</td></tr>
+{{with .TreeOptions}}<tr><th>Tree options</th><td>{{.PairOrder}} pairs, sorted leaves: {{.SortLeaves}},{{with .LeafOrder}} leaf order: {{.}},{{end}}{{with .Arity}} arity: {{.}},{{end}} odd node duplicated: {{.DuplicateOdd}}, {{.Hash}}</td></tr>{{end}}
+<tr><th>Generated</th><td>{{.GeneratedAt}}</td></tr>
+</table>
+
+<h2>Summary</h2>
+{{with .Stats}}<table>
+<tr><th>Leaves</th><td>{{.Leaves}}</td></tr>
+<tr><th>Calls</th><td>{{.Calls}}</td></tr>
+<tr><th>Distinct targets</th><td>{{.Targets}}</td></tr>
+<tr><th>Total value (wei)</th><td>{{.TotalValue}}</td></tr>
+<tr><th>Proof length</th><td>{{.Depth}}</td></tr>
+</table>{{end}}
+
+<h2>Checks</h2>
+<p>Selector policy: {{.Policy}}</p>
+{{if .Warnings}}<p class="warn">Lint: {{len .Warnings}} warning(s)</p>
+<table>
+<tr><th>Rule</th><th>Nonce</th><th>Call</th><th>To</th><th>Message</th></tr>
+{{range .Warnings}}<tr><td>{{.Rule}}</td><td>{{.Nonce}}</td><td>{{.Call}}</td><td><code>{{.To}}</code></td><td>{{.Message}}</td></tr>
+{{end}}</table>{{else}}<p class="ok">Lint: no warnings</p>{{end}}
+
+<h2>Leaves</h2>
+{{range .Leaves}}<h3>Nonce {{.Nonce}} (leaf {{.Index}})</h3>
+{{with .Description}}<p>{{.}}</p>{{end}}{{with .Labels}}<p>Labels: {{join . ", "}}</p>{{end}}
+{{if or .ValidAfter .ValidUntil}}<p>Valid after {{.ValidAfter}}, valid until {{.ValidUntil}}</p>{{end}}
+<table>
+<tr><th>#</th><th>To</th><th>Value (wei)</th><th>Selector</th><th>Arguments</th><th>Description</th></tr>
+{{range $i, $c := .Calls}}<tr><td>{{$i}}</td><td><code>{{$c.To}}</code>{{with $c.Label}}<div>{{.}}</div>{{end}}{{if $c.DelegateCall}}<div><strong>delegatecall</strong></div>{{end}}</td><td>{{$c.Value}}</td><td><code>{{$c.Selector}}</code>{{with $c.Signature}}<div><code>{{.}}</code></div>{{end}}</td><td>{{range $c.Args}}<div class="hash">{{.}}</div>{{end}}{{with $c.Tail}}<div class="hash">{{.}}</div>{{end}}</td><td>{{$c.Description}}{{with $c.Labels}} [{{join . ", "}}]{{end}}{{with $c.Timelock}}<div>{{.Kind}} <code>{{.OperationID}}</code>{{with .ETA}}, eta {{.}}{{end}}</div>{{end}}</td></tr>
+{{end}}</table>
+<details><summary>Leaf hash and proof</summary>
+<p>Leaf: <span class="hash">{{.Hash}}</span></p>
+<ol>{{range .Proof}}<li class="hash">{{.}}</li>{{end}}</ol>
+</details>
+{{end}}
+</body>
+</html>
+`))
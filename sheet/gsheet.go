@@ -0,0 +1,236 @@
+package sheet
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// GoogleSheetScheme prefixes the locations of Google Sheets, as gsheet://<spreadsheet-id>/<tab>
+const GoogleSheetScheme = "gsheet://"
+
+// Endpoints of the Google APIs read by ReadGoogleSheet
+const (
+	sheetsEndpoint = "https://sheets.googleapis.com/v4/spreadsheets/"
+	driveEndpoint  = "https://www.googleapis.com/drive/v3/files/"
+	googleScopes   = "https://www.googleapis.com/auth/spreadsheets.readonly https://www.googleapis.com/auth/drive.metadata.readonly"
+)
+
+// maxResponseSize bounds the size of an API response
+const maxResponseSize = 256 << 20
+
+// IsGoogleSheet reports whether location is a gsheet:// location
+func IsGoogleSheet(location string) bool {
+	return strings.HasPrefix(location, GoogleSheetScheme)
+}
+
+// ReadGoogleSheet reads the rows of a tab of a Google Sheet and the Drive
+// version of the spreadsheet they were read at. The sheet is read with the
+// service account of GOOGLE_APPLICATION_CREDENTIALS, or with the access token
+// in GOOGLE_OAUTH_ACCESS_TOKEN.
+func ReadGoogleSheet(ctx context.Context, location string) ([][]Cell, string, error) {
+	id, tab, ok := strings.Cut(strings.TrimPrefix(location, GoogleSheetScheme), "/")
+	if !ok || id == "" || tab == "" {
+		return nil, "", fmt.Errorf("invalid Google Sheet %q, expected %s<spreadsheet-id>/<tab>", location, GoogleSheetScheme)
+	}
+	tab, err := url.PathUnescape(tab)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid Google Sheet tab %q: %w", tab, err)
+	}
+
+	token, err := googleAccessToken(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// The version is read before and after the values, so rows edited in
+	// between are never attributed to either version
+	version, err := driveVersion(ctx, token, id)
+	if err != nil {
+		return nil, "", err
+	}
+	var values struct {
+		Values [][]interface{} `json:"values"`
+	}
+	query := url.Values{"valueRenderOption": {"UNFORMATTED_VALUE"}, "majorDimension": {"ROWS"}}
+	if err := googleGet(ctx, token, sheetsEndpoint+url.PathEscape(id)+"/values/"+url.PathEscape(quoteTab(tab))+"?"+query.Encode(), &values); err != nil {
+		return nil, "", fmt.Errorf("failed to read Google Sheet: %w", err)
+	}
+	after, err := driveVersion(ctx, token, id)
+	if err != nil {
+		return nil, "", err
+	}
+	if after != version {
+		return nil, "", fmt.Errorf("Google Sheet changed from version %s to %s while it was read, retry once it is no longer edited", version, after)
+	}
+
+	rows := make([][]Cell, 0, len(values.Values))
+	for _, values := range values.Values {
+		row := make([]Cell, 0, len(values))
+		for _, v := range values {
+			switch v := v.(type) {
+			case json.Number:
+				row = append(row, Cell{Text: v.String(), Number: true})
+			case string:
+				row = append(row, Cell{Text: v})
+			case bool:
+				row = append(row, Cell{Text: strings.ToUpper(fmt.Sprint(v))})
+			default:
+				row = append(row, Cell{})
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, version, nil
+}
+
+// quoteTab quotes a tab name for use as an A1 range
+func quoteTab(tab string) string {
+	return "'" + strings.ReplaceAll(tab, "'", "''") + "'"
+}
+
+// driveVersion reads the Drive version of a file, which increases with every edit
+func driveVersion(ctx context.Context, token string, id string) (string, error) {
+	var file struct {
+		Version string `json:"version"`
+	}
+	if err := googleGet(ctx, token, driveEndpoint+url.PathEscape(id)+"?fields=version&supportsAllDrives=true", &file); err != nil {
+		return "", fmt.Errorf("failed to read the version of Google Sheet %s: %w", id, err)
+	}
+	if file.Version == "" {
+		return "", fmt.Errorf("Drive returned no version for Google Sheet %s", id)
+	}
+	return file.Version, nil
+}
+
+// googleGet fetches a Google API URL and decodes its JSON response
+func googleGet(ctx context.Context, token string, rawURL string, result interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return doJSON(req, result)
+}
+
+// doJSON sends a request and decodes its JSON response, keeping numbers exact
+func doJSON(req *http.Request, result interface{}) error {
+	resp, err := (&http.Client{Timeout: 5 * time.Minute}).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize+1))
+	if err != nil {
+		return err
+	}
+	if len(data) > maxResponseSize {
+		return fmt.Errorf("response is larger than %d bytes", maxResponseSize)
+	}
+	if resp.StatusCode != http.StatusOK {
+		if len(data) > 512 {
+			data = data[:512]
+		}
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(data)))
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(result)
+}
+
+// serviceAccountKey holds the fields of a service account key file used to
+// obtain access tokens
+type serviceAccountKey struct {
+	Type         string `json:"type"`
+	ClientEmail  string `json:"client_email"`
+	PrivateKey   string `json:"private_key"`
+	PrivateKeyID string `json:"private_key_id"`
+	TokenURI     string `json:"token_uri"`
+}
+
+// googleAccessToken obtains an access token for the service account key file
+// in GOOGLE_APPLICATION_CREDENTIALS, or returns GOOGLE_OAUTH_ACCESS_TOKEN
+func googleAccessToken(ctx context.Context) (string, error) {
+	path := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+	if path == "" {
+		if token := os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN"); token != "" {
+			return token, nil
+		}
+		return "", fmt.Errorf("reading a Google Sheet requires a service account key file in GOOGLE_APPLICATION_CREDENTIALS or a token in GOOGLE_OAUTH_ACCESS_TOKEN")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read service account key: %w", err)
+	}
+	var key serviceAccountKey
+	if err := json.Unmarshal(data, &key); err != nil {
+		return "", fmt.Errorf("failed to parse service account key %s: %w", path, err)
+	}
+	if key.Type != "service_account" || key.ClientEmail == "" || key.TokenURI == "" {
+		return "", fmt.Errorf("%s is not a service account key file", path)
+	}
+	block, _ := pem.Decode([]byte(key.PrivateKey))
+	if block == nil {
+		return "", fmt.Errorf("service account key %s has no PEM private key", path)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse service account private key: %w", err)
+	}
+	rsaKey, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return "", fmt.Errorf("service account private key is not an RSA key")
+	}
+
+	// Exchange a signed JWT assertion for an access token (RFC 7523)
+	now := time.Now()
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": key.PrivateKeyID})
+	claims, _ := json.Marshal(map[string]interface{}{
+		"iss":   key.ClientEmail,
+		"scope": googleScopes,
+		"aud":   key.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign service account assertion: %w", err)
+	}
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {unsigned + "." + base64.RawURLEncoding.EncodeToString(signature)},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, key.TokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := doJSON(req, &token); err != nil {
+		return "", fmt.Errorf("failed to obtain an access token for %s: %w", key.ClientEmail, err)
+	}
+	if token.AccessToken == "" {
+		return "", fmt.Errorf("token endpoint returned no access token for %s", key.ClientEmail)
+	}
+	return token.AccessToken, nil
+}
@@ -0,0 +1,189 @@
+// Package sheet reads transaction batches from spreadsheets laid out with one
+// row per call, so payouts prepared in Excel need no CSV export step.
+package sheet
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"merkle-cli/models"
+)
+
+// Cell is the content of a spreadsheet cell. Number marks cells stored as
+// numbers, which spreadsheets keep as floating point.
+type Cell struct {
+	Text   string
+	Number bool
+}
+
+// maxExactNumber is the largest integer a number cell holds exactly
+const maxExactNumber = 1 << 53
+
+// Columns of the sheet layout, matched case-insensitively against the header row
+const (
+	ColumnNonce       = "nonce"
+	ColumnTo          = "to"
+	ColumnValue       = "value"
+	ColumnData        = "data"
+	ColumnOperation   = "operation"
+	ColumnValidAfter  = "validafter"
+	ColumnValidUntil  = "validuntil"
+	ColumnDescription = "description"
+)
+
+// groupKey identifies the group a row belongs to
+type groupKey struct {
+	nonce      uint64
+	validAfter uint64
+	validUntil uint64
+}
+
+// Batch converts the rows of a sheet into a transaction batch. The first
+// non-empty row names the columns; every later row is one call. Rows with the
+// same nonce and validity window form one group, with their calls in row
+// order. Other columns are ignored.
+func Batch(rows [][]Cell) (*models.TransactionBatch, error) {
+	header := -1
+	columns := make(map[string]int)
+	for i, row := range rows {
+		if isEmpty(row) {
+			continue
+		}
+		header = i
+		for j, cell := range row {
+			name := strings.ToLower(strings.TrimSpace(cell.Text))
+			if name == "" {
+				continue
+			}
+			if _, ok := columns[name]; ok {
+				return nil, fmt.Errorf("row %d: column %q appears twice", i+1, cell.Text)
+			}
+			columns[name] = j
+		}
+		break
+	}
+	if header < 0 {
+		return nil, fmt.Errorf("sheet is empty")
+	}
+	for _, required := range []string{ColumnNonce, ColumnTo} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("row %d: missing %q column", header+1, required)
+		}
+	}
+
+	batch := &models.TransactionBatch{}
+	groups := make(map[groupKey]int)
+	for i := header + 1; i < len(rows); i++ {
+		row := rows[i]
+		if isEmpty(row) {
+			continue
+		}
+		get := func(column string) (Cell, bool) {
+			j, ok := columns[column]
+			if !ok || j >= len(row) {
+				return Cell{}, false
+			}
+			cell := row[j]
+			cell.Text = strings.TrimSpace(cell.Text)
+			return cell, cell.Text != ""
+		}
+		fail := func(column string, err error) error {
+			return fmt.Errorf("row %d, column %s: %w", i+1, column, err)
+		}
+
+		nonceCell, ok := get(ColumnNonce)
+		if !ok {
+			return nil, fail(ColumnNonce, fmt.Errorf("missing nonce"))
+		}
+		nonce, err := parseInteger(nonceCell)
+		if err != nil {
+			return nil, fail(ColumnNonce, err)
+		}
+		to, ok := get(ColumnTo)
+		if !ok {
+			return nil, fail(ColumnTo, fmt.Errorf("missing target"))
+		}
+		call := models.Call{To: to.Text, Data: "0x"}
+		if data, ok := get(ColumnData); ok {
+			call.Data = data.Text
+		}
+		if value, ok := get(ColumnValue); ok {
+			if value.Number {
+				n, err := parseInteger(value)
+				if err != nil {
+					return nil, fail(ColumnValue, err)
+				}
+				call.Value = new(big.Int).SetUint64(n)
+			} else if call.Value, err = models.ParseValue(value.Text); err != nil {
+				return nil, fail(ColumnValue, err)
+			}
+		} else {
+			call.Value = new(big.Int)
+		}
+		if operation, ok := get(ColumnOperation); ok {
+			call.Operation = strings.ToLower(operation.Text)
+		}
+		if description, ok := get(ColumnDescription); ok {
+			call.Description = description.Text
+		}
+
+		var window [2]uint64
+		for k, column := range []string{ColumnValidAfter, ColumnValidUntil} {
+			if cell, ok := get(column); ok {
+				if window[k], err = parseInteger(cell); err != nil {
+					return nil, fail(column, err)
+				}
+			}
+		}
+
+		key := groupKey{nonce: nonce, validAfter: window[0], validUntil: window[1]}
+		g, ok := groups[key]
+		if !ok {
+			g = len(batch.Groups)
+			groups[key] = g
+			batch.Groups = append(batch.Groups, models.TransactionGroup{
+				Nonce:      nonce,
+				ValidAfter: window[0],
+				ValidUntil: window[1],
+			})
+		}
+		batch.Groups[g].Calls = append(batch.Groups[g].Calls, call)
+	}
+	if len(batch.Groups) == 0 {
+		return nil, fmt.Errorf("sheet has no calls below its header row")
+	}
+	batch.FormatVersion = models.BatchFormatVersion
+	return batch, nil
+}
+
+// parseInteger parses a non-negative integer cell. Number cells beyond 2^53
+// have been rounded by the spreadsheet and are rejected.
+func parseInteger(cell Cell) (uint64, error) {
+	if !cell.Number {
+		n, err := strconv.ParseUint(cell.Text, 0, 64)
+		if err != nil {
+			return 0, fmt.Errorf("%q is not a non-negative integer", cell.Text)
+		}
+		return n, nil
+	}
+	f, err := strconv.ParseFloat(cell.Text, 64)
+	if err == nil && f > maxExactNumber {
+		return 0, fmt.Errorf("%s is a number cell larger than 2^53, which the spreadsheet has rounded; format the column as text", cell.Text)
+	}
+	if err != nil || f < 0 || f != float64(uint64(f)) {
+		return 0, fmt.Errorf("%s is not a non-negative integer", cell.Text)
+	}
+	return uint64(f), nil
+}
+
+// isEmpty reports whether a row has no content
+func isEmpty(row []Cell) bool {
+	for _, cell := range row {
+		if strings.TrimSpace(cell.Text) != "" {
+			return false
+		}
+	}
+	return true
+}
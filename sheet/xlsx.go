@@ -0,0 +1,204 @@
+package sheet
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// maxPartSize bounds the uncompressed size of a workbook part, so a zip bomb
+// cannot exhaust memory
+const maxPartSize = 256 << 20
+
+type xlsxWorkbook struct {
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+		ID   string `xml:"id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRelationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+// xlsxText is a string item: plain text, or runs of rich text
+type xlsxText struct {
+	T    string `xml:"t"`
+	Runs []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xlsxText) String() string {
+	if len(t.Runs) == 0 {
+		return t.T
+	}
+	var s strings.Builder
+	for _, r := range t.Runs {
+		s.WriteString(r.T)
+	}
+	return s.String()
+}
+
+type xlsxSharedStrings struct {
+	Items []xlsxText `xml:"si"`
+}
+
+type xlsxWorksheet struct {
+	Rows []struct {
+		R     int `xml:"r,attr"`
+		Cells []struct {
+			R      string   `xml:"r,attr"`
+			T      string   `xml:"t,attr"`
+			V      string   `xml:"v"`
+			Inline xlsxText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// ReadXLSX reads the rows of a sheet of an XLSX workbook, the first sheet
+// when name is empty. Missing cells are returned empty, so every row is
+// indexed by column.
+func ReadXLSX(data []byte, name string) ([][]Cell, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open XLSX workbook: %w", err)
+	}
+	parts := make(map[string]*zip.File)
+	for _, f := range archive.File {
+		parts[f.Name] = f
+	}
+
+	var workbook xlsxWorkbook
+	if err := readPart(parts, "xl/workbook.xml", &workbook); err != nil {
+		return nil, err
+	}
+	var rels xlsxRelationships
+	if err := readPart(parts, "xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return nil, err
+	}
+	var strs xlsxSharedStrings
+	if _, ok := parts["xl/sharedStrings.xml"]; ok {
+		if err := readPart(parts, "xl/sharedStrings.xml", &strs); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(workbook.Sheets) == 0 {
+		return nil, fmt.Errorf("XLSX workbook has no sheets")
+	}
+	id := ""
+	var names []string
+	for _, s := range workbook.Sheets {
+		names = append(names, s.Name)
+		if (name == "" && id == "") || s.Name == name {
+			id = s.ID
+		}
+	}
+	if name != "" && id == "" {
+		return nil, fmt.Errorf("XLSX workbook has no sheet %q, it has %s", name, strings.Join(names, ", "))
+	}
+	target := ""
+	for _, r := range rels.Relationships {
+		if r.ID == id {
+			target = r.Target
+		}
+	}
+	if target == "" {
+		return nil, fmt.Errorf("XLSX workbook does not locate sheet %s", id)
+	}
+	if strings.HasPrefix(target, "/") {
+		target = strings.TrimPrefix(target, "/")
+	} else {
+		target = path.Join("xl", target)
+	}
+
+	var worksheet xlsxWorksheet
+	if err := readPart(parts, target, &worksheet); err != nil {
+		return nil, err
+	}
+	var rows [][]Cell
+	for _, row := range worksheet.Rows {
+		index := len(rows)
+		if row.R > 0 {
+			index = row.R - 1
+		}
+		for len(rows) <= index {
+			rows = append(rows, nil)
+		}
+		for i, c := range row.Cells {
+			column := i
+			if c.R != "" {
+				if column, err = columnIndex(c.R); err != nil {
+					return nil, err
+				}
+			}
+			for len(rows[index]) <= column {
+				rows[index] = append(rows[index], Cell{})
+			}
+			cell := Cell{Text: c.V}
+			switch c.T {
+			case "s":
+				n, err := strconv.Atoi(c.V)
+				if err != nil || n < 0 || n >= len(strs.Items) {
+					return nil, fmt.Errorf("cell %s refers to a missing shared string", c.R)
+				}
+				cell.Text = strs.Items[n].String()
+			case "inlineStr":
+				cell.Text = c.Inline.String()
+			case "str", "e":
+			case "b":
+				cell.Text = map[string]string{"0": "FALSE", "1": "TRUE"}[c.V]
+			default:
+				cell.Number = c.V != ""
+			}
+			rows[index][column] = cell
+		}
+	}
+	return rows, nil
+}
+
+// readPart decodes an XML part of the workbook
+func readPart(parts map[string]*zip.File, name string, v any) error {
+	f, ok := parts[name]
+	if !ok {
+		return fmt.Errorf("XLSX workbook has no %s", name)
+	}
+	r, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	defer r.Close()
+	data, err := io.ReadAll(io.LimitReader(r, maxPartSize+1))
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	if len(data) > maxPartSize {
+		return fmt.Errorf("%s is larger than %d bytes", name, maxPartSize)
+	}
+	if err := xml.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", name, err)
+	}
+	return nil
+}
+
+// columnIndex returns the 0-based column of a cell reference such as "AB12"
+func columnIndex(ref string) (int, error) {
+	column := 0
+	i := 0
+	for ; i < len(ref) && ref[i] >= 'A' && ref[i] <= 'Z'; i++ {
+		column = column*26 + int(ref[i]-'A') + 1
+	}
+	if i == 0 || column > 16384 {
+		return 0, fmt.Errorf("invalid cell reference %q", ref)
+	}
+	return column - 1, nil
+}
@@ -0,0 +1,241 @@
+package signer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"merkle-cli/models"
+	"merkle-cli/utils"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// ErrVerificationFailed is returned when a signature or signer set does not check out
+var ErrVerificationFailed = errors.New("signature verification failed")
+
+// Quorum is the set of addresses allowed to sign and how many of them must sign.
+// An empty Signers list accepts any signer.
+type Quorum struct {
+	Signers   []common.Address
+	Threshold int
+}
+
+// RecoveredSignature is a signature together with the address that produced it.
+// Contract signatures come from EIP-1271 signers and are not ECDSA signatures.
+type RecoveredSignature struct {
+	Signer    common.Address
+	Signature []byte
+	Contract  bool
+}
+
+// Aggregate resolves the signer of every signature over digest and returns them
+// ordered by ascending signer address, which is the order the OneSig contract
+// requires. ECDSA signatures are recovered; signatures from smart contract signers
+// are checked with EIP-1271 through verifier, which may be nil to only accept EOAs.
+// It fails if a signature comes from an unknown signer, a signer signed more than
+// once, or fewer than the threshold of signers signed.
+func Aggregate(digest []byte, files []models.SignatureFile, quorum Quorum, verifier *ContractVerifier) ([]RecoveredSignature, error) {
+	var recovered []RecoveredSignature
+
+	for i, file := range files {
+		sig, err := utils.HexToBytes(file.Signature)
+		if err != nil {
+			return nil, fmt.Errorf("signature %d: invalid hex: %w", i, err)
+		}
+
+		r, err := resolveSignature(digest, file, sig, verifier)
+		if err != nil {
+			return nil, fmt.Errorf("signature %d: %w", i, err)
+		}
+		recovered = append(recovered, *r)
+	}
+
+	sort.SliceStable(recovered, func(i, j int) bool {
+		return bytes.Compare(recovered[i].Signer.Bytes(), recovered[j].Signer.Bytes()) < 0
+	})
+
+	if err := quorum.check(recovered); err != nil {
+		return nil, err
+	}
+
+	return recovered, nil
+}
+
+// resolveSignature determines who produced a signature file, trying ECDSA recovery
+// first and falling back to EIP-1271 for the claimed signer
+func resolveSignature(digest []byte, file models.SignatureFile, sig []byte, verifier *ContractVerifier) (*RecoveredSignature, error) {
+	if len(sig) == crypto.SignatureLength {
+		addr, err := RecoverAddress(digest, sig)
+		if err == nil && (file.Signer == "" || common.HexToAddress(file.Signer) == addr) {
+			// Normalize the recovery ID to {27, 28} as expected by ecrecover
+			if sig[crypto.RecoveryIDOffset] < 27 {
+				sig[crypto.RecoveryIDOffset] += 27
+			}
+			return &RecoveredSignature{Signer: addr, Signature: sig}, nil
+		}
+	}
+
+	if file.Signer == "" {
+		return nil, fmt.Errorf("not a valid ECDSA signature and no signer address given")
+	}
+	claimed := common.HexToAddress(file.Signer)
+
+	if verifier == nil {
+		return nil, fmt.Errorf("%w: does not recover to %s (wrong root or seed?); contract signers require --rpc-url", ErrVerificationFailed, claimed.Hex())
+	}
+
+	isContract, err := verifier.IsContract(claimed)
+	if err != nil {
+		return nil, err
+	}
+	if !isContract {
+		return nil, fmt.Errorf("%w: does not recover to %s (wrong root or seed?)", ErrVerificationFailed, claimed.Hex())
+	}
+
+	valid, err := verifier.IsValidSignature(claimed, digest, sig)
+	if err != nil {
+		return nil, err
+	}
+	if !valid {
+		return nil, fmt.Errorf("%w: rejected by isValidSignature on contract signer %s", ErrVerificationFailed, claimed.Hex())
+	}
+
+	return &RecoveredSignature{Signer: claimed, Signature: sig, Contract: true}, nil
+}
+
+// VerifyPayload checks a signature payload exactly as the OneSig contract would:
+// signatures must be ordered by strictly ascending signer address, come from known
+// signers and reach the threshold. Contract signatures are checked through verifier.
+// It returns the resolved signers.
+func VerifyPayload(digest []byte, payload []byte, quorum Quorum, verifier *ContractVerifier) ([]common.Address, error) {
+	if len(payload) < crypto.SignatureLength {
+		return nil, fmt.Errorf("signature payload must hold at least one %d byte signature, got %d bytes", crypto.SignatureLength, len(payload))
+	}
+
+	var recovered []RecoveredSignature
+
+	// The static part ends where the first contract signature's data begins
+	staticEnd := len(payload)
+	for offset := 0; offset < staticEnd; offset += crypto.SignatureLength {
+		index := offset / crypto.SignatureLength
+		if offset+crypto.SignatureLength > staticEnd {
+			return nil, fmt.Errorf("signature %d: truncated", index)
+		}
+		chunk := payload[offset : offset+crypto.SignatureLength]
+
+		var r RecoveredSignature
+		if chunk[crypto.RecoveryIDOffset] == 0 {
+			signer, sig, dataStart, err := decodeContractSignature(payload, chunk)
+			if err != nil {
+				return nil, fmt.Errorf("signature %d: %w", index, err)
+			}
+			if dataStart < staticEnd {
+				staticEnd = dataStart
+			}
+			if verifier == nil {
+				return nil, fmt.Errorf("signature %d: contract signature from %s requires --rpc-url", index, signer.Hex())
+			}
+
+			valid, err := verifier.IsValidSignature(signer, digest, sig)
+			if err != nil {
+				return nil, fmt.Errorf("signature %d: %w", index, err)
+			}
+			if !valid {
+				return nil, fmt.Errorf("%w: signature %d: rejected by isValidSignature on contract signer %s", ErrVerificationFailed, index, signer.Hex())
+			}
+			r = RecoveredSignature{Signer: signer, Signature: sig, Contract: true}
+		} else {
+			addr, err := RecoverAddress(digest, chunk)
+			if err != nil {
+				return nil, fmt.Errorf("signature %d: %w", index, err)
+			}
+			r = RecoveredSignature{Signer: addr, Signature: chunk}
+		}
+
+		if n := len(recovered); n > 0 && bytes.Compare(recovered[n-1].Signer.Bytes(), r.Signer.Bytes()) > 0 {
+			return nil, fmt.Errorf("%w: signature %d: signer %s is out of order, signatures must be sorted by ascending signer address", ErrVerificationFailed, index, r.Signer.Hex())
+		}
+		recovered = append(recovered, r)
+	}
+
+	if err := quorum.check(recovered); err != nil {
+		return nil, err
+	}
+
+	signers := make([]common.Address, 0, len(recovered))
+	for _, r := range recovered {
+		signers = append(signers, r.Signer)
+	}
+	return signers, nil
+}
+
+// decodeContractSignature reads a contract signature slot: r holds the signer address,
+// s the payload offset of the length-prefixed signature data and v is zero
+func decodeContractSignature(payload []byte, chunk []byte) (common.Address, []byte, int, error) {
+	signer := common.BytesToAddress(chunk[12:32])
+
+	offset := new(big.Int).SetBytes(chunk[32:64])
+	if !offset.IsInt64() || offset.Int64()+32 > int64(len(payload)) {
+		return common.Address{}, nil, 0, fmt.Errorf("contract signature offset out of bounds")
+	}
+	start := int(offset.Int64())
+
+	length := new(big.Int).SetBytes(payload[start : start+32])
+	if !length.IsInt64() || int64(start)+32+length.Int64() > int64(len(payload)) {
+		return common.Address{}, nil, 0, fmt.Errorf("contract signature length out of bounds")
+	}
+
+	return signer, payload[start+32 : start+32+int(length.Int64())], start, nil
+}
+
+// check validates sorted signatures against the quorum
+func (q Quorum) check(recovered []RecoveredSignature) error {
+	allowed := make(map[common.Address]bool)
+	for _, s := range q.Signers {
+		allowed[s] = true
+	}
+
+	for i, r := range recovered {
+		if len(allowed) > 0 && !allowed[r.Signer] {
+			return fmt.Errorf("%w: %s is not a configured signer", ErrVerificationFailed, r.Signer.Hex())
+		}
+		if i > 0 && recovered[i-1].Signer == r.Signer {
+			return fmt.Errorf("%w: duplicate signature from %s", ErrVerificationFailed, r.Signer.Hex())
+		}
+	}
+
+	if len(recovered) < q.Threshold {
+		return fmt.Errorf("%w: quorum not met: %d of %d required signatures", ErrVerificationFailed, len(recovered), q.Threshold)
+	}
+	return nil
+}
+
+// Concat joins the signatures into the payload passed to the OneSig contract.
+// ECDSA signatures are packed as [R || S || V]. Contract signatures take a 65 byte
+// slot holding the signer address, the offset of their data and a zero V, and their
+// length-prefixed data is appended after all slots.
+func Concat(signatures []RecoveredSignature) []byte {
+	var static, dynamic []byte
+	staticLen := len(signatures) * crypto.SignatureLength
+
+	for _, s := range signatures {
+		if !s.Contract {
+			static = append(static, s.Signature...)
+			continue
+		}
+
+		offset := big.NewInt(int64(staticLen + len(dynamic)))
+		static = append(static, common.LeftPadBytes(s.Signer.Bytes(), 32)...)
+		static = append(static, common.LeftPadBytes(offset.Bytes(), 32)...)
+		static = append(static, 0)
+
+		dynamic = append(dynamic, common.LeftPadBytes(big.NewInt(int64(len(s.Signature))).Bytes(), 32)...)
+		dynamic = append(dynamic, s.Signature...)
+	}
+
+	return append(static, dynamic...)
+}
@@ -0,0 +1,77 @@
+package signer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"merkle-cli/chain"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// eip1271MagicValue is returned by isValidSignature(bytes32,bytes) for valid signatures
+var eip1271MagicValue = []byte{0x16, 0x26, 0xba, 0x7e}
+
+// isValidSignatureABI describes the EIP-1271 signature validation function
+const isValidSignatureABI = `[
+	{
+		"name": "isValidSignature",
+		"type": "function",
+		"inputs": [
+			{"name": "_hash", "type": "bytes32"},
+			{"name": "_signature", "type": "bytes"}
+		],
+		"outputs": [
+			{"name": "magicValue", "type": "bytes4"}
+		]
+	}
+]`
+
+// ContractVerifier checks EIP-1271 signatures of smart contract signers over RPC
+type ContractVerifier struct {
+	client *chain.Client
+}
+
+// NewContractVerifier creates a verifier using the given RPC client
+func NewContractVerifier(client *chain.Client) *ContractVerifier {
+	return &ContractVerifier{client: client}
+}
+
+// IsContract reports whether code is deployed at the address
+func (v *ContractVerifier) IsContract(addr common.Address) (bool, error) {
+	code, err := v.client.Code(addr)
+	if err != nil {
+		return false, fmt.Errorf("failed to fetch code of %s: %w", addr.Hex(), err)
+	}
+	return len(code) > 0, nil
+}
+
+// IsValidSignature calls isValidSignature on the signer contract and checks for the magic value
+func (v *ContractVerifier) IsValidSignature(signer common.Address, digest []byte, signature []byte) (bool, error) {
+	contractAbi, err := abi.JSON(strings.NewReader(isValidSignatureABI))
+	if err != nil {
+		return false, fmt.Errorf("failed to parse ABI: %w", err)
+	}
+
+	var hash [32]byte
+	copy(hash[:], digest)
+	calldata, err := contractAbi.Pack("isValidSignature", hash, signature)
+	if err != nil {
+		return false, fmt.Errorf("failed to encode isValidSignature call: %w", err)
+	}
+
+	ret, err := v.client.CallContract(chain.CallMsg{To: signer, Data: calldata})
+	var rpcErr *chain.RPCError
+	if errors.As(err, &rpcErr) {
+		// Contracts commonly revert instead of returning a failure value
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("isValidSignature call to %s failed: %w", signer.Hex(), err)
+	}
+
+	return len(ret) >= 4 && bytes.Equal(ret[:4], eip1271MagicValue), nil
+}
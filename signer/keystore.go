@@ -0,0 +1,147 @@
+package signer
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+)
+
+// keystoreFile is a version 3 web3 secret storage key file as written by geth
+type keystoreFile struct {
+	Address string         `json:"address"`
+	Crypto  keystoreCrypto `json:"crypto"`
+	// Some tools capitalize the crypto section
+	CryptoAlt *keystoreCrypto `json:"Crypto"`
+	Version   int             `json:"version"`
+}
+
+type keystoreCrypto struct {
+	Cipher       string `json:"cipher"`
+	CipherText   string `json:"ciphertext"`
+	CipherParams struct {
+		IV string `json:"iv"`
+	} `json:"cipherparams"`
+	KDF       string                 `json:"kdf"`
+	KDFParams map[string]interface{} `json:"kdfparams"`
+	MAC       string                 `json:"mac"`
+}
+
+// NewKeystoreSigner creates a signer from an encrypted keystore file
+func NewKeystoreSigner(path string, password string) (*KeySigner, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keystore: %w", err)
+	}
+
+	var file keystoreFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse keystore: %w", err)
+	}
+	if file.Version != 3 {
+		return nil, fmt.Errorf("unsupported keystore version %d", file.Version)
+	}
+	if file.CryptoAlt != nil {
+		file.Crypto = *file.CryptoAlt
+	}
+
+	keyBytes, err := decryptKeystore(file.Crypto, password)
+	if err != nil {
+		return nil, err
+	}
+	key, err := crypto.ToECDSA(keyBytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid keystore key: %w", err)
+	}
+
+	s := &KeySigner{key: key}
+	if file.Address != "" && common.HexToAddress(file.Address) != s.Address() {
+		return nil, fmt.Errorf("keystore address %s does not match its key", file.Address)
+	}
+	return s, nil
+}
+
+// decryptKeystore derives the key encryption key and decrypts the private key
+func decryptKeystore(c keystoreCrypto, password string) ([]byte, error) {
+	if c.Cipher != "aes-128-ctr" {
+		return nil, fmt.Errorf("unsupported keystore cipher %q", c.Cipher)
+	}
+
+	cipherText, err := hex.DecodeString(c.CipherText)
+	if err != nil {
+		return nil, fmt.Errorf("invalid keystore ciphertext: %w", err)
+	}
+	iv, err := hex.DecodeString(c.CipherParams.IV)
+	if err != nil {
+		return nil, fmt.Errorf("invalid keystore iv: %w", err)
+	}
+	mac, err := hex.DecodeString(c.MAC)
+	if err != nil {
+		return nil, fmt.Errorf("invalid keystore mac: %w", err)
+	}
+
+	derivedKey, err := deriveKeystoreKey(c, password)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(crypto.Keccak256(derivedKey[16:32], cipherText), mac) {
+		return nil, fmt.Errorf("could not decrypt keystore: wrong password")
+	}
+
+	block, err := aes.NewCipher(derivedKey[:16])
+	if err != nil {
+		return nil, err
+	}
+	plainText := make([]byte, len(cipherText))
+	cipher.NewCTR(block, iv).XORKeyStream(plainText, cipherText)
+	return plainText, nil
+}
+
+// deriveKeystoreKey runs the keystore's scrypt or pbkdf2 key derivation
+func deriveKeystoreKey(c keystoreCrypto, password string) ([]byte, error) {
+	salt, err := hex.DecodeString(kdfString(c.KDFParams, "salt"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid keystore salt: %w", err)
+	}
+	dkLen := kdfInt(c.KDFParams, "dklen")
+	if dkLen < 32 {
+		return nil, fmt.Errorf("keystore dklen must be at least 32, got %d", dkLen)
+	}
+
+	switch c.KDF {
+	case "scrypt":
+		n, r, p := kdfInt(c.KDFParams, "n"), kdfInt(c.KDFParams, "r"), kdfInt(c.KDFParams, "p")
+		return scrypt.Key([]byte(password), salt, n, r, p, dkLen)
+	case "pbkdf2":
+		if prf := kdfString(c.KDFParams, "prf"); prf != "hmac-sha256" {
+			return nil, fmt.Errorf("unsupported keystore pbkdf2 prf %q", prf)
+		}
+		return pbkdf2.Key([]byte(password), salt, kdfInt(c.KDFParams, "c"), dkLen, sha256.New), nil
+	default:
+		return nil, fmt.Errorf("unsupported keystore kdf %q", c.KDF)
+	}
+}
+
+func kdfInt(params map[string]interface{}, name string) int {
+	if v, ok := params[name].(float64); ok {
+		return int(v)
+	}
+	return 0
+}
+
+func kdfString(params map[string]interface{}, name string) string {
+	if v, ok := params[name].(string); ok {
+		return strings.TrimPrefix(v, "0x")
+	}
+	return ""
+}
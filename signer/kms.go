@@ -0,0 +1,264 @@
+package signer
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// gcpKMSEndpoint is the base URL of the Google Cloud KMS REST API
+const gcpKMSEndpoint = "https://cloudkms.googleapis.com/v1/"
+
+// secp256k1HalfN is half the order of the secp256k1 curve, the upper bound for canonical S values
+var secp256k1HalfN = new(big.Int).Rsh(crypto.S256().Params().N, 1)
+
+// kmsBackend signs SHA-256 sized digests with a key that never leaves the KMS
+type kmsBackend interface {
+	// publicKeyDER returns the DER encoded SubjectPublicKeyInfo of the key
+	publicKeyDER() ([]byte, error)
+	// signDigest returns a DER encoded ECDSA signature over the digest
+	signDigest(digest []byte) ([]byte, error)
+}
+
+// KMSSigner signs with a secp256k1 key held in AWS KMS or Google Cloud KMS
+type KMSSigner struct {
+	backend kmsBackend
+	pub     *ecdsa.PublicKey
+}
+
+// NewKMSSigner creates a signer from a "aws:<key-id>" or "gcp:<key-version-resource>" spec
+func NewKMSSigner(spec string) (*KMSSigner, error) {
+	provider, keyID, ok := strings.Cut(spec, ":")
+	if !ok || keyID == "" {
+		return nil, fmt.Errorf("invalid KMS key %q, expected aws:<key-id> or gcp:<key-version-resource>", spec)
+	}
+
+	var backend kmsBackend
+	switch provider {
+	case "aws":
+		backend = &awsKMS{keyID: keyID}
+	case "gcp":
+		backend = &gcpKMS{resource: keyID}
+	default:
+		return nil, fmt.Errorf("unsupported KMS provider %q", provider)
+	}
+
+	der, err := backend.publicKeyDER()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch KMS public key: %w", err)
+	}
+	pub, err := parseSecp256k1PublicKey(der)
+	if err != nil {
+		return nil, err
+	}
+
+	return &KMSSigner{backend: backend, pub: pub}, nil
+}
+
+// Address returns the address of the KMS key
+func (s *KMSSigner) Address() common.Address {
+	return crypto.PubkeyToAddress(*s.pub)
+}
+
+// SignDigest signs the digest in the KMS and converts the DER signature into an
+// Ethereum signature with a low S value and the recovery ID matching the key
+func (s *KMSSigner) SignDigest(digest []byte) ([]byte, error) {
+	der, err := s.backend.signDigest(digest)
+	if err != nil {
+		return nil, fmt.Errorf("KMS signing failed: %w", err)
+	}
+
+	var sig struct {
+		R, S *big.Int
+	}
+	if _, err := asn1.Unmarshal(der, &sig); err != nil {
+		return nil, fmt.Errorf("failed to parse KMS signature: %w", err)
+	}
+
+	// KMS does not enforce EIP-2 canonical signatures
+	if sig.S.Cmp(secp256k1HalfN) > 0 {
+		sig.S = new(big.Int).Sub(crypto.S256().Params().N, sig.S)
+	}
+
+	rs := append(common.LeftPadBytes(sig.R.Bytes(), 32), common.LeftPadBytes(sig.S.Bytes(), 32)...)
+	expected := crypto.FromECDSAPub(s.pub)
+	for v := byte(0); v < 2; v++ {
+		candidate := append(append([]byte{}, rs...), v)
+		recovered, err := crypto.Ecrecover(digest, candidate)
+		if err == nil && bytes.Equal(recovered, expected) {
+			candidate[crypto.RecoveryIDOffset] += 27
+			return candidate, nil
+		}
+	}
+
+	return nil, fmt.Errorf("KMS signature does not recover to the key's address")
+}
+
+// parseSecp256k1PublicKey decodes a SubjectPublicKeyInfo holding a secp256k1 key,
+// which the standard library does not support
+func parseSecp256k1PublicKey(der []byte) (*ecdsa.PublicKey, error) {
+	var spki struct {
+		Algorithm pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}
+	if _, err := asn1.Unmarshal(der, &spki); err != nil {
+		return nil, fmt.Errorf("failed to parse KMS public key: %w", err)
+	}
+
+	pub, err := crypto.UnmarshalPubkey(spki.PublicKey.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("KMS key is not a secp256k1 key: %w", err)
+	}
+	return pub, nil
+}
+
+// awsKMS signs through the AWS CLI, reusing its credential configuration
+type awsKMS struct {
+	keyID string
+}
+
+func (k *awsKMS) publicKeyDER() ([]byte, error) {
+	var result struct {
+		PublicKey string `json:"PublicKey"`
+	}
+	if err := runJSON(&result, "aws", "kms", "get-public-key", "--key-id", k.keyID, "--output", "json"); err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(result.PublicKey)
+}
+
+func (k *awsKMS) signDigest(digest []byte) ([]byte, error) {
+	tmp, err := os.CreateTemp("", "merkle-cli-digest-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(digest); err != nil {
+		tmp.Close()
+		return nil, err
+	}
+	tmp.Close()
+
+	var result struct {
+		Signature string `json:"Signature"`
+	}
+	err = runJSON(&result, "aws", "kms", "sign",
+		"--key-id", k.keyID,
+		"--message", "fileb://"+tmp.Name(),
+		"--message-type", "DIGEST",
+		"--signing-algorithm", "ECDSA_SHA_256",
+		"--output", "json",
+	)
+	if err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(result.Signature)
+}
+
+// gcpKMS signs through the Cloud KMS REST API with a gcloud access token
+type gcpKMS struct {
+	resource string
+}
+
+func (k *gcpKMS) publicKeyDER() ([]byte, error) {
+	var result struct {
+		Pem string `json:"pem"`
+	}
+	if err := k.request(&result, http.MethodGet, k.resource+"/publicKey", nil); err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode([]byte(result.Pem))
+	if block == nil {
+		return nil, fmt.Errorf("public key is not PEM encoded")
+	}
+	return block.Bytes, nil
+}
+
+func (k *gcpKMS) signDigest(digest []byte) ([]byte, error) {
+	body := map[string]interface{}{
+		"digest": map[string]string{"sha256": base64.StdEncoding.EncodeToString(digest)},
+	}
+
+	var result struct {
+		Signature string `json:"signature"`
+	}
+	if err := k.request(&result, http.MethodPost, k.resource+":asymmetricSign", body); err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(result.Signature)
+}
+
+// request calls the Cloud KMS REST API and decodes the JSON response
+func (k *gcpKMS) request(result interface{}, method string, path string, body interface{}) error {
+	token := os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN")
+	if token == "" {
+		out, err := exec.Command("gcloud", "auth", "print-access-token").Output()
+		if err != nil {
+			return fmt.Errorf("failed to get a gcloud access token: %w", err)
+		}
+		token = strings.TrimSpace(string(out))
+	}
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, gcpKMSEndpoint+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := (&http.Client{Timeout: 30 * time.Second}).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("cloud KMS returned %s: %s", resp.Status, strings.TrimSpace(string(data)))
+	}
+	return json.Unmarshal(data, result)
+}
+
+// runJSON runs a command and decodes its JSON standard output
+func runJSON(result interface{}, name string, args ...string) error {
+	var stderr bytes.Buffer
+	command := exec.Command(name, args...)
+	command.Stderr = &stderr
+
+	out, err := command.Output()
+	if err != nil {
+		return fmt.Errorf("%s %s failed: %w: %s", name, args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	if err := json.Unmarshal(out, result); err != nil {
+		return fmt.Errorf("failed to parse %s output: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package signer
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Signer signs 32 byte digests on behalf of a single address
+type Signer interface {
+	// Address returns the address whose signatures the signer produces
+	Address() common.Address
+	// SignDigest returns a 65 byte [R || S || V] signature with V in {27, 28}
+	SignDigest(digest []byte) ([]byte, error)
+}
+
+// KeySigner signs with an in-memory private key
+type KeySigner struct {
+	key *ecdsa.PrivateKey
+}
+
+// NewKeySigner creates a signer from a hex encoded private key
+func NewKeySigner(hexKey string) (*KeySigner, error) {
+	key, err := crypto.HexToECDSA(strings.TrimPrefix(hexKey, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key: %w", err)
+	}
+	return &KeySigner{key: key}, nil
+}
+
+// Address returns the address of the key
+func (s *KeySigner) Address() common.Address {
+	return crypto.PubkeyToAddress(s.key.PublicKey)
+}
+
+// SignDigest signs the digest with the key
+func (s *KeySigner) SignDigest(digest []byte) ([]byte, error) {
+	sig, err := crypto.Sign(digest, s.key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign digest: %w", err)
+	}
+	sig[crypto.RecoveryIDOffset] += 27
+	return sig, nil
+}
+
+// RecoverAddress returns the address that produced a 65 byte signature over digest.
+// Both {0, 1} and {27, 28} recovery IDs are accepted.
+func RecoverAddress(digest []byte, signature []byte) (common.Address, error) {
+	if len(signature) != crypto.SignatureLength {
+		return common.Address{}, fmt.Errorf("signature must be %d bytes, got %d", crypto.SignatureLength, len(signature))
+	}
+
+	sig := make([]byte, len(signature))
+	copy(sig, signature)
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+	if sig[crypto.RecoveryIDOffset] > 1 {
+		return common.Address{}, fmt.Errorf("invalid signature recovery ID %d", signature[crypto.RecoveryIDOffset])
+	}
+
+	pub, err := crypto.SigToPub(digest, sig)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("failed to recover signer: %w", err)
+	}
+	return crypto.PubkeyToAddress(*pub), nil
+}
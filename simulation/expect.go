@@ -0,0 +1,143 @@
+package simulation
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"merkle-cli/chain"
+	"merkle-cli/models"
+	"merkle-cli/utils"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// balanceOfSelector is the selector of ERC-20 balanceOf(address)
+var balanceOfSelector = crypto.Keccak256([]byte("balanceOf(address)"))[:4]
+
+// CheckExpectations compares the simulated leaf against its declared expectations
+// and records every unmet expectation in the result
+func (s *Simulator) CheckExpectations(result *LeafResult, expect *models.Expectations) error {
+	if expect == nil {
+		return nil
+	}
+
+	var logs []chain.Log
+	for _, call := range result.Calls {
+		logs = append(logs, call.Logs...)
+	}
+
+	for i, event := range expect.Events {
+		matched, err := eventEmitted(event, logs)
+		if err != nil {
+			return fmt.Errorf("expect.events[%d]: %w", i, err)
+		}
+		if !matched {
+			result.Failures = append(result.Failures, fmt.Sprintf("expected event %s was not emitted", describeEvent(event)))
+		}
+	}
+
+	for i, expected := range expect.Balances {
+		if expected.Balance == nil {
+			return fmt.Errorf("expect.balances[%d]: balance is required", i)
+		}
+
+		actual, err := s.balance(expected)
+		if err != nil {
+			return fmt.Errorf("expect.balances[%d]: %w", i, err)
+		}
+		if actual.Cmp(expected.Balance) != 0 {
+			result.Failures = append(result.Failures, fmt.Sprintf("balance of %s: expected %s, got %s",
+				describeBalance(expected), expected.Balance, actual))
+		}
+	}
+
+	if len(result.Failures) > 0 {
+		result.Success = false
+	}
+	return nil
+}
+
+// eventEmitted reports whether any log matches the expected event
+func eventEmitted(event models.ExpectedEvent, logs []chain.Log) (bool, error) {
+	topics := make([]string, 0, len(event.Topics)+1)
+	if event.Signature != "" {
+		topics = append(topics, crypto.Keccak256Hash([]byte(event.Signature)).Hex())
+	}
+	topics = append(topics, event.Topics...)
+
+	var data []byte
+	if event.Data != "" {
+		var err error
+		if data, err = utils.HexToBytes(event.Data); err != nil {
+			return false, fmt.Errorf("invalid data: %w", err)
+		}
+	}
+
+	for _, log := range logs {
+		if event.Address != "" && common.HexToAddress(event.Address) != log.Address {
+			continue
+		}
+		if len(topics) > len(log.Topics) {
+			continue
+		}
+
+		match := true
+		for i, topic := range topics {
+			if topic != "" && common.HexToHash(topic) != log.Topics[i] {
+				match = false
+				break
+			}
+		}
+		if event.Data != "" && string(data) != string(log.Data) {
+			match = false
+		}
+		if match {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// balance fetches the native or ERC-20 balance named by the expectation
+func (s *Simulator) balance(expected models.ExpectedBalance) (*big.Int, error) {
+	account := common.HexToAddress(expected.Address)
+	if expected.Token == "" {
+		return s.client.Balance(account)
+	}
+
+	calldata := append(append([]byte{}, balanceOfSelector...), common.LeftPadBytes(account.Bytes(), 32)...)
+	ret, err := s.client.CallContract(chain.CallMsg{To: common.HexToAddress(expected.Token), Data: calldata})
+	if err != nil {
+		return nil, fmt.Errorf("balanceOf call failed: %w", err)
+	}
+	if len(ret) < 32 {
+		return nil, fmt.Errorf("balanceOf returned %d bytes", len(ret))
+	}
+	return new(big.Int).SetBytes(ret[:32]), nil
+}
+
+// describeEvent renders an expected event for failure messages
+func describeEvent(event models.ExpectedEvent) string {
+	var parts []string
+	if event.Signature != "" {
+		parts = append(parts, event.Signature)
+	}
+	if event.Address != "" {
+		parts = append(parts, "from "+event.Address)
+	}
+	if len(event.Topics) > 0 {
+		parts = append(parts, "topics ["+strings.Join(event.Topics, ", ")+"]")
+	}
+	return strings.Join(parts, " ")
+}
+
+// describeBalance renders an expected balance for failure messages
+func describeBalance(expected models.ExpectedBalance) string {
+	if expected.Token == "" {
+		return expected.Address
+	}
+	return fmt.Sprintf("%s (token %s)", expected.Address, expected.Token)
+}
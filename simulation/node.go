@@ -0,0 +1,90 @@
+package simulation
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"time"
+
+	"merkle-cli/chain"
+)
+
+// nodeStartTimeout bounds how long a spawned node may take to answer RPC requests
+const nodeStartTimeout = 60 * time.Second
+
+// NodeKinds lists the local development nodes that can be spawned
+var NodeKinds = []string{"anvil", "hardhat"}
+
+// Node is a local development node spawned for a simulation or conformance run
+type Node struct {
+	Kind string
+	URL  string
+
+	process *exec.Cmd
+}
+
+// StartNode launches a development node of the given kind forking forkURL on port
+// and waits until it answers RPC requests. An empty forkURL starts a fresh chain.
+func StartNode(kind string, binary string, forkURL string, port int) (*Node, error) {
+	var process *exec.Cmd
+	switch kind {
+	case "anvil":
+		if binary == "" {
+			binary = "anvil"
+		}
+		// A zero base fee lets impersonated accounts send transactions without
+		// topping up their balance, which would distort value transfers
+		args := []string{"--port", strconv.Itoa(port), "--block-base-fee-per-gas", "0", "--silent"}
+		if forkURL != "" {
+			args = append(args, "--fork-url", forkURL)
+		}
+		process = exec.Command(binary, args...)
+	case "hardhat":
+		if binary == "" {
+			binary = "npx"
+		}
+		args := []string{"hardhat", "node", "--port", strconv.Itoa(port)}
+		if forkURL != "" {
+			args = append(args, "--fork", forkURL)
+		}
+		process = exec.Command(binary, args...)
+	default:
+		return nil, fmt.Errorf("unsupported node kind %q", kind)
+	}
+
+	if err := process.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start %s: %w", kind, err)
+	}
+
+	node := &Node{Kind: kind, URL: fmt.Sprintf("http://127.0.0.1:%d", port), process: process}
+	client := chain.NewClient(node.URL)
+
+	deadline := time.Now().Add(nodeStartTimeout)
+	for {
+		if _, err := client.BlockNumber(); err == nil {
+			return node, nil
+		}
+		if time.Now().After(deadline) {
+			node.Stop()
+			return nil, fmt.Errorf("%s did not become ready within %s", kind, nodeStartTimeout)
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+// Stop terminates the node process
+func (n *Node) Stop() {
+	if n.process == nil || n.process.Process == nil {
+		return
+	}
+	n.process.Process.Kill()
+	n.process.Wait()
+}
+
+// MethodPrefix returns the namespace of the node's development RPC methods
+func MethodPrefix(kind string) string {
+	if kind == "hardhat" {
+		return "hardhat"
+	}
+	return "anvil"
+}
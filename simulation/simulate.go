@@ -0,0 +1,176 @@
+package simulation
+
+import (
+	"fmt"
+	"math/big"
+	"time"
+
+	"merkle-cli/chain"
+	"merkle-cli/models"
+	"merkle-cli/utils"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// callGasLimit is the gas limit given to every simulated call
+const callGasLimit = 30_000_000
+
+// AccountDiff describes how a single account changed during a call
+type AccountDiff struct {
+	BalanceBefore *hexutil.Big                `json:"balanceBefore,omitempty"`
+	BalanceAfter  *hexutil.Big                `json:"balanceAfter,omitempty"`
+	Storage       map[common.Hash]common.Hash `json:"storage,omitempty"`
+}
+
+// CallResult is the outcome of one simulated call
+type CallResult struct {
+	To        string                         `json:"to"`
+	TxHash    string                         `json:"txHash"`
+	Success   bool                           `json:"success"`
+	GasUsed   uint64                         `json:"gasUsed"`
+	Logs      []chain.Log                    `json:"logs"`
+	StateDiff map[common.Address]AccountDiff `json:"stateDiff,omitempty"`
+}
+
+// LeafResult is the outcome of simulating every call of a leaf
+type LeafResult struct {
+	Nonce    uint64       `json:"nonce"`
+	LeafHash string       `json:"leafHash"`
+	Success  bool         `json:"success"`
+	Calls    []CallResult `json:"calls"`
+	Failures []string     `json:"failures,omitempty"`
+}
+
+// Simulator replays leaves on a forked node as if sent by the OneSig contract
+type Simulator struct {
+	client *chain.Client
+	prefix string
+	oneSig common.Address
+}
+
+// NewSimulator impersonates the OneSig contract on the node at url
+func NewSimulator(kind string, url string, oneSig common.Address) (*Simulator, error) {
+	s := &Simulator{client: chain.NewClient(url), prefix: MethodPrefix(kind), oneSig: oneSig}
+
+	if err := s.client.Call(nil, s.prefix+"_impersonateAccount", oneSig); err != nil {
+		return nil, fmt.Errorf("failed to impersonate %s: %w", oneSig.Hex(), err)
+	}
+
+	return s, nil
+}
+
+// SimulateLeaf executes every call of the leaf in order. Like the OneSig contract,
+// a leaf is atomic: if any call reverts, the state changes of the whole leaf are rolled back.
+func (s *Simulator) SimulateLeaf(entry models.ProofEntry) (*LeafResult, error) {
+	var snapshot hexutil.Big
+	if err := s.client.Call(&snapshot, "evm_snapshot"); err != nil {
+		return nil, fmt.Errorf("failed to snapshot state: %w", err)
+	}
+
+	result := &LeafResult{Nonce: entry.Nonce, LeafHash: entry.LeafHash, Success: true}
+	for i, call := range entry.Calls {
+		callResult, err := s.simulateCall(call)
+		if err != nil {
+			return nil, fmt.Errorf("call %d: %w", i, err)
+		}
+		result.Calls = append(result.Calls, *callResult)
+
+		if !callResult.Success {
+			result.Success = false
+			break
+		}
+	}
+
+	if !result.Success {
+		if err := s.client.Call(nil, "evm_revert", &snapshot); err != nil {
+			return nil, fmt.Errorf("failed to roll back reverted leaf: %w", err)
+		}
+		return result, nil
+	}
+
+	if err := s.CheckExpectations(result, entry.Expect); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// simulateCall sends a single call from the impersonated OneSig contract
+func (s *Simulator) simulateCall(call models.Call) (*CallResult, error) {
+	data, err := utils.HexToBytes(call.Data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid call data: %w", err)
+	}
+
+	gas := hexutil.Uint64(callGasLimit)
+	msg := chain.CallMsg{
+		From:     s.oneSig,
+		To:       common.HexToAddress(call.To),
+		Data:     data,
+		Gas:      &gas,
+		GasPrice: (*hexutil.Big)(new(big.Int)),
+	}
+	if call.Value != nil {
+		msg.Value = (*hexutil.Big)(call.Value)
+	}
+
+	hash, err := s.client.SendTransaction(msg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send call: %w", err)
+	}
+
+	receipt, err := s.client.WaitForReceipt(hash, 1, time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &CallResult{
+		To:      call.To,
+		TxHash:  hash.Hex(),
+		Success: receipt.Succeeded(),
+		GasUsed: uint64(receipt.GasUsed),
+		Logs:    receipt.Logs,
+	}
+
+	// State diffs rely on the prestate tracer, which not every node supports
+	if diff, err := s.stateDiff(hash); err == nil {
+		result.StateDiff = diff
+	}
+
+	return result, nil
+}
+
+// prestateAccount is an account entry returned by the prestate tracer
+type prestateAccount struct {
+	Balance *hexutil.Big                `json:"balance"`
+	Storage map[common.Hash]common.Hash `json:"storage"`
+}
+
+// stateDiff traces the transaction with the prestate tracer in diff mode
+func (s *Simulator) stateDiff(hash common.Hash) (map[common.Address]AccountDiff, error) {
+	var trace struct {
+		Pre  map[common.Address]prestateAccount `json:"pre"`
+		Post map[common.Address]prestateAccount `json:"post"`
+	}
+	tracer := map[string]interface{}{
+		"tracer":       "prestateTracer",
+		"tracerConfig": map[string]interface{}{"diffMode": true},
+	}
+	if err := s.client.Call(&trace, "debug_traceTransaction", hash, tracer); err != nil {
+		return nil, err
+	}
+
+	diff := make(map[common.Address]AccountDiff)
+	for addr, post := range trace.Post {
+		pre := trace.Pre[addr]
+		account := AccountDiff{Storage: post.Storage}
+		if post.Balance != nil {
+			account.BalanceBefore = pre.Balance
+			account.BalanceAfter = post.Balance
+		}
+		diff[addr] = account
+	}
+
+	return diff, nil
+}
@@ -0,0 +1,185 @@
+package timelock
+
+import (
+	"fmt"
+	"math/big"
+
+	"merkle-cli/chain"
+	"merkle-cli/models"
+	"merkle-cli/utils"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Entry is a call of a batch to a TimelockController
+type Entry struct {
+	Nonce    uint64
+	Call     int
+	Timelock common.Address
+	Op       *Operation
+	// ValidAfter and ValidUntil are the validity window of the call's leaf
+	ValidAfter uint64
+	ValidUntil uint64
+	// ETA is the earliest time the operation can execute, or 0 if unknown
+	ETA uint64
+}
+
+// Issue is a problem found with the schedules of a batch. Errors are calls
+// the timelock would reject; warnings are schedules that conflict.
+type Issue struct {
+	Nonce   uint64
+	Call    int
+	Error   bool
+	Message string
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("nonce %d call %d: %s", i.Nonce, i.Call, i.Message)
+}
+
+// Entries decodes the calls of a batch to TimelockControllers, in batch
+// order. Operations executed by a leaf get the ETA of their schedule in the
+// batch when the scheduling leaf has a validAfter.
+func Entries(batch *models.TransactionBatch) ([]Entry, error) {
+	var entries []Entry
+	etas := make(map[key]uint64)
+	for _, group := range batch.Groups {
+		for i, call := range group.Calls {
+			data, err := utils.ParseHex(call.Data, "data", false)
+			if err != nil {
+				return nil, fmt.Errorf("nonce %d call %d: %w", group.Nonce, i, err)
+			}
+			op, err := Decode(data)
+			if err != nil {
+				return nil, fmt.Errorf("nonce %d call %d: %w", group.Nonce, i, err)
+			}
+			if op == nil || !common.IsHexAddress(call.To) {
+				continue
+			}
+			entry := Entry{
+				Nonce:      group.Nonce,
+				Call:       i,
+				Timelock:   common.HexToAddress(call.To),
+				Op:         op,
+				ValidAfter: group.ValidAfter,
+				ValidUntil: group.ValidUntil,
+			}
+			k := key{entry.Timelock, op.ID}
+			switch op.Kind {
+			case KindSchedule, KindScheduleBatch:
+				if group.ValidAfter != 0 && op.Delay.IsUint64() {
+					entry.ETA = group.ValidAfter + op.Delay.Uint64()
+					if _, ok := etas[k]; !ok {
+						etas[k] = entry.ETA
+					}
+				}
+			case KindExecute, KindExecuteBatch:
+				entry.ETA = etas[k]
+			}
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// key identifies an operation on a timelock
+type key struct {
+	timelock common.Address
+	id       common.Hash
+}
+
+// Check validates the delays of the scheduled operations against the minimum
+// delay of their timelock and reports conflicting schedules: operations
+// scheduled twice, executed before or in the same leaf as their schedule,
+// executed by a leaf that expires before they are ready, executed before
+// their predecessor, or cancelled in the batch that schedules them.
+func Check(entries []Entry, minDelay func(timelock common.Address) (uint64, error)) ([]Issue, error) {
+	var issues []Issue
+	scheduled := make(map[key]Entry)
+	executed := make(map[key]Entry)
+	for _, e := range entries {
+		k := key{e.Timelock, e.Op.ID}
+		issue := func(isError bool, format string, args ...interface{}) {
+			issues = append(issues, Issue{Nonce: e.Nonce, Call: e.Call, Error: isError, Message: fmt.Sprintf(format, args...)})
+		}
+
+		switch e.Op.Kind {
+		case KindSchedule, KindScheduleBatch:
+			if minDelay != nil {
+				min, err := minDelay(e.Timelock)
+				if err != nil {
+					return nil, fmt.Errorf("failed to read the minimum delay of %s: %w", e.Timelock.Hex(), err)
+				}
+				if e.Op.Delay.Cmp(new(big.Int).SetUint64(min)) < 0 {
+					issue(true, "%s of %s requests a delay of %ss, below the minimum delay of %ds", e.Op.Kind, e.Op.ID.Hex(), e.Op.Delay, min)
+				}
+			}
+			if first, ok := scheduled[k]; ok {
+				issue(false, "operation %s is already scheduled by nonce %d call %d", e.Op.ID.Hex(), first.Nonce, first.Call)
+				continue
+			}
+			scheduled[k] = e
+
+		case KindExecute, KindExecuteBatch:
+			if s, ok := scheduled[k]; ok {
+				if s.Nonce == e.Nonce && s.Op.Delay.Sign() > 0 {
+					issue(false, "operation %s is executed in the leaf that schedules it, before its delay of %ss has passed", e.Op.ID.Hex(), s.Op.Delay)
+				}
+				if s.ETA != 0 && e.ValidUntil != 0 && e.ValidUntil < s.ETA {
+					issue(false, "leaf expires at %d, before operation %s is ready at %d", e.ValidUntil, e.Op.ID.Hex(), s.ETA)
+				}
+			} else if s, ok := later(entries, e, k, KindSchedule, KindScheduleBatch); ok {
+				issue(false, "operation %s is executed before nonce %d schedules it", e.Op.ID.Hex(), s.Nonce)
+			}
+			predecessor := key{e.Timelock, e.Op.Predecessor}
+			if _, ok := executed[predecessor]; !ok && e.Op.Predecessor != (common.Hash{}) {
+				if _, ok := later(entries, e, predecessor, KindExecute, KindExecuteBatch); ok {
+					issue(false, "operation %s is executed before its predecessor %s", e.Op.ID.Hex(), e.Op.Predecessor.Hex())
+				}
+			}
+			executed[k] = e
+
+		case KindCancel:
+			if s, ok := scheduled[k]; ok {
+				issue(false, "operation %s is cancelled after nonce %d schedules it in the same batch", e.Op.ID.Hex(), s.Nonce)
+			}
+		}
+	}
+	return issues, nil
+}
+
+// later returns the first entry after e that calls one of kinds for operation k
+func later(entries []Entry, e Entry, k key, kinds ...string) (Entry, bool) {
+	after := false
+	for _, other := range entries {
+		if other.Nonce == e.Nonce && other.Call == e.Call {
+			after = true
+			continue
+		}
+		if !after || (key{other.Timelock, other.Op.ID}) != k {
+			continue
+		}
+		for _, kind := range kinds {
+			if other.Op.Kind == kind {
+				return other, true
+			}
+		}
+	}
+	return Entry{}, false
+}
+
+// MinDelay reads getMinDelay() of a TimelockController
+func MinDelay(client *chain.Client, timelock common.Address) (uint64, error) {
+	result, err := client.CallContract(chain.CallMsg{To: timelock, Data: GetMinDelaySelector})
+	if err != nil {
+		return 0, err
+	}
+	if len(result) != 32 {
+		return 0, fmt.Errorf("getMinDelay() returned %d bytes", len(result))
+	}
+	delay := new(big.Int).SetBytes(result)
+	if !delay.IsUint64() {
+		return 0, fmt.Errorf("getMinDelay() returned %s", delay)
+	}
+	return delay.Uint64(), nil
+}
@@ -0,0 +1,200 @@
+// Package timelock decodes calls to OpenZeppelin TimelockController
+// contracts and computes the ids of the operations they schedule and execute.
+package timelock
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Operation kinds, named after the TimelockController functions
+const (
+	KindSchedule      = "schedule"
+	KindScheduleBatch = "scheduleBatch"
+	KindExecute       = "execute"
+	KindExecuteBatch  = "executeBatch"
+	KindCancel        = "cancel"
+)
+
+// selectors maps the selectors of the TimelockController functions to their kind
+var selectors = map[[4]byte]string{
+	selector("schedule(address,uint256,bytes,bytes32,bytes32,uint256)"):            KindSchedule,
+	selector("scheduleBatch(address[],uint256[],bytes[],bytes32,bytes32,uint256)"): KindScheduleBatch,
+	selector("execute(address,uint256,bytes,bytes32,bytes32)"):                     KindExecute,
+	selector("executeBatch(address[],uint256[],bytes[],bytes32,bytes32)"):          KindExecuteBatch,
+	selector("cancel(bytes32)"):                                                    KindCancel,
+}
+
+// timelockABI describes the TimelockController operation functions and the
+// views hashing their operation ids
+const timelockABI = `[
+	{
+		"name": "schedule",
+		"type": "function",
+		"inputs": [
+			{"name": "target", "type": "address"},
+			{"name": "value", "type": "uint256"},
+			{"name": "data", "type": "bytes"},
+			{"name": "predecessor", "type": "bytes32"},
+			{"name": "salt", "type": "bytes32"},
+			{"name": "delay", "type": "uint256"}
+		]
+	},
+	{
+		"name": "scheduleBatch",
+		"type": "function",
+		"inputs": [
+			{"name": "targets", "type": "address[]"},
+			{"name": "values", "type": "uint256[]"},
+			{"name": "payloads", "type": "bytes[]"},
+			{"name": "predecessor", "type": "bytes32"},
+			{"name": "salt", "type": "bytes32"},
+			{"name": "delay", "type": "uint256"}
+		]
+	},
+	{
+		"name": "execute",
+		"type": "function",
+		"inputs": [
+			{"name": "target", "type": "address"},
+			{"name": "value", "type": "uint256"},
+			{"name": "payload", "type": "bytes"},
+			{"name": "predecessor", "type": "bytes32"},
+			{"name": "salt", "type": "bytes32"}
+		]
+	},
+	{
+		"name": "executeBatch",
+		"type": "function",
+		"inputs": [
+			{"name": "targets", "type": "address[]"},
+			{"name": "values", "type": "uint256[]"},
+			{"name": "payloads", "type": "bytes[]"},
+			{"name": "predecessor", "type": "bytes32"},
+			{"name": "salt", "type": "bytes32"}
+		]
+	},
+	{
+		"name": "cancel",
+		"type": "function",
+		"inputs": [
+			{"name": "id", "type": "bytes32"}
+		]
+	},
+	{
+		"name": "hashOperation",
+		"type": "function",
+		"inputs": [
+			{"name": "target", "type": "address"},
+			{"name": "value", "type": "uint256"},
+			{"name": "data", "type": "bytes"},
+			{"name": "predecessor", "type": "bytes32"},
+			{"name": "salt", "type": "bytes32"}
+		],
+		"outputs": [{"name": "", "type": "bytes32"}]
+	},
+	{
+		"name": "hashOperationBatch",
+		"type": "function",
+		"inputs": [
+			{"name": "targets", "type": "address[]"},
+			{"name": "values", "type": "uint256[]"},
+			{"name": "payloads", "type": "bytes[]"},
+			{"name": "predecessor", "type": "bytes32"},
+			{"name": "salt", "type": "bytes32"}
+		],
+		"outputs": [{"name": "", "type": "bytes32"}]
+	}
+]`
+
+// GetMinDelaySelector is getMinDelay() on a TimelockController
+var GetMinDelaySelector = crypto.Keccak256([]byte("getMinDelay()"))[:4]
+
+func selector(sig string) [4]byte {
+	var s [4]byte
+	copy(s[:], crypto.Keccak256([]byte(sig)))
+	return s
+}
+
+// Operation is a timelock operation scheduled, executed or cancelled by a call
+type Operation struct {
+	Kind        string
+	ID          common.Hash
+	Targets     []common.Address
+	Values      []*big.Int
+	Payloads    [][]byte
+	Predecessor common.Hash
+	Salt        common.Hash
+	// Delay is the delay requested by schedule and scheduleBatch
+	Delay *big.Int
+}
+
+// Decode decodes the calldata of a call to a TimelockController. It returns
+// nil if the calldata does not call one of its operation functions.
+func Decode(data []byte) (*Operation, error) {
+	if len(data) < 4 {
+		return nil, nil
+	}
+	var s [4]byte
+	copy(s[:], data)
+	kind, ok := selectors[s]
+	if !ok {
+		return nil, nil
+	}
+	contractAbi, err := abi.JSON(strings.NewReader(timelockABI))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ABI: %w", err)
+	}
+	args, err := contractAbi.Methods[kind].Inputs.UnpackValues(data[4:])
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s calldata: %w", kind, err)
+	}
+	op := &Operation{Kind: kind}
+
+	switch kind {
+	case KindCancel:
+		op.ID = args[0].([32]byte)
+		return op, nil
+	case KindSchedule, KindExecute:
+		op.Targets = []common.Address{args[0].(common.Address)}
+		op.Values = []*big.Int{args[1].(*big.Int)}
+		op.Payloads = [][]byte{args[2].([]byte)}
+	default:
+		op.Targets = args[0].([]common.Address)
+		op.Values = args[1].([]*big.Int)
+		op.Payloads = args[2].([][]byte)
+		if len(op.Targets) != len(op.Values) || len(op.Targets) != len(op.Payloads) {
+			return nil, fmt.Errorf("%s has %d targets, %d values and %d payloads", kind, len(op.Targets), len(op.Values), len(op.Payloads))
+		}
+	}
+	op.Predecessor = args[3].([32]byte)
+	op.Salt = args[4].([32]byte)
+	if kind == KindSchedule || kind == KindScheduleBatch {
+		op.Delay = args[5].(*big.Int)
+	}
+	if op.ID, err = op.hash(contractAbi); err != nil {
+		return nil, err
+	}
+	return op, nil
+}
+
+// hash computes the operation id as TimelockController.hashOperation and
+// hashOperationBatch do, hashing their ABI-encoded arguments
+func (op *Operation) hash(contractAbi abi.ABI) (common.Hash, error) {
+	var encoded []byte
+	var err error
+	if op.Kind == KindSchedule || op.Kind == KindExecute {
+		encoded, err = contractAbi.Methods["hashOperation"].Inputs.Pack(op.Targets[0], op.Values[0], op.Payloads[0], op.Predecessor, op.Salt)
+	} else {
+		encoded, err = contractAbi.Methods["hashOperationBatch"].Inputs.Pack(op.Targets, op.Values, op.Payloads, op.Predecessor, op.Salt)
+	}
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("failed to encode %s operation: %w", op.Kind, err)
+	}
+	return crypto.Keccak256Hash(encoded), nil
+}
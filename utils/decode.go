@@ -0,0 +1,201 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+
+	"merkle-cli/models"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// leafHeaderLength is the size of the version, oneSigId, address and nonce fields
+const leafHeaderLength = 1 + 8 + 32 + 8
+
+// leafFieldLengths is the size of the fields each version packs after the nonce
+var leafFieldLengths = map[byte]int{
+	LeafEncodingVersion:          0,
+	LeafEncodingVersionWindowed:  16,
+	LeafEncodingVersionOperation: 16,
+	LeafEncodingVersionSalted:    16 + SaltSize,
+}
+
+// DecodeLeaf parses a packed leaf preimage produced by EncodeLeafData for
+// call targets of the given format and returns the leaf and its encoding
+// version. Only canonical encodings are accepted: re-encoding the result
+// yields exactly the input. Malformed input returns an error and never panics.
+func DecodeLeaf(data []byte, targetFormat string) (models.Leaf, byte, error) {
+	var leaf models.Leaf
+	if len(data) < leafHeaderLength {
+		return leaf, 0, fmt.Errorf("leaf data is %d bytes, shorter than the %d byte header", len(data), leafHeaderLength)
+	}
+
+	version := data[0]
+	fieldLength, ok := leafFieldLengths[version]
+	if !ok {
+		return leaf, 0, fmt.Errorf("%w: leaf encoding version %d", ErrUnsupportedVersion, version)
+	}
+	if len(data) < leafHeaderLength+fieldLength {
+		return leaf, 0, fmt.Errorf("leaf data is too short for version %d fields", version)
+	}
+
+	leaf.OneSigID = binary.BigEndian.Uint64(data[1:9])
+	if !isZero(data[9:21]) {
+		return leaf, 0, fmt.Errorf("contract address word has non-zero high bytes")
+	}
+	leaf.ContractAddr = common.BytesToAddress(data[21:41]).Hex()
+	leaf.Nonce = binary.BigEndian.Uint64(data[41:49])
+	if version >= LeafEncodingVersionWindowed {
+		leaf.ValidAfter = binary.BigEndian.Uint64(data[49:57])
+		leaf.ValidUntil = binary.BigEndian.Uint64(data[57:65])
+	}
+	if version >= LeafEncodingVersionSalted {
+		// A zero salt is the encoding of a leaf without one
+		if salt := data[65 : 65+SaltSize]; !isZero(salt) {
+			leaf.Salt = NormalizeHex(salt)
+		}
+	}
+
+	calls, err := DecodeCallsVersion(data[leafHeaderLength+fieldLength:], version, targetFormat)
+	if err != nil {
+		return models.Leaf{}, 0, err
+	}
+	leaf.Calls = calls
+	if targetFormat == models.TargetFormatBytes32 {
+		leaf.TargetFormat = targetFormat
+	}
+
+	reencoded, err := EncodeLeafData(leaf, version)
+	if err != nil {
+		return models.Leaf{}, 0, fmt.Errorf("decoded leaf does not re-encode: %w", err)
+	}
+	if !bytes.Equal(reencoded, data) {
+		return models.Leaf{}, 0, fmt.Errorf("leaf data is not canonically encoded")
+	}
+
+	return leaf, version, nil
+}
+
+// DecodeCalls parses abi.encode(Call[]) with bounds checks on every offset and length
+func DecodeCalls(data []byte) ([]models.Call, error) {
+	return DecodeCallsVersion(data, LeafEncodingVersion, models.TargetFormatAddress)
+}
+
+// DecodeCallsVersion parses abi.encode(Call[]) using the Call struct of the
+// given leaf encoding version and target format. Bytes32 targets are returned
+// as the full 32 byte word.
+func DecodeCallsVersion(data []byte, version byte, targetFormat string) ([]models.Call, error) {
+	bytes32 := targetFormat == models.TargetFormatBytes32
+	if !bytes32 && targetFormat != "" && targetFormat != models.TargetFormatAddress {
+		return nil, fmt.Errorf("%w: unknown target format %q", ErrTargetFormat, targetFormat)
+	}
+	arrayOffset, err := readWordInt(data, 0, "calls offset")
+	if err != nil {
+		return nil, err
+	}
+	count, err := readWordInt(data, arrayOffset, "calls length")
+	if err != nil {
+		return nil, err
+	}
+	if count > MaxCallsPerLeafHard {
+		return nil, fmt.Errorf("%w: leaf has %d calls, exceeding the hard limit of %d", ErrLimitExceeded, count, MaxCallsPerLeafHard)
+	}
+
+	// Tuple offsets are relative to the first word after the length
+	base := arrayOffset + 32
+	calls := make([]models.Call, 0, count)
+	for i := 0; i < count; i++ {
+		rel, err := readWordInt(data, base+32*i, fmt.Sprintf("calls[%d] offset", i))
+		if err != nil {
+			return nil, err
+		}
+		tuple := base + rel
+
+		to, err := readWord(data, tuple, fmt.Sprintf("calls[%d].to", i))
+		if err != nil {
+			return nil, err
+		}
+		if !bytes32 && !isZero(to[:12]) {
+			return nil, fmt.Errorf("calls[%d].to has non-zero high bytes", i)
+		}
+		value, err := readWord(data, tuple+32, fmt.Sprintf("calls[%d].value", i))
+		if err != nil {
+			return nil, err
+		}
+		dataRel, err := readWordInt(data, tuple+64, fmt.Sprintf("calls[%d].data offset", i))
+		if err != nil {
+			return nil, err
+		}
+		dataLen, err := readWordInt(data, tuple+dataRel, fmt.Sprintf("calls[%d].data length", i))
+		if err != nil {
+			return nil, err
+		}
+		if dataLen > MaxCallDataBytesHard {
+			return nil, fmt.Errorf("%w: calls[%d].data is %d bytes, exceeding the hard limit of %d", ErrLimitExceeded, i, dataLen, MaxCallDataBytesHard)
+		}
+		start := tuple + dataRel + 32
+		if start > len(data) || dataLen > len(data)-start {
+			return nil, fmt.Errorf("calls[%d].data runs past the end of the input", i)
+		}
+
+		call := models.Call{
+			To:    common.BytesToAddress(to[12:]).Hex(),
+			Value: new(big.Int).SetBytes(value),
+			Data:  NormalizeHex(data[start : start+dataLen]),
+		}
+		if bytes32 {
+			call.To = NormalizeHex(to)
+		}
+		if version >= LeafEncodingVersionOperation {
+			operation, err := readWord(data, tuple+96, fmt.Sprintf("calls[%d].operation", i))
+			if err != nil {
+				return nil, err
+			}
+			switch {
+			case isZero(operation):
+			case isZero(operation[:31]) && operation[31] == 1:
+				call.Operation = models.OperationDelegateCall
+			default:
+				return nil, fmt.Errorf("calls[%d].operation is neither call nor delegatecall", i)
+			}
+		}
+		calls = append(calls, call)
+	}
+
+	return calls, nil
+}
+
+// readWord returns the 32 byte word at offset
+func readWord(data []byte, offset int, field string) ([]byte, error) {
+	if offset < 0 || offset > len(data) || len(data)-offset < 32 {
+		return nil, fmt.Errorf("%s at offset %d runs past the end of the input", field, offset)
+	}
+	return data[offset : offset+32], nil
+}
+
+// readWordInt reads a word holding an offset or length that must fit the input
+func readWordInt(data []byte, offset int, field string) (int, error) {
+	word, err := readWord(data, offset, field)
+	if err != nil {
+		return 0, err
+	}
+	if !isZero(word[:24]) {
+		return 0, fmt.Errorf("%s is out of range", field)
+	}
+	v := binary.BigEndian.Uint64(word[24:])
+	if v > uint64(len(data)) {
+		return 0, fmt.Errorf("%s %d exceeds the input length %d", field, v, len(data))
+	}
+	return int(v), nil
+}
+
+func isZero(b []byte) bool {
+	for _, c := range b {
+		if c != 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,75 @@
+package utils
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"merkle-cli/models"
+)
+
+// fuzzLeaf is a leaf using every field some encoding version packs
+var fuzzLeaf = models.Leaf{
+	OneSigID:     1,
+	ContractAddr: "0x000000000000000000000000000000000000dEaD",
+	Nonce:        7,
+	Calls: []models.Call{
+		{To: "0x1111111111111111111111111111111111111111", Value: big.NewInt(1), Data: "0x"},
+		{To: "0x2222222222222222222222222222222222222222", Value: new(big.Int), Data: "0xa9059cbb0000000000000000000000003333333333333333333333333333333333333333"},
+	},
+}
+
+// fuzzBytes32Leaf is fuzzLeaf with calls addressed by 32 byte ids
+var fuzzBytes32Leaf = models.Leaf{
+	OneSigID:     fuzzLeaf.OneSigID,
+	ContractAddr: fuzzLeaf.ContractAddr,
+	Nonce:        fuzzLeaf.Nonce,
+	TargetFormat: models.TargetFormatBytes32,
+	Calls: []models.Call{
+		{To: "0xffffffffffffffffffffffff1111111111111111111111111111111111111111", Value: big.NewInt(1), Data: "0x"},
+		{To: "0x0000000000000000000000002222222222222222222222222222222222222222", Data: "0x01"},
+	},
+}
+
+// FuzzDecodeLeaf checks that DecodeLeaf never panics and that every leaf it
+// accepts re-encodes to exactly the input
+func FuzzDecodeLeaf(f *testing.F) {
+	for _, version := range SupportedLeafEncodingVersions() {
+		for _, leaf := range []models.Leaf{fuzzLeaf, fuzzBytes32Leaf} {
+			if version >= LeafEncodingVersionWindowed {
+				leaf.ValidAfter, leaf.ValidUntil = 100, 200
+			}
+			if version >= LeafEncodingVersionSalted {
+				leaf.Salt = NormalizeHex(bytes.Repeat([]byte{0x5a}, SaltSize))
+			}
+			data, err := EncodeLeafData(leaf, version)
+			if err != nil {
+				f.Fatal(err)
+			}
+			bytes32 := leaf.HasBytes32Targets()
+			f.Add(data, bytes32)
+			f.Add(data, !bytes32)
+			f.Add(data[:len(data)-1], bytes32)
+		}
+	}
+	f.Add([]byte{}, false)
+	f.Add([]byte{LeafEncodingVersion}, true)
+
+	f.Fuzz(func(t *testing.T, data []byte, bytes32 bool) {
+		targetFormat := models.TargetFormatAddress
+		if bytes32 {
+			targetFormat = models.TargetFormatBytes32
+		}
+		leaf, version, err := DecodeLeaf(data, targetFormat)
+		if err != nil {
+			return
+		}
+		reencoded, err := EncodeLeafData(leaf, version)
+		if err != nil {
+			t.Fatalf("decoded leaf does not re-encode: %v", err)
+		}
+		if !bytes.Equal(reencoded, data) {
+			t.Fatalf("decoded leaf re-encodes to 0x%x, decoded 0x%x", reencoded, data)
+		}
+	})
+}
@@ -0,0 +1,37 @@
+package utils
+
+import "errors"
+
+// Error kinds returned by the encoder and validators. Match them with errors.Is;
+// the returned errors wrap them with details about the offending input.
+var (
+	// ErrInvalidHex is returned for malformed hex strings
+	ErrInvalidHex = errors.New("invalid hex")
+
+	// ErrDuplicateNonce is returned when leaves sharing a nonce could be valid at the same time
+	ErrDuplicateNonce = errors.New("duplicate nonce")
+
+	// ErrDependency is returned when nonces do not order leaves after the leaves they depend on
+	ErrDependency = errors.New("dependency order violated")
+
+	// ErrUnsupportedVersion is returned for unknown leaf encoding and file format versions
+	ErrUnsupportedVersion = errors.New("unsupported version")
+
+	// ErrLimitExceeded is returned when an input exceeds a size limit
+	ErrLimitExceeded = errors.New("limit exceeded")
+
+	// ErrTargetFormat is returned for call targets that do not match the target
+	// format of their leaf, or leaves of one OneSig ID with different formats
+	ErrTargetFormat = errors.New("invalid target format")
+
+	// ErrUnknownField is returned for keys of an input document that are not
+	// fields of it, usually misspelled ones
+	ErrUnknownField = errors.New("unknown field")
+
+	// ErrValueRange is returned for call values, or sums of the values of a
+	// leaf's calls, that are negative or do not fit in a uint256
+	ErrValueRange = errors.New("value out of range")
+
+	// ErrEncoding is returned when well formed input fails to ABI encode
+	ErrEncoding = errors.New("encoding failed")
+)
@@ -0,0 +1,133 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// CheckUnknownFields reports the first key of a JSON document that is not a
+// field of v, which the document is decoded into. Unlike encoding/json, keys
+// must match the field names exactly, so "oneSigID" is reported for a
+// "oneSigId" field. Fields are found in nested objects and arrays, also those
+// of types with their own UnmarshalJSON, such as calls.
+func CheckUnknownFields(data []byte, v interface{}) error {
+	return unknownFields(data, reflect.TypeOf(v), "")
+}
+
+// unknownFields checks the JSON value raw against type t, at path in the document
+func unknownFields(raw json.RawMessage, t reflect.Type, path string) error {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	raw = json.RawMessage(strings.TrimSpace(string(raw)))
+	if len(raw) == 0 {
+		return nil
+	}
+
+	switch {
+	case raw[0] == '{' && t.Kind() == reflect.Struct:
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &object); err != nil {
+			return nil
+		}
+		fields := jsonFields(t)
+		keys := make([]string, 0, len(object))
+		for key := range object {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			field, ok := fields[key]
+			if !ok {
+				return unknownFieldError(key, path, fields)
+			}
+			if err := unknownFields(object[key], field, joinPath(path, key)); err != nil {
+				return err
+			}
+		}
+	case raw[0] == '{' && t.Kind() == reflect.Map:
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &object); err != nil {
+			return nil
+		}
+		for key, value := range object {
+			if err := unknownFields(value, t.Elem(), joinPath(path, key)); err != nil {
+				return err
+			}
+		}
+	case raw[0] == '[' && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array):
+		var array []json.RawMessage
+		if err := json.Unmarshal(raw, &array); err != nil {
+			return nil
+		}
+		for i, value := range array {
+			if err := unknownFields(value, t.Elem(), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// jsonFields maps the JSON names of the fields of a struct, including those
+// of embedded structs, to their types
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				for key, value := range jsonFields(embedded) {
+					if _, ok := fields[key]; !ok {
+						fields[key] = value
+					}
+				}
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		// Fields of the outer struct shadow those of embedded structs
+		fields[name] = field.Type
+	}
+	return fields
+}
+
+// unknownFieldError reports an unknown key, suggesting the field it differs
+// from only in case
+func unknownFieldError(key, path string, fields map[string]reflect.Type) error {
+	location := "the top level"
+	if path != "" {
+		location = path
+	}
+	for name := range fields {
+		if strings.EqualFold(name, key) {
+			return fmt.Errorf("%w %q in %s, did you mean %q?", ErrUnknownField, key, location, name)
+		}
+	}
+	return fmt.Errorf("%w %q in %s", ErrUnknownField, key, location)
+}
+
+// joinPath appends a key to a path in a JSON document
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,46 @@
+package utils
+
+import "strings"
+
+// FingerprintLength is the number of words in a digest fingerprint
+const FingerprintLength = 6
+
+// fingerprintWords maps each byte value to a distinct, easy to read word
+var fingerprintWords = [256]string{
+	"acid", "acorn", "actor", "adobe", "agent", "alarm", "album", "alpha", "amber", "angle", "apple",
+	"april", "arena", "armor", "arrow", "aspen", "atlas", "attic", "audio", "autumn", "bacon",
+	"badge", "bagel", "baker", "bamboo", "banjo", "barley", "basil", "beach", "beaver", "bell",
+	"berry", "bison", "blade", "blanket", "bloom", "bonus", "border", "bottle", "brave", "bread",
+	"brick", "bridge", "bronze", "brush", "bucket", "buffalo", "bugle", "butter", "cabin", "cactus",
+	"camel", "candle", "canoe", "canyon", "carbon", "cargo", "carpet", "castle", "cedar", "cello",
+	"chalk", "cherry", "chess", "cider", "cinema", "circus", "citrus", "clover", "cobalt", "cocoa",
+	"comet", "copper", "coral", "cotton", "cougar", "coyote", "crane", "crater", "cricket", "crystal",
+	"cycle", "dagger", "daisy", "delta", "denim", "desert", "diesel", "dinner", "dolphin", "donkey",
+	"dragon", "drum", "eagle", "echo", "eclipse", "elbow", "ember", "emerald", "engine", "falcon",
+	"feather", "ferry", "fiddle", "finch", "flame", "flute", "forest", "fossil", "fox", "galaxy",
+	"garlic", "garnet", "gecko", "ginger", "glacier", "globe", "goose", "granite", "grape", "gravel",
+	"guitar", "hammer", "harbor", "harvest", "hazel", "helmet", "heron", "honey", "hornet", "husky",
+	"igloo", "indigo", "iris", "island", "ivory", "jacket", "jaguar", "jasmine", "jelly", "jigsaw",
+	"jungle", "kayak", "kernel", "kettle", "kiwi", "koala", "ladder", "lagoon", "lantern", "lava",
+	"lemon", "lentil", "lilac", "linen", "lizard", "lobster", "locket", "lotus", "lunar", "magnet",
+	"mango", "maple", "marble", "meadow", "melon", "meteor", "mint", "mirror", "mocha", "monkey",
+	"mosaic", "motor", "muffin", "nectar", "needle", "nickel", "noodle", "nutmeg", "oasis", "ocean",
+	"olive", "onyx", "opal", "orbit", "orchid", "otter", "oyster", "paddle", "panda", "panther",
+	"papaya", "parrot", "peach", "pebble", "pepper", "piano", "pilot", "pine", "planet", "plum",
+	"pocket", "polar", "pony", "poppy", "prism", "puffin", "pumpkin", "quartz", "quill", "rabbit",
+	"radar", "radish", "raven", "reef", "ribbon", "rocket", "rose", "ruby", "saddle", "saffron",
+	"salmon", "satin", "scarf", "shadow", "shell", "silver", "sketch", "sparrow", "spider", "spruce",
+	"squid", "stone", "sugar", "summit", "tango", "tiger", "timber", "toast", "tomato", "topaz",
+	"torch", "tulip", "tundra", "turnip", "turtle", "valley", "velvet", "violet", "walnut", "walrus",
+	"willow", "yacht", "zebra", "zenith", "zinc",
+}
+
+// Fingerprint returns words derived from the first bytes of a digest, short
+// enough to read aloud and compare when checking a digest out of band
+func Fingerprint(digest []byte) string {
+	words := make([]string, 0, FingerprintLength)
+	for i := 0; i < FingerprintLength && i < len(digest); i++ {
+		words = append(words, fingerprintWords[digest[i]])
+	}
+	return strings.Join(words, "-")
+}
@@ -1,7 +1,7 @@
 package utils
 
 import (
-	"encoding/hex"
+	"context"
 	"fmt"
 	"math/big"
 	"strings"
@@ -13,38 +13,150 @@ import (
 	"github.com/ethereum/go-ethereum/crypto"
 )
 
+const (
+	// MaxCallDataBytesHard caps the calldata of a single call regardless of configuration
+	MaxCallDataBytesHard = 16 << 20
+
+	// MaxCallsPerLeafHard caps the number of calls in a leaf regardless of configuration
+	MaxCallsPerLeafHard = 1 << 16
+)
+
 const (
 	// LeafEncodingVersion is the version byte for the leaf encoding
 	LeafEncodingVersion byte = 1
+
+	// LeafEncodingVersionWindowed is the version byte for leaves that carry a validity window
+	LeafEncodingVersionWindowed byte = 2
+
+	// LeafEncodingVersionOperation is the version byte for leaves that carry a
+	// validity window and whose calls carry an operation type
+	LeafEncodingVersionOperation byte = 3
+
+	// LeafEncodingVersionSalted is the version byte for leaves that also carry
+	// a 32 byte salt after their validity window
+	LeafEncodingVersionSalted byte = 4
 )
 
+// EncoderRevision identifies the implementation of the leaf encoders and tree
+// builder. Bump it with any change that alters the output for the same input,
+// so results cached by earlier builds are not reused.
+const EncoderRevision = 2
+
+// leafFieldEncoders maps each supported encoding version to the fields it packs
+// between the nonce and the encoded calls. New versions only need a new entry here.
+var leafFieldEncoders = map[byte]func(leaf models.Leaf) []byte{
+	LeafEncodingVersion:          func(models.Leaf) []byte { return nil },
+	LeafEncodingVersionWindowed:  encodeValidityWindow,
+	LeafEncodingVersionOperation: encodeValidityWindow,
+	LeafEncodingVersionSalted:    encodeSaltedFields,
+}
+
+// SupportedLeafEncodingVersions returns every leaf encoding version known to the encoder
+func SupportedLeafEncodingVersions() []byte {
+	return []byte{LeafEncodingVersion, LeafEncodingVersionWindowed, LeafEncodingVersionOperation, LeafEncodingVersionSalted}
+}
+
 // EncodeLeaf encodes a transaction as a leaf according to OneSig spec
 func EncodeLeaf(oneSigID uint64, contractAddr string, nonce uint64, calls []models.Call) ([]byte, error) {
+	return EncodeLeafVersion(models.Leaf{
+		OneSigID:     oneSigID,
+		ContractAddr: contractAddr,
+		Nonce:        nonce,
+		Calls:        calls,
+	}, LeafEncodingVersion)
+}
+
+// EncodeLeafVersion encodes a leaf using the given leaf encoding version
+func EncodeLeafVersion(leaf models.Leaf, version byte) ([]byte, error) {
+	leafData, err := EncodeLeafData(leaf, version)
+	if err != nil {
+		return nil, err
+	}
+
+	// Double hash leaf data (equivalent to Solidity's keccak256(keccak256(...)))
+	firstHash := crypto.Keccak256(leafData)
+	finalHash := crypto.Keccak256(firstHash)
+
+	return finalHash, nil
+}
+
+// EncodeLeafData returns the packed leaf preimage that EncodeLeafVersion hashes.
+// It has no side effects and never panics, whatever the leaf holds.
+func EncodeLeafData(leaf models.Leaf, version byte) ([]byte, error) {
+	encodeFields, ok := leafFieldEncoders[version]
+	if !ok {
+		return nil, fmt.Errorf("%w: leaf encoding version %d", ErrUnsupportedVersion, version)
+	}
+
+	if version < LeafEncodingVersionWindowed && leaf.HasValidityWindow() {
+		return nil, fmt.Errorf("validity window requires leaf encoding version %d or later", LeafEncodingVersionWindowed)
+	}
+	if leaf.Salt != "" {
+		if version < LeafEncodingVersionSalted {
+			return nil, fmt.Errorf("salt requires leaf encoding version %d or later", LeafEncodingVersionSalted)
+		}
+		if _, err := saltWord(leaf.Salt); err != nil {
+			return nil, err
+		}
+	}
+
 	// Convert contract address
 	var addr common.Address
-	if contractAddr == "" {
+	if leaf.ContractAddr == "" {
 		// Use fixed contract address 0xdEaD as default
 		addr = common.HexToAddress("0xdEaD")
 	} else {
 		// Use user-specified contract address
-		addr = common.HexToAddress(contractAddr)
+		addr = common.HexToAddress(leaf.ContractAddr)
 	}
 
 	// Convert address to bytes32 (pad to 32 bytes)
 	addrBytes := common.LeftPadBytes(addr.Bytes(), 32)
 
-	// Encode oneSigID as 8 bytes
-	oneSigIDBytes := make([]byte, 8)
-	for i := 0; i < 8; i++ {
-		oneSigIDBytes[7-i] = byte(oneSigID >> (i * 8))
+	// Perform ABI encoding (equivalent to abi.encode(_calls))
+	callsEncoded, err := EncodeCallsVersion(leaf.Calls, version, leaf.TargetFormat)
+	if err != nil {
+		return nil, err
 	}
 
-	// Encode nonce as 8 bytes
-	nonceBytes := make([]byte, 8)
-	for i := 0; i < 8; i++ {
-		nonceBytes[7-i] = byte(nonce >> (i * 8))
+	// Implementation of abi.encodePacked
+	// Equivalent to Solidity's abi.encodePacked(LEAF_ENCODING_VERSION, ONE_SIG_ID, address(this), _nonce, ..., abi.encode(_calls))
+	leafData := []byte{version}
+	leafData = append(leafData, uint64ToBytes(leaf.OneSigID)...) // 8 bytes
+	leafData = append(leafData, addrBytes...)                    // 32 bytes
+	leafData = append(leafData, uint64ToBytes(leaf.Nonce)...)    // 8 bytes
+	leafData = append(leafData, encodeFields(leaf)...)           // version specific fields
+	leafData = append(leafData, callsEncoded...)                 // abi.encode(_calls)
+
+	return leafData, nil
+}
+
+// EncodeCalls ABI-encodes calls exactly like Solidity's abi.encode(Call[])
+func EncodeCalls(calls []models.Call) ([]byte, error) {
+	return EncodeCallsVersion(calls, LeafEncodingVersion, models.TargetFormatAddress)
+}
+
+// callComponents returns the ABI components of Solidity's Call struct for a
+// leaf encoding version and target format; version 3 adds the operation type
+func callComponents(version byte, targetFormat string) string {
+	toType := "address"
+	if targetFormat == models.TargetFormatBytes32 {
+		toType = "bytes32"
+	}
+	components := `
+						{"name": "to", "type": "` + toType + `"},
+						{"name": "value", "type": "uint256"},
+						{"name": "data", "type": "bytes"}`
+	if version >= LeafEncodingVersionOperation {
+		components += `,
+						{"name": "operation", "type": "uint8"}`
 	}
+	return components
+}
 
+// EncodeCallsVersion ABI-encodes calls like Solidity's abi.encode(Call[]),
+// using the Call struct of the given leaf encoding version and target format
+func EncodeCallsVersion(calls []models.Call, version byte, targetFormat string) ([]byte, error) {
 	// Create ABI definition identical to Solidity's Call struct
 	callsAbi, err := abi.JSON(strings.NewReader(`[
 		{
@@ -54,19 +166,7 @@ func EncodeLeaf(oneSigID uint64, contractAddr string, nonce uint64, calls []mode
 				{
 					"name": "calls",
 					"type": "tuple[]",
-					"components": [
-						{
-							"name": "to",
-							"type": "address"
-						},
-						{
-							"name": "value",
-							"type": "uint256"
-						},
-						{
-							"name": "data",
-							"type": "bytes"
-						}
+					"components": [` + callComponents(version, targetFormat) + `
 					]
 				}
 			]
@@ -76,58 +176,181 @@ func EncodeLeaf(oneSigID uint64, contractAddr string, nonce uint64, calls []mode
 		return nil, fmt.Errorf("failed to parse ABI: %w", err)
 	}
 
-	// Convert Go struct to Solidity struct format
-	var callsForAbi []struct {
-		To    common.Address
-		Value *big.Int
-		Data  []byte
+	callsForAbi, err := callsToABI(calls, version, targetFormat)
+	if err != nil {
+		return nil, err
+	}
+
+	callsEncoded, err := callsAbi.Methods["encodeCalls"].Inputs.Pack(callsForAbi)
+	if err != nil {
+		return nil, fmt.Errorf("%w: calls: %w", ErrEncoding, err)
+	}
+
+	return callsEncoded, nil
+}
+
+// abiCall mirrors Solidity's Call struct for ABI encoding
+type abiCall struct {
+	To    common.Address
+	Value *big.Int
+	Data  []byte
+}
+
+// abiOperationCall mirrors the Call struct of leaf encoding version 3
+type abiOperationCall struct {
+	To        common.Address
+	Value     *big.Int
+	Data      []byte
+	Operation uint8
+}
+
+// abiBytes32Call and abiBytes32OperationCall mirror the Call structs of
+// endpoints addressing their targets by 32 byte ids
+type abiBytes32Call struct {
+	To    [32]byte
+	Value *big.Int
+	Data  []byte
+}
+
+type abiBytes32OperationCall struct {
+	To        [32]byte
+	Value     *big.Int
+	Data      []byte
+	Operation uint8
+}
+
+// operationTypes maps call operations to the values encoded from version 3
+var operationTypes = map[string]uint8{
+	"":                           0,
+	models.OperationCall:         0,
+	models.OperationDelegateCall: 1,
+}
+
+// callsToABI converts calls to their Solidity struct representation for a
+// leaf encoding version and target format, treating a missing value as zero
+func callsToABI(calls []models.Call, version byte, targetFormat string) (interface{}, error) {
+	if len(calls) > MaxCallsPerLeafHard {
+		return nil, fmt.Errorf("%w: leaf has %d calls, exceeding the hard limit of %d", ErrLimitExceeded, len(calls), MaxCallsPerLeafHard)
 	}
+	bytes32 := targetFormat == models.TargetFormatBytes32
+	if !bytes32 && targetFormat != "" && targetFormat != models.TargetFormatAddress {
+		return nil, fmt.Errorf("%w: unknown target format %q", ErrTargetFormat, targetFormat)
+	}
+	withOperation := version >= LeafEncodingVersionOperation
+	total := new(big.Int)
 
-	for _, call := range calls {
-		callData, err := HexToBytes(call.Data)
+	var (
+		addressCalls          []abiCall
+		addressOperationCalls []abiOperationCall
+		bytes32Calls          []abiBytes32Call
+		bytes32OperationCalls []abiBytes32OperationCall
+	)
+	for i, call := range calls {
+		if len(call.Data) > 2+2*MaxCallDataBytesHard {
+			return nil, fmt.Errorf("%w: calls[%d].data exceeds the hard limit of %d bytes", ErrLimitExceeded, i, MaxCallDataBytesHard)
+		}
+		callData, err := ParseHex(call.Data, fmt.Sprintf("calls[%d].data", i), false)
 		if err != nil {
-			return nil, fmt.Errorf("invalid call data: %w", err)
+			return nil, err
+		}
+
+		value := new(big.Int)
+		if call.Value != nil {
+			value = call.Value
+		}
+		if value.Sign() < 0 {
+			return nil, fmt.Errorf("%w: calls[%d].value %s is negative", ErrValueRange, i, value)
+		}
+		if value.BitLen() > 256 {
+			return nil, fmt.Errorf("%w: calls[%d].value %s exceeds the uint256 maximum", ErrValueRange, i, value)
+		}
+		// The contract must hold the sum of the values to execute the leaf
+		if total.Add(total, value); total.BitLen() > 256 {
+			return nil, fmt.Errorf("%w: the values of calls[0] to calls[%d] sum to %s, exceeding the uint256 maximum", ErrValueRange, i, total)
+		}
+
+		operation, ok := operationTypes[call.Operation]
+		if !ok {
+			return nil, fmt.Errorf("calls[%d].operation %q must be %q or %q", i, call.Operation, models.OperationCall, models.OperationDelegateCall)
+		}
+		if operation != 0 && !withOperation {
+			return nil, fmt.Errorf("calls[%d]: delegatecall requires leaf encoding version %d or later", i, LeafEncodingVersionOperation)
 		}
 
-		callsForAbi = append(callsForAbi, struct {
-			To    common.Address
-			Value *big.Int
-			Data  []byte
-		}{
-			To:    common.HexToAddress(call.To),
-			Value: call.Value,
-			Data:  callData,
-		})
+		if !bytes32 {
+			to := common.HexToAddress(call.To)
+			addressCalls = append(addressCalls, abiCall{To: to, Value: value, Data: callData})
+			addressOperationCalls = append(addressOperationCalls, abiOperationCall{To: to, Value: value, Data: callData, Operation: operation})
+			continue
+		}
+		if operation != 0 {
+			return nil, fmt.Errorf("%w: calls[%d]: delegatecall is not available for bytes32 targets", ErrTargetFormat, i)
+		}
+		id, err := ParseBytes32Target(call.To, fmt.Sprintf("calls[%d].to", i))
+		if err != nil {
+			return nil, err
+		}
+		bytes32Calls = append(bytes32Calls, abiBytes32Call{To: id, Value: value, Data: callData})
+		bytes32OperationCalls = append(bytes32OperationCalls, abiBytes32OperationCall{To: id, Value: value, Data: callData})
 	}
 
-	// Perform ABI encoding (equivalent to abi.encode(_calls))
-	callsEncoded, err := callsAbi.Methods["encodeCalls"].Inputs.Pack(callsForAbi)
-	if err != nil {
-		return nil, fmt.Errorf("failed to encode calls: %w", err)
+	switch {
+	case bytes32 && withOperation:
+		return bytes32OperationCalls, nil
+	case bytes32:
+		return bytes32Calls, nil
+	case withOperation:
+		return addressOperationCalls, nil
+	default:
+		return addressCalls, nil
 	}
+}
 
-	// Implementation of abi.encodePacked
-	// Equivalent to Solidity's abi.encodePacked(LEAF_ENCODING_VERSION, ONE_SIG_ID, address(this), _nonce, abi.encode(_calls))
-	leafData := []byte{LeafEncodingVersion}
-	leafData = append(leafData, oneSigIDBytes...) // 8 bytes
-	leafData = append(leafData, addrBytes...)     // 32 bytes
-	leafData = append(leafData, nonceBytes...)    // 8 bytes
-	leafData = append(leafData, callsEncoded...)  // abi.encode(_calls)
+// ParseBytes32Target parses a call target given as a 32 byte id
+func ParseBytes32Target(to string, field string) ([32]byte, error) {
+	var id [32]byte
+	b, err := ParseHex(to, field, false)
+	if err != nil {
+		return id, err
+	}
+	if len(b) != 32 {
+		return id, fmt.Errorf("%w: %s must be a 32 byte id, got %d bytes", ErrTargetFormat, field, len(b))
+	}
+	copy(id[:], b)
+	return id, nil
+}
 
-	// Double hash leaf data (equivalent to Solidity's keccak256(keccak256(...)))
-	firstHash := crypto.Keccak256(leafData)
-	finalHash := crypto.Keccak256(firstHash)
+// encodeValidityWindow packs validAfter and validUntil as two 8 byte values
+func encodeValidityWindow(leaf models.Leaf) []byte {
+	return append(uint64ToBytes(leaf.ValidAfter), uint64ToBytes(leaf.ValidUntil)...)
+}
 
-	return finalHash, nil
+// uint64ToBytes encodes a uint64 as 8 big-endian bytes
+func uint64ToBytes(v uint64) []byte {
+	b := make([]byte, 8)
+	for i := 0; i < 8; i++ {
+		b[7-i] = byte(v >> (i * 8))
+	}
+	return b
 }
 
-// HexToBytes converts a hex string to bytes
+// HexToBytes converts a hex string to bytes, with or without a 0x prefix
 func HexToBytes(hexStr string) ([]byte, error) {
-	// Remove 0x prefix if present
-	if strings.HasPrefix(hexStr, "0x") {
-		hexStr = hexStr[2:]
-	}
+	return ParseHex(hexStr, "", false)
+}
 
-	// Convert hex to bytes
-	return hex.DecodeString(hexStr)
+// EncodeLeavesContext encodes every leaf, stopping with the context's error once ctx is cancelled
+func EncodeLeavesContext(ctx context.Context, leaves []models.Leaf, version byte) ([][]byte, error) {
+	hashes := make([][]byte, 0, len(leaves))
+	for _, leaf := range leaves {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		hash, err := EncodeLeafVersion(leaf, version)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode leaf for group %d: %w", leaf.Nonce, err)
+		}
+		hashes = append(hashes, hash)
+	}
+	return hashes, nil
 }
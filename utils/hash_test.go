@@ -0,0 +1,82 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	"merkle-cli/models"
+)
+
+// oversizedCallData is one byte more calldata than any leaf may hold
+var oversizedCallData = "0x" + strings.Repeat("00", MaxCallDataBytesHard+1)
+
+// FuzzEncodeLeafData checks that EncodeLeafData never panics, encodes a
+// missing value as zero, rejects calldata over the hard limit and produces
+// leaves DecodeLeaf reads back
+func FuzzEncodeLeafData(f *testing.F) {
+	id := "0xffffffffffffffffffffffff1111111111111111111111111111111111111111"
+	f.Add(LeafEncodingVersion, uint64(1), uint64(0), "0x1111111111111111111111111111111111111111", false, []byte{1}, false, "0x", false, "")
+	f.Add(LeafEncodingVersion, uint64(1), uint64(1), "0x1111111111111111111111111111111111111111", false, []byte(nil), true, "0xdeadbeef", false, "")
+	f.Add(LeafEncodingVersionWindowed, uint64(5), uint64(9), "0x2222222222222222222222222222222222222222", false, []byte{0xff, 0xff}, false, "0x00", false, "")
+	f.Add(LeafEncodingVersionOperation, uint64(5), uint64(9), "0x2222222222222222222222222222222222222222", false, []byte{}, false, "0x", false, models.OperationDelegateCall)
+	f.Add(LeafEncodingVersionSalted, uint64(5), uint64(9), "0x2222222222222222222222222222222222222222", false, []byte{}, true, "0x", false, "")
+	f.Add(LeafEncodingVersion, uint64(1), uint64(0), id, true, []byte{1}, false, "0x", false, "")
+	f.Add(LeafEncodingVersionOperation, uint64(1), uint64(0), id, true, []byte{}, true, "0x01", false, "")
+	f.Add(LeafEncodingVersion, uint64(1), uint64(0), "0x1111111111111111111111111111111111111111", false, []byte{}, false, "", true, "")
+	f.Add(byte(0), uint64(0), uint64(0), "not an address", true, bytes.Repeat([]byte{0xff}, 33), false, "0xzz", false, "bogus")
+
+	f.Fuzz(func(t *testing.T, version byte, oneSigID, nonce uint64, to string, bytes32 bool, value []byte, nilValue bool, data string, oversized bool, operation string) {
+		call := models.Call{To: to, Value: new(big.Int).SetBytes(value), Data: data, Operation: operation}
+		if nilValue {
+			call.Value = nil
+		}
+		leaf := models.Leaf{OneSigID: oneSigID, Nonce: nonce, Calls: []models.Call{call}}
+		if bytes32 {
+			leaf.TargetFormat = models.TargetFormatBytes32
+		}
+
+		if oversized {
+			// Oversized calldata fails every leaf, with ErrLimitExceeded if it is otherwise valid
+			_, validErr := EncodeLeafData(leaf, version)
+			leaf.Calls[0].Data = oversizedCallData
+			_, err := EncodeLeafData(leaf, version)
+			if err == nil || (validErr == nil && !errors.Is(err, ErrLimitExceeded)) {
+				t.Fatalf("oversized calldata: got %v, want %v", err, ErrLimitExceeded)
+			}
+			return
+		}
+
+		encoded, err := EncodeLeafData(leaf, version)
+		if err != nil {
+			return
+		}
+
+		if nilValue {
+			zeroCall := call
+			zeroCall.Value = new(big.Int)
+			zero := leaf
+			zero.Calls = []models.Call{zeroCall}
+			encodedZero, err := EncodeLeafData(zero, version)
+			if err != nil {
+				t.Fatalf("zero value does not encode: %v", err)
+			}
+			if !bytes.Equal(encoded, encodedZero) {
+				t.Fatal("a missing value encodes differently from zero")
+			}
+		}
+
+		decoded, decodedVersion, err := DecodeLeaf(encoded, leaf.TargetFormat)
+		if err != nil {
+			t.Fatalf("encoded leaf does not decode: %v", err)
+		}
+		if decodedVersion != version {
+			t.Fatalf("decoded version %d, encoded %d", decodedVersion, version)
+		}
+		if decoded.OneSigID != oneSigID || decoded.Nonce != nonce || decoded.HasBytes32Targets() != bytes32 || len(decoded.Calls) != 1 {
+			t.Fatalf("decoded leaf %+v differs from the encoded one", decoded)
+		}
+	})
+}
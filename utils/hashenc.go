@@ -0,0 +1,42 @@
+package utils
+
+import (
+	"encoding/base64"
+	"fmt"
+)
+
+// Hash encodings for roots and proofs in serialized output
+const (
+	HashEncodingHex    = "hex"
+	HashEncodingBase64 = "base64"
+)
+
+// CheckHashEncoding rejects unknown hash encodings
+func CheckHashEncoding(encoding string) error {
+	if encoding != HashEncodingHex && encoding != HashEncodingBase64 {
+		return fmt.Errorf("unsupported hash encoding %q, expected %s or %s", encoding, HashEncodingHex, HashEncodingBase64)
+	}
+	return nil
+}
+
+// FormatHash serializes a hash as 0x prefixed hex or standard base64.
+// An empty encoding means hex.
+func FormatHash(b []byte, encoding string) string {
+	if encoding == HashEncodingBase64 {
+		return base64.StdEncoding.EncodeToString(b)
+	}
+	return NormalizeHex(b)
+}
+
+// ParseHash decodes a hash serialized with FormatHash
+func ParseHash(value string, encoding string, field string) ([]byte, error) {
+	if encoding != HashEncodingBase64 {
+		return ParseHex(value, field, false)
+	}
+
+	b, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base64 in %s %q: %w", field, value, err)
+	}
+	return b, nil
+}
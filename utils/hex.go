@@ -0,0 +1,136 @@
+package utils
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"merkle-cli/models"
+)
+
+// maxHexErrorValue caps how much of an offending value is echoed in errors
+const maxHexErrorValue = 48
+
+// HexError describes a hex value that failed to parse
+type HexError struct {
+	Field  string
+	Value  string
+	Reason string
+}
+
+// Unwrap makes HexError match ErrInvalidHex
+func (e *HexError) Unwrap() error {
+	return ErrInvalidHex
+}
+
+func (e *HexError) Error() string {
+	value := e.Value
+	if len(value) > maxHexErrorValue {
+		value = value[:maxHexErrorValue] + "..."
+	}
+	if e.Field == "" {
+		return fmt.Sprintf("invalid hex %q: %s", value, e.Reason)
+	}
+	return fmt.Sprintf("invalid hex in %s %q: %s", e.Field, value, e.Reason)
+}
+
+// ParseHex decodes a hex string. Permissive mode accepts a missing or
+// upper case prefix; strict mode requires a lower case 0x prefix. Both modes
+// reject odd length strings and name the first invalid character.
+func ParseHex(value string, field string, strict bool) ([]byte, error) {
+	digits := value
+	switch {
+	case strings.HasPrefix(value, "0x"):
+		digits = value[2:]
+	case strings.HasPrefix(value, "0X"):
+		if strict {
+			return nil, &HexError{Field: field, Value: value, Reason: "prefix must be lower case 0x"}
+		}
+		digits = value[2:]
+	case strict:
+		return nil, &HexError{Field: field, Value: value, Reason: "missing 0x prefix"}
+	}
+
+	for i, c := range digits {
+		if !isHexDigit(c) {
+			return nil, &HexError{Field: field, Value: value, Reason: fmt.Sprintf("invalid character %q at position %d", c, len(value)-len(digits)+i)}
+		}
+	}
+	if len(digits)%2 != 0 {
+		return nil, &HexError{Field: field, Value: value, Reason: fmt.Sprintf("odd length (%d hex digits)", len(digits))}
+	}
+
+	return hex.DecodeString(digits)
+}
+
+// ParseHexAddress decodes a 20 byte hex address
+func ParseHexAddress(value string, field string, strict bool) ([]byte, error) {
+	b, err := ParseHex(value, field, strict)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) != 20 {
+		return nil, &HexError{Field: field, Value: value, Reason: fmt.Sprintf("address must be 20 bytes, got %d", len(b))}
+	}
+	return b, nil
+}
+
+// NormalizeHex returns the canonical lower case, 0x prefixed form of b
+func NormalizeHex(b []byte) string {
+	return "0x" + hex.EncodeToString(b)
+}
+
+// CheckBatchHex validates the hex fields of every call and the salt of every
+// group in the batch, reporting errors with their path in the batch. Call data
+// and salts are rewritten to their normalized form in strict mode.
+func CheckBatchHex(batch *models.TransactionBatch, strict bool) error {
+	for i := range batch.Groups {
+		if group := &batch.Groups[i]; group.Salt != "" {
+			salt, err := ParseSalt(group.Salt, fmt.Sprintf("groups[%d].salt", i), strict)
+			if err != nil {
+				return err
+			}
+			if strict {
+				group.Salt = NormalizeHex(salt)
+			}
+		}
+		for j := range batch.Groups[i].Calls {
+			call := &batch.Groups[i].Calls[j]
+			path := fmt.Sprintf("groups[%d].calls[%d]", i, j)
+
+			if strict {
+				if _, err := ParseHexAddress(call.To, path+".to", true); err != nil {
+					return err
+				}
+			}
+
+			data, err := ParseHex(call.Data, path+".data", strict)
+			if err != nil {
+				return err
+			}
+			if strict {
+				call.Data = NormalizeHex(data)
+			}
+		}
+	}
+	return nil
+}
+
+// EmptyDataCalls returns the paths of the calls of the batch whose data is
+// missing or empty, which is usually a mistake; calls meant to carry no
+// calldata write "0x"
+func EmptyDataCalls(batch *models.TransactionBatch) []string {
+	var paths []string
+	for i, group := range batch.Groups {
+		for j, call := range group.Calls {
+			if call.Data == "" {
+				paths = append(paths, fmt.Sprintf("groups[%d].calls[%d].data", i, j))
+			}
+		}
+	}
+	return paths
+}
+
+func isHexDigit(c rune) bool {
+	return ('0' <= c && c <= '9') || ('a' <= c && c <= 'f') || ('A' <= c && c <= 'F')
+}
@@ -0,0 +1,14 @@
+package utils
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// PersonalMessageDigest returns the EIP-191 personal_sign digest of a message,
+// the digest wallets and `cast wallet sign` produce for it
+func PersonalMessageDigest(message []byte) []byte {
+	prefix := fmt.Sprintf("\x19Ethereum Signed Message:\n%d", len(message))
+	return crypto.Keccak256([]byte(prefix), message)
+}
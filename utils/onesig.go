@@ -0,0 +1,417 @@
+package utils
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"merkle-cli/models"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// OneSig EIP-712 domain. Signatures over a root are chain agnostic, so the domain
+// uses a fixed chain ID and verifying contract rather than the deployment's.
+const (
+	EIP712DomainName    = "OneSig"
+	EIP712DomainVersion = "0.0.1"
+	EIP712ChainID       = 1
+	EIP712VerifyingAddr = "0x000000000000000000000000000000000000dEaD"
+)
+
+var (
+	eip712DomainTypeHash   = crypto.Keccak256([]byte("EIP712Domain(string name,string version,uint256 chainId,address verifyingContract)"))
+	signMerkleRootTypeHash = crypto.Keccak256([]byte("SignMerkleRoot(bytes32 seed,bytes32 merkleRoot)"))
+	// signMerkleRootExpiryTypeHash is used by OneSig deployments that bind a
+	// root to the timestamp after which it can no longer be set
+	signMerkleRootExpiryTypeHash = crypto.Keccak256([]byte("SignMerkleRoot(bytes32 seed,bytes32 merkleRoot,uint256 expiry)"))
+)
+
+// EIP712Domain is the EIP-712 domain signatures over a root are made in
+type EIP712Domain struct {
+	Name              string         `json:"name"`
+	Version           string         `json:"version"`
+	ChainID           uint64         `json:"chainId"`
+	VerifyingContract common.Address `json:"verifyingContract"`
+}
+
+// DefaultDomain is the OneSig EIP-712 domain
+var DefaultDomain = EIP712Domain{
+	Name:              EIP712DomainName,
+	Version:           EIP712DomainVersion,
+	ChainID:           EIP712ChainID,
+	VerifyingContract: common.HexToAddress(EIP712VerifyingAddr),
+}
+
+// Separator returns the EIP-712 domain separator of the domain
+func (d EIP712Domain) Separator() []byte {
+	return crypto.Keccak256(
+		eip712DomainTypeHash,
+		crypto.Keccak256([]byte(d.Name)),
+		crypto.Keccak256([]byte(d.Version)),
+		common.LeftPadBytes(new(big.Int).SetUint64(d.ChainID).Bytes(), 32),
+		common.LeftPadBytes(d.VerifyingContract.Bytes(), 32),
+	)
+}
+
+// RootDigest returns the EIP-712 digest signers sign in the domain to approve
+// a Merkle root. A non-zero expiry binds the root to it.
+func (d EIP712Domain) RootDigest(merkleRoot []byte, seed []byte, expiry uint64) ([]byte, error) {
+	if len(merkleRoot) != 32 {
+		return nil, fmt.Errorf("merkle root must be 32 bytes, got %d", len(merkleRoot))
+	}
+	if len(seed) != 32 {
+		return nil, fmt.Errorf("seed must be 32 bytes, got %d", len(seed))
+	}
+
+	structHash := crypto.Keccak256(signMerkleRootTypeHash, seed, merkleRoot)
+	if expiry != 0 {
+		expiryWord := common.LeftPadBytes(new(big.Int).SetUint64(expiry).Bytes(), 32)
+		structHash = crypto.Keccak256(signMerkleRootExpiryTypeHash, seed, merkleRoot, expiryWord)
+	}
+	return crypto.Keccak256([]byte("\x19\x01"), d.Separator(), structHash), nil
+}
+
+// DomainSeparator returns the OneSig EIP-712 domain separator
+func DomainSeparator() []byte {
+	return DefaultDomain.Separator()
+}
+
+// MerkleRootDigest returns the EIP-712 digest signers sign to approve a Merkle root
+func MerkleRootDigest(merkleRoot []byte, seed []byte) ([]byte, error) {
+	return DefaultDomain.RootDigest(merkleRoot, seed, 0)
+}
+
+// MerkleRootExpiryDigest returns the EIP-712 digest of a Merkle root bound to
+// an expiry timestamp. An expiry of 0 gives the digest without an expiry.
+func MerkleRootExpiryDigest(merkleRoot []byte, seed []byte, expiry uint64) ([]byte, error) {
+	return DefaultDomain.RootDigest(merkleRoot, seed, expiry)
+}
+
+// RootSubmissionMethods lists the OneSig functions that accept a signed Merkle root
+var RootSubmissionMethods = []string{"setRoot", "commitRoot"}
+
+// oneSigABI describes the OneSig contract functions the CLI builds calldata for
+const oneSigABI = `[
+	{
+		"name": "setRoot",
+		"type": "function",
+		"inputs": [
+			{
+				"name": "_merkleRoot",
+				"type": "bytes32"
+			},
+			{
+				"name": "_signatures",
+				"type": "bytes"
+			}
+		]
+	},
+	{
+		"name": "commitRoot",
+		"type": "function",
+		"inputs": [
+			{
+				"name": "_merkleRoot",
+				"type": "bytes32"
+			},
+			{
+				"name": "_signatures",
+				"type": "bytes"
+			}
+		]
+	}
+]`
+
+// oneSigExpiryABI describes the root submission functions of OneSig
+// deployments that bind a root to an expiry, passed between root and signatures
+const oneSigExpiryABI = `[
+	{
+		"name": "setRoot",
+		"type": "function",
+		"inputs": [
+			{
+				"name": "_merkleRoot",
+				"type": "bytes32"
+			},
+			{
+				"name": "_expiry",
+				"type": "uint256"
+			},
+			{
+				"name": "_signatures",
+				"type": "bytes"
+			}
+		]
+	},
+	{
+		"name": "commitRoot",
+		"type": "function",
+		"inputs": [
+			{
+				"name": "_merkleRoot",
+				"type": "bytes32"
+			},
+			{
+				"name": "_expiry",
+				"type": "uint256"
+			},
+			{
+				"name": "_signatures",
+				"type": "bytes"
+			}
+		]
+	}
+]`
+
+// executeABIs describes the OneSig execute function for each leaf encoding version.
+// The contract rebuilds the leaf from these arguments, so every field committed
+// to by the leaf must be passed alongside the proof.
+var executeABIs = map[byte]string{
+	LeafEncodingVersion: `[
+		{
+			"name": "execute",
+			"type": "function",
+			"inputs": [
+				{"name": "_proof", "type": "bytes32[]"},
+				{
+					"name": "_calls",
+					"type": "tuple[]",
+					"components": [
+						{"name": "to", "type": "address"},
+						{"name": "value", "type": "uint256"},
+						{"name": "data", "type": "bytes"}
+					]
+				},
+				{"name": "_nonce", "type": "uint64"}
+			]
+		}
+	]`,
+	LeafEncodingVersionWindowed: `[
+		{
+			"name": "execute",
+			"type": "function",
+			"inputs": [
+				{"name": "_proof", "type": "bytes32[]"},
+				{
+					"name": "_calls",
+					"type": "tuple[]",
+					"components": [
+						{"name": "to", "type": "address"},
+						{"name": "value", "type": "uint256"},
+						{"name": "data", "type": "bytes"}
+					]
+				},
+				{"name": "_nonce", "type": "uint64"},
+				{"name": "_validAfter", "type": "uint64"},
+				{"name": "_validUntil", "type": "uint64"}
+			]
+		}
+	]`,
+	LeafEncodingVersionOperation: `[
+		{
+			"name": "execute",
+			"type": "function",
+			"inputs": [
+				{"name": "_proof", "type": "bytes32[]"},
+				{
+					"name": "_calls",
+					"type": "tuple[]",
+					"components": [
+						{"name": "to", "type": "address"},
+						{"name": "value", "type": "uint256"},
+						{"name": "data", "type": "bytes"},
+						{"name": "operation", "type": "uint8"}
+					]
+				},
+				{"name": "_nonce", "type": "uint64"},
+				{"name": "_validAfter", "type": "uint64"},
+				{"name": "_validUntil", "type": "uint64"}
+			]
+		}
+	]`,
+	LeafEncodingVersionSalted: `[
+		{
+			"name": "execute",
+			"type": "function",
+			"inputs": [
+				{"name": "_proof", "type": "bytes32[]"},
+				{
+					"name": "_calls",
+					"type": "tuple[]",
+					"components": [
+						{"name": "to", "type": "address"},
+						{"name": "value", "type": "uint256"},
+						{"name": "data", "type": "bytes"},
+						{"name": "operation", "type": "uint8"}
+					]
+				},
+				{"name": "_nonce", "type": "uint64"},
+				{"name": "_validAfter", "type": "uint64"},
+				{"name": "_validUntil", "type": "uint64"},
+				{"name": "_salt", "type": "bytes32"}
+			]
+		}
+	]`,
+}
+
+// EncodeExecute ABI-encodes the OneSig execute call for a proof entry
+func EncodeExecute(entry models.ProofEntry, version byte) ([]byte, error) {
+	executeABI, ok := executeABIs[version]
+	if !ok {
+		return nil, fmt.Errorf("%w: leaf encoding version %d", ErrUnsupportedVersion, version)
+	}
+
+	contractAbi, err := abi.JSON(strings.NewReader(executeABI))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ABI: %w", err)
+	}
+
+	proof := make([][32]byte, len(entry.Proof))
+	for i, p := range entry.Proof {
+		node, err := HexToBytes(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proof element %d: %w", i, err)
+		}
+		if len(node) != 32 {
+			return nil, fmt.Errorf("proof element %d must be 32 bytes, got %d", i, len(node))
+		}
+		copy(proof[i][:], node)
+	}
+
+	if entry.HasBytes32Targets() {
+		return nil, fmt.Errorf("%w: execute calldata is only defined for address targets", ErrTargetFormat)
+	}
+	calls, err := callsToABI(entry.Calls, version, models.TargetFormatAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	args := []interface{}{proof, calls, entry.Nonce}
+	if version >= LeafEncodingVersionWindowed {
+		args = append(args, entry.ValidAfter, entry.ValidUntil)
+	}
+	if version >= LeafEncodingVersionSalted {
+		salt, err := saltWord(entry.Salt)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, salt)
+	}
+
+	calldata, err := contractAbi.Pack("execute", args...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: execute call: %w", ErrEncoding, err)
+	}
+
+	return calldata, nil
+}
+
+// EncodeLeafCall ABI-encodes a call to the encodeLeaf view of a OneSig
+// contract, which hashes the leaf of the given fields on chain. From version 2
+// the validity window, and from version 4 the salt, is passed between the nonce
+// and the calls, in the order the leaf packs them.
+func EncodeLeafCall(leaf models.Leaf, version byte) ([]byte, error) {
+	if _, ok := leafFieldEncoders[version]; !ok {
+		return nil, fmt.Errorf("%w: leaf encoding version %d", ErrUnsupportedVersion, version)
+	}
+
+	inputs := `{"name": "_nonce", "type": "uint64"},`
+	if version >= LeafEncodingVersionWindowed {
+		inputs += `
+				{"name": "_validAfter", "type": "uint64"},
+				{"name": "_validUntil", "type": "uint64"},`
+	}
+	if version >= LeafEncodingVersionSalted {
+		inputs += `
+				{"name": "_salt", "type": "bytes32"},`
+	}
+	contractAbi, err := abi.JSON(strings.NewReader(`[
+		{
+			"name": "encodeLeaf",
+			"type": "function",
+			"inputs": [
+				` + inputs + `
+				{
+					"name": "_calls",
+					"type": "tuple[]",
+					"components": [` + callComponents(version, leaf.TargetFormat) + `
+					]
+				}
+			],
+			"outputs": [{"name": "", "type": "bytes32"}]
+		}
+	]`))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ABI: %w", err)
+	}
+
+	calls, err := callsToABI(leaf.Calls, version, leaf.TargetFormat)
+	if err != nil {
+		return nil, err
+	}
+	args := []interface{}{leaf.Nonce}
+	if version >= LeafEncodingVersionWindowed {
+		args = append(args, leaf.ValidAfter, leaf.ValidUntil)
+	}
+	if version >= LeafEncodingVersionSalted {
+		salt, err := saltWord(leaf.Salt)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, salt)
+	}
+	calldata, err := contractAbi.Pack("encodeLeaf", append(args, calls)...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: encodeLeaf call: %w", ErrEncoding, err)
+	}
+
+	return calldata, nil
+}
+
+// EncodeRootSubmission ABI-encodes a call to the given root submission method.
+// A non-zero expiry selects the variant that binds the root to an expiry.
+func EncodeRootSubmission(method string, merkleRoot []byte, expiry uint64, signatures []byte) ([]byte, error) {
+	if len(merkleRoot) != 32 {
+		return nil, fmt.Errorf("merkle root must be 32 bytes, got %d", len(merkleRoot))
+	}
+
+	if !isRootSubmissionMethod(method) {
+		return nil, fmt.Errorf("unknown root submission method %q (expected one of %s)", method, strings.Join(RootSubmissionMethods, ", "))
+	}
+
+	abiJSON := oneSigABI
+	if expiry != 0 {
+		abiJSON = oneSigExpiryABI
+	}
+	contractAbi, err := abi.JSON(strings.NewReader(abiJSON))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ABI: %w", err)
+	}
+
+	var root [32]byte
+	copy(root[:], merkleRoot)
+
+	args := []interface{}{root}
+	if expiry != 0 {
+		args = append(args, new(big.Int).SetUint64(expiry))
+	}
+	calldata, err := contractAbi.Pack(method, append(args, signatures)...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s call: %w", ErrEncoding, method, err)
+	}
+
+	return calldata, nil
+}
+
+// isRootSubmissionMethod reports whether method is one of RootSubmissionMethods
+func isRootSubmissionMethod(method string) bool {
+	for _, m := range RootSubmissionMethods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,55 @@
+package utils
+
+import (
+	"fmt"
+
+	"merkle-cli/models"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// SaltSize is the size of the salt of a leaf
+const SaltSize = 32
+
+// saltTag separates derived salts from other hashes of the same fields
+const saltTag = "onesig.leaf.salt"
+
+// ParseSalt decodes a 32 byte leaf salt
+func ParseSalt(value string, field string, strict bool) ([]byte, error) {
+	b, err := ParseHex(value, field, strict)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) != SaltSize {
+		return nil, &HexError{Field: field, Value: value, Reason: fmt.Sprintf("salt must be %d bytes, got %d", SaltSize, len(b))}
+	}
+	return b, nil
+}
+
+// DeriveSalt returns the deterministic salt of the leaf of a nonce on a OneSig
+// instance, keccak256(tag, oneSigId, nonce, domain). The domain, such as the
+// name of a campaign, keeps otherwise identical leaves of different roots apart.
+func DeriveSalt(domain string, oneSigID, nonce uint64) string {
+	return NormalizeHex(crypto.Keccak256([]byte(saltTag), uint64ToBytes(oneSigID), uint64ToBytes(nonce), []byte(domain)))
+}
+
+// saltWord returns the salt of a leaf as a word, zero when it has none
+func saltWord(salt string) ([SaltSize]byte, error) {
+	var word [SaltSize]byte
+	if salt == "" {
+		return word, nil
+	}
+	b, err := ParseSalt(salt, "salt", false)
+	if err != nil {
+		return word, err
+	}
+	copy(word[:], b)
+	return word, nil
+}
+
+// encodeSaltedFields packs the validity window followed by the salt. The salt
+// has been validated by EncodeLeafData.
+func encodeSaltedFields(leaf models.Leaf) []byte {
+	salt, _ := saltWord(leaf.Salt)
+	return append(encodeValidityWindow(leaf), salt[:]...)
+}
@@ -0,0 +1,209 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"merkle-cli/models"
+)
+
+// ValidateValidityWindows checks that every validity window is well formed and
+// that leaves sharing a (oneSigId, nonce) pair can never be valid at the same time.
+// A window covers the timestamps after ValidAfter up to and including ValidUntil,
+// where a zero ValidUntil leaves the window open ended.
+func ValidateValidityWindows(leaves []models.Leaf) error {
+	type nonceKey struct {
+		oneSigID uint64
+		nonce    uint64
+	}
+	byNonce := make(map[nonceKey][]models.Leaf)
+
+	for _, leaf := range leaves {
+		if leaf.ValidUntil != 0 && leaf.ValidAfter >= leaf.ValidUntil {
+			return fmt.Errorf("invalid validity window for nonce %d: validAfter (%d) must be before validUntil (%d)",
+				leaf.Nonce, leaf.ValidAfter, leaf.ValidUntil)
+		}
+
+		key := nonceKey{oneSigID: leaf.OneSigID, nonce: leaf.Nonce}
+		for _, other := range byNonce[key] {
+			if windowsOverlap(leaf, other) {
+				return fmt.Errorf("%w: overlapping validity windows for nonce %d: (%d, %d] and (%d, %d]", ErrDuplicateNonce,
+					leaf.Nonce, other.ValidAfter, windowEnd(other), leaf.ValidAfter, windowEnd(leaf))
+			}
+		}
+		byNonce[key] = append(byNonce[key], leaf)
+	}
+
+	return nil
+}
+
+// windowsOverlap reports whether two validity windows share any timestamp
+func windowsOverlap(a, b models.Leaf) bool {
+	return a.ValidAfter < windowEnd(b) && b.ValidAfter < windowEnd(a)
+}
+
+// windowEnd returns the last timestamp covered by a leaf's validity window
+func windowEnd(leaf models.Leaf) uint64 {
+	if leaf.ValidUntil == 0 {
+		return math.MaxUint64
+	}
+	return leaf.ValidUntil
+}
+
+// ValidateDependencies checks that the leaves each leaf depends on execute
+// before it. OneSig executes the leaves of an instance in nonce order, so a
+// dependency holds when its nonce is lower; nonces missing from the batch are
+// taken to be executed already. Cycles are reported before misordered nonces.
+func ValidateDependencies(leaves []models.Leaf) error {
+	type nonceKey struct {
+		oneSigID uint64
+		nonce    uint64
+	}
+	deps := make(map[nonceKey][]uint64)
+	var keys []nonceKey
+	for _, leaf := range leaves {
+		key := nonceKey{oneSigID: leaf.OneSigID, nonce: leaf.Nonce}
+		if _, ok := deps[key]; !ok {
+			keys = append(keys, key)
+		}
+		deps[key] = append(deps[key], leaf.DependsOn...)
+	}
+
+	// Depth-first search for a cycle, tracking the path being explored
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[nonceKey]int)
+	var path []uint64
+	var visit func(key nonceKey) error
+	visit = func(key nonceKey) error {
+		switch state[key] {
+		case visiting:
+			var cycle []string
+			for i := len(path) - 1; i >= 0; i-- {
+				cycle = append([]string{fmt.Sprint(path[i])}, cycle...)
+				if path[i] == key.nonce {
+					break
+				}
+			}
+			cycle = append(cycle, fmt.Sprint(key.nonce))
+			return fmt.Errorf("%w: oneSigId %d has a dependency cycle: %s", ErrDependency, key.oneSigID, strings.Join(cycle, " -> "))
+		case done:
+			return nil
+		}
+		state[key] = visiting
+		path = append(path, key.nonce)
+		for _, dep := range deps[key] {
+			depKey := nonceKey{oneSigID: key.oneSigID, nonce: dep}
+			if _, ok := deps[depKey]; !ok {
+				continue
+			}
+			if err := visit(depKey); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[key] = done
+		return nil
+	}
+	for _, key := range keys {
+		if err := visit(key); err != nil {
+			return err
+		}
+	}
+
+	for _, key := range keys {
+		for _, dep := range deps[key] {
+			if dep >= key.nonce {
+				return fmt.Errorf("%w: nonce %d depends on nonce %d, which OneSig only executes after it", ErrDependency, key.nonce, dep)
+			}
+		}
+	}
+	return nil
+}
+
+// ValidateTargetFormats checks that every call target matches the target
+// format of its leaf, so that a 32 byte id is never truncated to an address
+// or an address mistaken for an id, and that all the leaves of a OneSig ID
+// share one format.
+func ValidateTargetFormats(leaves []models.Leaf) error {
+	formats := make(map[uint64]string)
+	for _, leaf := range leaves {
+		format := leaf.TargetFormat
+		if format == "" {
+			format = models.TargetFormatAddress
+		}
+		if format != models.TargetFormatAddress && format != models.TargetFormatBytes32 {
+			return fmt.Errorf("%w: nonce %d has unknown target format %q", ErrTargetFormat, leaf.Nonce, leaf.TargetFormat)
+		}
+		if other, ok := formats[leaf.OneSigID]; ok && other != format {
+			return fmt.Errorf("%w: oneSigId %d mixes %s and %s targets", ErrTargetFormat, leaf.OneSigID, other, format)
+		}
+		formats[leaf.OneSigID] = format
+
+		for i, call := range leaf.Calls {
+			field := fmt.Sprintf("nonce %d calls[%d].to", leaf.Nonce, i)
+			if format == models.TargetFormatBytes32 {
+				if _, err := ParseBytes32Target(call.To, field); err != nil {
+					return err
+				}
+				continue
+			}
+			if b, err := ParseHex(call.To, field, false); err == nil && len(b) == 32 {
+				return fmt.Errorf("%w: %s is a 32 byte id; set \"targetFormat\": \"bytes32\" for endpoints addressing targets by id", ErrTargetFormat, field)
+			}
+		}
+	}
+	return nil
+}
+
+// Limits bounds the size of a batch so malformed input cannot exhaust memory
+type Limits struct {
+	MaxLeaves        int
+	MaxCallDataBytes int
+	MaxCallsPerLeaf  int
+	// MaxProofLength caps the number of hashes in a proof, 0 for no cap
+	MaxProofLength int
+}
+
+// DefaultLimits are generous enough for million-leaf batches of ordinary calls
+var DefaultLimits = Limits{
+	MaxLeaves:        1000000,
+	MaxCallDataBytes: 128 * 1024,
+	MaxCallsPerLeaf:  256,
+}
+
+// CheckLimits rejects batches that exceed the limits, before any calldata is decoded.
+// A zero limit falls back to the hard limit of the encoder.
+func CheckLimits(batch *models.TransactionBatch, limits Limits) error {
+	maxLeaves := limits.MaxLeaves
+	if maxLeaves > 0 && len(batch.Groups) > maxLeaves {
+		return fmt.Errorf("%w: batch has %d groups, exceeding the limit of %d (--max-leaves)", ErrLimitExceeded, len(batch.Groups), maxLeaves)
+	}
+
+	maxCalls := limits.MaxCallsPerLeaf
+	if maxCalls <= 0 || maxCalls > MaxCallsPerLeafHard {
+		maxCalls = MaxCallsPerLeafHard
+	}
+	maxData := limits.MaxCallDataBytes
+	if maxData <= 0 || maxData > MaxCallDataBytesHard {
+		maxData = MaxCallDataBytesHard
+	}
+
+	for i, group := range batch.Groups {
+		if len(group.Calls) > maxCalls {
+			return fmt.Errorf("%w: groups[%d] has %d calls, exceeding the limit of %d (--max-calls-per-leaf)", ErrLimitExceeded, i, len(group.Calls), maxCalls)
+		}
+		for j, call := range group.Calls {
+			// Two hex digits per byte, ignoring the prefix
+			if size := (len(call.Data) - 1) / 2; size > maxData {
+				return fmt.Errorf("%w: groups[%d].calls[%d].data is about %d bytes, exceeding the limit of %d (--max-calldata-bytes)", ErrLimitExceeded, i, j, size, maxData)
+			}
+		}
+	}
+
+	return nil
+}
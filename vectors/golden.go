@@ -0,0 +1,115 @@
+package vectors
+
+import (
+	"fmt"
+
+	"merkle-cli/merkle"
+	"merkle-cli/models"
+	"merkle-cli/utils"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Golden is an embedded vector: the batch generated from a seed and the root
+// it must produce with the given encoding version and tree options. An empty
+// leaf order sorts the leaves and a zero arity builds a binary tree. AutoSalt
+// leaves the even nonces of a version 4 batch without a salt, so theirs are
+// derived in GoldenSaltDomain.
+type Golden struct {
+	Name         string
+	Seed         uint64
+	Leaves       int
+	LeafVersion  uint8
+	PairOrder    merkle.PairOrder
+	LeafOrder    merkle.LeafOrder
+	Arity        int
+	TargetFormat string
+	AutoSalt     bool
+	OneSigID     uint64
+	ContractAddr string
+	Root         string
+}
+
+// goldenContract is the OneSig contract address of the golden vectors
+const goldenContract = "0x000000000000000000000000000000000000dEaD"
+
+// GoldenSaltDomain is the salt domain of the golden vectors with AutoSalt
+const GoldenSaltDomain = "merkle-cli golden vectors"
+
+// Goldens cover every leaf encoding version with both pair orders and both
+// target formats, given and derived salts, every leaf order and wider arities,
+// plus single leaf and power of two trees
+var Goldens = []Golden{
+	{Name: "v1-sorted", Seed: 1, Leaves: 7, LeafVersion: 1, PairOrder: merkle.PairOrderSorted, TargetFormat: models.TargetFormatAddress, OneSigID: 1, ContractAddr: goldenContract, Root: "0xfe57d5949f36db042bc6e6ad9b5a3b29ed125c1b754f829a9f11e37825a904ac"},
+	{Name: "v1-positional", Seed: 2, Leaves: 7, LeafVersion: 1, PairOrder: merkle.PairOrderPositional, TargetFormat: models.TargetFormatAddress, OneSigID: 1, ContractAddr: goldenContract, Root: "0x8a51b700f056ddfcbe07ed04020b6d1f39c5b56b458a94f536df6d0e08cdd0fc"},
+	{Name: "v2-sorted", Seed: 3, Leaves: 7, LeafVersion: 2, PairOrder: merkle.PairOrderSorted, TargetFormat: models.TargetFormatAddress, OneSigID: 10, ContractAddr: goldenContract, Root: "0xb46ce6912594d336fa51f954eed83f85dec5c34bae187de145a9bd16d3c1d498"},
+	{Name: "v2-positional", Seed: 4, Leaves: 7, LeafVersion: 2, PairOrder: merkle.PairOrderPositional, TargetFormat: models.TargetFormatAddress, OneSigID: 10, ContractAddr: goldenContract, Root: "0xc5ffb26894576487a9ae77125652b1dd2b22eedbdcfc13adbb689d064d0416b2"},
+	{Name: "v3-sorted", Seed: 5, Leaves: 7, LeafVersion: 3, PairOrder: merkle.PairOrderSorted, TargetFormat: models.TargetFormatAddress, OneSigID: 8453, ContractAddr: goldenContract, Root: "0xed9f9dd340deb8d2f92b621d8a95303d345a78c7189948ec3e814e07c39b8899"},
+	{Name: "v3-positional", Seed: 6, Leaves: 7, LeafVersion: 3, PairOrder: merkle.PairOrderPositional, TargetFormat: models.TargetFormatAddress, OneSigID: 8453, ContractAddr: goldenContract, Root: "0x854afff24b578998e2e2f31eb7cba8be26ebd4168c39808b2a8f554cd474e866"},
+	{Name: "v1-sorted-bytes32", Seed: 7, Leaves: 5, LeafVersion: 1, PairOrder: merkle.PairOrderSorted, TargetFormat: models.TargetFormatBytes32, OneSigID: 30168, ContractAddr: goldenContract, Root: "0x1c39ce7e677658fbc354de361b1b608a7e27ccbdb8d36428839d2e3a70ba1216"},
+	{Name: "v2-positional-bytes32", Seed: 8, Leaves: 5, LeafVersion: 2, PairOrder: merkle.PairOrderPositional, TargetFormat: models.TargetFormatBytes32, OneSigID: 30168, ContractAddr: goldenContract, Root: "0xb06aca6478d7cbfeeda1fe8bd31495d50834f3552eb9a605ec694b788106251d"},
+	{Name: "v3-sorted-bytes32", Seed: 9, Leaves: 5, LeafVersion: 3, PairOrder: merkle.PairOrderSorted, TargetFormat: models.TargetFormatBytes32, OneSigID: 30168, ContractAddr: goldenContract, Root: "0x69695dba34e2bb822ae35eedf00094b336e11fb5f417b4405e5114c11b39847c"},
+	{Name: "v1-sorted-single", Seed: 10, Leaves: 1, LeafVersion: 1, PairOrder: merkle.PairOrderSorted, TargetFormat: models.TargetFormatAddress, OneSigID: 1, ContractAddr: goldenContract, Root: "0x78d26ee285ea49616ee06692b3b456d3358bfc4a8880343e0ae8f1ffc1066898"},
+	{Name: "v2-sorted-16", Seed: 11, Leaves: 16, LeafVersion: 2, PairOrder: merkle.PairOrderSorted, TargetFormat: models.TargetFormatAddress, OneSigID: 1, ContractAddr: goldenContract, Root: "0xe6845b14d63496717abe21ceeb02397fcf19855e81dc8464791aacf27191a219"},
+	{Name: "v3-positional-16", Seed: 12, Leaves: 16, LeafVersion: 3, PairOrder: merkle.PairOrderPositional, TargetFormat: models.TargetFormatAddress, OneSigID: 1, ContractAddr: goldenContract, Root: "0x9a4a35265c26b49e3643418439977e490311ead6a3602d191977ed560ec130a9"},
+	{Name: "v4-sorted", Seed: 13, Leaves: 7, LeafVersion: 4, PairOrder: merkle.PairOrderSorted, TargetFormat: models.TargetFormatAddress, OneSigID: 8453, ContractAddr: goldenContract, Root: "0x3c32f72eb0e6038790e3072712493ce5f62846881866b73c18bbe7a5c6a43424"},
+	{Name: "v4-positional", Seed: 14, Leaves: 7, LeafVersion: 4, PairOrder: merkle.PairOrderPositional, TargetFormat: models.TargetFormatAddress, OneSigID: 8453, ContractAddr: goldenContract, Root: "0x8902ba5b9f51dc319fc5c6166928c7a19190c9c59917bf567221ad048b8fd7a7"},
+	{Name: "v4-sorted-bytes32", Seed: 15, Leaves: 5, LeafVersion: 4, PairOrder: merkle.PairOrderSorted, TargetFormat: models.TargetFormatBytes32, OneSigID: 30168, ContractAddr: goldenContract, Root: "0x2f7d59309b2ccc0aab2cbb050bc0e1cd4cca9f6b734ff211132a1e58b5c65916"},
+	{Name: "v4-sorted-auto-salt", Seed: 16, Leaves: 7, LeafVersion: 4, PairOrder: merkle.PairOrderSorted, TargetFormat: models.TargetFormatAddress, AutoSalt: true, OneSigID: 8453, ContractAddr: goldenContract, Root: "0x8e8087e62bf2c4affb904555e42d071800f97416e1c920a0b5274b0e48d9e2a9"},
+	{Name: "v1-sorted-input-order", Seed: 17, Leaves: 7, LeafVersion: 1, PairOrder: merkle.PairOrderSorted, LeafOrder: merkle.LeafOrderInput, TargetFormat: models.TargetFormatAddress, OneSigID: 1, ContractAddr: goldenContract, Root: "0xc6dbd81499d9fcf6cd17c9dc732c1366dd1de0239eab8a6a7c6866ce47125f52"},
+	{Name: "v2-positional-by-nonce", Seed: 18, Leaves: 7, LeafVersion: 2, PairOrder: merkle.PairOrderPositional, LeafOrder: merkle.LeafOrderByNonce, TargetFormat: models.TargetFormatAddress, OneSigID: 10, ContractAddr: goldenContract, Root: "0x7c392edbfb4d5b96e87075047a2fc7797d13f4905b094e8eb1c6e893fe3bd29d"},
+	{Name: "v1-sorted-arity-4", Seed: 19, Leaves: 10, LeafVersion: 1, PairOrder: merkle.PairOrderSorted, Arity: 4, TargetFormat: models.TargetFormatAddress, OneSigID: 1, ContractAddr: goldenContract, Root: "0x6191adaadcc7ec119a114cb33bef40d320157d6e98825a9a1453f1f7295f50e1"},
+	{Name: "v4-positional-arity-3", Seed: 20, Leaves: 10, LeafVersion: 4, PairOrder: merkle.PairOrderPositional, LeafOrder: merkle.LeafOrderByOneSigNonce, Arity: 3, TargetFormat: models.TargetFormatAddress, OneSigID: 8453, ContractAddr: goldenContract, Root: "0x7e698bce28bcfea8c73d0e54501a31f307f775a6e751fb3af77157cb9805d526"},
+}
+
+// Options returns the tree options of the vector
+func (g Golden) Options() models.TreeOptions {
+	leafOrder := g.LeafOrder
+	if leafOrder == "" {
+		leafOrder = merkle.DefaultLeafOrder
+	}
+	options := merkle.TreeOptionsWith(g.PairOrder, leafOrder)
+	options.Arity = g.Arity
+	return options
+}
+
+// Batch generates the input batch of the vector. From version 3, the first
+// call of every odd nonce is a delegatecall, unless targets are bytes32 ids,
+// which are the 32 byte left padded form of the generated addresses.
+func (g Golden) Batch() (*models.TransactionBatch, error) {
+	batch, err := Batch(g.Seed, g.Leaves, g.LeafVersion)
+	if err != nil {
+		return nil, fmt.Errorf("golden vector %s: %w", g.Name, err)
+	}
+	if g.AutoSalt {
+		for i := range batch.Groups {
+			if batch.Groups[i].Nonce%2 == 0 {
+				batch.Groups[i].Salt = ""
+			}
+		}
+	}
+	if g.TargetFormat != models.TargetFormatBytes32 {
+		if g.LeafVersion >= utils.LeafEncodingVersionOperation {
+			MarkDelegateCalls(batch)
+		}
+		return batch, nil
+	}
+	batch.TargetFormat = models.TargetFormatBytes32
+	for i := range batch.Groups {
+		for c := range batch.Groups[i].Calls {
+			call := &batch.Groups[i].Calls[c]
+			call.To = fmt.Sprintf("0x%x", common.LeftPadBytes(common.HexToAddress(call.To).Bytes(), 32))
+		}
+	}
+	return batch, nil
+}
+
+// MarkDelegateCalls makes the first call of every odd nonce a delegatecall, so
+// batches encoded with leaf encoding version 3 cover both operations
+func MarkDelegateCalls(batch *models.TransactionBatch) {
+	for i := range batch.Groups {
+		if group := &batch.Groups[i]; group.Nonce%2 == 1 && len(group.Calls) > 0 {
+			group.Calls[0].Operation = models.OperationDelegateCall
+		}
+	}
+}
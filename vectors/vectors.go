@@ -0,0 +1,97 @@
+// Package vectors generates deterministic pseudo-random transaction batches
+// for cross-language conformance testing. Randomness comes from
+// keccak256(seed || counter), so other implementations can reproduce a batch
+// from its seed without depending on Go's math/rand.
+package vectors
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/big"
+
+	"merkle-cli/models"
+	"merkle-cli/utils"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// maxCallsPerLeaf and maxCallData bound the size of generated leaves
+const (
+	maxCallsPerLeaf = 3
+	maxCallData     = 132
+)
+
+// stream produces pseudo-random bytes from keccak256(seed || counter)
+type stream struct {
+	seed    []byte
+	counter uint64
+	buf     []byte
+}
+
+func (s *stream) bytes(n int) []byte {
+	for len(s.buf) < n {
+		block := make([]byte, 8)
+		binary.BigEndian.PutUint64(block, s.counter)
+		s.counter++
+		s.buf = append(s.buf, crypto.Keccak256(s.seed, block)...)
+	}
+	out := s.buf[:n]
+	s.buf = s.buf[n:]
+	return out
+}
+
+func (s *stream) uint64n(n uint64) uint64 {
+	return binary.BigEndian.Uint64(s.bytes(8)) % n
+}
+
+// Batch generates a batch of the given number of leaves from a seed, with the
+// fields of a leaf encoding version. Leaves get consecutive nonces; from
+// version 2 each also gets a validity window, and from version 4 a salt.
+func Batch(seed uint64, leaves int, version uint8) (*models.TransactionBatch, error) {
+	if leaves < 1 {
+		return nil, fmt.Errorf("at least one leaf is required")
+	}
+
+	seedBytes := make([]byte, 8)
+	binary.BigEndian.PutUint64(seedBytes, seed)
+	s := &stream{seed: seedBytes}
+
+	batch := &models.TransactionBatch{Groups: make([]models.TransactionGroup, 0, leaves)}
+	for i := 0; i < leaves; i++ {
+		group := models.TransactionGroup{Nonce: uint64(i)}
+
+		calls := 1 + int(s.uint64n(maxCallsPerLeaf))
+		for c := 0; c < calls; c++ {
+			value := new(big.Int)
+			if s.uint64n(2) == 1 {
+				value.SetBytes(s.bytes(8))
+			}
+
+			// Either a plain transfer or a selector followed by whole words
+			var data []byte
+			if s.uint64n(4) != 0 {
+				words := int(s.uint64n((maxCallData - 4) / 32))
+				data = s.bytes(4 + 32*words)
+			}
+
+			group.Calls = append(group.Calls, models.Call{
+				To:    common.BytesToAddress(s.bytes(common.AddressLength)).Hex(),
+				Value: value,
+				Data:  fmt.Sprintf("0x%x", data),
+			})
+		}
+
+		if version >= utils.LeafEncodingVersionWindowed {
+			group.ValidAfter = s.uint64n(1 << 32)
+			group.ValidUntil = group.ValidAfter + 1 + s.uint64n(1<<32)
+		}
+		if version >= utils.LeafEncodingVersionSalted {
+			group.Salt = fmt.Sprintf("0x%x", s.bytes(utils.SaltSize))
+		}
+
+		batch.Groups = append(batch.Groups, group)
+	}
+
+	return batch, nil
+}